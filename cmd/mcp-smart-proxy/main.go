@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"mcp-smart-proxy/internal/config"
+	"mcp-smart-proxy/internal/grpcserver"
+	"mcp-smart-proxy/internal/launcher"
+	"mcp-smart-proxy/internal/proxy"
+	"mcp-smart-proxy/internal/server"
+)
+
+func main() {
+	launcher.Run()
+
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	smartProxy, err := proxy.NewWithOptions(cfg.ConfigPath, proxy.Options{
+		ConfigTimeout:  cfg.ConfigTimeout,
+		ConfigHeaders:  cfg.ConfigHeaders,
+		LLMProvider:    cfg.LLMProvider,
+		ConnectTimeout: cfg.ConnectTimeout,
+		ReadTimeout:    cfg.ReadTimeout,
+		LogLevel:       cfg.LogLevel,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create proxy: %v", err)
+	}
+
+	if err := smartProxy.Initialize(context.Background()); err != nil {
+		smartProxy.Close()
+		log.Fatalf("Failed to initialize proxy: %v", err)
+	}
+
+	if cfg.GRPCAddr != "" {
+		go func() {
+			if err := grpcserver.New(smartProxy).Start(cfg.GRPCAddr); err != nil {
+				log.Fatalf("gRPC server failed: %v", err)
+			}
+		}()
+	}
+
+	// SIGINT and SIGTERM stop the HTTP server, then Close saves caches and stops the MCP servers
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	serveErr := server.NewWithOptions(smartProxy, server.Options{
+		MaxBodyBytes:      int64(cfg.MaxBodyBytes),
+		PathPrefix:        cfg.PathPrefix,
+		GzipMinBytes:      cfg.GzipMinBytes,
+		StrictJSON:        cfg.StrictJSON,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		BodyReadTimeout:   cfg.BodyReadTimeout,
+	}).Run(ctx, cfg.ListenAddr)
+	if err := smartProxy.Close(); err != nil {
+		log.Printf("Failed to close proxy cleanly: %v", err)
+	}
+	if serveErr != nil {
+		log.Fatalf("Server failed: %v", serveErr)
+	}
+	log.Printf("Proxy stopped")
+}
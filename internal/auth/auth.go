@@ -0,0 +1,33 @@
+// Package auth carries the authenticated API principal through contexts
+package auth
+
+import (
+	"context"
+	"strings"
+)
+
+// KeyHeader is the HTTP header (and gRPC metadata key, lower-cased) accepted as an alternative
+// to an Authorization bearer token
+const KeyHeader = "X-API-Key"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the principal
+func NewContext(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// FromContext returns the principal stored in ctx, or "" if the caller was not authenticated
+func FromContext(ctx context.Context) string {
+	principal, _ := ctx.Value(contextKey{}).(string)
+	return principal
+}
+
+// APIKey picks the API key from an Authorization header value ("Bearer <key>") or, failing
+// that, from the X-API-Key value
+func APIKey(authorization, apiKey string) string {
+	if token, ok := strings.CutPrefix(authorization, "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return strings.TrimSpace(apiKey)
+}
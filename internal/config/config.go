@@ -0,0 +1,216 @@
+// Package config collects the process-level settings of the proxy binary from environment
+// variables and command-line flags
+package config
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables read by Load; a flag given on the command line takes precedence
+const (
+	EnvConfigPath     = "MCP_CONFIG"
+	EnvListenAddr     = "MCP_PROXY_ADDR"
+	EnvGRPCAddr       = "MCP_PROXY_GRPC_ADDR"
+	EnvLLMProvider    = "LLM_PROVIDER"
+	EnvConfigTimeout  = "MCP_CONFIG_TIMEOUT"
+	EnvConfigHeaders  = "MCP_CONFIG_HEADERS"
+	EnvConnectTimeout = "MCP_CONNECT_TIMEOUT"
+	EnvReadTimeout    = "MCP_READ_TIMEOUT"
+	EnvLogLevel       = "LOG_LEVEL"
+
+	EnvPathPrefix        = "MCP_PROXY_PATH_PREFIX"
+	EnvMaxBodyBytes      = "MCP_MAX_BODY_BYTES"
+	EnvGzipMinBytes      = "MCP_GZIP_MIN_BYTES"
+	EnvStrictJSON        = "MCP_STRICT_JSON"
+	EnvReadHeaderTimeout = "MCP_READ_HEADER_TIMEOUT"
+	EnvBodyReadTimeout   = "MCP_BODY_READ_TIMEOUT"
+)
+
+// Config holds the settings the entrypoint needs before the MCP config file is read
+type Config struct {
+	ConfigPath     string        // MCP config file path or http(s) URL
+	ListenAddr     string        // HTTP listen address
+	GRPCAddr       string        // gRPC listen address; empty disables gRPC
+	LLMProvider    string        // openai or gemini; empty picks whichever API key is set
+	ConfigTimeout  time.Duration // limit for fetching a remote MCP config; 0 uses the proxy default
+	ConfigHeaders  headers       // extra request headers for a remote MCP config, e.g. Authorization
+	ConnectTimeout time.Duration // default server handshake timeout; 0 uses the proxy default
+	ReadTimeout    time.Duration // default server response timeout; 0 uses the proxy default
+	LogLevel       string        // default MCP logging level requested from servers; empty leaves server defaults
+
+	// HTTP API settings, passed to server.Options; zero values keep the server defaults
+	PathPrefix        string        // base path of the API routes
+	MaxBodyBytes      int           // largest accepted request body
+	GzipMinBytes      int           // smallest response that is compressed; negative disables compression
+	StrictJSON        bool          // reject request bodies with unknown fields
+	ReadHeaderTimeout time.Duration // limit for reading request headers; negative disables
+	BodyReadTimeout   time.Duration // limit for reading a JSON request body; negative disables
+}
+
+// Default returns the settings used when neither the environment nor flags set a value
+func Default() Config {
+	return Config{
+		ConfigPath: "./mcp.json",
+		ListenAddr: ":8080",
+	}
+}
+
+// Load builds the config from environment variables (read through getenv) and then args, which
+// override them. Addresses may reference other variables, e.g. MCP_PROXY_ADDR=":${PORT}".
+func Load(args []string, getenv func(string) string) (Config, error) {
+	cfg := Default()
+	if err := cfg.applyEnv(getenv); err != nil {
+		return cfg, err
+	}
+
+	flags := flag.NewFlagSet("mcp-smart-proxy", flag.ContinueOnError)
+	flags.StringVar(&cfg.ConfigPath, "config", cfg.ConfigPath, "Path or http(s) URL of the MCP configuration file (env "+EnvConfigPath+")")
+	flags.StringVar(&cfg.ListenAddr, "addr", cfg.ListenAddr, "Address to listen on (env "+EnvListenAddr+")")
+	flags.StringVar(&cfg.GRPCAddr, "grpc-addr", cfg.GRPCAddr, "Address for the gRPC service; empty disables it (env "+EnvGRPCAddr+")")
+	flags.StringVar(&cfg.LLMProvider, "llm-provider", cfg.LLMProvider, "LLM provider type, openai or gemini (env "+EnvLLMProvider+")")
+	flags.DurationVar(&cfg.ConfigTimeout, "config-timeout", cfg.ConfigTimeout, "Timeout for fetching a remote config (env "+EnvConfigTimeout+")")
+	flags.Var(&cfg.ConfigHeaders, "config-header", "Request header \"Name: value\" for fetching a remote config; repeatable (env "+EnvConfigHeaders+", one per line)")
+	flags.DurationVar(&cfg.ConnectTimeout, "connect-timeout", cfg.ConnectTimeout, "Default MCP server handshake timeout (env "+EnvConnectTimeout+")")
+	flags.DurationVar(&cfg.ReadTimeout, "read-timeout", cfg.ReadTimeout, "Default MCP server response timeout (env "+EnvReadTimeout+")")
+	flags.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "Default MCP logging level requested from servers (env "+EnvLogLevel+")")
+	flags.StringVar(&cfg.PathPrefix, "path-prefix", cfg.PathPrefix, "Base path of the HTTP API, default /api/v1 (env "+EnvPathPrefix+")")
+	flags.IntVar(&cfg.MaxBodyBytes, "max-body-bytes", cfg.MaxBodyBytes, "Largest accepted request body, default 1MB (env "+EnvMaxBodyBytes+")")
+	flags.IntVar(&cfg.GzipMinBytes, "gzip-min-bytes", cfg.GzipMinBytes, "Smallest compressed response, default 1KB; negative disables (env "+EnvGzipMinBytes+")")
+	flags.BoolVar(&cfg.StrictJSON, "strict-json", cfg.StrictJSON, "Reject request bodies with unknown fields (env "+EnvStrictJSON+")")
+	flags.DurationVar(&cfg.ReadHeaderTimeout, "read-header-timeout", cfg.ReadHeaderTimeout, "Timeout for reading request headers, default 10s; negative disables (env "+EnvReadHeaderTimeout+")")
+	flags.DurationVar(&cfg.BodyReadTimeout, "body-read-timeout", cfg.BodyReadTimeout, "Timeout for reading a request body, default 10s; negative disables (env "+EnvBodyReadTimeout+")")
+	if err := flags.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	cfg.ListenAddr = os.Expand(cfg.ListenAddr, getenv)
+	cfg.GRPCAddr = os.Expand(cfg.GRPCAddr, getenv)
+	return cfg, cfg.validate()
+}
+
+// applyEnv overrides the defaults with the environment variables that are set
+func (c *Config) applyEnv(getenv func(string) string) error {
+	texts := map[string]*string{
+		EnvConfigPath:  &c.ConfigPath,
+		EnvListenAddr:  &c.ListenAddr,
+		EnvGRPCAddr:    &c.GRPCAddr,
+		EnvLLMProvider: &c.LLMProvider,
+		EnvLogLevel:    &c.LogLevel,
+		EnvPathPrefix:  &c.PathPrefix,
+	}
+	for name, field := range texts {
+		if value := getenv(name); value != "" {
+			*field = value
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		EnvConfigTimeout:  &c.ConfigTimeout,
+		EnvConnectTimeout: &c.ConnectTimeout,
+		EnvReadTimeout:    &c.ReadTimeout,
+
+		EnvReadHeaderTimeout: &c.ReadHeaderTimeout,
+		EnvBodyReadTimeout:   &c.BodyReadTimeout,
+	}
+	for name, field := range durations {
+		value := getenv(name)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		*field = d
+	}
+
+	ints := map[string]*int{
+		EnvMaxBodyBytes: &c.MaxBodyBytes,
+		EnvGzipMinBytes: &c.GzipMinBytes,
+	}
+	for name, field := range ints {
+		value := getenv(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		*field = n
+	}
+
+	for _, line := range strings.Split(getenv(EnvConfigHeaders), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if err := c.ConfigHeaders.Set(line); err != nil {
+			return fmt.Errorf("%s: %w", EnvConfigHeaders, err)
+		}
+	}
+
+	if value := getenv(EnvStrictJSON); value != "" {
+		strict, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", EnvStrictJSON, err)
+		}
+		c.StrictJSON = strict
+	}
+	return nil
+}
+
+// validate rejects values the proxy would only fail on later
+func (c Config) validate() error {
+	switch c.LLMProvider {
+	case "", "openai", "gemini":
+	default:
+		return fmt.Errorf("unsupported LLM provider %q (expected openai or gemini)", c.LLMProvider)
+	}
+
+	switch c.LogLevel {
+	case "", "debug", "info", "notice", "warning", "error", "critical", "alert", "emergency":
+	default:
+		return fmt.Errorf("unsupported log level %q", c.LogLevel)
+	}
+
+	if c.ListenAddr == "" {
+		return fmt.Errorf("listen address is empty")
+	}
+	if c.MaxBodyBytes < 0 {
+		return fmt.Errorf("maximum body size %d is negative", c.MaxBodyBytes)
+	}
+	return nil
+}
+
+// headers collects "Name: value" request headers given as repeated flags or lines of a variable;
+// a later value for a name replaces an earlier one
+type headers map[string]string
+
+func (h headers) String() string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func (h *headers) Set(value string) error {
+	name, text, ok := strings.Cut(value, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return fmt.Errorf("invalid header %q (expected \"Name: value\")", value)
+	}
+	if *h == nil {
+		*h = make(headers)
+	}
+	(*h)[http.CanonicalHeaderKey(name)] = strings.TrimSpace(text)
+	return nil
+}
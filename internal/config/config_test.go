@@ -0,0 +1,75 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		want    func(cfg *Config)
+		wantErr bool
+	}{
+		{name: "defaults", want: func(cfg *Config) {}},
+		{
+			name: "server options from flags",
+			args: []string{"-path-prefix", "/mcp/api/v1", "-max-body-bytes", "2048", "-gzip-min-bytes", "-1", "-strict-json", "-read-header-timeout", "5s", "-body-read-timeout", "-1s"},
+			want: func(cfg *Config) {
+				cfg.PathPrefix, cfg.MaxBodyBytes, cfg.GzipMinBytes, cfg.StrictJSON = "/mcp/api/v1", 2048, -1, true
+				cfg.ReadHeaderTimeout, cfg.BodyReadTimeout = 5*time.Second, -time.Second
+			},
+		},
+		{
+			name: "server options from the environment",
+			env: map[string]string{
+				EnvPathPrefix: "/proxy", EnvMaxBodyBytes: "4096", EnvGzipMinBytes: "512", EnvStrictJSON: "true",
+				EnvReadHeaderTimeout: "3s", EnvBodyReadTimeout: "20s",
+			},
+			want: func(cfg *Config) {
+				cfg.PathPrefix, cfg.MaxBodyBytes, cfg.GzipMinBytes, cfg.StrictJSON = "/proxy", 4096, 512, true
+				cfg.ReadHeaderTimeout, cfg.BodyReadTimeout = 3*time.Second, 20*time.Second
+			},
+		},
+		{
+			name: "flags override the environment",
+			args: []string{"-max-body-bytes", "100", "-strict-json=false"},
+			env:  map[string]string{EnvMaxBodyBytes: "4096", EnvStrictJSON: "1"},
+			want: func(cfg *Config) { cfg.MaxBodyBytes = 100 },
+		},
+		{
+			name: "config headers from the environment and flags",
+			args: []string{"-config-header", "x-tenant: acme", "-config-header", "Authorization: Bearer flag"},
+			env:  map[string]string{EnvConfigHeaders: "Authorization: Bearer env\n\nX-Region: eu\n"},
+			want: func(cfg *Config) {
+				cfg.ConfigHeaders = headers{"Authorization": "Bearer flag", "X-Region": "eu", "X-Tenant": "acme"}
+			},
+		},
+		{name: "header without a value separator", args: []string{"-config-header", "Authorization"}, wantErr: true},
+		{name: "header without a name", env: map[string]string{EnvConfigHeaders: ": value"}, wantErr: true},
+		{name: "invalid size", env: map[string]string{EnvMaxBodyBytes: "1MB"}, wantErr: true},
+		{name: "invalid boolean", env: map[string]string{EnvStrictJSON: "sometimes"}, wantErr: true},
+		{name: "negative body limit", args: []string{"-max-body-bytes", "-1"}, wantErr: true},
+		{name: "invalid timeout", env: map[string]string{EnvBodyReadTimeout: "10"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load(tt.args, func(name string) string { return tt.env[name] })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			want := Default()
+			tt.want(&want)
+			if !reflect.DeepEqual(cfg, want) {
+				t.Errorf("Load() = %+v, want %+v", cfg, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+// Package grpcserver exposes the MCP Smart Proxy over gRPC alongside the HTTP API
+package grpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+
+	"mcp-smart-proxy/internal/auth"
+	"mcp-smart-proxy/internal/grpcserver/pb"
+	"mcp-smart-proxy/internal/requestid"
+	"mcp-smart-proxy/internal/server"
+	"mcp-smart-proxy/pkg/types"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Server implements the SmartProxy gRPC service on top of the same proxy as the HTTP server
+type Server struct {
+	pb.UnimplementedSmartProxyServer
+	proxy server.ProxyInterface
+}
+
+// New creates a new gRPC service backed by the proxy
+func New(proxy server.ProxyInterface) *Server {
+	return &Server{proxy: proxy}
+}
+
+// Start registers the service on a new gRPC server and serves it on the specified address
+func (s *Server) Start(addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Starting gRPC server on %s", addr)
+	return s.Serve(lis)
+}
+
+// Serve registers the service on a new gRPC server and serves it on lis
+func (s *Server) Serve(lis net.Listener) error {
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(s.authUnary), grpc.StreamInterceptor(s.authStream))
+	pb.RegisterSmartProxyServer(grpcServer, s)
+	return grpcServer.Serve(lis)
+}
+
+// authenticate resolves the API key in the call metadata ("authorization: Bearer <key>" or
+// "x-api-key") and stores the principal in the returned context
+func (s *Server) authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	principal, err := s.proxy.Authenticate(auth.APIKey(first(md.Get("authorization")), first(md.Get(auth.KeyHeader))))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if principal != "" {
+		ctx = auth.NewContext(ctx, principal)
+	}
+	return ctx, nil
+}
+
+func (s *Server) authUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) authStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticatedStream overrides the stream context with one carrying the principal
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// first returns the first metadata value, or ""
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// ListTools returns every cached tool
+func (s *Server) ListTools(ctx context.Context, req *pb.ListToolsRequest) (*pb.ListToolsResponse, error) {
+	tools, err := s.proxy.ListTools(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &pb.ListToolsResponse{Tools: make([]*pb.Tool, 0, len(tools))}
+	for _, tool := range tools {
+		pbTool, err := toProtoTool(tool)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to encode tool %s: %v", tool.Name, err)
+		}
+		resp.Tools = append(resp.Tools, pbTool)
+	}
+
+	return resp, nil
+}
+
+// DiscoverTools streams the recommended tools for a query in ranked order
+func (s *Server) DiscoverTools(req *pb.DiscoverToolsRequest, stream pb.SmartProxy_DiscoverToolsServer) error {
+	if req.GetQuery() == "" {
+		return status.Error(codes.InvalidArgument, "query is required")
+	}
+
+	proxyReq := types.ProxyRequest{Query: req.GetQuery(), Provider: req.GetProvider(), Tier: req.GetTier(), Tag: req.GetTag(), Exclude: req.GetExclude()}
+	err := s.proxy.DiscoverToolsStream(stream.Context(), proxyReq, func(tool types.Tool) error {
+		pbTool, err := toProtoTool(tool)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to encode tool %s: %v", tool.Name, err)
+		}
+		return stream.Send(pbTool)
+	})
+	if err != nil {
+		return toStatus(err)
+	}
+
+	return nil
+}
+
+// UseTool executes a tool and returns its result
+func (s *Server) UseTool(ctx context.Context, req *pb.UseToolRequest) (*pb.UseToolResponse, error) {
+	if req.GetTool() == "" {
+		return nil, status.Error(codes.InvalidArgument, "tool is required")
+	}
+
+	// As over HTTP, calls without an explicit ID get a fresh one
+	callID := req.GetCallId()
+	if callID == "" {
+		callID = requestid.New()
+	}
+	result, err := s.proxy.UseTool(ctx, req.GetTool(), types.ToolRequest{
+		Arguments: req.GetArguments().AsMap(),
+		Confirm:   req.GetConfirm(),
+		CallID:    callID,
+		NoCache:   req.GetNoCache(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	pbResult, err := structpb.NewStruct(result)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode result: %v", err)
+	}
+
+	return &pb.UseToolResponse{Result: pbResult, CallId: callID}, nil
+}
+
+// toStatus maps proxy errors onto gRPC status codes
+func toStatus(err error) error {
+	var confirmErr *types.ConfirmationRequiredError
+	var argErr *types.ForbiddenArgumentError
+	var missingErr *types.MissingArgumentsError
+	switch {
+	case errors.As(err, &confirmErr):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.As(err, &argErr):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.As(err, &missingErr):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, types.ErrUnknownProvider), errors.Is(err, types.ErrUnknownTier):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, types.ErrCallCancelled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, types.ErrCallIDInUse):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, types.ErrUnauthorized):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, types.ErrToolNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, types.ErrToolFailed):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, types.ErrCircuitOpen), errors.Is(err, types.ErrNoToolsAvailable), errors.Is(err, types.ErrServerUnavailable):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, types.ErrLLMTimeout), errors.Is(err, types.ErrToolTimeout), errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// toProtoTool converts a cached tool into its protobuf representation
+func toProtoTool(tool types.Tool) (*pb.Tool, error) {
+	schema, err := toProtoValue(tool.InputSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	pbTool := &pb.Tool{
+		Name:        tool.Name,
+		Description: tool.Description,
+		InputSchema: schema,
+		ServerName:  tool.ServerName,
+	}
+
+	if tool.Annotations != nil {
+		value, err := toProtoValue(tool.Annotations)
+		if err != nil {
+			return nil, err
+		}
+		pbTool.Annotations = value.GetStructValue()
+	}
+
+	return pbTool, nil
+}
+
+// toProtoValue converts any JSON-encodable value into a protobuf Value via its JSON form
+func toProtoValue(v interface{}) (*structpb.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+
+	return structpb.NewValue(generic)
+}
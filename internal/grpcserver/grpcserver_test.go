@@ -0,0 +1,259 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+
+	"mcp-smart-proxy/internal/grpcserver/pb"
+	"mcp-smart-proxy/internal/proxy"
+	"mcp-smart-proxy/pkg/types"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// fakeClient is an in-memory MCP server with the tools read, write and the destructive delete;
+// each call answers with the tool name and its arguments
+type fakeClient struct{}
+
+func (fakeClient) ListTools(ctx context.Context) ([]types.Tool, error) {
+	destructive := true
+	var tools []types.Tool
+	for _, name := range []string{"read", "write", "delete"} {
+		tool := types.Tool{Name: name, Description: "The " + name + " tool", InputSchema: map[string]interface{}{"type": "object"}}
+		if name == "delete" {
+			tool.Annotations = &types.ToolAnnotations{DestructiveHint: &destructive}
+		}
+		tools = append(tools, tool)
+	}
+	return tools, nil
+}
+
+func (fakeClient) CallTool(ctx context.Context, toolName string, arguments map[string]interface{}) (map[string]interface{}, error) {
+	return map[string]interface{}{"tool": toolName, "arguments": arguments}, nil
+}
+
+func (fakeClient) Close() error { return nil }
+
+// fakeProvider selects every candidate tool
+type fakeProvider struct{}
+
+func (fakeProvider) SelectBestTools(ctx context.Context, query string, tools []types.Tool) ([]types.Tool, error) {
+	return tools, nil
+}
+
+// newTestClient serves the gRPC service of an initialized in-memory proxy over an in-process
+// connection and returns a client for it
+func newTestClient(t *testing.T) pb.SmartProxyClient {
+	t.Helper()
+	config := types.MCPConfig{Proxy: types.ProxySettings{
+		RequireConfirmation: true,
+		Tenants: map[string]types.Tenant{
+			"reader": {APIKey: "reader-key", Tools: []string{"read"}},
+			"writer": {APIKey: "writer-key"},
+		},
+	}}
+	p, err := proxy.NewInMemory(config, fakeProvider{}, map[string]types.MCPClient{"files": fakeClient{}})
+	if err != nil {
+		t.Fatalf("NewInMemory() error = %v", err)
+	}
+	if err := p.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	go New(p).Serve(lis)
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		lis.Close()
+		p.Close()
+	})
+	return pb.NewSmartProxyClient(conn)
+}
+
+// withKey returns a context sending apiKey as a bearer token, or no key when it is empty
+func withKey(apiKey string) context.Context {
+	if apiKey == "" {
+		return context.Background()
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+apiKey)
+}
+
+func TestListTools(t *testing.T) {
+	client := newTestClient(t)
+
+	tests := []struct {
+		name      string
+		apiKey    string
+		wantTools string // sorted and comma-separated
+		wantCode  codes.Code
+	}{
+		{name: "unrestricted tenant", apiKey: "writer-key", wantTools: "delete,read,write"},
+		{name: "restricted tenant", apiKey: "reader-key", wantTools: "read"},
+		{name: "no key", wantCode: codes.Unauthenticated},
+		{name: "unknown key", apiKey: "nope", wantCode: codes.Unauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.ListTools(withKey(tt.apiKey), &pb.ListToolsRequest{})
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("ListTools() error = %v, want code %s", err, tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			if got := toolNames(resp.GetTools()); got != tt.wantTools {
+				t.Errorf("ListTools() = %s, want %s", got, tt.wantTools)
+			}
+		})
+	}
+}
+
+func TestDiscoverTools(t *testing.T) {
+	client := newTestClient(t)
+
+	tests := []struct {
+		name      string
+		apiKey    string
+		req       *pb.DiscoverToolsRequest
+		wantTools string // sorted and comma-separated
+		wantCode  codes.Code
+	}{
+		{name: "streams the recommendations", apiKey: "writer-key", req: &pb.DiscoverToolsRequest{Query: "files"}, wantTools: "delete,read,write"},
+		{name: "excluded tools are left out", apiKey: "writer-key", req: &pb.DiscoverToolsRequest{Query: "files", Exclude: []string{"delete", "write"}}, wantTools: "read"},
+		{name: "restricted tenant", apiKey: "reader-key", req: &pb.DiscoverToolsRequest{Query: "files"}, wantTools: "read"},
+		{name: "query is required", apiKey: "writer-key", req: &pb.DiscoverToolsRequest{}, wantCode: codes.InvalidArgument},
+		{name: "unknown tier", apiKey: "writer-key", req: &pb.DiscoverToolsRequest{Query: "files", Tier: "huge"}, wantCode: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := client.DiscoverTools(withKey(tt.apiKey), tt.req)
+			if err != nil {
+				t.Fatalf("DiscoverTools() error = %v", err)
+			}
+			var tools []*pb.Tool
+			for {
+				tool, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					if status.Code(err) != tt.wantCode {
+						t.Fatalf("DiscoverTools() stream error = %v, want code %s", err, tt.wantCode)
+					}
+					return
+				}
+				tools = append(tools, tool)
+			}
+			if tt.wantCode != codes.OK {
+				t.Fatalf("DiscoverTools() succeeded, want code %s", tt.wantCode)
+			}
+			if got := toolNames(tools); got != tt.wantTools {
+				t.Errorf("DiscoverTools() streamed %s, want %s", got, tt.wantTools)
+			}
+		})
+	}
+}
+
+func TestUseTool(t *testing.T) {
+	client := newTestClient(t)
+	arguments, _ := structpb.NewStruct(map[string]interface{}{"path": "/tmp"})
+
+	tests := []struct {
+		name       string
+		apiKey     string
+		req        *pb.UseToolRequest
+		wantCode   codes.Code
+		wantCallID string // empty expects a generated one
+	}{
+		{name: "calls the tool", apiKey: "writer-key", req: &pb.UseToolRequest{Tool: "read", Arguments: arguments}},
+		{name: "keeps the call ID", apiKey: "writer-key", req: &pb.UseToolRequest{Tool: "read", CallId: "call-1"}, wantCallID: "call-1"},
+		{name: "destructive tool without confirm", apiKey: "writer-key", req: &pb.UseToolRequest{Tool: "delete"}, wantCode: codes.FailedPrecondition},
+		{name: "destructive tool with confirm", apiKey: "writer-key", req: &pb.UseToolRequest{Tool: "delete", Confirm: true}},
+		{name: "unknown tool", apiKey: "writer-key", req: &pb.UseToolRequest{Tool: "missing"}, wantCode: codes.NotFound},
+		{name: "tool outside the tenant", apiKey: "reader-key", req: &pb.UseToolRequest{Tool: "write"}, wantCode: codes.NotFound},
+		{name: "tool is required", apiKey: "writer-key", req: &pb.UseToolRequest{}, wantCode: codes.InvalidArgument},
+		{name: "no key", req: &pb.UseToolRequest{Tool: "read"}, wantCode: codes.Unauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.UseTool(withKey(tt.apiKey), tt.req)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("UseTool() error = %v, want code %s", err, tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			result := resp.GetResult().AsMap()
+			if result["tool"] != tt.req.GetTool() {
+				t.Errorf("UseTool() result = %v, want an answer from %s", result, tt.req.GetTool())
+			}
+			if want := tt.req.GetArguments().AsMap(); fmt.Sprint(result["arguments"]) != fmt.Sprint(want) {
+				t.Errorf("UseTool() passed arguments %v, want %v", result["arguments"], want)
+			}
+			if callID := resp.GetCallId(); callID == "" || (tt.wantCallID != "" && callID != tt.wantCallID) {
+				t.Errorf("UseTool() call ID = %q, want %q", callID, tt.wantCallID)
+			}
+		})
+	}
+}
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{err: &types.ConfirmationRequiredError{Tool: "delete"}, want: codes.FailedPrecondition},
+		{err: &types.ForbiddenArgumentError{Tool: "write", Argument: "path"}, want: codes.PermissionDenied},
+		{err: &types.MissingArgumentsError{Tool: "write"}, want: codes.InvalidArgument},
+		{err: types.ErrUnknownProvider, want: codes.InvalidArgument},
+		{err: types.ErrCallCancelled, want: codes.Canceled},
+		{err: types.ErrCallIDInUse, want: codes.AlreadyExists},
+		{err: types.ErrUnauthorized, want: codes.Unauthenticated},
+		{err: fmt.Errorf("tool read: %w", types.ErrToolNotFound), want: codes.NotFound},
+		{err: types.ErrToolFailed, want: codes.Aborted},
+		{err: types.ErrCircuitOpen, want: codes.Unavailable},
+		{err: types.ErrServerUnavailable, want: codes.Unavailable},
+		{err: types.ErrToolTimeout, want: codes.DeadlineExceeded},
+		{err: fmt.Errorf("%w after 20s", types.ErrLLMTimeout), want: codes.DeadlineExceeded},
+		{err: context.DeadlineExceeded, want: codes.DeadlineExceeded},
+		{err: context.Canceled, want: codes.Canceled},
+		{err: errors.New("boom"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		if got := status.Code(toStatus(tt.err)); got != tt.want {
+			t.Errorf("toStatus(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+// toolNames returns the sorted, comma-separated names of tools
+func toolNames(tools []*pb.Tool) string {
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.GetName())
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,211 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: smartproxy.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	SmartProxy_ListTools_FullMethodName     = "/smartproxy.v1.SmartProxy/ListTools"
+	SmartProxy_DiscoverTools_FullMethodName = "/smartproxy.v1.SmartProxy/DiscoverTools"
+	SmartProxy_UseTool_FullMethodName       = "/smartproxy.v1.SmartProxy/UseTool"
+)
+
+// SmartProxyClient is the client API for SmartProxy service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SmartProxyClient interface {
+	ListTools(ctx context.Context, in *ListToolsRequest, opts ...grpc.CallOption) (*ListToolsResponse, error)
+	DiscoverTools(ctx context.Context, in *DiscoverToolsRequest, opts ...grpc.CallOption) (SmartProxy_DiscoverToolsClient, error)
+	UseTool(ctx context.Context, in *UseToolRequest, opts ...grpc.CallOption) (*UseToolResponse, error)
+}
+
+type smartProxyClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSmartProxyClient(cc grpc.ClientConnInterface) SmartProxyClient {
+	return &smartProxyClient{cc}
+}
+
+func (c *smartProxyClient) ListTools(ctx context.Context, in *ListToolsRequest, opts ...grpc.CallOption) (*ListToolsResponse, error) {
+	out := new(ListToolsResponse)
+	err := c.cc.Invoke(ctx, SmartProxy_ListTools_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *smartProxyClient) DiscoverTools(ctx context.Context, in *DiscoverToolsRequest, opts ...grpc.CallOption) (SmartProxy_DiscoverToolsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &SmartProxy_ServiceDesc.Streams[0], SmartProxy_DiscoverTools_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &smartProxyDiscoverToolsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SmartProxy_DiscoverToolsClient interface {
+	Recv() (*Tool, error)
+	grpc.ClientStream
+}
+
+type smartProxyDiscoverToolsClient struct {
+	grpc.ClientStream
+}
+
+func (x *smartProxyDiscoverToolsClient) Recv() (*Tool, error) {
+	m := new(Tool)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *smartProxyClient) UseTool(ctx context.Context, in *UseToolRequest, opts ...grpc.CallOption) (*UseToolResponse, error) {
+	out := new(UseToolResponse)
+	err := c.cc.Invoke(ctx, SmartProxy_UseTool_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SmartProxyServer is the server API for SmartProxy service.
+// All implementations must embed UnimplementedSmartProxyServer
+// for forward compatibility
+type SmartProxyServer interface {
+	ListTools(context.Context, *ListToolsRequest) (*ListToolsResponse, error)
+	DiscoverTools(*DiscoverToolsRequest, SmartProxy_DiscoverToolsServer) error
+	UseTool(context.Context, *UseToolRequest) (*UseToolResponse, error)
+	mustEmbedUnimplementedSmartProxyServer()
+}
+
+// UnimplementedSmartProxyServer must be embedded to have forward compatible implementations.
+type UnimplementedSmartProxyServer struct {
+}
+
+func (UnimplementedSmartProxyServer) ListTools(context.Context, *ListToolsRequest) (*ListToolsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTools not implemented")
+}
+func (UnimplementedSmartProxyServer) DiscoverTools(*DiscoverToolsRequest, SmartProxy_DiscoverToolsServer) error {
+	return status.Errorf(codes.Unimplemented, "method DiscoverTools not implemented")
+}
+func (UnimplementedSmartProxyServer) UseTool(context.Context, *UseToolRequest) (*UseToolResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UseTool not implemented")
+}
+func (UnimplementedSmartProxyServer) mustEmbedUnimplementedSmartProxyServer() {}
+
+// UnsafeSmartProxyServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SmartProxyServer will
+// result in compilation errors.
+type UnsafeSmartProxyServer interface {
+	mustEmbedUnimplementedSmartProxyServer()
+}
+
+func RegisterSmartProxyServer(s grpc.ServiceRegistrar, srv SmartProxyServer) {
+	s.RegisterService(&SmartProxy_ServiceDesc, srv)
+}
+
+func _SmartProxy_ListTools_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListToolsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SmartProxyServer).ListTools(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SmartProxy_ListTools_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SmartProxyServer).ListTools(ctx, req.(*ListToolsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SmartProxy_DiscoverTools_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DiscoverToolsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SmartProxyServer).DiscoverTools(m, &smartProxyDiscoverToolsServer{stream})
+}
+
+type SmartProxy_DiscoverToolsServer interface {
+	Send(*Tool) error
+	grpc.ServerStream
+}
+
+type smartProxyDiscoverToolsServer struct {
+	grpc.ServerStream
+}
+
+func (x *smartProxyDiscoverToolsServer) Send(m *Tool) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _SmartProxy_UseTool_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UseToolRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SmartProxyServer).UseTool(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SmartProxy_UseTool_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SmartProxyServer).UseTool(ctx, req.(*UseToolRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SmartProxy_ServiceDesc is the grpc.ServiceDesc for SmartProxy service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SmartProxy_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "smartproxy.v1.SmartProxy",
+	HandlerType: (*SmartProxyServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListTools",
+			Handler:    _SmartProxy_ListTools_Handler,
+		},
+		{
+			MethodName: "UseTool",
+			Handler:    _SmartProxy_UseTool_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "DiscoverTools",
+			Handler:       _SmartProxy_DiscoverTools_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "smartproxy.proto",
+}
@@ -0,0 +1,125 @@
+// Package launcher starts MCP server processes under resource limits. Limits cannot be set on
+// another process before it runs, so a server is started through a launcher: a copy of the
+// current binary that applies them to itself and then execs the server. Binaries that start
+// servers with limits must call Run first thing in main
+package launcher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// limitsEnv marks a launcher process: the binary re-executed by Start with the limits to apply,
+// as JSON, before it execs the server command given in its arguments
+const limitsEnv = "MCP_SMART_PROXY_LIMITS"
+
+// reportFD is the descriptor on which a launcher reports why it could not exec the server; it is
+// closed on exec, so the parent reads EOF once the server is running
+const reportFD = 3
+
+// enabled is set by Run, before any server is started, in binaries that can act as the launcher
+var enabled bool
+
+// Run lets the binary start servers with resource limits. In a process started as a launcher it
+// applies the limits and execs the server, never returning; otherwise it returns at once.
+// Binaries that do not call it fail to start servers with limits rather than re-executing
+// themselves with no way to apply them
+func Run() {
+	enabled = true
+	if spec, ok := os.LookupEnv(limitsEnv); ok {
+		launch(spec)
+	}
+}
+
+// Start starts a server process with its limits in effect from its first instruction
+func Start(cmd *exec.Cmd, limits types.ResourceLimits) error {
+	if limits == (types.ResourceLimits{}) {
+		return cmd.Start()
+	}
+	if cmd.Err != nil {
+		return cmd.Err
+	}
+	if !enabled {
+		return errors.New("resource limits need the binary to call launcher.Run at startup")
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to apply resource limits: %w", err)
+	}
+	spec, err := json.Marshal(limits)
+	if err != nil {
+		return err
+	}
+	report, reportWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer report.Close()
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, limitsEnv+"="+string(spec))
+	cmd.Args = append([]string{self, cmd.Path}, cmd.Args...)
+	cmd.Path = self
+	cmd.ExtraFiles = []*os.File{reportWriter}
+
+	err = cmd.Start()
+	reportWriter.Close()
+	if err != nil {
+		return err
+	}
+	if failure, _ := io.ReadAll(report); len(failure) > 0 {
+		cmd.Wait()
+		return errors.New(string(failure))
+	}
+	return nil
+}
+
+// launch applies the limits in spec to the current process and execs the server command, never
+// returning; os.Args holds the launcher, the server's path and then the server's argv
+func launch(spec string) {
+	// The nice value belongs to the thread and survives exec only from the thread it was set on
+	runtime.LockOSThread()
+	report := os.NewFile(reportFD, "launcher-report")
+	fail := func(err error) {
+		fmt.Fprintf(report, "failed to apply resource limits: %v", err)
+		os.Exit(126)
+	}
+
+	var limits types.ResourceLimits
+	if err := json.Unmarshal([]byte(spec), &limits); err != nil {
+		fail(err)
+	}
+	if len(os.Args) < 3 {
+		fail(errors.New("launcher started without a command"))
+	}
+	os.Unsetenv(limitsEnv)
+
+	if limits.MemoryMB > 0 {
+		bytes := uint64(limits.MemoryMB) << 20
+		if err := unix.Setrlimit(unix.RLIMIT_AS, &unix.Rlimit{Cur: bytes, Max: bytes}); err != nil {
+			fail(fmt.Errorf("memory limit: %w", err))
+		}
+	}
+	if limits.Nice != 0 {
+		if err := unix.Setpriority(unix.PRIO_PROCESS, 0, limits.Nice); err != nil {
+			fail(fmt.Errorf("nice value: %w", err))
+		}
+	}
+
+	unix.CloseOnExec(reportFD)
+	err := unix.Exec(os.Args[1], os.Args[2:], os.Environ())
+	fmt.Fprintf(report, "failed to start %s: %v", os.Args[1], err)
+	os.Exit(126)
+}
@@ -0,0 +1,127 @@
+package launcher
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// TestMain lets the test binary act as the launcher Start re-executes
+func TestMain(m *testing.M) {
+	Run()
+	os.Exit(m.Run())
+}
+
+// limitsScript prints the address space limit, the nice value and whether the launcher's
+// environment variable leaked into the server
+const limitsScript = "ulimit -v; nice; echo ${" + limitsEnv + ":-unset}"
+
+func TestStartLimits(t *testing.T) {
+	baseline, err := exec.Command("sh", "-c", limitsScript).Output()
+	if err != nil {
+		t.Skipf("sh unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		limits types.ResourceLimits
+		want   string
+	}{
+		{name: "no limits", want: string(baseline)},
+		{name: "memory", limits: types.ResourceLimits{MemoryMB: 256}, want: "262144\n" + strings.SplitAfterN(string(baseline), "\n", 2)[1]},
+		{name: "memory and nice", limits: types.ResourceLimits{MemoryMB: 512, Nice: 7}, want: "524288\n7\nunset\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command("sh", "-c", limitsScript)
+			stdout, err := cmd.StdoutPipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := Start(cmd, tt.limits); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			got, _ := io.ReadAll(stdout)
+			if err := cmd.Wait(); err != nil {
+				t.Fatalf("server exited with %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("server saw limits %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartReportsExecFailure(t *testing.T) {
+	script := t.TempDir() + "/server"
+	if err := os.WriteFile(script, []byte("#!/nonexistent/interpreter\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Start(exec.Command(script), types.ResourceLimits{Nice: 5})
+	if err == nil || !strings.Contains(err.Error(), "failed to start "+script) {
+		t.Errorf("Start() error = %v, want the launcher's exec failure", err)
+	}
+}
+
+func TestStartAppliesLimitsToTheServer(t *testing.T) {
+	cmd := exec.Command("sleep", "10")
+	if err := Start(cmd, types.ResourceLimits{MemoryMB: 256, Nice: 7}); err != nil {
+		t.Skipf("sleep unavailable: %v", err)
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+	proc := fmt.Sprintf("/proc/%d/", cmd.Process.Pid)
+
+	// Start returns once the launcher has exec'd the server, so the process is sleep by now
+	if comm, err := os.ReadFile(proc + "comm"); err != nil || string(comm) != "sleep\n" {
+		t.Fatalf("process is %q (%v), want the server itself", comm, err)
+	}
+
+	limits, err := os.ReadFile(proc + "limits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var addressSpace string
+	for _, line := range strings.Split(string(limits), "\n") {
+		if strings.HasPrefix(line, "Max address space") {
+			addressSpace = strings.Join(strings.Fields(line)[3:5], " ")
+		}
+	}
+	if want := "268435456 268435456"; addressSpace != want {
+		t.Errorf("address space soft and hard limits = %q, want %q", addressSpace, want)
+	}
+
+	// the nice value is the 19th field of stat, the 17th after the parenthesised command name
+	stat, err := os.ReadFile(proc + "stat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := strings.Fields(string(stat)[strings.LastIndexByte(string(stat), ')')+1:])
+	if len(fields) < 17 || fields[16] != "7" {
+		t.Errorf("stat = %q, want nice value 7", stat)
+	}
+}
+
+func TestStartWithoutRun(t *testing.T) {
+	enabled = false
+	defer func() { enabled = true }()
+
+	if err := Start(exec.Command("true"), types.ResourceLimits{Nice: 5}); err == nil {
+		t.Error("Start() with limits succeeded in a binary that did not call Run, want an error")
+	}
+	cmd := exec.Command("true")
+	if err := Start(cmd, types.ResourceLimits{}); err != nil {
+		t.Errorf("Start() without limits error = %v", err)
+	} else {
+		cmd.Wait()
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package launcher
+
+import (
+	"errors"
+	"os/exec"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// Run does nothing; resource limits are only supported on Linux
+func Run() {}
+
+// Start starts a server process; resource limits are only supported on Linux
+func Start(cmd *exec.Cmd, limits types.ResourceLimits) error {
+	if limits != (types.ResourceLimits{}) {
+		return errors.New("resource limits are only supported on Linux")
+	}
+	return cmd.Start()
+}
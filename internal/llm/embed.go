@@ -0,0 +1,55 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	genai "github.com/google/generative-ai-go/genai"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Embedding models used by the embeddings selection strategy
+const (
+	openAIEmbeddingModel = openai.SmallEmbedding3
+	geminiEmbeddingModel = "embedding-001"
+)
+
+// Embed computes embeddings for texts with OpenAI, in input order
+func (p *OpenAIProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	resp, err := p.client.CreateEmbeddings(ctx, openai.EmbeddingRequest{Input: texts, Model: openAIEmbeddingModel})
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, data := range resp.Data {
+		if data.Index < 0 || data.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", data.Index)
+		}
+		vectors[data.Index] = data.Embedding
+	}
+	return vectors, nil
+}
+
+// Embed computes embeddings for texts with Gemini, in input order
+func (p *GeminiProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	model := p.client.EmbeddingModel(geminiEmbeddingModel)
+	batch := model.NewBatch()
+	for _, text := range texts {
+		batch.AddContent(genai.Text(text))
+	}
+
+	resp, err := model.BatchEmbedContents(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings from Gemini, got %d", len(texts), len(resp.Embeddings))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for i, embedding := range resp.Embeddings {
+		vectors[i] = embedding.Values
+	}
+	return vectors, nil
+}
@@ -0,0 +1,125 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// Tier is a vendor-neutral model class, resolved to a concrete model per provider type
+type Tier string
+
+// Model tiers, from cheapest and quickest to most capable
+const (
+	TierFast     Tier = "fast"
+	TierBalanced Tier = "balanced"
+	TierBest     Tier = "best"
+)
+
+// DefaultTier is used when neither the request nor the config picks a tier
+const DefaultTier = TierBalanced
+
+// ModelRegistry maps provider type ("openai", "gemini") and tier to a model name
+type ModelRegistry map[string]map[Tier]string
+
+// DefaultModels returns the built-in registry; the balanced tier keeps each provider's historical default
+func DefaultModels() ModelRegistry {
+	return ModelRegistry{
+		"openai": {
+			TierFast:     "gpt-4o-mini",
+			TierBalanced: "gpt-3.5-turbo",
+			TierBest:     "gpt-4o",
+		},
+		"gemini": {
+			TierFast:     "gemini-1.5-flash",
+			TierBalanced: "gemini-pro",
+			TierBest:     "gemini-1.5-pro",
+		},
+	}
+}
+
+// ParseTier validates a tier name; an empty name yields an empty tier meaning "use the default"
+func ParseTier(name string) (Tier, error) {
+	switch tier := Tier(name); tier {
+	case "", TierFast, TierBalanced, TierBest:
+		return tier, nil
+	default:
+		return "", fmt.Errorf("%w: %q (expected fast, balanced or best)", types.ErrUnknownTier, name)
+	}
+}
+
+// WithOverrides returns a copy of the registry with the configured models applied on top
+func (r ModelRegistry) WithOverrides(overrides map[string]map[string]string) (ModelRegistry, error) {
+	merged := make(ModelRegistry, len(r))
+	for providerType, models := range r {
+		merged[providerType] = make(map[Tier]string, len(models))
+		for tier, model := range models {
+			merged[providerType][tier] = model
+		}
+	}
+
+	for providerType, models := range overrides {
+		if merged[providerType] == nil {
+			merged[providerType] = make(map[Tier]string)
+		}
+		for name, model := range models {
+			tier, err := ParseTier(name)
+			if err == nil && tier == "" {
+				err = fmt.Errorf("%w: empty tier name", types.ErrUnknownTier)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("models.%s: %w", providerType, err)
+			}
+			merged[providerType][tier] = model
+		}
+	}
+	return merged, nil
+}
+
+// Resolve returns the model for a provider type and tier, with an empty tier meaning DefaultTier
+func (r ModelRegistry) Resolve(providerType string, tier Tier) (string, error) {
+	if tier == "" {
+		tier = DefaultTier
+	}
+	model, ok := r[providerType][tier]
+	if !ok {
+		return "", fmt.Errorf("no %s model configured for provider type %s", tier, providerType)
+	}
+	return model, nil
+}
+
+type tierKey struct{}
+
+// WithTier returns a context asking providers to select tools with the given tier's model
+func WithTier(ctx context.Context, tier Tier) context.Context {
+	return context.WithValue(ctx, tierKey{}, tier)
+}
+
+// tierFromContext returns the tier requested in ctx, or fallback when none was set
+func tierFromContext(ctx context.Context, fallback Tier) Tier {
+	if tier, ok := ctx.Value(tierKey{}).(Tier); ok && tier != "" {
+		return tier
+	}
+	return fallback
+}
+
+// modelSelector picks a provider's model per request: a pinned model wins, otherwise the tier is resolved
+type modelSelector struct {
+	providerType string
+	pinned       string // LLMProviderConfig.Model; used for every tier when set
+	registry     ModelRegistry
+	defaultTier  Tier
+}
+
+// model returns the model to use for the request carried by ctx
+func (s modelSelector) model(ctx context.Context) (string, error) {
+	if s.pinned != "" {
+		return s.pinned, nil
+	}
+	registry := s.registry
+	if registry == nil {
+		registry = DefaultModels()
+	}
+	return registry.Resolve(s.providerType, tierFromContext(ctx, s.defaultTier))
+}
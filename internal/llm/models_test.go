@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestResolveDefaultModels(t *testing.T) {
+	want := map[string]map[Tier]string{
+		"openai": {TierFast: "gpt-4o-mini", TierBalanced: "gpt-3.5-turbo", TierBest: "gpt-4o", "": "gpt-3.5-turbo"},
+		"gemini": {TierFast: "gemini-1.5-flash", TierBalanced: "gemini-pro", TierBest: "gemini-1.5-pro", "": "gemini-pro"},
+	}
+	for providerType, tiers := range want {
+		for tier, wantModel := range tiers {
+			if got, err := DefaultModels().Resolve(providerType, tier); err != nil || got != wantModel {
+				t.Errorf("Resolve(%s, %q) = %q, %v, want %s", providerType, tier, got, err, wantModel)
+			}
+		}
+	}
+	if got, err := DefaultModels().Resolve("claude", TierFast); err == nil {
+		t.Errorf("Resolve(claude, fast) = %q, want an error for an unknown provider type", got)
+	}
+}
+
+func TestModelOverrides(t *testing.T) {
+	registry, err := DefaultModels().WithOverrides(map[string]map[string]string{
+		"openai": {"best": "gpt-4.1"},
+		"local":  {"fast": "llama3"},
+	})
+	if err != nil {
+		t.Fatalf("WithOverrides() error = %v", err)
+	}
+
+	tests := []struct {
+		providerType string
+		tier         Tier
+		want         string
+		wantErr      bool
+	}{
+		{providerType: "openai", tier: TierBest, want: "gpt-4.1"},
+		{providerType: "openai", tier: TierFast, want: "gpt-4o-mini"},
+		{providerType: "local", tier: TierFast, want: "llama3"},
+		{providerType: "local", tier: TierBest, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := registry.Resolve(tt.providerType, tt.tier)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("Resolve(%s, %s) = %q, %v, want %q", tt.providerType, tt.tier, got, err, tt.want)
+		}
+	}
+	if got, _ := DefaultModels().Resolve("openai", TierBest); got != "gpt-4o" {
+		t.Errorf("WithOverrides() changed the registry it was called on: best = %s", got)
+	}
+
+	for _, bad := range []string{"huge", ""} {
+		if _, err := DefaultModels().WithOverrides(map[string]map[string]string{"openai": {bad: "x"}}); !errors.Is(err, types.ErrUnknownTier) {
+			t.Errorf("WithOverrides() with tier %q error = %v, want ErrUnknownTier", bad, err)
+		}
+	}
+}
+
+func TestParseTier(t *testing.T) {
+	for _, name := range []string{"", "fast", "balanced", "best"} {
+		if tier, err := ParseTier(name); err != nil || string(tier) != name {
+			t.Errorf("ParseTier(%q) = %q, %v", name, tier, err)
+		}
+	}
+	if _, err := ParseTier("Fast"); !errors.Is(err, types.ErrUnknownTier) {
+		t.Errorf("ParseTier(Fast) error = %v, want ErrUnknownTier", err)
+	}
+}
+
+func TestModelSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector modelSelector
+		ctx      context.Context
+		want     string
+	}{
+		{name: "default tier", selector: modelSelector{providerType: "openai", defaultTier: TierBalanced}, ctx: context.Background(), want: "gpt-3.5-turbo"},
+		{name: "configured default tier", selector: modelSelector{providerType: "openai", defaultTier: TierBest}, ctx: context.Background(), want: "gpt-4o"},
+		{name: "requested tier", selector: modelSelector{providerType: "gemini", defaultTier: TierBest}, ctx: WithTier(context.Background(), TierFast), want: "gemini-1.5-flash"},
+		{name: "pinned model wins", selector: modelSelector{providerType: "openai", pinned: "gpt-4.1-nano"}, ctx: WithTier(context.Background(), TierBest), want: "gpt-4.1-nano"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := tt.selector.model(tt.ctx); err != nil || got != tt.want {
+				t.Errorf("model() = %q, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectionUsesTheTierModel(t *testing.T) {
+	fake, provider := newFakeOpenAI(t, `["read"]`)
+	if _, err := provider.SelectBestTools(WithTier(context.Background(), TierFast), "read", catalog("read")); err != nil {
+		t.Fatalf("SelectBestTools() error = %v", err)
+	}
+	if requests := fake.received(); len(requests) != 1 || requests[0].Model != "gpt-4o-mini" {
+		t.Errorf("LLM received %+v, want one request for gpt-4o-mini", requests)
+	}
+}
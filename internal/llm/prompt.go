@@ -0,0 +1,85 @@
+package llm
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// defaultPromptTemplate is the tool selection prompt used when none is configured
+//
+//go:embed prompt.tmpl
+var defaultPromptTemplate string
+
+// PromptData is the data available to a selection prompt template
+type PromptData struct {
+	Query    string // the user's query
+	Tools    string // candidate tools as a JSON array
+	MaxTools int    // selection limit; 0 means no limit
+	Hints    string // "- <server>: <hint>" lines for the candidates' servers that have a selection hint
+}
+
+type hintsKey struct{}
+
+// WithServerHints returns a context carrying selection hints by server name, which the prompt
+// passes on for the servers whose tools are candidates
+func WithServerHints(ctx context.Context, hints map[string]string) context.Context {
+	return context.WithValue(ctx, hintsKey{}, hints)
+}
+
+// serverHints renders the hints in ctx for the servers offering the tools, in server name order
+func serverHints(ctx context.Context, tools []types.Tool) string {
+	hints, _ := ctx.Value(hintsKey{}).(map[string]string)
+	if len(hints) == 0 {
+		return ""
+	}
+
+	seen := make(map[string]bool)
+	var servers []string
+	for _, tool := range tools {
+		if hints[tool.ServerName] != "" && !seen[tool.ServerName] {
+			seen[tool.ServerName] = true
+			servers = append(servers, tool.ServerName)
+		}
+	}
+	sort.Strings(servers)
+
+	lines := make([]string, len(servers))
+	for i, server := range servers {
+		lines[i] = fmt.Sprintf("- %s: %s", server, hints[server])
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ParsePromptTemplate parses a selection prompt template, e.g. from config
+func ParsePromptTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// defaultPrompt is the parsed default template
+var defaultPrompt = template.Must(ParsePromptTemplate(defaultPromptTemplate))
+
+// selectionPrompt renders the tool selection prompt shared by all providers
+func (s Settings) selectionPrompt(ctx context.Context, query string, tools []types.Tool) (string, error) {
+	tmpl := s.Prompt
+	if tmpl == nil {
+		tmpl = defaultPrompt
+	}
+
+	toolsJSON, _ := json.Marshal(tools)
+	var prompt strings.Builder
+	if err := tmpl.Execute(&prompt, PromptData{Query: query, Tools: string(toolsJSON), MaxTools: s.MaxTools, Hints: serverHints(ctx, tools)}); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	return prompt.String(), nil
+}
@@ -0,0 +1,96 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestCustomPromptIsSent(t *testing.T) {
+	fake, provider := newFakeOpenAI(t, `["lesen"]`)
+	prompt, err := ParsePromptTemplate(`Wähle höchstens {{.MaxTools}} Werkzeuge für „{{.Query}}“.{{if .Hints}} Hinweise: {{.Hints}}{{end}} Werkzeuge: {{.Tools}}`)
+	if err != nil {
+		t.Fatalf("ParsePromptTemplate() error = %v", err)
+	}
+	provider.settings.Prompt = prompt
+	provider.settings.MaxTools = 2
+
+	tools := []types.Tool{{Name: "lesen", Description: "Datei lesen", ServerName: "files"}}
+	ctx := WithServerHints(context.Background(), map[string]string{"files": "nur lokal", "db": "unused"})
+	if _, err := provider.SelectBestTools(ctx, "Konfiguration lesen", tools); err != nil {
+		t.Fatalf("SelectBestTools() error = %v", err)
+	}
+
+	requests := fake.received()
+	if len(requests) != 1 || len(requests[0].Messages) != 1 {
+		t.Fatalf("LLM received %+v, want one message", requests)
+	}
+	got := requests[0].Messages[0].Content
+	want := `Wähle höchstens 2 Werkzeuge für „Konfiguration lesen“. Hinweise: - files: nur lokal Werkzeuge: [{"name":"lesen","description":"Datei lesen"`
+	if !strings.HasPrefix(got, want) {
+		t.Errorf("prompt = %q, want it to start with %q", got, want)
+	}
+}
+
+func TestPromptTemplateErrors(t *testing.T) {
+	if _, err := ParsePromptTemplate("{{.Query"); err == nil || !strings.Contains(err.Error(), "invalid prompt template") {
+		t.Errorf("ParsePromptTemplate() of a malformed template error = %v, want invalid prompt template", err)
+	}
+
+	prompt, err := ParsePromptTemplate("{{.Question}}")
+	if err != nil {
+		t.Fatalf("ParsePromptTemplate() error = %v", err)
+	}
+	if _, err := (Settings{Prompt: prompt}).selectionPrompt(context.Background(), "find files", catalog("read")); err == nil {
+		t.Error("selectionPrompt() with an unknown field succeeded, want a render error")
+	}
+}
+
+func TestServerHintsInPrompt(t *testing.T) {
+	tools := []types.Tool{
+		{Name: "s3_list", ServerName: "aws"},
+		{Name: "read", ServerName: "files"},
+		{Name: "s3_get", ServerName: "aws"},
+		{Name: "query", ServerName: "db"},
+	}
+
+	tests := []struct {
+		name      string
+		hints     map[string]string
+		wantNotes string // empty expects no notes section
+	}{
+		{name: "no hints"},
+		{
+			name:      "hints of candidate servers in name order",
+			hints:     map[string]string{"files": "local files only", "aws": "use only for AWS resources"},
+			wantNotes: "- aws: use only for AWS resources\n- files: local files only",
+		},
+		{name: "hints of other servers are left out", hints: map[string]string{"github": "repositories"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.hints != nil {
+				ctx = WithServerHints(ctx, tt.hints)
+			}
+			prompt, err := (Settings{}).selectionPrompt(ctx, "list my buckets", tools)
+			if err != nil {
+				t.Fatalf("selectionPrompt() error = %v", err)
+			}
+
+			hasNotes := strings.Contains(prompt, "Notes on the servers")
+			if hasNotes != (tt.wantNotes != "") {
+				t.Errorf("prompt = %q, want a notes section = %v", prompt, tt.wantNotes != "")
+			}
+			if tt.wantNotes != "" && !strings.Contains(prompt, "(match them by serverName):\n"+tt.wantNotes+"\n") {
+				t.Errorf("prompt = %q, want the notes %q", prompt, tt.wantNotes)
+			}
+			if strings.Contains(prompt, "repositories") {
+				t.Errorf("prompt = %q, want no hint for a server without candidates", prompt)
+			}
+		})
+	}
+}
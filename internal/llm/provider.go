@@ -6,69 +6,135 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
 	"mcp-smart-proxy/pkg/types"
 
-	"github.com/sashabaranov/go-openai"
 	genai "github.com/google/generative-ai-go/genai"
+	"github.com/sashabaranov/go-openai"
 	"google.golang.org/api/option"
 )
 
 // OpenAIProvider implements LLMProvider using OpenAI's API
 type OpenAIProvider struct {
-	client *openai.Client
+	client   *openai.Client
+	models   modelSelector
+	settings Settings
 }
 
-// NewOpenAIProvider creates a new OpenAI provider
+// NewOpenAIProvider creates a new OpenAI provider. Requests go to OPENAI_BASE_URL when it is set,
+// e.g. an enterprise gateway or an OpenAI-compatible server, and to the default endpoint otherwise
 func NewOpenAIProvider(apiKey string) *OpenAIProvider {
-	client := openai.NewClient(apiKey)
-	return &OpenAIProvider{client: client}
+	config := openai.DefaultConfig(apiKey)
+	if baseURL := os.Getenv("OPENAI_BASE_URL"); baseURL != "" {
+		config.BaseURL = strings.TrimSuffix(baseURL, "/")
+	}
+	client := openai.NewClientWithConfig(config)
+	return &OpenAIProvider{client: client, models: modelSelector{providerType: "openai", defaultTier: DefaultTier}, settings: DefaultSettings()}
 }
 
 // SelectBestTools selects the most relevant tools using OpenAI
 func (p *OpenAIProvider) SelectBestTools(ctx context.Context, query string, availableTools []types.Tool) ([]types.Tool, error) {
-	toolsJSON, _ := json.Marshal(availableTools)
-
-	prompt := fmt.Sprintf(`You are a tool selection expert. Given the user query and available tools, select the most relevant tools that would help answer the query.
-
-RULES:
-- Select AT MOST 5 tools 
-- Rank them by relevance (most relevant first)
-- Include tools that could directly solve the query
-- Include tools that could provide supporting information
-- Always prioritize quality over quantity
-
-User Query: %s
+	debug, err := p.SelectBestToolsDebug(ctx, query, availableTools)
+	if err != nil {
+		return nil, err
+	}
+	return debug.SelectedTools, nil
+}
 
-Available Tools:
-%s
+// SelectBestToolsDebug selects tools using OpenAI and reports the prompt and raw completion
+func (p *OpenAIProvider) SelectBestToolsDebug(ctx context.Context, query string, availableTools []types.Tool) (*types.DiscoveryDebug, error) {
+	model, err := p.models.model(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-Return a JSON array of tool names only, ranked by relevance. Example: ["most_relevant", "second_choice", "supporting_tool"]`,
-		query, string(toolsJSON))
+	prompt, err := p.settings.selectionPrompt(ctx, query, availableTools)
+	if err != nil {
+		return nil, err
+	}
 
-	resp, err := p.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model: openai.GPT3Dot5Turbo,
+	chatReq := openai.ChatCompletionRequest{
+		Model: model,
 		Messages: []openai.ChatCompletionMessage{
 			{Role: openai.ChatMessageRoleUser, Content: prompt},
 		},
-		MaxTokens: 200,
-	})
+		MaxTokens: p.settings.selectionMaxTokens(),
+	}
+
+	resp, err := p.client.CreateChatCompletion(ctx, chatReq)
 
 	if err != nil {
 		return nil, err
 	}
 
+	raw := resp.Choices[0].Message.Content
 	var selectedNames []string
-	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &selectedNames); err != nil {
+	if err := json.Unmarshal([]byte(raw), &selectedNames); err != nil {
 		return nil, err
 	}
 
-	return filterToolsByNames(selectedNames, availableTools), nil
+	return &types.DiscoveryDebug{
+		Prompt:         prompt,
+		CandidateTools: availableTools,
+		RawResponse:    raw,
+		SelectedTools:  filterToolsByNames(selectedNames, availableTools, p.settings.MaxTools),
+	}, nil
+}
+
+// CreateMessage serves an MCP sampling request with an OpenAI chat completion
+func (p *OpenAIProvider) CreateMessage(ctx context.Context, req types.SamplingRequest) (*types.SamplingResult, error) {
+	model, err := p.models.model(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []openai.ChatCompletionMessage
+	if req.SystemPrompt != "" {
+		messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: req.SystemPrompt})
+	}
+	for _, message := range req.Messages {
+		role := openai.ChatMessageRoleUser
+		if message.Role == "assistant" {
+			role = openai.ChatMessageRoleAssistant
+		}
+		messages = append(messages, openai.ChatCompletionMessage{Role: role, Content: message.Text})
+	}
+
+	chatReq := openai.ChatCompletionRequest{
+		Model:     model,
+		Messages:  messages,
+		MaxTokens: req.MaxTokens,
+	}
+	if req.Temperature != nil {
+		chatReq.Temperature = float32(*req.Temperature)
+	}
+
+	resp, err := p.client.CreateChatCompletion(ctx, chatReq)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no response from OpenAI")
+	}
+
+	stopReason := "endTurn"
+	if resp.Choices[0].FinishReason == openai.FinishReasonLength {
+		stopReason = "maxTokens"
+	}
+
+	return &types.SamplingResult{
+		Text:       resp.Choices[0].Message.Content,
+		Model:      resp.Model,
+		StopReason: stopReason,
+	}, nil
 }
 
 // GeminiProvider implements LLMProvider using Google's Gemini API
 type GeminiProvider struct {
-	client *genai.Client
+	client   *genai.Client
+	models   modelSelector
+	settings Settings
 }
 
 // NewGeminiProvider creates a new Gemini provider
@@ -78,47 +144,116 @@ func NewGeminiProvider(apiKey string) (*GeminiProvider, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &GeminiProvider{client: client}, nil
+	return &GeminiProvider{client: client, models: modelSelector{providerType: "gemini", defaultTier: DefaultTier}, settings: DefaultSettings()}, nil
 }
 
 // SelectBestTools selects the most relevant tools using Gemini
 func (p *GeminiProvider) SelectBestTools(ctx context.Context, query string, availableTools []types.Tool) ([]types.Tool, error) {
-	model := p.client.GenerativeModel("gemini-pro")
+	debug, err := p.SelectBestToolsDebug(ctx, query, availableTools)
+	if err != nil {
+		return nil, err
+	}
+	return debug.SelectedTools, nil
+}
 
-	toolsJSON, _ := json.Marshal(availableTools)
-	prompt := fmt.Sprintf(`You are a tool selection expert. Given the user query and available tools, select the most relevant tools that would help answer the query.
+// SelectBestToolsDebug selects tools using Gemini and reports the prompt and raw response
+func (p *GeminiProvider) SelectBestToolsDebug(ctx context.Context, query string, availableTools []types.Tool) (*types.DiscoveryDebug, error) {
+	modelName, err := p.models.model(ctx)
+	if err != nil {
+		return nil, err
+	}
+	model := p.client.GenerativeModel(modelName)
+
+	prompt, err := p.settings.selectionPrompt(ctx, query, availableTools)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
+	if err != nil {
+		return nil, err
+	}
 
-RULES:
-- Select AT MOST 5 tools
-- Rank them by relevance (most relevant first) 
-- Include tools that could directly solve the query
-- Include tools that could provide supporting information
-- Always prioritize quality over quantity
+	raw, err := geminiResponseText(resp)
+	if err != nil {
+		return nil, err
+	}
 
-User Query: %s
+	var selectedNames []string
+	if err := json.Unmarshal([]byte(raw), &selectedNames); err != nil {
+		return nil, err
+	}
 
-Available Tools:
-%s
+	return &types.DiscoveryDebug{
+		Prompt:         prompt,
+		CandidateTools: availableTools,
+		RawResponse:    raw,
+		SelectedTools:  filterToolsByNames(selectedNames, availableTools, p.settings.MaxTools),
+	}, nil
+}
 
-Return only a JSON array of tool names, ranked by relevance. Example: ["most_relevant", "second_choice", "supporting_tool"]`,
-		query, string(toolsJSON))
+// CreateMessage serves an MCP sampling request with a Gemini chat session
+func (p *GeminiProvider) CreateMessage(ctx context.Context, req types.SamplingRequest) (*types.SamplingResult, error) {
+	if len(req.Messages) == 0 {
+		return nil, fmt.Errorf("sampling request has no messages")
+	}
 
-	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
+	modelName, err := p.models.model(ctx)
 	if err != nil {
 		return nil, err
 	}
+	model := p.client.GenerativeModel(modelName)
+	if req.MaxTokens > 0 {
+		model.SetMaxOutputTokens(int32(req.MaxTokens))
+	}
+	if req.Temperature != nil {
+		model.SetTemperature(float32(*req.Temperature))
+	}
 
-	if len(resp.Candidates) == 0 {
-		return nil, fmt.Errorf("no response from Gemini")
+	// This Gemini API version has no system instruction, so the system prompt leads the conversation
+	chat := model.StartChat()
+	if req.SystemPrompt != "" {
+		chat.History = append(chat.History, &genai.Content{Role: "user", Parts: []genai.Part{genai.Text(req.SystemPrompt)}})
+	}
+	for _, message := range req.Messages[:len(req.Messages)-1] {
+		role := "user"
+		if message.Role == "assistant" {
+			role = "model"
+		}
+		chat.History = append(chat.History, &genai.Content{Role: role, Parts: []genai.Part{genai.Text(message.Text)}})
 	}
 
-	content := resp.Candidates[0].Content.Parts[0]
-	var selectedNames []string
-	if err := json.Unmarshal([]byte(fmt.Sprintf("%v", content)), &selectedNames); err != nil {
+	resp, err := chat.SendMessage(ctx, genai.Text(req.Messages[len(req.Messages)-1].Text))
+	if err != nil {
 		return nil, err
 	}
 
-	return filterToolsByNames(selectedNames, availableTools), nil
+	text, err := geminiResponseText(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.SamplingResult{Text: text, Model: modelName, StopReason: "endTurn"}, nil
+}
+
+// geminiResponseText concatenates the text parts of the first candidate in a Gemini response
+func geminiResponseText(resp *genai.GenerateContentResponse) (string, error) {
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return "", fmt.Errorf("no response from Gemini")
+	}
+
+	var text strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if t, ok := part.(genai.Text); ok {
+			text.WriteString(string(t))
+		}
+	}
+
+	if text.Len() == 0 {
+		return "", fmt.Errorf("no text in Gemini response")
+	}
+
+	return text.String(), nil
 }
 
 // Close closes the Gemini client
@@ -127,37 +262,115 @@ func (p *GeminiProvider) Close() error {
 }
 
 // NewProvider creates an LLM provider based on environment variables
-func NewProvider() (types.LLMProvider, error) {
+func NewProvider(settings Settings) (types.LLMProvider, error) {
 	if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
-		return NewOpenAIProvider(apiKey), nil
+		provider := NewOpenAIProvider(apiKey)
+		provider.settings = settings
+		provider.models = settings.modelSelector("openai", "")
+		return provider, nil
 	}
 
 	if apiKey := os.Getenv("GEMINI_API_KEY"); apiKey != "" {
-		return NewGeminiProvider(apiKey)
+		provider, err := NewGeminiProvider(apiKey)
+		if err != nil {
+			return nil, err
+		}
+		provider.settings = settings
+		provider.models = settings.modelSelector("gemini", "")
+		return provider, nil
 	}
 
 	return nil, fmt.Errorf("no LLM provider configured. Set OPENAI_API_KEY or GEMINI_API_KEY")
 }
 
-// filterToolsByNames filters tools by their names and limits to max 5 tools
-func filterToolsByNames(selectedNames []string, availableTools []types.Tool) []types.Tool {
+// NewProviderOfType creates a provider of the given type ("openai" or "gemini") keyed by its
+// default environment variable; an empty type behaves like NewProvider
+func NewProviderOfType(providerType string, settings Settings) (types.LLMProvider, error) {
+	if providerType == "" {
+		return NewProvider(settings)
+	}
+	return newConfiguredProvider(types.LLMProviderConfig{Type: providerType}, settings)
+}
+
+// NewProviders creates the named LLM providers described by the config
+func NewProviders(configs map[string]types.LLMProviderConfig, settings Settings) (map[string]types.LLMProvider, error) {
+	providers := make(map[string]types.LLMProvider, len(configs))
+	for name, cfg := range configs {
+		provider, err := newConfiguredProvider(cfg, settings)
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: %w", name, err)
+		}
+		providers[name] = provider
+	}
+	return providers, nil
+}
+
+// newConfiguredProvider creates a single provider from its config
+func newConfiguredProvider(cfg types.LLMProviderConfig, settings Settings) (types.LLMProvider, error) {
+	switch cfg.Type {
+	case "openai":
+		apiKey, err := apiKeyFromEnv(cfg.APIKeyEnv, "OPENAI_API_KEY")
+		if err != nil {
+			return nil, err
+		}
+		provider := NewOpenAIProvider(apiKey)
+		provider.settings = settings
+		provider.models = settings.modelSelector(cfg.Type, cfg.Model)
+		return provider, nil
+
+	case "gemini":
+		apiKey, err := apiKeyFromEnv(cfg.APIKeyEnv, "GEMINI_API_KEY")
+		if err != nil {
+			return nil, err
+		}
+		provider, err := NewGeminiProvider(apiKey)
+		if err != nil {
+			return nil, err
+		}
+		provider.settings = settings
+		provider.models = settings.modelSelector(cfg.Type, cfg.Model)
+		return provider, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported provider type %q", cfg.Type)
+	}
+}
+
+// apiKeyFromEnv reads an API key from the named env var, falling back to a default var name
+func apiKeyFromEnv(name, fallback string) (string, error) {
+	if name == "" {
+		name = fallback
+	}
+	apiKey := os.Getenv(name)
+	if apiKey == "" {
+		return "", fmt.Errorf("%s is not set", name)
+	}
+	return apiKey, nil
+}
+
+// filterToolsByNames maps the LLM's ranked names to catalog tools, limited to maxTools tools (0
+// means no limit). The result follows the LLM's order: names not in the catalog are skipped and a
+// repeated name keeps only its first position, so neither uses up one of the maxTools places
+func filterToolsByNames(selectedNames []string, availableTools []types.Tool, maxTools int) []types.Tool {
 	var selectedTools []types.Tool
 	toolMap := make(map[string]types.Tool)
 	for _, tool := range availableTools {
 		toolMap[tool.Name] = tool
 	}
 
-	// Limit to at most 5 tools
-	maxTools := 5
-	if len(selectedNames) > maxTools {
-		selectedNames = selectedNames[:maxTools]
-	}
-
+	seen := make(map[string]bool, len(selectedNames))
 	for _, name := range selectedNames {
-		if tool, exists := toolMap[name]; exists {
-			selectedTools = append(selectedTools, tool)
+		if maxTools > 0 && len(selectedTools) >= maxTools {
+			break
 		}
+
+		tool, exists := toolMap[name]
+		if !exists || seen[name] {
+			continue
+		}
+		seen[name] = true
+		selectedTools = append(selectedTools, tool)
 	}
 
 	return selectedTools
-}
\ No newline at end of file
+}
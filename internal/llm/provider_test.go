@@ -0,0 +1,290 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+
+	genai "github.com/google/generative-ai-go/genai"
+	"github.com/sashabaranov/go-openai"
+)
+
+// fakeOpenAI is an OpenAI-compatible endpoint that answers every chat completion with reply and
+// records the requests it receives
+type fakeOpenAI struct {
+	mu       sync.Mutex
+	reply    string
+	requests []openai.ChatCompletionRequest
+}
+
+// newFakeOpenAI serves a fakeOpenAI and returns it with a provider pointed at it through
+// OPENAI_BASE_URL
+func newFakeOpenAI(t *testing.T, reply string) (*fakeOpenAI, *OpenAIProvider) {
+	t.Helper()
+	fake := &fakeOpenAI{reply: reply}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		if r.URL.Path != "/chat/completions" || json.NewDecoder(r.Body).Decode(&req) != nil {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		fake.mu.Lock()
+		fake.requests = append(fake.requests, req)
+		fake.mu.Unlock()
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{Model: req.Model, Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: fake.reply}, FinishReason: openai.FinishReasonStop},
+		}})
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("OPENAI_BASE_URL", server.URL)
+	return fake, NewOpenAIProvider("test-key")
+}
+
+// received returns the chat completion requests the endpoint has seen
+func (f *fakeOpenAI) received() []openai.ChatCompletionRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]openai.ChatCompletionRequest(nil), f.requests...)
+}
+
+// catalog returns tools with the given names
+func catalog(names ...string) []types.Tool {
+	var tools []types.Tool
+	for _, name := range names {
+		tools = append(tools, types.Tool{Name: name, Description: "The " + name + " tool"})
+	}
+	return tools
+}
+
+func TestSelectBestToolsDebug(t *testing.T) {
+	reply := `["write", "missing", "read"]`
+	fake, provider := newFakeOpenAI(t, reply)
+
+	debug, err := provider.SelectBestToolsDebug(context.Background(), "update the config file", catalog("read", "write", "delete"))
+	if err != nil {
+		t.Fatalf("SelectBestToolsDebug() error = %v", err)
+	}
+
+	requests := fake.received()
+	if len(requests) != 1 || len(requests[0].Messages) != 1 || requests[0].Messages[0].Content != debug.Prompt {
+		t.Fatalf("LLM received %+v, want one message carrying the reported prompt", requests)
+	}
+	for _, want := range []string{"update the config file", `"read"`, `"write"`, `"delete"`} {
+		if !strings.Contains(debug.Prompt, want) {
+			t.Errorf("prompt does not mention %s:\n%s", want, debug.Prompt)
+		}
+	}
+	if debug.RawResponse != reply {
+		t.Errorf("raw response = %q, want %q", debug.RawResponse, reply)
+	}
+	if got := toolNames(debug.CandidateTools); got != "read,write,delete" {
+		t.Errorf("candidate tools = %s, want the whole catalog", got)
+	}
+	if got := toolNames(debug.SelectedTools); got != "write,read" {
+		t.Errorf("selected tools = %s, want write,read", got)
+	}
+}
+
+func TestOpenAIBaseURL(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "gateway path", path: "/gateway/v1"},
+		{name: "trailing slash", path: "/gateway/v1/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var paths, auths []string
+			gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				paths = append(paths, r.URL.Path)
+				auths = append(auths, r.Header.Get("Authorization"))
+				mu.Unlock()
+				json.NewEncoder(w).Encode(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{
+					{Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: `["read"]`}},
+				}})
+			}))
+			t.Cleanup(gateway.Close)
+			t.Setenv("OPENAI_BASE_URL", gateway.URL+tt.path)
+
+			tools, err := NewOpenAIProvider("test-key").SelectBestTools(context.Background(), "read a file", catalog("read", "write"))
+			if err != nil || toolNames(tools) != "read" {
+				t.Fatalf("SelectBestTools() = %s, %v, want read from the gateway", toolNames(tools), err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if len(paths) != 1 || paths[0] != "/gateway/v1/chat/completions" || auths[0] != "Bearer test-key" {
+				t.Errorf("gateway received %q with %q, want one /gateway/v1/chat/completions request with the API key", paths, auths)
+			}
+		})
+	}
+}
+
+func TestSelectBestToolsRejectsProse(t *testing.T) {
+	_, provider := newFakeOpenAI(t, "I would use the read tool")
+	if tools, err := provider.SelectBestTools(context.Background(), "read a file", catalog("read")); err == nil {
+		t.Errorf("SelectBestTools() = %v, want an error for a reply that is not a JSON array", tools)
+	}
+}
+
+func TestFilterToolsByNames(t *testing.T) {
+	available := catalog("a", "b", "c", "d", "e", "f", "g")
+
+	tests := []struct {
+		name     string
+		selected []string
+		maxTools int
+		want     string
+	}{
+		{name: "capped", selected: []string{"a", "b", "c", "d", "e", "f", "g"}, maxTools: 5, want: "a,b,c,d,e"},
+		{name: "no limit returns every ranked tool", selected: []string{"g", "f", "e", "d", "c", "b", "a"}, maxTools: 0, want: "g,f,e,d,c,b,a"},
+		{name: "no limit keeps only ranked tools", selected: []string{"c", "a"}, maxTools: 0, want: "c,a"},
+		{name: "fewer ranked than the cap", selected: []string{"b"}, maxTools: 5, want: "b"},
+		{name: "duplicates keep their first position", selected: []string{"c", "a", "c", "b", "a"}, maxTools: 0, want: "c,a,b"},
+		{name: "duplicates do not use up the cap", selected: []string{"a", "a", "a", "b", "c"}, maxTools: 2, want: "a,b"},
+		{name: "names outside the catalog are skipped in place", selected: []string{"x", "d", "y", "b"}, maxTools: 0, want: "d,b"},
+		{name: "names outside the catalog do not use up the cap", selected: []string{"x", "y", "z", "e", "f", "g"}, maxTools: 2, want: "e,f"},
+		{name: "nothing in the catalog", selected: []string{"x", "y"}, maxTools: 5, want: ""},
+		{name: "names are case sensitive", selected: []string{"A", "a"}, maxTools: 5, want: "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toolNames(filterToolsByNames(tt.selected, available, tt.maxTools)); got != tt.want {
+				t.Errorf("filterToolsByNames() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectionPromptLimit(t *testing.T) {
+	tests := []struct {
+		maxTools int
+		want     string
+		dontWant string
+	}{
+		{maxTools: 3, want: "Select AT MOST 3 tools", dontWant: "no upper limit"},
+		{maxTools: 0, want: "Select EVERY tool that is relevant, with no upper limit", dontWant: "AT MOST"},
+	}
+
+	for _, tt := range tests {
+		prompt, err := Settings{MaxTools: tt.maxTools}.selectionPrompt(context.Background(), "find files", catalog("read"))
+		if err != nil {
+			t.Fatalf("selectionPrompt() error = %v", err)
+		}
+		if !strings.Contains(prompt, tt.want) || strings.Contains(prompt, tt.dontWant) {
+			t.Errorf("prompt for MaxTools %d = %q, want %q and not %q", tt.maxTools, prompt, tt.want, tt.dontWant)
+		}
+	}
+}
+
+func TestUnlimitedSelection(t *testing.T) {
+	fake, provider := newFakeOpenAI(t, `["a", "b", "c", "d", "e", "f", "g"]`)
+	provider.settings.MaxTools = 0
+
+	tools, err := provider.SelectBestTools(context.Background(), "everything", catalog("a", "b", "c", "d", "e", "f", "g"))
+	if err != nil {
+		t.Fatalf("SelectBestTools() error = %v", err)
+	}
+	if len(tools) != 7 {
+		t.Errorf("SelectBestTools() returned %d tools, want all 7", len(tools))
+	}
+	if requests := fake.received(); len(requests) != 1 || requests[0].MaxTokens != 0 {
+		t.Errorf("LLM received %+v, want one request without a token cap", requests)
+	}
+}
+
+func TestOpenAICreateMessage(t *testing.T) {
+	fake, provider := newFakeOpenAI(t, "Paris")
+	temperature := 0.5
+
+	result, err := provider.CreateMessage(context.Background(), types.SamplingRequest{
+		SystemPrompt: "Answer briefly",
+		Messages: []types.SamplingMessage{
+			{Role: "user", Text: "Capital of Spain?"},
+			{Role: "assistant", Text: "Madrid"},
+			{Role: "user", Text: "And France?"},
+		},
+		MaxTokens:   20,
+		Temperature: &temperature,
+	})
+	if err != nil {
+		t.Fatalf("CreateMessage() error = %v", err)
+	}
+	if result.Text != "Paris" || result.StopReason != "endTurn" || result.Model == "" {
+		t.Errorf("CreateMessage() = %+v, want Paris with endTurn and the model", result)
+	}
+
+	requests := fake.received()
+	if len(requests) != 1 {
+		t.Fatalf("LLM received %d requests, want 1", len(requests))
+	}
+	var messages []string
+	for _, message := range requests[0].Messages {
+		messages = append(messages, message.Role+": "+message.Content)
+	}
+	want := "system: Answer briefly|user: Capital of Spain?|assistant: Madrid|user: And France?"
+	if got := strings.Join(messages, "|"); got != want {
+		t.Errorf("LLM received messages %q, want %q", got, want)
+	}
+	if requests[0].MaxTokens != 20 || requests[0].Temperature != 0.5 {
+		t.Errorf("LLM received maxTokens %d and temperature %v, want 20 and 0.5", requests[0].MaxTokens, requests[0].Temperature)
+	}
+}
+
+func TestGeminiResponseText(t *testing.T) {
+	response := func(parts ...genai.Part) *genai.GenerateContentResponse {
+		return &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{Content: &genai.Content{Role: "model", Parts: parts}}}}
+	}
+
+	tests := []struct {
+		name    string
+		resp    *genai.GenerateContentResponse
+		want    string
+		wantErr bool
+	}{
+		{name: "single text part", resp: response(genai.Text(`["read", "write"]`)), want: `["read", "write"]`},
+		{name: "array split across parts", resp: response(genai.Text(`["read", `), genai.Text(`"write"]`)), want: `["read", "write"]`},
+		{name: "non-text parts are skipped", resp: response(genai.Blob{MIMEType: "image/png", Data: []byte{1}}, genai.Text(`["read"]`)), want: `["read"]`},
+		{name: "only the first candidate is read", resp: &genai.GenerateContentResponse{Candidates: []*genai.Candidate{
+			{Content: &genai.Content{Parts: []genai.Part{genai.Text(`["read"]`)}}},
+			{Content: &genai.Content{Parts: []genai.Part{genai.Text(`["write"]`)}}},
+		}}, want: `["read"]`},
+		{name: "no candidates", resp: &genai.GenerateContentResponse{}, wantErr: true},
+		{name: "candidate without content", resp: &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{}}}, wantErr: true},
+		{name: "no text parts", resp: response(genai.Blob{MIMEType: "image/png"}), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := geminiResponseText(tt.resp)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Fatalf("geminiResponseText() = %q, %v, want %q", got, err, tt.want)
+			}
+			if err == nil {
+				var names []string
+				if err := json.Unmarshal([]byte(got), &names); err != nil {
+					t.Errorf("extracted text %q is not a JSON array: %v", got, err)
+				}
+			}
+		})
+	}
+}
+
+// toolNames returns the comma-separated names of tools in order
+func toolNames(tools []types.Tool) string {
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	return strings.Join(names, ",")
+}
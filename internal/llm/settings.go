@@ -0,0 +1,37 @@
+package llm
+
+import "text/template"
+
+// DefaultMaxTools is the number of tools selected when no limit is configured
+const DefaultMaxTools = 5
+
+// Settings holds tool selection behaviour shared by every provider
+type Settings struct {
+	MaxTools    int                // maximum tools returned per selection; 0 means no limit
+	Models      ModelRegistry      // provider type and tier to model name
+	DefaultTier Tier               // tier used when a request does not pick one
+	Prompt      *template.Template // selection prompt; nil uses the embedded default
+}
+
+// DefaultSettings returns the settings used when nothing is configured
+func DefaultSettings() Settings {
+	return Settings{MaxTools: DefaultMaxTools, Models: DefaultModels(), DefaultTier: DefaultTier}
+}
+
+// selectionTokensPerTool budgets the completion tokens of one selected tool name, quotes and
+// separator included; namespaced names such as github__search_code_v2 run to a dozen or more
+const selectionTokensPerTool = 32
+
+// selectionMaxTokens bounds the completion of a selection: enough for MaxTools names and the
+// array around them, or no bound when the selection is unlimited
+func (s Settings) selectionMaxTokens() int {
+	if s.MaxTools <= 0 {
+		return 0
+	}
+	return 16 + s.MaxTools*selectionTokensPerTool
+}
+
+// modelSelector builds the model selection for a provider, pinned to a single model when one is configured
+func (s Settings) modelSelector(providerType, pinned string) modelSelector {
+	return modelSelector{providerType: providerType, pinned: pinned, registry: s.Models, defaultTier: s.DefaultTier}
+}
@@ -0,0 +1,151 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"mcp-smart-proxy/pkg/types"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// errSelectionComplete stops reading a stream once MaxTools tools have been emitted
+var errSelectionComplete = errors.New("selection complete")
+
+// SelectBestToolsStream selects tools with a streamed OpenAI completion, emitting each tool as soon
+// as its name has been fully received
+func (p *OpenAIProvider) SelectBestToolsStream(ctx context.Context, query string, availableTools []types.Tool, emit func(types.Tool) error) error {
+	model, err := p.models.model(ctx)
+	if err != nil {
+		return err
+	}
+
+	prompt, err := p.settings.selectionPrompt(ctx, query, availableTools)
+	if err != nil {
+		return err
+	}
+
+	chatReq := openai.ChatCompletionRequest{
+		Model: model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleUser, Content: prompt},
+		},
+		Stream:    true,
+		MaxTokens: p.settings.selectionMaxTokens(),
+	}
+
+	stream, err := p.client.CreateChatCompletionStream(ctx, chatReq)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	selector := newStreamSelector(availableTools, p.settings.MaxTools, emit)
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return selector.finish()
+		}
+		if err != nil {
+			return err
+		}
+		if len(resp.Choices) == 0 {
+			continue
+		}
+
+		if err := selector.feed(resp.Choices[0].Delta.Content); err != nil {
+			if errors.Is(err, errSelectionComplete) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// streamSelector turns completion text chunks into emitted tools
+type streamSelector struct {
+	names    nameScanner
+	tools    map[string]types.Tool
+	emitted  map[string]bool
+	maxTools int
+	emit     func(types.Tool) error
+	raw      strings.Builder
+}
+
+func newStreamSelector(availableTools []types.Tool, maxTools int, emit func(types.Tool) error) *streamSelector {
+	tools := make(map[string]types.Tool, len(availableTools))
+	for _, tool := range availableTools {
+		tools[tool.Name] = tool
+	}
+	return &streamSelector{tools: tools, emitted: make(map[string]bool), maxTools: maxTools, emit: emit}
+}
+
+// feed consumes a chunk of completion text, emitting each newly completed catalog tool name;
+// repeated and unknown names are skipped
+func (s *streamSelector) feed(chunk string) error {
+	s.raw.WriteString(chunk)
+	for _, name := range s.names.feed(chunk) {
+		tool, ok := s.tools[name]
+		if !ok || s.emitted[name] {
+			continue
+		}
+		s.emitted[name] = true
+		if err := s.emit(tool); err != nil {
+			return err
+		}
+		if s.maxTools > 0 && len(s.emitted) >= s.maxTools {
+			return errSelectionComplete
+		}
+	}
+	return nil
+}
+
+// finish checks the complete response once the stream has ended
+func (s *streamSelector) finish() error {
+	var names []string
+	if err := json.Unmarshal([]byte(s.raw.String()), &names); err != nil {
+		return fmt.Errorf("invalid selection response: %w", err)
+	}
+	return nil
+}
+
+// nameScanner incrementally extracts the string elements of a JSON array of strings
+type nameScanner struct {
+	current  strings.Builder
+	inString bool
+	escaped  bool
+}
+
+// feed consumes more text and returns the strings completed by it
+func (s *nameScanner) feed(chunk string) []string {
+	var names []string
+	for _, r := range chunk {
+		if !s.inString {
+			if r == '"' {
+				s.inString = true
+				s.current.Reset()
+			}
+			continue
+		}
+
+		switch {
+		case s.escaped:
+			s.escaped = false
+		case r == '\\':
+			s.escaped = true
+		case r == '"':
+			s.inString = false
+			var name string
+			if err := json.Unmarshal([]byte(`"`+s.current.String()+`"`), &name); err == nil {
+				names = append(names, name)
+			}
+			continue
+		}
+		s.current.WriteRune(r)
+	}
+	return names
+}
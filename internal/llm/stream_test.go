@@ -0,0 +1,118 @@
+package llm
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestNameScanner(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		want   []string
+	}{
+		{name: "whole array", chunks: []string{`["read", "write"]`}, want: []string{"read", "write"}},
+		{name: "name split across chunks", chunks: []string{`["re`, `ad", "wr`, `ite"]`}, want: []string{"read", "write"}},
+		{name: "escaped quote", chunks: []string{`["say \"hi\"", "b"]`}, want: []string{`say "hi"`, "b"}},
+		{name: "escape split from the quote", chunks: []string{`["a\`, `"b", "c"]`}, want: []string{`a"b`, "c"}},
+		{name: "escaped backslash before the closing quote", chunks: []string{`["a\\`, `", "b"]`}, want: []string{`a\`, "b"}},
+		{name: "unicode escape split", chunks: []string{`["\u00`, `e9té"]`}, want: []string{"été"}},
+		{name: "text around the array", chunks: []string{"Here you go:\n```json\n[\"read\"]\n```"}, want: []string{"read"}},
+		{name: "unterminated name", chunks: []string{`["read", "wri`}, want: []string{"read"}},
+		{name: "one character at a time", chunks: strings.Split(`["a\"b", "c"]`, ""), want: []string{`a"b`, "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var scanner nameScanner
+			var got []string
+			for _, chunk := range tt.chunks {
+				got = append(got, scanner.feed(chunk)...)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("names = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamSelector(t *testing.T) {
+	catalog := []types.Tool{{Name: "read"}, {Name: "write"}, {Name: "delete"}, {Name: `say "hi"`}}
+
+	tests := []struct {
+		name        string
+		maxTools    int
+		chunks      []string
+		emitErr     error // returned by emit
+		wantEmitted []string
+		wantErr     error // from feeding the chunks
+		wantInvalid bool  // finish rejects the complete response
+	}{
+		{name: "emits in order", chunks: []string{`["write", `, `"read"]`}, wantEmitted: []string{"write", "read"}},
+		{name: "escaped name split across chunks", chunks: []string{`["say \`, `"hi\""]`}, wantEmitted: []string{`say "hi"`}},
+		{name: "unknown names are skipped", chunks: []string{`["read", "format_disk", "write"]`}, wantEmitted: []string{"read", "write"}},
+		{name: "duplicates are skipped", chunks: []string{`["read", "read", "write", "read"]`}, wantEmitted: []string{"read", "write"}},
+		{
+			name:        "stops at MaxTools",
+			maxTools:    2,
+			chunks:      []string{`["read", "read", `, `"write", "delete"]`},
+			wantEmitted: []string{"read", "write"},
+			wantErr:     errSelectionComplete,
+		},
+		{name: "unknown names do not count towards MaxTools", maxTools: 2, chunks: []string{`["nope", "read", "also_nope", "write"]`}, wantEmitted: []string{"read", "write"}, wantErr: errSelectionComplete},
+		{name: "no limit", chunks: []string{`["read", "write", "delete"]`}, wantEmitted: []string{"read", "write", "delete"}},
+		{name: "emit failure stops the stream", chunks: []string{`["read", "write"]`}, emitErr: errors.New("client gone"), wantEmitted: []string{"read"}},
+		{name: "non-JSON response", chunks: []string{"I would use read and write"}, wantInvalid: true},
+		{name: "prose around the names", chunks: []string{`Use "read" then "write".`}, wantEmitted: []string{"read", "write"}, wantInvalid: true},
+		{name: "truncated array", chunks: []string{`["read", "wri`}, wantEmitted: []string{"read"}, wantInvalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var emitted []string
+			selector := newStreamSelector(catalog, tt.maxTools, func(tool types.Tool) error {
+				emitted = append(emitted, tool.Name)
+				return tt.emitErr
+			})
+
+			var err error
+			for _, chunk := range tt.chunks {
+				if err = selector.feed(chunk); err != nil {
+					break
+				}
+			}
+			wantErr := tt.wantErr
+			if tt.emitErr != nil {
+				wantErr = tt.emitErr
+			}
+			if !errors.Is(err, wantErr) || (wantErr == nil) != (err == nil) {
+				t.Errorf("feed() error = %v, want %v", err, wantErr)
+			}
+			if !reflect.DeepEqual(emitted, tt.wantEmitted) {
+				t.Errorf("emitted %q, want %q", emitted, tt.wantEmitted)
+			}
+			if err != nil {
+				return
+			}
+
+			if err := selector.finish(); (err != nil) != tt.wantInvalid {
+				t.Errorf("finish() error = %v, want invalid = %v", err, tt.wantInvalid)
+			}
+		})
+	}
+}
+
+func TestSelectionMaxTokens(t *testing.T) {
+	if got := (Settings{MaxTools: 0}).selectionMaxTokens(); got != 0 {
+		t.Errorf("selectionMaxTokens() without a limit = %d, want no cap", got)
+	}
+	for _, maxTools := range []int{1, DefaultMaxTools, 20, 100} {
+		if got := (Settings{MaxTools: maxTools}).selectionMaxTokens(); got < maxTools*selectionTokensPerTool {
+			t.Errorf("selectionMaxTokens() for %d tools = %d, want room for every name", maxTools, got)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// errBatchUnsupported signals that the server answered a batch with something other than a batch
+var errBatchUnsupported = errors.New("server did not answer the batch")
+
+// initializeBatch sends initialize, the initialized notification and tools/list as a single
+// JSON-RPC batch, saving two round trips; the tools/list result is kept for the first ListTools.
+// Only a reply that is not a batch marks batches unsupported: a server that is merely slow to start
+// gets the same connect and read timeouts as the sequential handshake
+func (c *StdioClient) initializeBatch(ctx context.Context) error {
+	initReq, listReq := c.initializeRequest(), c.listToolsRequest()
+	batch := []interface{}{initReq, initializedNotification(), listReq}
+	if err := c.writeMessage(batch); err != nil {
+		return err
+	}
+
+	responses, err := c.readBatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	var initResp, listResp map[string]interface{}
+	for _, response := range responses {
+		switch {
+		case sameID(response["id"], initReq["id"]):
+			initResp = response
+		case sameID(response["id"], listReq["id"]):
+			listResp = response
+		}
+	}
+
+	if initResp == nil || listResp == nil {
+		return fmt.Errorf("%w: batch reply is missing responses", errBatchUnsupported)
+	}
+	if err := c.negotiateVersion(initResp); err != nil {
+		return err
+	}
+
+	tools, err := parseTools(listResp)
+	if err != nil {
+		return err
+	}
+	c.prefetchedTools = tools
+	return nil
+}
+
+// readBatch reads the reply to a batch, answering any server-initiated messages that arrive first
+func (c *StdioClient) readBatch(ctx context.Context) ([]map[string]interface{}, error) {
+	for {
+		line, err := c.nextLine(ctx, c.opts.ReadTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 && trimmed[0] == '[' {
+			var responses []map[string]interface{}
+			if err := json.Unmarshal(trimmed, &responses); err != nil {
+				return nil, err
+			}
+			return responses, nil
+		}
+
+		message, err := decodeMessage(line)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, isServerMessage := message["method"]; isServerMessage {
+			if err := c.handleServerMessage(ctx, message); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		return nil, fmt.Errorf("%w: got a single response %v", errBatchUnsupported, message)
+	}
+}
@@ -0,0 +1,45 @@
+package mcp
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInitializeBatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		modes    string
+		wantErr  bool
+		received []string // methods the server saw up to the first ListTools
+	}{
+		{name: "batch reply", modes: "batch", received: []string{"batch"}},
+		{name: "slow batch reply is waited for", modes: "slowbatch", received: []string{"batch"}},
+		{name: "error reply falls back", modes: "nobatch", received: []string{"batch", "initialize", "notifications/initialized", "tools/list"}},
+		{name: "no reply times out without falling back", modes: "silentbatch", wantErr: true, received: []string{"batch"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logPath := t.TempDir() + "/received.log"
+			env := map[string]string{"MCP_FAKE_SERVER": tt.modes, "MCP_FAKE_LOG": logPath}
+			client, err := NewStdioClient(os.Args[0], nil, env, Options{Batch: true, ConnectTimeout: time.Second})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewStdioClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				defer client.Close()
+				tools, err := client.ListTools(context.Background())
+				if err != nil || len(tools) != 1 || tools[0].Name != "echo" {
+					t.Fatalf("ListTools() = %v, %v, want the echo tool", tools, err)
+				}
+			}
+
+			if got := receivedMethods(logPath); strings.Join(got, " ") != strings.Join(tt.received, " ") {
+				t.Errorf("server received %v, want %v", got, tt.received)
+			}
+		})
+	}
+}
@@ -2,26 +2,93 @@
 package mcp
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"os"
 	"os/exec"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"mcp-smart-proxy/internal/launcher"
 	"mcp-smart-proxy/pkg/types"
 )
 
-// StdioClient implements MCPClient using stdio protocol
+var (
+	// ErrTimeout is returned when the server does not answer within the read timeout
+	ErrTimeout = errors.New("timed out waiting for response")
+	// ErrClosed is returned when the server's output stream has ended
+	ErrClosed = errors.New("server connection closed")
+	// ErrMessageTooLarge is returned when a server message exceeds Options.MaxMessageSize
+	ErrMessageTooLarge = errors.New("message exceeds size limit")
+	// ErrProcessExited is returned when the server process is gone and can no longer be written to
+	ErrProcessExited = errors.New("server process exited")
+	// ErrToolError is returned when the server answers a tools/call with a JSON-RPC error
+	ErrToolError = errors.New("tool error")
+	// ErrUnresponsive is returned once the server stopped answering after a request was cancelled
+	ErrUnresponsive = errors.New("server unresponsive")
+)
+
+// DefaultMaxMessageSize caps a single JSON-RPC message when Options.MaxMessageSize is unset;
+// tool results carrying base64 images or audio are routinely several megabytes
+const DefaultMaxMessageSize = 32 << 20
+
+// DefaultConnectTimeout bounds the initialize handshake when Options.ConnectTimeout is unset
+const DefaultConnectTimeout = 30 * time.Second
+
+// DefaultCancelGrace is how long a server may take to answer the ping sent after a cancelled
+// request when Options.CancelGrace is unset
+const DefaultCancelGrace = 2 * time.Second
+
+// reapTimeout bounds how long Close waits for a killed server process to be reaped
+const reapTimeout = 5 * time.Second
+
+// Options configures a StdioClient
+type Options struct {
+	ConnectTimeout time.Duration            // limit for the initialize handshake
+	ReadTimeout    time.Duration            // limit for each individual response; 0 waits for the caller's context
+	Roots          []types.Root             // filesystem roots advertised through the roots capability
+	Sampler        types.CompletionProvider // serves sampling/createMessage requests; nil disables sampling
+	Batch          bool                     // send initialize, initialized and tools/list as one JSON-RPC batch
+	MaxMessageSize int                      // bytes accepted per server message; larger ones are discarded unread
+	Name           string                   // server name attached to the server's log messages
+	LogLevel       string                   // minimum level requested through logging/setLevel; empty leaves the server default
+	IDPrefix       string                   // send string request ids "<IDPrefix><n>" instead of integers
+	ClientInfo     types.ClientInfo         // name and version sent in initialize; empty fields use the defaults
+	Capabilities   map[string]interface{}   // merged over the derived client capabilities; nil values remove one
+	Limits         types.ResourceLimits     // constraints the server process runs under from its start
+	CancelGrace    time.Duration            // time the server gets to answer a ping after a cancellation
+}
+
+// StdioClient implements MCPClient using stdio protocol. All traffic goes through a
+// queue served by a single goroutine, so concurrent callers are safely serialized.
 type StdioClient struct {
-	cmd    *exec.Cmd
-	stdin  io.WriteCloser
-	stdout io.ReadCloser
-	reader *bufio.Scanner
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	stdout    io.ReadCloser
+	messages  chan []byte   // lines read from stdout by readLoop; nil marks a discarded oversized line
+	jobs      chan job      // work needing exclusive use of the pipes, run in order by serve
+	done      chan struct{} // closed by Close to stop readLoop and serve
+	closeOnce sync.Once
+	closeErr  error // result of killing the server process, returned by every Close
+	opts      Options
+	exited    atomic.Bool // set once a write finds the server's stdin broken
+	stuck     atomic.Bool // set once the server ignored the ping following a cancellation
+
+	// Owned by the serve goroutine
+	lastID             int                    // id of the most recent client request
+	protocolVersion    string                 // revision agreed during initialize; written before any concurrent use
+	serverCapabilities map[string]interface{} // capabilities the server reported in initialize
+	prefetchedTools    []types.Tool           // tools/list result received with a batched initialize
 }
 
 // NewStdioClient creates a new MCP client using stdio protocol
-func NewStdioClient(command string, args []string, env map[string]string) (*StdioClient, error) {
+func NewStdioClient(command string, args []string, env map[string]string, opts Options) (*StdioClient, error) {
 	cmd := exec.Command(command, args...)
 
 	// Set environment variables
@@ -39,19 +106,36 @@ func NewStdioClient(command string, args []string, env map[string]string) (*Stdi
 		return nil, err
 	}
 
-	if err := cmd.Start(); err != nil {
+	if err := launcher.Start(cmd, opts.Limits); err != nil {
 		return nil, err
 	}
 
+	if opts.ConnectTimeout <= 0 {
+		opts.ConnectTimeout = DefaultConnectTimeout
+	}
+	if opts.MaxMessageSize <= 0 {
+		opts.MaxMessageSize = DefaultMaxMessageSize
+	}
+	if opts.CancelGrace <= 0 {
+		opts.CancelGrace = DefaultCancelGrace
+	}
+
 	client := &StdioClient{
-		cmd:    cmd,
-		stdin:  stdin,
-		stdout: stdout,
-		reader: bufio.NewScanner(stdout),
+		cmd:      cmd,
+		stdin:    stdin,
+		stdout:   stdout,
+		messages: make(chan []byte),
+		jobs:     make(chan job),
+		done:     make(chan struct{}),
+		opts:     opts,
 	}
+	go client.readLoop()
+	go client.serve()
 
 	// Initialize MCP connection
-	if err := client.initialize(); err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.ConnectTimeout)
+	defer cancel()
+	if err := client.do(ctx, func() error { return client.initialize(ctx) }); err != nil {
 		client.Close()
 		return nil, err
 	}
@@ -59,73 +143,281 @@ func NewStdioClient(command string, args []string, env map[string]string) (*Stdi
 	return client, nil
 }
 
-// initialize sends the MCP initialize request
-func (c *StdioClient) initialize() error {
-	initReq := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      1,
-		"method":  "initialize",
-		"params": map[string]interface{}{
-			"protocolVersion": "2024-11-05",
-			"capabilities":    map[string]interface{}{},
-			"clientInfo": map[string]interface{}{
-				"name":    "mcp-smart-proxy",
-				"version": "1.0.0",
-			},
-		},
+// readLoop forwards each message written by the server to the messages channel until stdout closes
+func (c *StdioClient) readLoop() {
+	defer close(c.messages)
+
+	frames := newFrameReader(c.stdout, c.opts.MaxMessageSize, c.opts.Name)
+	for {
+		line, err := frames.next()
+		if err != nil && !errors.Is(err, ErrMessageTooLarge) {
+			select {
+			case <-c.done:
+				// Closed by Close; the read error is expected
+			default:
+				if err != io.EOF {
+					log.Printf("Stopped reading from MCP server %s: %v", c.opts.Name, err)
+				}
+			}
+			return
+		}
+
+		select {
+		case c.messages <- line:
+		case <-c.done:
+			return
+		}
 	}
+}
 
-	if err := c.sendRequest(initReq); err != nil {
+// initialize performs the MCP handshake and applies the requested log level
+func (c *StdioClient) initialize(ctx context.Context) error {
+	if err := c.handshake(ctx); err != nil {
 		return err
 	}
+	return c.setLogLevel(ctx)
+}
 
-	// Read and discard the initialize response
-	_, err := c.readResponse()
-	return err
+// handshake exchanges initialize and initialized, batched with the first tools/list when enabled
+func (c *StdioClient) handshake(ctx context.Context) error {
+	if c.opts.Batch {
+		err := c.initializeBatch(ctx)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, errBatchUnsupported) {
+			return fmt.Errorf("initialize failed: %w", err)
+		}
+		log.Printf("Server does not support JSON-RPC batches, initializing sequentially: %v", err)
+	}
+
+	response, err := c.roundTrip(ctx, c.initializeRequest())
+	if err != nil {
+		return fmt.Errorf("initialize failed: %w", err)
+	}
+	if err := c.negotiateVersion(response); err != nil {
+		return fmt.Errorf("initialize failed: %w", err)
+	}
+
+	// Servers only issue requests such as roots/list once the client reports it is initialized
+	return c.sendRequest(initializedNotification())
+}
+
+// initializeRequest builds the MCP initialize request
+func (c *StdioClient) initializeRequest() map[string]interface{} {
+	return c.newRequest("initialize", map[string]interface{}{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    c.capabilities(),
+		"clientInfo":      c.clientInfo(),
+	})
+}
+
+// clientInfo builds the clientInfo sent in initialize from the defaults and Options.ClientInfo
+func (c *StdioClient) clientInfo() map[string]interface{} {
+	info := map[string]interface{}{"name": DefaultClientName, "version": DefaultClientVersion}
+	if c.opts.ClientInfo.Name != "" {
+		info["name"] = c.opts.ClientInfo.Name
+	}
+	if c.opts.ClientInfo.Version != "" {
+		info["version"] = c.opts.ClientInfo.Version
+	}
+	return info
+}
+
+// initializedNotification builds the notification that completes the handshake
+func initializedNotification() map[string]interface{} {
+	return map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "notifications/initialized",
+	}
+}
+
+// listToolsRequest builds a tools/list request
+func (c *StdioClient) listToolsRequest() map[string]interface{} {
+	return c.newRequest("tools/list", nil)
 }
 
 // sendRequest sends a JSON-RPC request to the MCP server
 func (c *StdioClient) sendRequest(req map[string]interface{}) error {
-	data, err := json.Marshal(req)
+	return c.writeMessage(req)
+}
+
+// writeMessage writes a single JSON-RPC message or batch as one line. A broken pipe means the
+// server process has exited; the client is then marked dead and every later write fails fast
+// with ErrProcessExited. Writes to a server marked unresponsive fail with ErrUnresponsive
+func (c *StdioClient) writeMessage(message interface{}) error {
+	if c.exited.Load() {
+		return ErrProcessExited
+	}
+	if c.stuck.Load() {
+		return ErrUnresponsive
+	}
+
+	data, err := json.Marshal(message)
 	if err != nil {
 		return err
 	}
 
 	_, err = c.stdin.Write(append(data, '\n'))
+	if err != nil && c.brokenPipe(err) {
+		c.exited.Store(true)
+		log.Printf("MCP server %s exited; its stdin is closed", c.opts.Name)
+		return fmt.Errorf("%w: %w", ErrProcessExited, err)
+	}
 	return err
 }
 
-// readResponse reads a JSON-RPC response from the MCP server
-func (c *StdioClient) readResponse() (map[string]interface{}, error) {
-	if !c.reader.Scan() {
-		return nil, fmt.Errorf("failed to read response")
+// brokenPipe reports whether a write error means the server closed its end of stdin, as opposed
+// to Close having closed ours
+func (c *StdioClient) brokenPipe(err error) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
 	}
+	return errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrClosedPipe) || errors.Is(err, os.ErrClosed)
+}
 
-	var response map[string]interface{}
-	if err := json.Unmarshal(c.reader.Bytes(), &response); err != nil {
+// Exited reports whether the server process was found to have exited
+func (c *StdioClient) Exited() bool {
+	return c.exited.Load()
+}
+
+// Unresponsive reports whether the server stopped answering after a cancelled request
+func (c *StdioClient) Unresponsive() bool {
+	return c.stuck.Load()
+}
+
+// roundTrip sends a request and reads its response. When the caller stops waiting because ctx
+// ended or the read timed out, the server is told to abandon the request and is then pinged; a
+// server that does not answer the ping within the grace period is marked unresponsive and the
+// returned error also wraps ErrUnresponsive
+func (c *StdioClient) roundTrip(ctx context.Context, req map[string]interface{}) (map[string]interface{}, error) {
+	if err := c.sendRequest(req); err != nil {
 		return nil, err
 	}
 
-	return response, nil
+	response, err := c.readResponse(ctx, req["id"], c.opts.ReadTimeout)
+	if err != nil && (ctx.Err() != nil || errors.Is(err, ErrTimeout)) && req["method"] != "initialize" {
+		// The spec forbids cancelling initialize; the connection is dropped instead
+		if cancelErr := c.writeMessage(cancelledNotification(req["id"], err.Error())); cancelErr != nil {
+			log.Printf("Failed to send cancellation for MCP request %v: %v", req["id"], cancelErr)
+		}
+		if probeErr := c.probe(); probeErr != nil {
+			c.stuck.Store(true)
+			log.Printf("MCP server %s did not recover after cancelling request %v: %v", c.opts.Name, req["id"], probeErr)
+			return nil, fmt.Errorf("%w; %w", err, ErrUnresponsive)
+		}
+	}
+	return response, err
 }
 
-// ListTools retrieves all available tools from the MCP server
-func (c *StdioClient) ListTools(ctx context.Context) ([]types.Tool, error) {
-	req := map[string]interface{}{
+// probe pings the server and waits up to the cancel grace period for the answer. A server still
+// busy with the abandoned request answers only after it, so the late response is skipped
+func (c *StdioClient) probe() error {
+	req := c.newRequest("ping", nil)
+	if err := c.sendRequest(req); err != nil {
+		return err
+	}
+	_, err := c.readResponse(context.Background(), req["id"], c.opts.CancelGrace)
+	return err
+}
+
+// cancelledNotification builds the notification telling the server a request was abandoned
+func cancelledNotification(id interface{}, reason string) map[string]interface{} {
+	return map[string]interface{}{
 		"jsonrpc": "2.0",
-		"id":      2,
-		"method":  "tools/list",
+		"method":  "notifications/cancelled",
+		"params": map[string]interface{}{
+			"requestId": id,
+			"reason":    reason,
+		},
 	}
+}
 
-	if err := c.sendRequest(req); err != nil {
-		return nil, err
+// readResponse reads the JSON-RPC response with the given id from the MCP server, answering any
+// server-initiated requests and notifications that arrive before it. Responses carrying another
+// id, such as a late answer to a request that already timed out, are logged and skipped. Each
+// message must arrive within timeout; 0 waits for ctx.
+func (c *StdioClient) readResponse(ctx context.Context, id interface{}, timeout time.Duration) (map[string]interface{}, error) {
+	for {
+		line, err := c.nextLine(ctx, timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		message, err := decodeMessage(line)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, isServerMessage := message["method"]; isServerMessage {
+			if err := c.handleServerMessage(ctx, message); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// A null id only appears on errors for requests the server could not parse, i.e. ours
+		if !sameID(message["id"], id) && !(message["id"] == nil && message["error"] != nil) {
+			log.Printf("Skipping MCP response with id %v while waiting for id %v", message["id"], id)
+			continue
+		}
+
+		return message, nil
 	}
+}
 
-	response, err := c.readResponse()
-	if err != nil {
-		return nil, err
+// nextLine reads one raw JSON-RPC message, giving up when ctx ends or the timeout elapses
+func (c *StdioClient) nextLine(ctx context.Context, timeout time.Duration) ([]byte, error) {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
 	}
 
+	select {
+	case line, ok := <-c.messages:
+		if !ok {
+			return nil, fmt.Errorf("failed to read response: %w", ErrClosed)
+		}
+		if line == nil {
+			return nil, fmt.Errorf("%w of %d bytes", ErrMessageTooLarge, c.opts.MaxMessageSize)
+		}
+		return line, nil
+
+	case <-expired:
+		return nil, fmt.Errorf("%w after %s", ErrTimeout, timeout)
+
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// ListTools retrieves all available tools from the MCP server
+func (c *StdioClient) ListTools(ctx context.Context) ([]types.Tool, error) {
+	var tools []types.Tool
+	err := c.do(ctx, func() error {
+		// The first listing may already have arrived with a batched initialize
+		if c.prefetchedTools != nil {
+			tools, c.prefetchedTools = c.prefetchedTools, nil
+			return nil
+		}
+
+		response, err := c.roundTrip(ctx, c.listToolsRequest())
+		if err != nil {
+			return err
+		}
+
+		tools, err = parseTools(response)
+		return err
+	})
+	return tools, err
+}
+
+// parseTools extracts tools from a tools/list response
+func parseTools(response map[string]interface{}) ([]types.Tool, error) {
 	result, ok := response["result"].(map[string]interface{})
 	if !ok {
 		return nil, fmt.Errorf("invalid response format: %v", response)
@@ -144,9 +436,11 @@ func (c *StdioClient) ListTools(ctx context.Context) ([]types.Tool, error) {
 		}
 
 		tool := types.Tool{
-			Name:        getString(toolMap, "name"),
-			Description: getString(toolMap, "description"),
-			InputSchema: toolMap["inputSchema"],
+			Name:         getString(toolMap, "name"),
+			Description:  getString(toolMap, "description"),
+			InputSchema:  toolMap["inputSchema"],
+			OutputSchema: toolMap["outputSchema"],
+			Annotations:  getAnnotations(toolMap, "annotations"),
 		}
 		tools = append(tools, tool)
 	}
@@ -156,27 +450,23 @@ func (c *StdioClient) ListTools(ctx context.Context) ([]types.Tool, error) {
 
 // CallTool executes a tool on the MCP server
 func (c *StdioClient) CallTool(ctx context.Context, toolName string, arguments map[string]interface{}) (map[string]interface{}, error) {
-	req := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      3,
-		"method":  "tools/call",
-		"params": map[string]interface{}{
+	var response map[string]interface{}
+	err := c.do(ctx, func() error {
+		req := c.newRequest("tools/call", map[string]interface{}{
 			"name":      toolName,
 			"arguments": arguments,
-		},
-	}
-
-	if err := c.sendRequest(req); err != nil {
-		return nil, err
-	}
+		})
 
-	response, err := c.readResponse()
+		var err error
+		response, err = c.roundTrip(ctx, req)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	if errorData, exists := response["error"]; exists {
-		return nil, fmt.Errorf("tool error: %v", errorData)
+		return nil, fmt.Errorf("%w: %v", ErrToolError, errorData)
 	}
 
 	result, ok := response["result"].(map[string]interface{})
@@ -187,18 +477,54 @@ func (c *StdioClient) CallTool(ctx context.Context, toolName string, arguments m
 	return result, nil
 }
 
-// Close closes the MCP client and terminates the server process
+// Request sends a request for any MCP method and returns the server's JSON-RPC response as is,
+// including an error member if the server reported one
+func (c *StdioClient) Request(ctx context.Context, method string, params interface{}) (map[string]interface{}, error) {
+	var response map[string]interface{}
+	err := c.do(ctx, func() error {
+		var err error
+		response, err = c.roundTrip(ctx, c.newRequest(method, params))
+		return err
+	})
+	return response, err
+}
+
+// Close closes the MCP client, terminates the server process and reaps it
 func (c *StdioClient) Close() error {
-	if c.stdin != nil {
-		c.stdin.Close()
-	}
-	if c.stdout != nil {
-		c.stdout.Close()
+	c.closeOnce.Do(func() {
+		close(c.done)
+		if c.stdin != nil {
+			c.stdin.Close()
+		}
+		if c.stdout != nil {
+			c.stdout.Close()
+		}
+		if c.cmd != nil && c.cmd.Process != nil {
+			c.closeErr = c.kill()
+		}
+	})
+	return c.closeErr
+}
+
+// kill kills the server process and waits for it, so no zombie is left behind. The wait is
+// bounded: a process stuck in the kernel may take arbitrarily long to die
+func (c *StdioClient) kill() error {
+	err := c.cmd.Process.Kill()
+	if errors.Is(err, os.ErrProcessDone) {
+		err = nil
 	}
-	if c.cmd != nil && c.cmd.Process != nil {
-		return c.cmd.Process.Kill()
+
+	reaped := make(chan struct{})
+	go func() {
+		c.cmd.Wait()
+		close(reaped)
+	}()
+	select {
+	case <-reaped:
+	case <-time.After(reapTimeout):
+		log.Printf("MCP server %s (pid %d) was not reaped within %s of being killed", c.opts.Name, c.cmd.Process.Pid, reapTimeout)
 	}
-	return nil
+	return err
 }
 
 // getString safely extracts a string value from a map
@@ -207,4 +533,24 @@ func getString(m map[string]interface{}, key string) string {
 		return val
 	}
 	return ""
-}
\ No newline at end of file
+}
+
+// getAnnotations extracts tool annotations from a map, returning nil when absent or malformed
+func getAnnotations(m map[string]interface{}, key string) *types.ToolAnnotations {
+	raw, ok := m[key].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+
+	var annotations types.ToolAnnotations
+	if err := json.Unmarshal(data, &annotations); err != nil {
+		return nil
+	}
+
+	return &annotations
+}
@@ -0,0 +1,402 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCallToolMatchesResponseIDs(t *testing.T) {
+	tests := []struct {
+		name  string
+		modes string
+		opts  Options
+	}{
+		{name: "integer ids", modes: "stale"},
+		{name: "prefixed string ids", modes: "stale", opts: Options{IDPrefix: "proxy-"}},
+		{name: "log lines between responses", modes: "stale,chatty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := startFakeServer(t, tt.modes, tt.opts)
+
+			for n := 1; n <= 2; n++ {
+				want := fmt.Sprintf(`{"n":%d}`, n)
+				result, err := client.CallTool(context.Background(), "echo", map[string]interface{}{"n": n})
+				if err != nil {
+					t.Fatalf("CallTool() error = %v", err)
+				}
+				if got := resultText(t, result); got != want {
+					t.Errorf("CallTool() = %s, want %s", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCallToolError(t *testing.T) {
+	client, _ := startFakeServer(t, "", Options{})
+	if _, err := client.CallTool(context.Background(), "fail", nil); !errors.Is(err, ErrToolError) {
+		t.Errorf("CallTool(fail) error = %v, want ErrToolError", err)
+	}
+}
+
+func TestSameID(t *testing.T) {
+	tests := []struct {
+		a, b interface{}
+		want bool
+	}{
+		{a: 7, b: float64(7), want: true},
+		{a: "p-7", b: "p-7", want: true},
+		{a: "7", b: float64(7), want: false},
+		{a: 7, b: float64(8), want: false},
+		{a: nil, b: nil, want: true},
+		{a: nil, b: 0, want: false},
+	}
+	for _, tt := range tests {
+		if got := sameID(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameID(%#v, %#v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// resultText returns the text of the first content item of a tools/call result
+func resultText(t *testing.T, result map[string]interface{}) string {
+	t.Helper()
+	content, _ := result["content"].([]interface{})
+	if len(content) == 0 {
+		t.Fatalf("result without content: %v", result)
+	}
+	item, _ := content[0].(map[string]interface{})
+	return getString(item, "text")
+}
+
+func TestCallToolCancellation(t *testing.T) {
+	tests := []struct {
+		name           string
+		modes          string
+		opts           Options
+		timeout        time.Duration // caller's deadline; 0 relies on the read timeout
+		wantErr        error
+		wantStuck      bool
+		wantReceived   []string
+		wantUsableNext bool
+	}{
+		{
+			name:           "caller deadline cancels and pings",
+			timeout:        100 * time.Millisecond,
+			wantErr:        context.DeadlineExceeded,
+			wantReceived:   []string{"tools/call", "notifications/cancelled", "ping"},
+			wantUsableNext: true,
+		},
+		{
+			name:           "read timeout cancels and pings",
+			opts:           Options{ReadTimeout: 100 * time.Millisecond},
+			wantErr:        ErrTimeout,
+			wantReceived:   []string{"tools/call", "notifications/cancelled", "ping"},
+			wantUsableNext: true,
+		},
+		{
+			name:      "server ignoring the ping is marked unresponsive",
+			modes:     "blocking",
+			opts:      Options{CancelGrace: 100 * time.Millisecond},
+			timeout:   100 * time.Millisecond,
+			wantErr:   ErrUnresponsive,
+			wantStuck: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, received := startFakeServer(t, tt.modes, tt.opts)
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			_, err := client.CallTool(ctx, "sleep", map[string]interface{}{"ms": 500})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CallTool(sleep) error = %v, want %v", err, tt.wantErr)
+			}
+			if client.Unresponsive() != tt.wantStuck {
+				t.Errorf("Unresponsive() = %v, want %v", client.Unresponsive(), tt.wantStuck)
+			}
+
+			if !tt.wantUsableNext {
+				if _, err := client.CallTool(context.Background(), "echo", nil); !errors.Is(err, ErrUnresponsive) {
+					t.Errorf("CallTool after giving up error = %v, want ErrUnresponsive", err)
+				}
+				return
+			}
+
+			// Let the late answer to the cancelled call arrive; it must not be taken for the next one
+			time.Sleep(500 * time.Millisecond)
+			result, err := client.CallTool(context.Background(), "echo", map[string]interface{}{"n": 1})
+			if err != nil || resultText(t, result) != `{"n":1}` {
+				t.Fatalf("CallTool after cancellation = %v, %v", result, err)
+			}
+			// Skip initialize and notifications/initialized
+			if got := received()[2:]; strings.Join(got, " ") != strings.Join(append(tt.wantReceived, "tools/call"), " ") {
+				t.Errorf("server received %v, want %v then tools/call", got, tt.wantReceived)
+			}
+		})
+	}
+}
+
+func TestRequest(t *testing.T) {
+	client, received := startFakeServer(t, "", Options{})
+
+	tests := []struct {
+		method    string
+		wantError bool // the response carries an error member, returned unchanged
+	}{
+		{method: "ping"},
+		{method: "resources/list", wantError: true},
+	}
+	for _, tt := range tests {
+		response, err := client.Request(context.Background(), tt.method, nil)
+		if err != nil {
+			t.Fatalf("Request(%s) error = %v", tt.method, err)
+		}
+		if _, hasError := response["error"]; hasError != tt.wantError || response["jsonrpc"] != "2.0" {
+			t.Errorf("Request(%s) = %v, want the raw response with error = %v", tt.method, response, tt.wantError)
+		}
+	}
+	if got := received()[2:]; strings.Join(got, " ") != "ping resources/list" {
+		t.Errorf("server received %v, want ping and resources/list", got)
+	}
+}
+
+func TestCloseReapsTheServer(t *testing.T) {
+	tests := []struct {
+		name  string
+		modes string
+		stuck bool // the server is busy with a call it ignores the cancellation of, as before a restart
+	}{
+		{name: "idle server"},
+		{name: "stuck server", modes: "blocking", stuck: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := startFakeServer(t, tt.modes, Options{CancelGrace: 100 * time.Millisecond})
+			pid := client.cmd.Process.Pid
+			if tt.stuck {
+				ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+				defer cancel()
+				if _, err := client.CallTool(ctx, "sleep", map[string]interface{}{"ms": 5000}); !errors.Is(err, ErrUnresponsive) {
+					t.Fatalf("CallTool(sleep) error = %v, want ErrUnresponsive", err)
+				}
+			}
+
+			if err := client.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if client.cmd.ProcessState == nil {
+				t.Fatal("Close() returned without waiting for the server process")
+			}
+			// A zombie still accepts signals; a reaped process is gone
+			if process, err := os.FindProcess(pid); err == nil && process.Signal(syscall.Signal(0)) == nil {
+				t.Errorf("server process %d still exists after Close", pid)
+			}
+			if err := client.Close(); err != nil {
+				t.Errorf("second Close() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestWriteToExitedServer(t *testing.T) {
+	client, _ := startFakeServer(t, "", Options{})
+	if err := client.cmd.Process.Kill(); err != nil {
+		t.Fatalf("Kill() error = %v", err)
+	}
+	// Once the process is gone so is the read end of its stdin
+	client.cmd.Process.Wait()
+
+	for i := 0; i < 2; i++ {
+		_, err := client.CallTool(context.Background(), "echo", nil)
+		if !errors.Is(err, ErrProcessExited) {
+			t.Errorf("CallTool() %d after the server exited error = %v, want %v", i+1, err, ErrProcessExited)
+		}
+	}
+	if !client.Exited() {
+		t.Error("Exited() = false after writing to a dead server, want true")
+	}
+
+	closed, _ := startFakeServer(t, "", Options{})
+	closed.Close()
+	if _, err := closed.CallTool(context.Background(), "echo", nil); errors.Is(err, ErrProcessExited) || closed.Exited() {
+		t.Errorf("CallTool() after Close error = %v, want it not blamed on the server exiting", err)
+	}
+}
+
+func TestConcurrentCallsAreSerialized(t *testing.T) {
+	// The fake server answers sleep calls concurrently, so only the client's queue orders them
+	client, received := startFakeServer(t, "", Options{})
+
+	const calls = 8
+	const sleep = 50 * time.Millisecond
+	// resultText fails the test, which must not happen off the test goroutine
+	text := func(result map[string]interface{}) string {
+		content, _ := result["content"].([]interface{})
+		if len(content) == 0 {
+			return ""
+		}
+		item, _ := content[0].(map[string]interface{})
+		return getString(item, "text")
+	}
+	results := make(chan error, 2*calls)
+	start := time.Now()
+	for n := 0; n < calls; n++ {
+		go func(n int) {
+			result, err := client.CallTool(context.Background(), "sleep", map[string]interface{}{"ms": sleep.Milliseconds()})
+			if err == nil && text(result) != "slept" {
+				err = fmt.Errorf("sleep answered %v", result)
+			}
+			results <- err
+		}(n)
+		go func(n int) {
+			want := fmt.Sprintf(`{"n":%d}`, n)
+			result, err := client.CallTool(context.Background(), "echo", map[string]interface{}{"n": n})
+			if err == nil && text(result) != want {
+				err = fmt.Errorf("echo %d answered %s", n, text(result))
+			}
+			results <- err
+		}(n)
+	}
+	for i := 0; i < 2*calls; i++ {
+		if err := <-results; err != nil {
+			t.Errorf("CallTool() error = %v", err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < calls*sleep {
+		t.Errorf("%d sleep calls finished in %s, want them run one at a time (at least %s)", calls, elapsed, calls*sleep)
+	}
+	if got := len(received()) - 2; got != 2*calls {
+		t.Errorf("server received %d calls, want %d", got, 2*calls)
+	}
+}
+
+func TestParseToolsAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations interface{}
+		want        string // the parsed annotations as JSON
+	}{
+		{
+			name:        "hints are kept",
+			annotations: map[string]interface{}{"title": "Drop table", "destructiveHint": true, "readOnlyHint": false, "idempotentHint": true, "openWorldHint": false},
+			want:        `{"title":"Drop table","readOnlyHint":false,"destructiveHint":true,"idempotentHint":true,"openWorldHint":false}`,
+		},
+		{name: "unset hints stay unset", annotations: map[string]interface{}{"readOnlyHint": true}, want: `{"readOnlyHint":true}`},
+		{name: "no annotations", want: "null"},
+		{name: "not an object", annotations: "read-only", want: "null"},
+		{name: "hint of the wrong type", annotations: map[string]interface{}{"readOnlyHint": "yes"}, want: "null"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := map[string]interface{}{"name": "drop", "inputSchema": map[string]interface{}{"type": "object"}}
+			if tt.annotations != nil {
+				tool["annotations"] = tt.annotations
+			}
+			tools, err := parseTools(map[string]interface{}{"result": map[string]interface{}{"tools": []interface{}{tool}}})
+			if err != nil || len(tools) != 1 {
+				t.Fatalf("parseTools() = %v, %v, want one tool", tools, err)
+			}
+			if got, _ := json.Marshal(tools[0].Annotations); string(got) != tt.want {
+				t.Errorf("annotations = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseToolsOutputSchema(t *testing.T) {
+	schema := map[string]interface{}{"type": "object", "properties": map[string]interface{}{"title": map[string]interface{}{"type": "string"}}}
+	tools, err := parseTools(map[string]interface{}{"result": map[string]interface{}{"tools": []interface{}{
+		map[string]interface{}{"name": "report", "inputSchema": map[string]interface{}{"type": "object"}, "outputSchema": schema},
+		map[string]interface{}{"name": "read", "inputSchema": map[string]interface{}{"type": "object"}},
+	}}})
+	if err != nil || len(tools) != 2 {
+		t.Fatalf("parseTools() = %v, %v, want two tools", tools, err)
+	}
+	if got, _ := json.Marshal(tools[0].OutputSchema); string(got) != `{"properties":{"title":{"type":"string"}},"type":"object"}` {
+		t.Errorf("report output schema = %s, want the declared schema", got)
+	}
+	if tools[1].OutputSchema != nil {
+		t.Errorf("read output schema = %v, want none", tools[1].OutputSchema)
+	}
+}
+
+func TestConnectTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr error
+	}{
+		{name: "slow handshake within the connect timeout", opts: Options{ConnectTimeout: 5 * time.Second}},
+		{name: "slow handshake past the connect timeout", opts: Options{ConnectTimeout: 100 * time.Millisecond}, wantErr: context.DeadlineExceeded},
+		{name: "read timeout also bounds the handshake", opts: Options{ConnectTimeout: 5 * time.Second, ReadTimeout: 100 * time.Millisecond}, wantErr: ErrTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{"MCP_FAKE_SERVER": "slowinit"}
+			start := time.Now()
+			client, err := NewStdioClient(os.Args[0], nil, env, tt.opts)
+			if err == nil {
+				client.Close()
+			}
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Fatalf("NewStdioClient() error = %v, want %v", err, tt.wantErr)
+			}
+			if elapsed := time.Since(start); tt.wantErr != nil && elapsed > 500*time.Millisecond {
+				t.Errorf("NewStdioClient() gave up after %s, want it to stop at the timeout", elapsed)
+			}
+		})
+	}
+}
+
+func TestReadTimeoutUnderALongDeadline(t *testing.T) {
+	client, _ := startFakeServer(t, "", Options{ReadTimeout: 100 * time.Millisecond})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := client.CallTool(ctx, "sleep", map[string]interface{}{"ms": 2000}); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("CallTool(sleep 2s) error = %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("CallTool() returned after %s, want the read timeout to end it", elapsed)
+	}
+
+	// Each message gets its own read timeout, so a quick answer after the slow one still arrives
+	if result, err := client.CallTool(ctx, "sleep", map[string]interface{}{"ms": 50}); err != nil || resultText(t, result) != "slept" {
+		t.Errorf("CallTool(sleep 50ms) = %v, %v, want it to succeed", result, err)
+	}
+}
+
+func TestOversizedResponse(t *testing.T) {
+	client, _ := startFakeServer(t, "", Options{MaxMessageSize: 4096})
+
+	big := strings.Repeat("x", 64*1024)
+	if _, err := client.CallTool(context.Background(), "echo", map[string]interface{}{"data": big}); !errors.Is(err, ErrMessageTooLarge) {
+		t.Fatalf("CallTool() with a 64KB answer error = %v, want ErrMessageTooLarge", err)
+	}
+
+	// The oversized answer is discarded without closing the connection
+	if result, err := client.CallTool(context.Background(), "echo", map[string]interface{}{"n": 1}); err != nil || resultText(t, result) != `{"n":1}` {
+		t.Errorf("CallTool() after the oversized answer = %v, %v, want it to succeed", result, err)
+	}
+}
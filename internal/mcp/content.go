@@ -0,0 +1,63 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// binaryContentTypes are tools/call content block types whose payload is base64 data
+var binaryContentTypes = map[string]bool{
+	"image": true,
+	"audio": true,
+}
+
+// DescribeResult summarises a tools/call result for logging without including any payload,
+// so base64 blobs and non-UTF8 text never end up in log lines
+func DescribeResult(result map[string]interface{}) string {
+	blocks, ok := result["content"].([]interface{})
+	if !ok {
+		return "no content"
+	}
+
+	parts := make([]string, 0, len(blocks))
+	for _, raw := range blocks {
+		block, ok := raw.(map[string]interface{})
+		if !ok {
+			parts = append(parts, "unknown")
+			continue
+		}
+		parts = append(parts, describeBlock(block))
+	}
+
+	summary := fmt.Sprintf("%d content block(s): %s", len(blocks), strings.Join(parts, ", "))
+	if isError, _ := result["isError"].(bool); isError {
+		summary += " (isError)"
+	}
+	return summary
+}
+
+// describeBlock summarises a single content block by type and size
+func describeBlock(block map[string]interface{}) string {
+	blockType := getString(block, "type")
+
+	switch {
+	case blockType == "text":
+		return fmt.Sprintf("text(%d bytes)", len(getString(block, "text")))
+
+	case binaryContentTypes[blockType]:
+		return fmt.Sprintf("%s(%s, %d bytes base64)", blockType, getString(block, "mimeType"), len(getString(block, "data")))
+
+	case blockType == "resource":
+		resource, _ := block["resource"].(map[string]interface{})
+		if resource == nil {
+			return "resource"
+		}
+		if blob := getString(resource, "blob"); blob != "" {
+			return fmt.Sprintf("resource(%s, %s, %d bytes base64)", getString(resource, "uri"), getString(resource, "mimeType"), len(blob))
+		}
+		return fmt.Sprintf("resource(%s, %d bytes text)", getString(resource, "uri"), len(getString(resource, "text")))
+
+	default:
+		return blockType
+	}
+}
@@ -0,0 +1,74 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestDescribeResult(t *testing.T) {
+	blob := base64.StdEncoding.EncodeToString(fakeImage)
+
+	tests := []struct {
+		name   string
+		result map[string]interface{}
+		want   string
+	}{
+		{name: "text", result: textResult("hello"), want: "1 content block(s): text(5 bytes)"},
+		{
+			name: "image and audio",
+			result: map[string]interface{}{"content": []interface{}{
+				map[string]interface{}{"type": "image", "mimeType": "image/png", "data": blob},
+				map[string]interface{}{"type": "audio", "mimeType": "audio/wav", "data": "AAAA"},
+			}},
+			want: "2 content block(s): image(image/png, 20 bytes base64), audio(audio/wav, 4 bytes base64)",
+		},
+		{
+			name: "resources",
+			result: map[string]interface{}{"content": []interface{}{
+				map[string]interface{}{"type": "resource", "resource": map[string]interface{}{"uri": "file:///a.bin", "mimeType": "application/octet-stream", "blob": blob}},
+				map[string]interface{}{"type": "resource", "resource": map[string]interface{}{"uri": "file:///a.txt", "text": "abc"}},
+				map[string]interface{}{"type": "resource"},
+			}},
+			want: "3 content block(s): resource(file:///a.bin, application/octet-stream, 20 bytes base64), resource(file:///a.txt, 3 bytes text), resource",
+		},
+		{name: "error result", result: map[string]interface{}{"content": []interface{}{map[string]interface{}{"type": "text", "text": "boom"}}, "isError": true}, want: "1 content block(s): text(4 bytes) (isError)"},
+		{name: "malformed block", result: map[string]interface{}{"content": []interface{}{"raw"}}, want: "1 content block(s): unknown"},
+		{name: "no content", result: map[string]interface{}{}, want: "no content"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DescribeResult(tt.result)
+			if got != tt.want {
+				t.Errorf("DescribeResult() = %q, want %q", got, tt.want)
+			}
+			if strings.Contains(got, blob) || strings.Contains(got, "hello") {
+				t.Errorf("DescribeResult() = %q, leaks the payload", got)
+			}
+		})
+	}
+}
+
+func TestBinaryResultRoundTrip(t *testing.T) {
+	client, _ := startFakeServer(t, "", Options{})
+
+	result, err := client.CallTool(context.Background(), "image", nil)
+	if err != nil {
+		t.Fatalf("CallTool(image) error = %v", err)
+	}
+	content, _ := result["content"].([]interface{})
+	if len(content) != 1 {
+		t.Fatalf("result = %v, want one content block", result)
+	}
+	block, _ := content[0].(map[string]interface{})
+	if getString(block, "type") != "image" || getString(block, "mimeType") != "image/png" {
+		t.Errorf("block = %v, want an image/png block", block)
+	}
+	data, err := base64.StdEncoding.DecodeString(getString(block, "data"))
+	if err != nil || !bytes.Equal(data, fakeImage) {
+		t.Errorf("image data = %v, %v, want %v", data, err, fakeImage)
+	}
+}
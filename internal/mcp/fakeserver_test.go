@@ -0,0 +1,261 @@
+package mcp
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestMain lets the test binary double as a fake MCP server: with MCP_FAKE_SERVER set it serves
+// JSON-RPC on stdin and stdout in the modes listed there instead of running the tests
+func TestMain(m *testing.M) {
+	if modes, ok := os.LookupEnv("MCP_FAKE_SERVER"); ok {
+		runFakeServer(strings.Split(modes, ","), os.Getenv("MCP_FAKE_LOG"))
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeServer answers initialize, tools/list, ping, logging/setLevel (recording "level=" and the
+// level) and tools/call for the tools echo (returns its arguments), sleep (answers after
+// arguments.ms milliseconds), fail (JSON-RPC error), image (returns fakeImage as an image block),
+// initparams (returns the initialize params it received), ask (sends the client a request with
+// arguments.method, arguments.params and the raw JSON id arguments.id if given, and returns the
+// client's response once it echoes that id) and log (sends its arguments as a logging
+// notification first). Modes:
+//
+//	batch        answer batches with a batch
+//	nobatch      answer batches with a single error, as servers without batch support do
+//	slowbatch    wait 300ms before answering a batch
+//	silentbatch  never answer a batch
+//	stale        send a response with an unknown id before each tools/call result
+//	blocking     handle sleep inline, so nothing is answered until it finishes
+//	chatty       print a log line to stdout before every response
+//	slowinit     wait 1s before answering initialize
+//	newversion   negotiate protocol version 2025-06-18 rather than the one requested
+//	badversion   negotiate the unsupported protocol version 1999-01-01
+//	noversion    leave protocolVersion out of the initialize result
+//	logging      advertise the logging capability
+type fakeServer struct {
+	modes      map[string]bool
+	mu         sync.Mutex
+	out        *bufio.Writer
+	log        *os.File
+	initParams interface{}
+	asks       map[string]interface{} // raw JSON id of each request sent by ask to the id of its tools/call
+}
+
+func runFakeServer(modes []string, logPath string) {
+	s := &fakeServer{modes: map[string]bool{}, out: bufio.NewWriter(os.Stdout), asks: map[string]interface{}{}}
+	for _, mode := range modes {
+		s.modes[mode] = true
+	}
+	if logPath != "" {
+		s.log, _ = os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	}
+
+	decoder := json.NewDecoder(os.Stdin)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return
+		}
+		if raw[0] == '[' {
+			s.handleBatch(raw)
+			continue
+		}
+		var message map[string]interface{}
+		json.Unmarshal(raw, &message)
+		if _, hasMethod := message["method"]; !hasMethod {
+			// Keep the id of an answer as sent, so ask can check it was echoed exactly
+			var envelope struct {
+				ID json.RawMessage `json:"id"`
+			}
+			json.Unmarshal(raw, &envelope)
+			message["id"] = string(envelope.ID)
+		}
+		s.record(getString(message, "method"))
+		if response := s.handle(message); response != nil {
+			s.send(response)
+		}
+	}
+}
+
+func (s *fakeServer) record(entry string) {
+	if s.log != nil && entry != "" {
+		fmt.Fprintln(s.log, entry)
+	}
+}
+
+func (s *fakeServer) send(message interface{}) {
+	data, _ := json.Marshal(message)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.modes["chatty"] {
+		s.out.WriteString("Config: loaded\n")
+	}
+	s.out.Write(append(data, '\n'))
+	s.out.Flush()
+}
+
+func (s *fakeServer) handleBatch(raw json.RawMessage) {
+	s.record("batch")
+	var messages []map[string]interface{}
+	json.Unmarshal(raw, &messages)
+
+	switch {
+	case s.modes["silentbatch"]:
+		return
+	case s.modes["nobatch"]:
+		s.send(map[string]interface{}{"jsonrpc": "2.0", "id": nil, "error": map[string]interface{}{"code": -32600, "message": "batches not supported"}})
+		return
+	case s.modes["slowbatch"]:
+		time.Sleep(300 * time.Millisecond)
+	}
+
+	var responses []interface{}
+	for _, message := range messages {
+		if response := s.handle(message); response != nil {
+			responses = append(responses, response)
+		}
+	}
+	s.send(responses)
+}
+
+// handle returns the response to a message, or nil for notifications and answers sent later
+func (s *fakeServer) handle(message map[string]interface{}) map[string]interface{} {
+	id, isRequest := message["id"]
+	if !isRequest {
+		return nil
+	}
+	if _, hasMethod := message["method"]; !hasMethod {
+		// The client answered a request sent by ask; the answer is the tool's result
+		askID, _ := id.(string)
+		callID, ok := s.asks[askID]
+		if !ok {
+			// Not an id ask sent; the tools/call stays unanswered
+			return nil
+		}
+		delete(s.asks, askID)
+		delete(message, "jsonrpc")
+		delete(message, "id")
+		data, _ := json.Marshal(message)
+		return map[string]interface{}{"jsonrpc": "2.0", "id": callID, "result": textResult(string(data))}
+	}
+	result := func(result interface{}) map[string]interface{} {
+		return map[string]interface{}{"jsonrpc": "2.0", "id": id, "result": result}
+	}
+
+	switch getString(message, "method") {
+	case "initialize":
+		if s.modes["slowinit"] {
+			time.Sleep(time.Second)
+		}
+		s.initParams = message["params"]
+		initResult := map[string]interface{}{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]interface{}{"name": "fake", "version": "1"},
+		}
+		switch {
+		case s.modes["newversion"]:
+			initResult["protocolVersion"] = "2025-06-18"
+		case s.modes["badversion"]:
+			initResult["protocolVersion"] = "1999-01-01"
+		case s.modes["noversion"]:
+			delete(initResult, "protocolVersion")
+		}
+		if s.modes["logging"] {
+			initResult["capabilities"].(map[string]interface{})["logging"] = map[string]interface{}{}
+		}
+		return result(initResult)
+	case "logging/setLevel":
+		params, _ := message["params"].(map[string]interface{})
+		s.record("level=" + getString(params, "level"))
+		return result(map[string]interface{}{})
+	case "tools/list":
+		return result(map[string]interface{}{"tools": []interface{}{
+			map[string]interface{}{"name": "echo", "description": "Echo the arguments", "inputSchema": map[string]interface{}{"type": "object"}},
+		}})
+	case "ping":
+		return result(map[string]interface{}{})
+	case "tools/call":
+		params, _ := message["params"].(map[string]interface{})
+		arguments, _ := params["arguments"].(map[string]interface{})
+		if s.modes["stale"] {
+			s.send(map[string]interface{}{"jsonrpc": "2.0", "id": 999, "result": map[string]interface{}{}})
+		}
+		switch getString(params, "name") {
+		case "fail":
+			return map[string]interface{}{"jsonrpc": "2.0", "id": id, "error": map[string]interface{}{"code": -32000, "message": "failed"}}
+		case "initparams":
+			data, _ := json.Marshal(s.initParams)
+			return result(textResult(string(data)))
+		case "ask":
+			askID := fmt.Sprintf(`"ask-%d"`, len(s.asks)+1)
+			if rawID, ok := arguments["id"].(string); ok {
+				askID = rawID
+			}
+			s.asks[askID] = id
+			s.send(map[string]interface{}{"jsonrpc": "2.0", "id": json.RawMessage(askID), "method": arguments["method"], "params": arguments["params"]})
+			return nil
+		case "log":
+			s.send(map[string]interface{}{"jsonrpc": "2.0", "method": "notifications/message", "params": arguments})
+			return result(textResult("logged"))
+		case "image":
+			return result(map[string]interface{}{"content": []interface{}{
+				map[string]interface{}{"type": "image", "mimeType": "image/png", "data": base64.StdEncoding.EncodeToString(fakeImage)},
+			}})
+		case "sleep":
+			ms, _ := arguments["ms"].(float64)
+			reply := func() {
+				time.Sleep(time.Duration(ms) * time.Millisecond)
+				s.send(result(textResult("slept")))
+			}
+			if s.modes["blocking"] {
+				reply()
+			} else {
+				go reply()
+			}
+			return nil
+		default:
+			data, _ := json.Marshal(arguments)
+			return result(textResult(string(data)))
+		}
+	default:
+		return map[string]interface{}{"jsonrpc": "2.0", "id": id, "error": map[string]interface{}{"code": errCodeMethodNotFound, "message": "method not found"}}
+	}
+}
+
+// fakeImage is the payload of the image tool: a PNG signature followed by bytes that are not UTF-8
+var fakeImage = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0x00, 0xff, 0xfe, 0xc3, 0x28}
+
+func textResult(text string) map[string]interface{} {
+	return map[string]interface{}{"content": []interface{}{map[string]interface{}{"type": "text", "text": text}}}
+}
+
+// startFakeServer starts the test binary as a fake MCP server in the given modes and returns
+// the client and a function reading the methods the server received so far
+func startFakeServer(t *testing.T, modes string, opts Options) (*StdioClient, func() []string) {
+	t.Helper()
+	logPath := t.TempDir() + "/received.log"
+	env := map[string]string{"MCP_FAKE_SERVER": modes, "MCP_FAKE_LOG": logPath}
+
+	client, err := NewStdioClient(os.Args[0], nil, env, opts)
+	if err != nil {
+		t.Fatalf("NewStdioClient(%q) error = %v", modes, err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client, func() []string { return receivedMethods(logPath) }
+}
+
+func receivedMethods(logPath string) []string {
+	data, _ := os.ReadFile(logPath)
+	return strings.Fields(string(data))
+}
@@ -0,0 +1,194 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// contentLengthHeader starts an LSP-style framed message
+const contentLengthHeader = "content-length:"
+
+// errMalformedFrame reports Content-Length headers that cannot be used to read a message
+var errMalformedFrame = errors.New("malformed message headers")
+
+// frameReader splits the server's output into JSON-RPC messages. It accepts newline-delimited
+// JSON, several objects on one line, objects spread over several lines and LSP-style
+// Content-Length framing, and drops messages larger than limit without buffering them. Other
+// output, such as log lines a server prints to stdout, is logged and skipped.
+type frameReader struct {
+	reader *bufio.Reader
+	limit  int
+	name   string // server name used when logging skipped output
+}
+
+// newFrameReader creates a frameReader accepting messages of at most limit bytes
+func newFrameReader(r io.Reader, limit int, name string) *frameReader {
+	return &frameReader{reader: bufio.NewReaderSize(r, 64*1024), limit: limit, name: name}
+}
+
+// next returns the next message, or ErrMessageTooLarge after discarding an oversized one
+func (f *frameReader) next() ([]byte, error) {
+	for {
+		first, err := f.skipWhitespace()
+		if err != nil {
+			return nil, err
+		}
+		if first == '{' || first == '[' {
+			return f.readValue()
+		}
+
+		if f.framed() {
+			message, err := f.readFramed()
+			if !errors.Is(err, errMalformedFrame) {
+				return message, err
+			}
+			log.Printf("Skipping output from MCP server %s: %v", f.name, err)
+			continue
+		}
+
+		line, err := f.readLine()
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Skipping non-JSON output from MCP server %s: %q", f.name, line)
+	}
+}
+
+// framed reports whether the upcoming line is a Content-Length header, matched case-insensitively
+func (f *frameReader) framed() bool {
+	prefix, _ := f.reader.Peek(len(contentLengthHeader))
+	return strings.EqualFold(string(prefix), contentLengthHeader)
+}
+
+// skipWhitespace consumes whitespace between messages and peeks at the next byte
+func (f *frameReader) skipWhitespace() (byte, error) {
+	for {
+		b, err := f.reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, f.reader.UnreadByte()
+	}
+}
+
+// readValue reads exactly one JSON object or array by tracking nesting outside of strings
+func (f *frameReader) readValue() ([]byte, error) {
+	var value []byte
+	oversized := false
+	depth, inString, escaped := 0, false, false
+
+	for {
+		b, err := f.reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		if !oversized {
+			if len(value) >= f.limit {
+				oversized, value = true, nil
+			} else {
+				value = append(value, b)
+			}
+		}
+
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case b == '\\':
+				escaped = true
+			case b == '"':
+				inString = false
+			}
+		case b == '"':
+			inString = true
+		case b == '{' || b == '[':
+			depth++
+		case b == '}' || b == ']':
+			depth--
+			if depth == 0 {
+				if oversized {
+					return nil, ErrMessageTooLarge
+				}
+				return value, nil
+			}
+		}
+	}
+}
+
+// readFramed reads a message preceded by Content-Length headers and a blank line. Headers that
+// cannot be used are reported as errMalformedFrame; a JSON message where the blank line should be
+// is left unread so it is not lost
+func (f *frameReader) readFramed() ([]byte, error) {
+	length := -1
+	var invalid error
+	for {
+		if next, _ := f.reader.Peek(1); len(next) == 1 && (next[0] == '{' || next[0] == '[') {
+			return nil, fmt.Errorf("%w: headers not followed by a blank line", errMalformedFrame)
+		}
+		line, err := f.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 {
+			break
+		}
+
+		name, value, found := strings.Cut(string(line), ":")
+		if !found {
+			return nil, fmt.Errorf("%w: header line %q", errMalformedFrame, line)
+		}
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || length < 0 {
+				invalid = fmt.Errorf("%w: invalid Content-Length %q", errMalformedFrame, value)
+			}
+		}
+	}
+	if invalid != nil {
+		return nil, invalid
+	}
+
+	if length > f.limit {
+		if _, err := io.CopyN(ioutil.Discard, f.reader, int64(length)); err != nil {
+			return nil, err
+		}
+		return nil, ErrMessageTooLarge
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(f.reader, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// readLine reads up to the next newline, dropping the content beyond limit
+func (f *frameReader) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := f.reader.ReadSlice('\n')
+		if len(line) < f.limit {
+			line = append(line, chunk[:min(len(chunk), f.limit-len(line))]...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return nil, err
+		}
+		return bytes.TrimRight(line, "\r\n"), nil
+	}
+}
@@ -0,0 +1,110 @@
+package mcp
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFrameReader(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		limit int
+		want  []string // messages in order; "" stands for ErrMessageTooLarge
+	}{
+		{
+			name:  "newline delimited",
+			input: "{\"id\":1}\n{\"id\":2}\n",
+			want:  []string{`{"id":1}`, `{"id":2}`},
+		},
+		{
+			name:  "several objects on one line",
+			input: `{"id":1}{"id":2} [{"id":3}]`,
+			want:  []string{`{"id":1}`, `{"id":2}`, `[{"id":3}]`},
+		},
+		{
+			name:  "object spread over lines with braces in strings",
+			input: "{\n  \"text\": \"a } b \\\" {\",\n  \"id\": 1\n}\n",
+			want:  []string{"{\n  \"text\": \"a } b \\\" {\",\n  \"id\": 1\n}"},
+		},
+		{
+			name:  "content length framing",
+			input: "Content-Length: 8\r\nContent-Type: application/json\r\n\r\n{\"id\":1}{\"id\":2}\n",
+			want:  []string{`{"id":1}`, `{"id":2}`},
+		},
+		{
+			name:  "lower case header",
+			input: "content-length: 8\n\n{\"id\":1}",
+			want:  []string{`{"id":1}`},
+		},
+		{
+			name:  "log lines starting with C are skipped",
+			input: "Config: loaded\n{\"id\":1}\nConnecting to db: ok\n{\"id\":2}\n",
+			want:  []string{`{"id":1}`, `{"id":2}`},
+		},
+		{
+			name:  "other non-JSON output is skipped",
+			input: "starting server...\n{\"id\":1}\n",
+			want:  []string{`{"id":1}`},
+		},
+		{
+			name:  "invalid content length is skipped",
+			input: "Content-Length: abc\n\n{\"id\":1}\n",
+			want:  []string{`{"id":1}`},
+		},
+		{
+			name:  "header without blank line keeps the following message",
+			input: "Content-Length: 8\n{\"id\":1}\n",
+			want:  []string{`{"id":1}`},
+		},
+		{
+			name:  "non-header line ends a header block",
+			input: "Content-Length: 8\nready\n{\"id\":1}\n",
+			want:  []string{`{"id":1}`},
+		},
+		{
+			name:  "oversized object is dropped",
+			input: "{\"data\":\"xxxxxxxxxxxxxxxxxxxx\"}\n{\"id\":1}\n",
+			limit: 16,
+			want:  []string{"", `{"id":1}`},
+		},
+		{
+			name:  "oversized framed message is dropped",
+			input: "Content-Length: 30\n\n{\"data\":\"xxxxxxxxxxxxxxxxxx\"}{\"id\":1}",
+			limit: 16,
+			want:  []string{"", `{"id":1}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit := tt.limit
+			if limit == 0 {
+				limit = DefaultMaxMessageSize
+			}
+			frames := newFrameReader(strings.NewReader(tt.input), limit, "test")
+
+			var got []string
+			for {
+				message, err := frames.next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if errors.Is(err, ErrMessageTooLarge) {
+					got = append(got, "")
+					continue
+				}
+				if err != nil {
+					t.Fatalf("next() error = %v after %q", err, got)
+				}
+				got = append(got, string(message))
+			}
+
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("messages = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// JSON-RPC error codes used when answering server-initiated requests
+const (
+	errCodeInvalidParams  = -32602
+	errCodeMethodNotFound = -32601
+	errCodeInternal       = -32603
+)
+
+// capabilities builds the client capabilities advertised in initialize
+func (c *StdioClient) capabilities() map[string]interface{} {
+	capabilities := map[string]interface{}{}
+	if len(c.opts.Roots) > 0 {
+		capabilities["roots"] = map[string]interface{}{"listChanged": false}
+	}
+	if c.opts.Sampler != nil {
+		capabilities["sampling"] = map[string]interface{}{}
+	}
+	for name, value := range c.opts.Capabilities {
+		if value == nil {
+			delete(capabilities, name)
+			continue
+		}
+		capabilities[name] = value
+	}
+	return capabilities
+}
+
+// handleServerMessage answers a request or consumes a notification sent by the server
+func (c *StdioClient) handleServerMessage(ctx context.Context, message map[string]interface{}) error {
+	method := getString(message, "method")
+	id, isRequest := message["id"]
+	if !isRequest {
+		// Notifications need no reply
+		c.handleNotification(method, message["params"])
+		return nil
+	}
+
+	switch method {
+	case "ping":
+		return c.sendResult(id, map[string]interface{}{})
+	case "roots/list":
+		return c.sendResult(id, map[string]interface{}{"roots": c.roots()})
+	case "sampling/createMessage":
+		if c.opts.Sampler == nil {
+			return c.sendError(id, errCodeMethodNotFound, "sampling is not enabled for this server")
+		}
+		return c.handleSampling(ctx, id, message["params"])
+	default:
+		log.Printf("MCP server sent unsupported request %q", method)
+		return c.sendError(id, errCodeMethodNotFound, "method not found: "+method)
+	}
+}
+
+// roots returns the configured roots in their wire format
+func (c *StdioClient) roots() []map[string]interface{} {
+	roots := make([]map[string]interface{}, 0, len(c.opts.Roots))
+	for _, root := range c.opts.Roots {
+		entry := map[string]interface{}{"uri": root.URI}
+		if root.Name != "" {
+			entry["name"] = root.Name
+		}
+		roots = append(roots, entry)
+	}
+	return roots
+}
+
+// sendResult replies to a server-initiated request with a result
+func (c *StdioClient) sendResult(id interface{}, result interface{}) error {
+	return c.sendRequest(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"result":  result,
+	})
+}
+
+// sendError replies to a server-initiated request with a JSON-RPC error
+func (c *StdioClient) sendError(id interface{}, code int, message string) error {
+	return c.sendRequest(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"error":   map[string]interface{}{"code": code, "message": message},
+	})
+}
+
+// samplingParams is the subset of sampling/createMessage params the proxy understands
+type samplingParams struct {
+	Messages []struct {
+		Role    string `json:"role"`
+		Content struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	} `json:"messages"`
+	SystemPrompt string   `json:"systemPrompt"`
+	MaxTokens    int      `json:"maxTokens"`
+	Temperature  *float64 `json:"temperature"`
+}
+
+// handleSampling routes a sampling/createMessage request to the configured LLM provider
+func (c *StdioClient) handleSampling(ctx context.Context, id interface{}, rawParams interface{}) error {
+	data, err := json.Marshal(rawParams)
+	if err != nil {
+		return c.sendError(id, errCodeInvalidParams, err.Error())
+	}
+
+	var params samplingParams
+	if err := json.Unmarshal(data, &params); err != nil {
+		return c.sendError(id, errCodeInvalidParams, err.Error())
+	}
+
+	req := types.SamplingRequest{
+		SystemPrompt: params.SystemPrompt,
+		MaxTokens:    params.MaxTokens,
+		Temperature:  params.Temperature,
+	}
+	for _, message := range params.Messages {
+		if message.Content.Type != "text" {
+			return c.sendError(id, errCodeInvalidParams, fmt.Sprintf("unsupported sampling content type %q", message.Content.Type))
+		}
+		req.Messages = append(req.Messages, types.SamplingMessage{Role: message.Role, Text: message.Content.Text})
+	}
+
+	result, err := c.opts.Sampler.CreateMessage(ctx, req)
+	if err != nil {
+		log.Printf("Sampling request failed: %v", err)
+		return c.sendError(id, errCodeInternal, err.Error())
+	}
+
+	return c.sendResult(id, map[string]interface{}{
+		"role":       "assistant",
+		"content":    map[string]interface{}{"type": "text", "text": result.Text},
+		"model":      result.Model,
+		"stopReason": result.StopReason,
+	})
+}
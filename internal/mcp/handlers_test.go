@@ -0,0 +1,263 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// callJSON calls a tool of the fake server and decodes the JSON text of its result into v
+func callJSON(t *testing.T, client *StdioClient, toolName string, arguments map[string]interface{}, v interface{}) {
+	t.Helper()
+	result, err := client.CallTool(context.Background(), toolName, arguments)
+	if err != nil {
+		t.Fatalf("CallTool(%s) error = %v", toolName, err)
+	}
+	if err := json.Unmarshal([]byte(resultText(t, result)), v); err != nil {
+		t.Fatalf("CallTool(%s) returned %v: %v", toolName, result, err)
+	}
+}
+
+func TestRoots(t *testing.T) {
+	tests := []struct {
+		name      string
+		roots     []types.Root
+		wantRoots string // roots/list answer as JSON
+	}{
+		{
+			name:      "configured roots",
+			roots:     []types.Root{{URI: "file:///srv/repo", Name: "repo"}, {URI: "file:///tmp"}},
+			wantRoots: `[{"name":"repo","uri":"file:///srv/repo"},{"uri":"file:///tmp"}]`,
+		},
+		{name: "no roots", wantRoots: `[]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := startFakeServer(t, "", Options{Roots: tt.roots})
+
+			var init struct {
+				Capabilities map[string]interface{} `json:"capabilities"`
+			}
+			callJSON(t, client, "initparams", nil, &init)
+			if _, advertised := init.Capabilities["roots"]; advertised != (len(tt.roots) > 0) {
+				t.Errorf("initialize capabilities = %v, want roots advertised = %v", init.Capabilities, len(tt.roots) > 0)
+			}
+
+			var response struct {
+				Result struct {
+					Roots json.RawMessage `json:"roots"`
+				} `json:"result"`
+			}
+			callJSON(t, client, "ask", map[string]interface{}{"method": "roots/list"}, &response)
+			if string(response.Result.Roots) != tt.wantRoots {
+				t.Errorf("roots/list = %s, want %s", response.Result.Roots, tt.wantRoots)
+			}
+		})
+	}
+}
+
+func TestUnsupportedServerRequest(t *testing.T) {
+	client, _ := startFakeServer(t, "", Options{})
+
+	var response struct {
+		Error struct {
+			Code int `json:"code"`
+		} `json:"error"`
+	}
+	callJSON(t, client, "ask", map[string]interface{}{"method": "elicitation/create"}, &response)
+	if response.Error.Code != errCodeMethodNotFound {
+		t.Errorf("unsupported request answered with code %d, want %d", response.Error.Code, errCodeMethodNotFound)
+	}
+
+	// The connection is still usable afterwards
+	if _, err := client.CallTool(context.Background(), "echo", nil); err != nil {
+		t.Errorf("CallTool(echo) error = %v", err)
+	}
+}
+
+func TestServerRequestIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		id   string // raw JSON id of the server's request
+	}{
+		{name: "string id", id: `"srv-1"`},
+		{name: "integer id clashing with client ids", id: `1`},
+		{name: "integer id of the call in flight", id: `2`},
+		{name: "integer beyond float64 precision", id: `9007199254740993`},
+		{name: "string id clashing with prefixed client ids", opts: Options{IDPrefix: "proxy-"}, id: `"proxy-2"`},
+		{name: "fractional id", id: `1.5`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.ReadTimeout = 2 * time.Second
+			client, _ := startFakeServer(t, "", opts)
+
+			// The fake only answers the call once the client's reply echoes the id exactly
+			result, err := client.CallTool(context.Background(), "ask", map[string]interface{}{"method": "ping", "id": tt.id})
+			if err != nil {
+				t.Fatalf("CallTool(ask) error = %v, want the reply to ping %s to carry that id", err, tt.id)
+			}
+			if got := resultText(t, result); got != `{"result":{}}` {
+				t.Errorf("ping answered with %s, want an empty result", got)
+			}
+		})
+	}
+}
+
+// fakeSampler answers sampling requests with a canned completion, or fails with err, and records
+// the last request
+type fakeSampler struct {
+	err      error
+	received types.SamplingRequest
+}
+
+func (s *fakeSampler) CreateMessage(ctx context.Context, req types.SamplingRequest) (*types.SamplingResult, error) {
+	s.received = req
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &types.SamplingResult{Text: "Paris", Model: "fake-model", StopReason: "endTurn"}, nil
+}
+
+func TestSampling(t *testing.T) {
+	temperature := 0.2
+	params := map[string]interface{}{
+		"systemPrompt": "Answer briefly",
+		"maxTokens":    50,
+		"temperature":  temperature,
+		"messages": []interface{}{
+			map[string]interface{}{"role": "user", "content": map[string]interface{}{"type": "text", "text": "Capital of France?"}},
+		},
+	}
+	imageParams := map[string]interface{}{"messages": []interface{}{
+		map[string]interface{}{"role": "user", "content": map[string]interface{}{"type": "image", "data": "AAAA"}},
+	}}
+
+	tests := []struct {
+		name       string
+		sampler    *fakeSampler // nil disables sampling
+		params     map[string]interface{}
+		wantResult string // result as JSON
+		wantCode   int    // JSON-RPC error code when the request is refused
+	}{
+		{name: "completion", sampler: &fakeSampler{}, params: params, wantResult: `{"content":{"text":"Paris","type":"text"},"model":"fake-model","role":"assistant","stopReason":"endTurn"}`},
+		{name: "provider failure", sampler: &fakeSampler{err: errors.New("quota exceeded")}, params: params, wantCode: errCodeInternal},
+		{name: "non-text content", sampler: &fakeSampler{}, params: imageParams, wantCode: errCodeInvalidParams},
+		{name: "sampling disabled", params: params, wantCode: errCodeMethodNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{}
+			if tt.sampler != nil {
+				opts.Sampler = tt.sampler
+			}
+			client, _ := startFakeServer(t, "", opts)
+
+			var init struct {
+				Capabilities map[string]interface{} `json:"capabilities"`
+			}
+			callJSON(t, client, "initparams", nil, &init)
+			if _, advertised := init.Capabilities["sampling"]; advertised != (tt.sampler != nil) {
+				t.Errorf("initialize capabilities = %v, want sampling advertised = %v", init.Capabilities, tt.sampler != nil)
+			}
+
+			var response struct {
+				Result json.RawMessage `json:"result"`
+				Error  struct {
+					Code int `json:"code"`
+				} `json:"error"`
+			}
+			callJSON(t, client, "ask", map[string]interface{}{"method": "sampling/createMessage", "params": tt.params}, &response)
+			if string(response.Result) != tt.wantResult || response.Error.Code != tt.wantCode {
+				t.Fatalf("sampling/createMessage = result %s, error code %d, want %s, %d", response.Result, response.Error.Code, tt.wantResult, tt.wantCode)
+			}
+
+			if tt.wantResult != "" {
+				want := types.SamplingRequest{
+					Messages:     []types.SamplingMessage{{Role: "user", Text: "Capital of France?"}},
+					SystemPrompt: "Answer briefly",
+					MaxTokens:    50,
+					Temperature:  &temperature,
+				}
+				if !reflect.DeepEqual(tt.sampler.received, want) {
+					t.Errorf("provider received %+v, want %+v", tt.sampler.received, want)
+				}
+			}
+		})
+	}
+}
+
+func TestInitializeParams(t *testing.T) {
+	roots := []types.Root{{URI: "file:///tmp"}}
+
+	tests := []struct {
+		name             string
+		opts             Options
+		wantClientInfo   map[string]interface{}
+		wantCapabilities map[string]interface{}
+	}{
+		{
+			name:             "defaults",
+			wantClientInfo:   map[string]interface{}{"name": DefaultClientName, "version": DefaultClientVersion},
+			wantCapabilities: map[string]interface{}{},
+		},
+		{
+			name:             "client info override",
+			opts:             Options{ClientInfo: types.ClientInfo{Name: "claude-desktop", Version: "0.9.2"}},
+			wantClientInfo:   map[string]interface{}{"name": "claude-desktop", "version": "0.9.2"},
+			wantCapabilities: map[string]interface{}{},
+		},
+		{
+			name:             "name only keeps the default version",
+			opts:             Options{ClientInfo: types.ClientInfo{Name: "agent"}},
+			wantClientInfo:   map[string]interface{}{"name": "agent", "version": DefaultClientVersion},
+			wantCapabilities: map[string]interface{}{},
+		},
+		{
+			name:             "capabilities merged over the derived ones",
+			opts:             Options{Roots: roots, Capabilities: map[string]interface{}{"experimental": map[string]interface{}{"streaming": true}}},
+			wantClientInfo:   map[string]interface{}{"name": DefaultClientName, "version": DefaultClientVersion},
+			wantCapabilities: map[string]interface{}{"roots": map[string]interface{}{"listChanged": false}, "experimental": map[string]interface{}{"streaming": true}},
+		},
+		{
+			name:             "capability replaced",
+			opts:             Options{Roots: roots, Capabilities: map[string]interface{}{"roots": map[string]interface{}{"listChanged": true}}},
+			wantClientInfo:   map[string]interface{}{"name": DefaultClientName, "version": DefaultClientVersion},
+			wantCapabilities: map[string]interface{}{"roots": map[string]interface{}{"listChanged": true}},
+		},
+		{
+			name:             "null removes a capability",
+			opts:             Options{Roots: roots, Capabilities: map[string]interface{}{"roots": nil}},
+			wantClientInfo:   map[string]interface{}{"name": DefaultClientName, "version": DefaultClientVersion},
+			wantCapabilities: map[string]interface{}{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := startFakeServer(t, "", tt.opts)
+
+			var init struct {
+				ClientInfo   map[string]interface{} `json:"clientInfo"`
+				Capabilities map[string]interface{} `json:"capabilities"`
+			}
+			callJSON(t, client, "initparams", nil, &init)
+			if !reflect.DeepEqual(init.ClientInfo, tt.wantClientInfo) {
+				t.Errorf("initialize clientInfo = %v, want %v", init.ClientInfo, tt.wantClientInfo)
+			}
+			if !reflect.DeepEqual(init.Capabilities, tt.wantCapabilities) {
+				t.Errorf("initialize capabilities = %v, want %v", init.Capabilities, tt.wantCapabilities)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+)
+
+// handleNotification consumes a notification sent by the server
+func (c *StdioClient) handleNotification(method string, params interface{}) {
+	switch method {
+	case "notifications/message":
+		c.logServerMessage(params)
+	}
+}
+
+// logServerMessage writes a logging notification to the proxy's log, attributed to the server
+func (c *StdioClient) logServerMessage(params interface{}) {
+	message, _ := params.(map[string]interface{})
+	level := getString(message, "level")
+	if level == "" {
+		level = "info"
+	}
+
+	source := c.opts.Name
+	if logger := getString(message, "logger"); logger != "" {
+		source += "/" + logger
+	}
+
+	data, ok := message["data"].(string)
+	if !ok {
+		encoded, _ := json.Marshal(message["data"])
+		data = string(encoded)
+	}
+
+	log.Printf("[mcp:%s] %s: %s", source, level, data)
+}
+
+// setLogLevel asks a server that supports logging to send messages at Options.LogLevel and above
+func (c *StdioClient) setLogLevel(ctx context.Context) error {
+	if c.opts.LogLevel == "" {
+		return nil
+	}
+	if _, ok := c.serverCapabilities["logging"]; !ok {
+		log.Printf("MCP server %s does not support logging; ignoring log level %q", c.opts.Name, c.opts.LogLevel)
+		return nil
+	}
+
+	response, err := c.roundTrip(ctx, c.newRequest("logging/setLevel", map[string]interface{}{"level": c.opts.LogLevel}))
+	if err != nil {
+		return err
+	}
+	if errorData, exists := response["error"]; exists {
+		log.Printf("MCP server %s rejected log level %q: %v", c.opts.Name, c.opts.LogLevel, errorData)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// logBuffer collects log output written from the client's goroutines
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestServerLogMessages(t *testing.T) {
+	logs := &logBuffer{}
+	log.SetOutput(logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	client, _ := startFakeServer(t, "logging", Options{Name: "db"})
+
+	tests := []struct {
+		name      string
+		arguments map[string]interface{}
+		want      string
+	}{
+		{name: "text message", arguments: map[string]interface{}{"level": "warning", "data": "disk almost full"}, want: "[mcp:db] warning: disk almost full"},
+		{name: "named logger", arguments: map[string]interface{}{"level": "error", "logger": "pool", "data": "connection lost"}, want: "[mcp:db/pool] error: connection lost"},
+		{name: "structured data", arguments: map[string]interface{}{"level": "debug", "data": map[string]interface{}{"rows": 3}}, want: `[mcp:db] debug: {"rows":3}`},
+		{name: "no level", arguments: map[string]interface{}{"data": "ready"}, want: "[mcp:db] info: ready"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := client.CallTool(context.Background(), "log", tt.arguments); err != nil {
+				t.Fatalf("CallTool(log) error = %v", err)
+			}
+			if !strings.Contains(logs.String(), tt.want) {
+				t.Errorf("log = %q, want %q", logs.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSetLogLevel(t *testing.T) {
+	tests := []struct {
+		name  string
+		modes string
+		level string
+		want  []string
+	}{
+		{name: "level sent to a server with logging", modes: "logging", level: "debug", want: []string{"initialize", "notifications/initialized", "logging/setLevel", "level=debug"}},
+		{name: "no level configured", modes: "logging", want: []string{"initialize", "notifications/initialized"}},
+		{name: "server without logging", level: "debug", want: []string{"initialize", "notifications/initialized"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, received := startFakeServer(t, tt.modes, Options{Name: "db", LogLevel: tt.level})
+			if _, err := client.CallTool(context.Background(), "echo", nil); err != nil {
+				t.Fatalf("CallTool() error = %v", err)
+			}
+			if got := received(); !reflect.DeepEqual(got[:len(got)-1], tt.want) {
+				t.Errorf("server received %v, want %v before the call", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// job is a unit of work that needs exclusive use of the server's stdin and stdout
+type job func()
+
+// serve owns the stdio pipes: it runs queued jobs one at a time in arrival order and,
+// while idle, answers requests and notifications the server sends on its own
+func (c *StdioClient) serve() {
+	for {
+		select {
+		case run := <-c.jobs:
+			run()
+
+		case line, ok := <-c.messages:
+			if !ok {
+				// The server went away; keep serving jobs so callers get ErrClosed instead of hanging
+				c.drainJobs()
+				return
+			}
+			if line == nil {
+				log.Printf("Discarded unsolicited MCP server message larger than %d bytes", c.opts.MaxMessageSize)
+				continue
+			}
+			c.handleIdleMessage(line)
+
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// drainJobs runs remaining jobs after stdout closed; their reads fail immediately with ErrClosed
+func (c *StdioClient) drainJobs() {
+	for {
+		select {
+		case run := <-c.jobs:
+			run()
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// handleIdleMessage processes a message that arrived while no request was in flight
+func (c *StdioClient) handleIdleMessage(line []byte) {
+	message, err := decodeMessage(line)
+	if err != nil {
+		log.Printf("Ignoring malformed message from MCP server: %v", err)
+		return
+	}
+
+	if _, isServerMessage := message["method"]; !isServerMessage {
+		log.Printf("Ignoring unexpected response from MCP server with id %v", message["id"])
+		return
+	}
+
+	if err := c.handleServerMessage(context.Background(), message); err != nil {
+		log.Printf("Failed to handle message from MCP server: %v", err)
+	}
+}
+
+// do queues fn to run with exclusive access to the server and waits for it to finish
+func (c *StdioClient) do(ctx context.Context, fn func() error) error {
+	result := make(chan error, 1)
+
+	select {
+	case c.jobs <- func() { result <- fn() }:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.done:
+		return fmt.Errorf("client closed: %w", ErrClosed)
+	}
+
+	// fn honours ctx itself, so once queued it is waited for to keep the pipes consistent
+	select {
+	case err := <-result:
+		return err
+	case <-c.done:
+		return fmt.Errorf("client closed: %w", ErrClosed)
+	}
+}
+
+// newRequest builds a JSON-RPC request with the next request id; it must only be called from a job
+func (c *StdioClient) newRequest(method string, params interface{}) map[string]interface{} {
+	c.lastID++
+	var id interface{} = c.lastID
+	if c.opts.IDPrefix != "" {
+		id = c.opts.IDPrefix + strconv.Itoa(c.lastID)
+	}
+
+	req := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  method,
+	}
+	if params != nil {
+		req["params"] = params
+	}
+	return req
+}
+
+// decodeMessage parses one JSON-RPC message. The id of a server-initiated request is kept as the
+// raw JSON the server sent, so the reply echoes it exactly: ids share no space between the two
+// directions, and a number like 9007199254740993 would not survive a round trip through float64
+func decodeMessage(line []byte) (map[string]interface{}, error) {
+	var message map[string]interface{}
+	if err := json.Unmarshal(line, &message); err != nil {
+		return nil, err
+	}
+
+	if _, isServerMessage := message["method"]; isServerMessage {
+		var envelope struct {
+			ID json.RawMessage `json:"id"`
+		}
+		if err := json.Unmarshal(line, &envelope); err != nil {
+			return nil, err
+		}
+		if envelope.ID != nil {
+			message["id"] = envelope.ID
+		}
+	}
+	return message, nil
+}
+
+// sameID reports whether two JSON-RPC ids are equal, treating an int we sent and the float64
+// it decodes to as the same id
+func sameID(a, b interface{}) bool {
+	return idKey(a) == idKey(b)
+}
+
+// idKey normalizes a JSON-RPC id for comparison; strings and numbers never compare equal
+func idKey(id interface{}) string {
+	switch v := id.(type) {
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return strconv.Quote(v)
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T:%v", v, v)
+	}
+}
@@ -0,0 +1,62 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+// ProtocolVersion is the MCP revision requested during initialize
+const ProtocolVersion = "2024-11-05"
+
+// DefaultClientName and DefaultClientVersion identify the proxy in initialize unless a server's
+// clientInfo overrides them
+const (
+	DefaultClientName    = "mcp-smart-proxy"
+	DefaultClientVersion = "1.0.0"
+)
+
+// supportedProtocolVersions lists the revisions this client can speak if the server counter-offers
+var supportedProtocolVersions = []string{"2024-11-05", "2025-03-26", "2025-06-18"}
+
+// ErrUnsupportedProtocolVersion is returned when the server negotiates a revision this client cannot speak
+var ErrUnsupportedProtocolVersion = errors.New("unsupported MCP protocol version")
+
+// negotiateVersion records the protocolVersion and server capabilities from an initialize
+// response, rejecting revisions outside supportedProtocolVersions
+func (c *StdioClient) negotiateVersion(response map[string]interface{}) error {
+	if errorData, exists := response["error"]; exists {
+		return fmt.Errorf("initialize error: %v", errorData)
+	}
+
+	result, _ := response["result"].(map[string]interface{})
+	version := getString(result, "protocolVersion")
+	if version == "" {
+		return fmt.Errorf("%w: server did not report a protocolVersion", ErrUnsupportedProtocolVersion)
+	}
+	if !isSupportedVersion(version) {
+		return fmt.Errorf("%w: server speaks %q, client supports %v", ErrUnsupportedProtocolVersion, version, supportedProtocolVersions)
+	}
+	if version != ProtocolVersion {
+		log.Printf("MCP server negotiated protocol version %s instead of requested %s", version, ProtocolVersion)
+	}
+
+	c.protocolVersion = version
+	c.serverCapabilities, _ = result["capabilities"].(map[string]interface{})
+	return nil
+}
+
+// isSupportedVersion reports whether version is one this client can speak
+func isSupportedVersion(version string) bool {
+	for _, supported := range supportedProtocolVersions {
+		if version == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// ProtocolVersion returns the MCP revision agreed with the server during initialize
+func (c *StdioClient) ProtocolVersion() string {
+	return c.protocolVersion
+}
@@ -0,0 +1,54 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProtocolVersionNegotiation(t *testing.T) {
+	tests := []struct {
+		name        string
+		modes       string
+		wantVersion string
+		wantErr     string // in the NewStdioClient error, which then wraps ErrUnsupportedProtocolVersion
+	}{
+		{name: "requested version", wantVersion: ProtocolVersion},
+		{name: "supported counter-offer", modes: "newversion", wantVersion: "2025-06-18"},
+		{name: "supported counter-offer in a batch", modes: "newversion,batch", wantVersion: "2025-06-18"},
+		{name: "unsupported version", modes: "badversion", wantErr: `server speaks "1999-01-01"`},
+		{name: "unsupported version in a batch", modes: "badversion,batch", wantErr: `server speaks "1999-01-01"`},
+		{name: "no version", modes: "noversion", wantErr: "did not report a protocolVersion"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{"MCP_FAKE_SERVER": tt.modes}
+			opts := Options{Batch: strings.Contains(tt.modes, "batch"), ConnectTimeout: 5 * time.Second}
+			client, err := NewStdioClient(os.Args[0], nil, env, opts)
+			if tt.wantErr != "" {
+				if err == nil {
+					client.Close()
+				}
+				if !errors.Is(err, ErrUnsupportedProtocolVersion) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("NewStdioClient() error = %v, want ErrUnsupportedProtocolVersion with %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewStdioClient() error = %v", err)
+			}
+			defer client.Close()
+
+			if got := client.ProtocolVersion(); got != tt.wantVersion {
+				t.Errorf("ProtocolVersion() = %q, want %q", got, tt.wantVersion)
+			}
+			if _, err := client.CallTool(context.Background(), "echo", nil); err != nil {
+				t.Errorf("CallTool(echo) after negotiation error = %v", err)
+			}
+		})
+	}
+}
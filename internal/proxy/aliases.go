@@ -0,0 +1,37 @@
+package proxy
+
+import (
+	"sort"
+)
+
+// resolveAlias returns the tool name an alias from proxy.aliases stands for; a real tool of the
+// same name takes precedence over the alias. The caller must hold p.mu
+func (p *SmartProxy) resolveAlias(name string) string {
+	if p.searchToolEnabled(name) {
+		return name
+	}
+	if _, exists := p.toolCache.Tools[name]; exists {
+		return name
+	}
+	if target, ok := p.config.Proxy.Aliases[name]; ok {
+		return target
+	}
+	return name
+}
+
+// aliasesOf returns the usable aliases of a tool, leaving out those shadowed by a real tool;
+// the caller must hold p.mu
+func (p *SmartProxy) aliasesOf(toolName string) []string {
+	var aliases []string
+	for alias, target := range p.config.Proxy.Aliases {
+		if target != toolName {
+			continue
+		}
+		if _, shadowed := p.toolCache.Tools[alias]; shadowed {
+			continue
+		}
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	return aliases
+}
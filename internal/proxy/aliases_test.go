@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestAliases(t *testing.T) {
+	config := types.MCPConfig{Proxy: types.ProxySettings{Aliases: map[string]string{
+		"open":  "read_file_v2",
+		"cat":   "read_file_v2",
+		"write": "read_file_v2", // shadowed by the real write tool
+		"gone":  "missing",
+	}}}
+	p := newTestProxy(t, config, map[string]types.MCPClient{"files": newFakeClient("read_file_v2", "write")})
+
+	tests := []struct {
+		name     string
+		tool     string
+		wantTool string // tool answering the call; empty expects ErrToolNotFound
+	}{
+		{name: "real name", tool: "read_file_v2", wantTool: "read_file_v2"},
+		{name: "alias", tool: "open", wantTool: "read_file_v2"},
+		{name: "second alias", tool: "cat", wantTool: "read_file_v2"},
+		{name: "real tool wins a collision", tool: "write", wantTool: "write"},
+		{name: "alias of a missing tool", tool: "gone"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := p.UseTool(context.Background(), tt.tool, types.ToolRequest{})
+			if tt.wantTool == "" {
+				if !errors.Is(err, types.ErrToolNotFound) {
+					t.Errorf("UseTool(%q) error = %v, want %v", tt.tool, err, types.ErrToolNotFound)
+				}
+				return
+			}
+			if err != nil || resultText(result) != tt.wantTool {
+				t.Errorf("UseTool(%q) = %v, %v, want an answer from %s", tt.tool, result, err, tt.wantTool)
+			}
+
+			tool, err := p.GetTool(context.Background(), tt.tool)
+			if err != nil || tool.Name != tt.wantTool {
+				t.Errorf("GetTool(%q) = %v, %v, want %s", tt.tool, tool, err, tt.wantTool)
+			}
+		})
+	}
+
+	tools, err := p.ListTools(context.Background())
+	if err != nil {
+		t.Fatalf("ListTools() error = %v", err)
+	}
+	aliases := make(map[string][]string)
+	for _, tool := range tools {
+		aliases[tool.Name] = tool.Aliases
+	}
+	want := map[string][]string{"read_file_v2": {"cat", "open"}, "write": nil}
+	if !reflect.DeepEqual(aliases, want) {
+		t.Errorf("ListTools() aliases = %v, want %v", aliases, want)
+	}
+
+	discovered, err := p.DiscoverTools(context.Background(), types.ProxyRequest{Query: "files", Exclude: []string{"open"}})
+	if err != nil {
+		t.Fatalf("DiscoverTools() error = %v", err)
+	}
+	for _, tool := range discovered {
+		if tool.Name == "read_file_v2" {
+			t.Errorf("DiscoverTools() excluding the alias open = %s, want read_file_v2 left out", joinToolNames(discovered))
+		}
+	}
+}
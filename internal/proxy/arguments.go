@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"mcp-smart-proxy/pkg/types"
+)
+
+// checkArguments enforces the global and per-tool argument key policies before a call is dispatched.
+// The allow-list names a tool's parameters and applies to top-level keys only; denied keys are
+// rejected at any depth, so nesting a key inside an object or array does not slip it through
+func (p *SmartProxy) checkArguments(toolName string, arguments map[string]interface{}) error {
+	global := p.config.Proxy.Arguments
+	perTool := p.config.Proxy.ToolArguments[toolName]
+
+	allow := global.Allow
+	if len(perTool.Allow) > 0 {
+		allow = perTool.Allow
+	}
+
+	for key := range arguments {
+		if len(allow) > 0 && !contains(allow, key) {
+			return &types.ForbiddenArgumentError{Tool: toolName, Argument: key, Reason: "not in the allow-list"}
+		}
+	}
+
+	if path := deniedKey(arguments, "", func(key string) bool { return contains(global.Deny, key) || contains(perTool.Deny, key) }); path != "" {
+		return &types.ForbiddenArgumentError{Tool: toolName, Argument: path, Reason: "denied by policy"}
+	}
+	return nil
+}
+
+// deniedKey returns the dotted path of the first key in value, at any depth, that denied reports,
+// or "" when there is none
+func deniedKey(value interface{}, prefix string, denied func(key string) bool) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			path := prefix + key
+			if denied(key) {
+				return path
+			}
+			if found := deniedKey(nested, path+".", denied); found != "" {
+				return found
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if found := deniedKey(item, prefix, denied); found != "" {
+				return found
+			}
+		}
+	}
+	return ""
+}
+
+// contains reports whether list includes value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
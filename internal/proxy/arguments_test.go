@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestArgumentPolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		global       types.ArgumentRule
+		perTool      map[string]types.ArgumentRule
+		tool         string
+		arguments    map[string]interface{}
+		wantArgument string // path of the rejected argument; empty expects the call to go through
+	}{
+		{name: "no policy", tool: "query", arguments: map[string]interface{}{"shell": "rm -rf /"}},
+		{name: "allowed payload", global: types.ArgumentRule{Deny: []string{"shell"}}, tool: "query", arguments: map[string]interface{}{"sql": "select 1", "limit": 5}},
+		{name: "denied key", global: types.ArgumentRule{Deny: []string{"shell"}}, tool: "query", arguments: map[string]interface{}{"sql": "select 1", "shell": "sh"}, wantArgument: "shell"},
+		{
+			name:         "denied key nested in an object",
+			global:       types.ArgumentRule{Deny: []string{"shell"}},
+			tool:         "query",
+			arguments:    map[string]interface{}{"options": map[string]interface{}{"env": map[string]interface{}{"shell": "sh"}}},
+			wantArgument: "options.env.shell",
+		},
+		{
+			name:         "denied key nested in an array",
+			perTool:      map[string]types.ArgumentRule{"query": {Deny: []string{"shell"}}},
+			tool:         "query",
+			arguments:    map[string]interface{}{"steps": []interface{}{map[string]interface{}{"sql": "select 1"}, map[string]interface{}{"shell": "sh"}}},
+			wantArgument: "steps.shell",
+		},
+		{name: "denied name as a value is fine", global: types.ArgumentRule{Deny: []string{"shell"}}, tool: "query", arguments: map[string]interface{}{"sql": "shell", "tags": []interface{}{"shell"}}},
+		{name: "key outside the allow-list", global: types.ArgumentRule{Allow: []string{"sql"}}, tool: "query", arguments: map[string]interface{}{"sql": "select 1", "limit": 5}, wantArgument: "limit"},
+		{
+			name:      "allow-list covers top-level keys only",
+			global:    types.ArgumentRule{Allow: []string{"sql", "options"}},
+			tool:      "query",
+			arguments: map[string]interface{}{"sql": "select 1", "options": map[string]interface{}{"timeout": 5}},
+		},
+		{
+			name:      "per-tool allow-list replaces the global one",
+			global:    types.ArgumentRule{Allow: []string{"sql"}},
+			perTool:   map[string]types.ArgumentRule{"query": {Allow: []string{"sql", "limit"}}},
+			tool:      "query",
+			arguments: map[string]interface{}{"sql": "select 1", "limit": 5},
+		},
+		{
+			name:         "per-tool deny wins over the global allow-list",
+			global:       types.ArgumentRule{Allow: []string{"sql", "limit"}},
+			perTool:      map[string]types.ArgumentRule{"query": {Deny: []string{"limit"}}},
+			tool:         "query",
+			arguments:    map[string]interface{}{"sql": "select 1", "limit": 5},
+			wantArgument: "limit",
+		},
+		{name: "other tools keep the global policy", perTool: map[string]types.ArgumentRule{"query": {Deny: []string{"shell"}}}, tool: "schemaless", arguments: map[string]interface{}{"shell": "sh"}},
+		{name: "schemaless tool outside the allow-list", global: types.ArgumentRule{Allow: []string{"path"}}, tool: "schemaless", arguments: map[string]interface{}{"path": "/tmp", "mode": "x"}, wantArgument: "mode"},
+		{name: "schemaless tool within the allow-list", global: types.ArgumentRule{Allow: []string{"path"}}, tool: "schemaless", arguments: map[string]interface{}{"path": "/tmp"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeClient("query")
+			// A tool without an input schema, which has no properties to check arguments against
+			client.tools = append(client.tools, types.Tool{Name: "schemaless", Description: "No schema"})
+			config := types.MCPConfig{Proxy: types.ProxySettings{Arguments: tt.global, ToolArguments: tt.perTool}}
+			p := newTestProxy(t, config, map[string]types.MCPClient{"db": client})
+
+			_, err := p.UseTool(context.Background(), tt.tool, types.ToolRequest{Arguments: tt.arguments})
+			var argErr *types.ForbiddenArgumentError
+			if tt.wantArgument == "" {
+				if err != nil || client.calls != 1 {
+					t.Errorf("UseTool(%s) error = %v after %d server calls, want one successful call", tt.tool, err, client.calls)
+				}
+				return
+			}
+			if !errors.As(err, &argErr) || argErr.Argument != tt.wantArgument {
+				t.Fatalf("UseTool(%s) error = %v, want argument %s forbidden", tt.tool, err, tt.wantArgument)
+			}
+			if client.calls != 0 {
+				t.Errorf("forbidden call reached the server")
+			}
+		})
+	}
+}
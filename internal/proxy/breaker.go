@@ -0,0 +1,122 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"syscall"
+	"time"
+
+	"mcp-smart-proxy/internal/mcp"
+	"mcp-smart-proxy/pkg/types"
+)
+
+// defaultBreakerCooldown is how long an open breaker rejects calls when proxy.breakerCooldown is unset
+const defaultBreakerCooldown = 30 * time.Second
+
+// Breaker states as reported in server status
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half-open"
+)
+
+// breaker is a per-server circuit breaker: after threshold consecutive failures it rejects
+// calls for the cooldown, then lets a single probe call through to test recovery
+type breaker struct {
+	threshold int // consecutive failures that open the breaker; 0 disables it
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool // a half-open probe call is in flight
+}
+
+// newBreaker creates a closed breaker
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+	return &breaker{threshold: threshold, cooldown: cooldown, state: breakerClosed}
+}
+
+// allow reports whether a call may proceed, returning an error wrapping types.ErrCircuitOpen when it may not
+func (b *breaker) allow(serverName string) error {
+	if b.threshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerOpen && time.Since(b.openedAt) >= b.cooldown {
+		b.state = breakerHalfOpen
+	}
+
+	switch b.state {
+	case breakerOpen:
+		retryIn := b.cooldown - time.Since(b.openedAt)
+		return fmt.Errorf("%w: server %s failed %d consecutive calls, retry in %s",
+			types.ErrCircuitOpen, serverName, b.failures, retryIn.Round(time.Second))
+	case breakerHalfOpen:
+		if b.probing {
+			return fmt.Errorf("%w: server %s is being probed for recovery", types.ErrCircuitOpen, serverName)
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// record updates the breaker with the outcome of a call that allow let through
+func (b *breaker) record(err error) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if errors.Is(err, context.Canceled) {
+		// The caller gave up; this says nothing about the server
+		return
+	}
+	if err == nil || !isServerFailure(err) {
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// status reports the breaker state and consecutive failure count
+func (b *breaker) status() (string, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerOpen && time.Since(b.openedAt) >= b.cooldown {
+		return breakerHalfOpen, b.failures
+	}
+	return b.state, b.failures
+}
+
+// isServerFailure reports whether an error means the server itself is unhealthy, as opposed
+// to it answering with a tool or protocol error
+func isServerFailure(err error) bool {
+	return isTransient(err) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, mcp.ErrClosed) ||
+		errors.Is(err, mcp.ErrProcessExited) ||
+		errors.Is(err, mcp.ErrUnresponsive) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrClosedPipe)
+}
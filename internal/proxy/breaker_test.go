@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"mcp-smart-proxy/internal/mcp"
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestBreaker(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+
+	// A call waits before it is made, is let through or rejected, and then reports its outcome
+	type call struct {
+		wait        time.Duration
+		err         error
+		wantAllowed bool
+	}
+	failure := call{err: mcp.ErrClosed, wantAllowed: true}
+	success := call{wantAllowed: true}
+
+	tests := []struct {
+		name         string
+		threshold    int
+		calls        []call
+		wantState    string
+		wantFailures int
+	}{
+		{name: "stays closed below the threshold", threshold: 3, calls: []call{failure, failure}, wantState: breakerClosed, wantFailures: 2},
+		{name: "opens at the threshold", threshold: 3, calls: []call{failure, failure, failure, {err: nil, wantAllowed: false}}, wantState: breakerOpen, wantFailures: 3},
+		{name: "success resets the count", threshold: 3, calls: []call{failure, failure, success, failure, failure}, wantState: breakerClosed, wantFailures: 2},
+		{name: "tool errors do not count", threshold: 2, calls: []call{{err: mcp.ErrToolError, wantAllowed: true}, {err: mcp.ErrToolError, wantAllowed: true}}, wantState: breakerClosed},
+		{name: "cancelled calls do not count", threshold: 2, calls: []call{failure, {err: context.Canceled, wantAllowed: true}}, wantState: breakerClosed, wantFailures: 1},
+		{name: "successful probe closes", threshold: 1, calls: []call{failure, {wait: cooldown, wantAllowed: true}}, wantState: breakerClosed},
+		{name: "failed probe reopens", threshold: 2, calls: []call{failure, failure, {wait: cooldown, err: mcp.ErrClosed, wantAllowed: true}, {err: nil, wantAllowed: false}}, wantState: breakerOpen, wantFailures: 3},
+		{name: "disabled", threshold: 0, calls: []call{failure, failure, failure, success}, wantState: breakerClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBreaker(tt.threshold, cooldown)
+			for i, c := range tt.calls {
+				time.Sleep(c.wait)
+				err := b.allow("db")
+				if allowed := err == nil; allowed != c.wantAllowed {
+					t.Fatalf("call %d: allow() error = %v, want allowed = %v", i, err, c.wantAllowed)
+				}
+				if err != nil {
+					if !errors.Is(err, types.ErrCircuitOpen) {
+						t.Fatalf("call %d: allow() error = %v, want ErrCircuitOpen", i, err)
+					}
+					continue
+				}
+				b.record(c.err)
+			}
+
+			if state, failures := b.status(); state != tt.wantState || failures != tt.wantFailures {
+				t.Errorf("status() = %s with %d failures, want %s with %d", state, failures, tt.wantState, tt.wantFailures)
+			}
+		})
+	}
+}
+
+func TestBreakerLetsOneProbeThrough(t *testing.T) {
+	b := newBreaker(1, 10*time.Millisecond)
+	b.allow("db")
+	b.record(mcp.ErrClosed)
+	time.Sleep(10 * time.Millisecond)
+
+	if state, _ := b.status(); state != breakerHalfOpen {
+		t.Fatalf("status() after the cooldown = %s, want %s", state, breakerHalfOpen)
+	}
+	if err := b.allow("db"); err != nil {
+		t.Fatalf("allow() for the probe error = %v", err)
+	}
+	if err := b.allow("db"); !errors.Is(err, types.ErrCircuitOpen) {
+		t.Errorf("allow() during the probe error = %v, want ErrCircuitOpen", err)
+	}
+}
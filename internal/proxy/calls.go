@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"mcp-smart-proxy/internal/auth"
+	"mcp-smart-proxy/internal/requestid"
+	"mcp-smart-proxy/pkg/types"
+)
+
+// inFlightCall is a running tool call that can be cancelled
+type inFlightCall struct {
+	info   types.InFlightCall
+	cancel context.CancelCauseFunc
+}
+
+// callRegistry tracks in-flight tool calls by call ID
+type callRegistry struct {
+	mu    sync.Mutex
+	calls map[string]*inFlightCall
+}
+
+// newCallRegistry creates an empty call registry
+func newCallRegistry() *callRegistry {
+	return &callRegistry{calls: make(map[string]*inFlightCall)}
+}
+
+// start registers a call and returns a context that CancelCall can cancel, plus a function
+// that must be called when the call finishes
+func (r *callRegistry) start(ctx context.Context, id, toolName string) (context.Context, func(), error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.calls[id]; exists {
+		return nil, nil, fmt.Errorf("%w: %s", types.ErrCallIDInUse, id)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	call := &inFlightCall{
+		info:   types.InFlightCall{ID: id, Tool: toolName, Tenant: auth.FromContext(ctx), RequestID: requestid.FromContext(ctx), Started: time.Now()},
+		cancel: cancel,
+	}
+	r.calls[id] = call
+
+	done := func() {
+		r.mu.Lock()
+		// A cancelled call is already gone and its ID may have been reused
+		if r.calls[id] == call {
+			delete(r.calls, id)
+		}
+		r.mu.Unlock()
+		cancel(nil)
+	}
+	return ctx, done, nil
+}
+
+// cancel aborts an in-flight call and forgets it. A non-empty tenant may only cancel its own calls;
+// other tenants' calls are reported as not found
+func (r *callRegistry) cancel(id, tenant string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	call, exists := r.calls[id]
+	if !exists || (tenant != "" && call.info.Tenant != tenant) {
+		return fmt.Errorf("%w: %s", types.ErrCallNotFound, id)
+	}
+	delete(r.calls, id)
+	call.cancel(types.ErrCallCancelled)
+	return nil
+}
+
+// list returns the in-flight calls, oldest first; a non-empty tenant only sees its own calls
+func (r *callRegistry) list(tenant string) []types.InFlightCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	calls := make([]types.InFlightCall, 0, len(r.calls))
+	for _, call := range r.calls {
+		if tenant == "" || call.info.Tenant == tenant {
+			calls = append(calls, call.info)
+		}
+	}
+	sort.Slice(calls, func(i, j int) bool { return calls[i].Started.Before(calls[j].Started) })
+	return calls
+}
+
+// callID returns the ID a tool call is tracked under: the one the caller chose, else a fresh one.
+// The request ID is not used, since clients commonly share one X-Request-ID across concurrent
+// calls and only IDs chosen explicitly should ever collide
+func callID(req types.ToolRequest) string {
+	if req.CallID != "" {
+		return req.CallID
+	}
+	return requestid.New()
+}
+
+// CancelCall cancels the in-flight tool call with the given ID; tenants other than admins can only
+// cancel their own calls
+func (p *SmartProxy) CancelCall(ctx context.Context, id string) error {
+	return p.calls.cancel(id, p.callOwner(ctx))
+}
+
+// Calls lists the tool calls currently in flight; tenants other than admins only see their own
+func (p *SmartProxy) Calls(ctx context.Context) []types.InFlightCall {
+	return p.calls.list(p.callOwner(ctx))
+}
+
+// callOwner returns the principal whose calls the caller is limited to, or "" for admins and
+// unauthenticated callers, who may see and cancel every call
+func (p *SmartProxy) callOwner(ctx context.Context) string {
+	if p.authorizeAdmin(ctx) == nil {
+		return ""
+	}
+	return auth.FromContext(ctx)
+}
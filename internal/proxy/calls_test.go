@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"mcp-smart-proxy/internal/requestid"
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestCallIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		callIDs [2]string // IDs of a running call and one started after it
+		wantErr error     // error of the second call
+	}{
+		{name: "calls without an ID do not collide"},
+		{name: "distinct IDs", callIDs: [2]string{"a", "b"}},
+		{name: "ID in use", callIDs: [2]string{"a", "a"}, wantErr: types.ErrCallIDInUse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			release := make(chan struct{})
+			client := newFakeClient("block", "read")
+			client.handlers["block"] = func(ctx context.Context, arguments map[string]interface{}) (map[string]interface{}, error) {
+				close(started)
+				<-release
+				return textResult("block"), nil
+			}
+			p := newTestProxy(t, types.MCPConfig{}, map[string]types.MCPClient{"files": client})
+			// Both calls are made in the same request, as when a client reuses its X-Request-ID
+			ctx := requestid.NewContext(context.Background(), "shared")
+
+			done := make(chan error)
+			go func() {
+				_, err := p.UseTool(ctx, "block", types.ToolRequest{CallID: tt.callIDs[0]})
+				done <- err
+			}()
+			<-started
+			_, err := p.UseTool(ctx, "read", types.ToolRequest{CallID: tt.callIDs[1]})
+			calls := p.Calls(ctx)
+			close(release)
+			if err := <-done; err != nil {
+				t.Fatalf("UseTool(block) error = %v", err)
+			}
+
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Errorf("UseTool(read) error = %v, want %v", err, tt.wantErr)
+			}
+			if len(calls) != 1 || calls[0].RequestID != "shared" {
+				t.Fatalf("Calls() = %+v, want the block call made in request shared", calls)
+			}
+			if want := tt.callIDs[0]; (want != "" && calls[0].ID != want) || calls[0].ID == "shared" {
+				t.Errorf("block call tracked under %q, want %q", calls[0].ID, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package proxy
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// toolVersion records when a cached tool appeared and when it last changed or was removed
+type toolVersion struct {
+	tool      types.Tool
+	firstSeen time.Time
+	modified  time.Time
+}
+
+// trackChanges compares the tool cache with the versions recorded at the previous sync, stamping
+// new and changed tools and remembering the removed ones; the caller must hold p.mu
+func (p *SmartProxy) trackChanges(now time.Time) {
+	for name, tool := range p.toolCache.Tools {
+		version, ok := p.versions[name]
+		switch {
+		case !ok:
+			version = toolVersion{firstSeen: now, modified: now}
+			delete(p.removed, name)
+		case !toolsEqual(version.tool, tool):
+			version.modified = now
+		}
+		version.tool = tool
+		p.versions[name] = version
+	}
+
+	for name, version := range p.versions {
+		if _, ok := p.toolCache.Tools[name]; !ok {
+			delete(p.versions, name)
+			version.modified = now
+			p.removed[name] = version
+		}
+	}
+}
+
+// ToolsSince returns the tools added or changed after since and the names of tools removed after
+// it, with the sync the delta reflects; pass its LastSync as the next since
+func (p *SmartProxy) ToolsSince(ctx context.Context, since time.Time) (*types.ToolListing, error) {
+	tenant := p.tenant(ctx)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	listing := &types.ToolListing{RecommendedTools: []types.Tool{}, Removed: []string{}, LastSync: p.toolCache.LastSync}
+	for _, tool := range p.listTools(tenant) {
+		if tool.ModifiedAt != nil && tool.ModifiedAt.After(since) {
+			listing.RecommendedTools = append(listing.RecommendedTools, tool)
+		}
+	}
+	for name, version := range p.removed {
+		if version.modified.After(since) && tenantAllows(tenant, name, version.tool.ServerName) {
+			listing.Removed = append(listing.Removed, name)
+		}
+	}
+	sort.Strings(listing.Removed)
+	return listing, nil
+}
@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestToolsSince(t *testing.T) {
+	client := newFakeClient("read", "write", "delete")
+	p := newTestProxy(t, types.MCPConfig{}, map[string]types.MCPClient{"files": client})
+
+	delta := func(since time.Time) (tools, removed []string, lastSync time.Time) {
+		t.Helper()
+		listing, err := p.ToolsSince(context.Background(), since)
+		if err != nil {
+			t.Fatalf("ToolsSince() error = %v", err)
+		}
+		for _, tool := range listing.RecommendedTools {
+			tools = append(tools, tool.Name)
+		}
+		return tools, listing.Removed, listing.LastSync
+	}
+
+	tools, removed, initial := delta(time.Time{})
+	if len(tools) != 3 || len(removed) != 0 {
+		t.Fatalf("ToolsSince(zero) = %v, removed %v, want every tool", tools, removed)
+	}
+	if tools, removed, _ := delta(initial); len(tools) != 0 || len(removed) != 0 {
+		t.Errorf("ToolsSince(last sync) = %v, removed %v, want no changes", tools, removed)
+	}
+
+	// An unchanged catalog leaves the delta empty
+	time.Sleep(time.Millisecond)
+	if err := p.SoftRefreshTools(context.Background()); err != nil {
+		t.Fatalf("SoftRefreshTools() error = %v", err)
+	}
+	if tools, removed, _ := delta(initial); len(tools) != 0 || len(removed) != 0 {
+		t.Errorf("ToolsSince() after an unchanged refresh = %v, removed %v, want no changes", tools, removed)
+	}
+
+	// write changes, delete goes away and search appears
+	client.update(func(c *fakeClient) {
+		write := fakeTool("write", "string")
+		write.Description = "Write or append to a file"
+		c.tools = []types.Tool{fakeTool("read", "string"), write, fakeTool("search", "string")}
+	})
+	time.Sleep(time.Millisecond)
+	if err := p.SoftRefreshTools(context.Background()); err != nil {
+		t.Fatalf("SoftRefreshTools() error = %v", err)
+	}
+
+	tools, removed, lastSync := delta(initial)
+	sort.Strings(tools)
+	if want := []string{"search", "write"}; !reflect.DeepEqual(tools, want) {
+		t.Errorf("ToolsSince() after the refresh = %v, want %v", tools, want)
+	}
+	if want := []string{"delete"}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("ToolsSince() removed = %v, want %v", removed, want)
+	}
+	if !lastSync.After(initial) {
+		t.Errorf("ToolsSince() last sync = %s, want later than %s", lastSync, initial)
+	}
+	if tools, removed, _ := delta(lastSync); len(tools) != 0 || len(removed) != 0 {
+		t.Errorf("ToolsSince(new last sync) = %v, removed %v, want no changes", tools, removed)
+	}
+
+	listed, err := p.ListTools(context.Background())
+	if err != nil {
+		t.Fatalf("ListTools() error = %v", err)
+	}
+	for _, tool := range listed {
+		if tool.Name == "read" && (tool.FirstSeen == nil || !tool.FirstSeen.Equal(initial) || !tool.ModifiedAt.Equal(initial)) {
+			t.Errorf("read first seen %v, modified %v, want both kept at %s", tool.FirstSeen, tool.ModifiedAt, initial)
+		}
+	}
+}
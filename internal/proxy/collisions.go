@@ -0,0 +1,51 @@
+package proxy
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// recordServerTools remembers the tools of a server as it reports them and logs the ones another
+// server uses too; the caller must hold p.mu
+func (p *SmartProxy) recordServerTools(serverName string, tools []types.Tool) {
+	p.serverTools[serverName] = tools
+
+	for _, collision := range p.collisions(serverName) {
+		log.Printf("Tool name %s is used by several servers: %s", collision.Tool, strings.Join(collision.Servers, ", "))
+	}
+}
+
+// collisions lists the tool names of a server that other servers use too, whether or not
+// namespacing or deduplication keeps them apart; the caller must hold p.mu
+func (p *SmartProxy) collisions(serverName string) []types.ToolCollision {
+	var collisions []types.ToolCollision
+	for _, tool := range p.serverTools[serverName] {
+		toolName := tool.Name
+		servers := []string{serverName}
+		for otherName, otherTools := range p.serverTools {
+			if otherName != serverName && offersTool(otherTools, toolName) {
+				servers = append(servers, otherName)
+			}
+		}
+		if len(servers) > 1 {
+			sort.Strings(servers)
+			collisions = append(collisions, types.ToolCollision{Tool: toolName, Servers: servers})
+		}
+	}
+
+	sort.Slice(collisions, func(i, j int) bool { return collisions[i].Tool < collisions[j].Tool })
+	return collisions
+}
+
+// offersTool reports whether tools holds a tool with the given name
+func offersTool(tools []types.Tool, name string) bool {
+	for _, tool := range tools {
+		if tool.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package proxy
+
+import (
+	"strings"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestCollisionPrecedence(t *testing.T) {
+	tests := []struct {
+		name         string
+		deduplicate  bool
+		sameSchema   bool
+		order        []string // order in which the servers register
+		wantServer   string
+		wantReplicas []string
+	}{
+		{name: "first sorting server wins", order: []string{"alpha", "beta"}, wantServer: "alpha"},
+		{name: "registration order does not matter", order: []string{"beta", "alpha"}, wantServer: "alpha"},
+		{name: "replicas keep the first as primary", deduplicate: true, sameSchema: true, order: []string{"beta", "alpha"}, wantServer: "alpha", wantReplicas: []string{"beta"}},
+		{name: "differing schemas are not merged", deduplicate: true, order: []string{"beta", "alpha"}, wantServer: "alpha"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.MCPConfig{Proxy: types.ProxySettings{DeduplicateTools: tt.deduplicate}}
+			p := newTestProxy(t, config, nil)
+
+			p.mu.Lock()
+			for _, serverName := range tt.order {
+				argumentType := "string"
+				if !tt.sameSchema && serverName == "beta" {
+					argumentType = "number"
+				}
+				p.registerTools(serverName, []types.Tool{fakeTool("search", argumentType)})
+			}
+			p.mu.Unlock()
+
+			server, replicas := servingServer(p, "search")
+			if server != tt.wantServer || strings.Join(replicas, ",") != strings.Join(tt.wantReplicas, ",") {
+				t.Errorf("search served by %s with replicas %v, want %s with %v", server, replicas, tt.wantServer, tt.wantReplicas)
+			}
+		})
+	}
+}
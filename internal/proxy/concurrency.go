@@ -0,0 +1,66 @@
+package proxy
+
+import (
+	"context"
+	"time"
+
+	"mcp-smart-proxy/internal/requestid"
+)
+
+// semaphore bounds the calls in flight to one server; a nil semaphore allows any number
+type semaphore chan struct{}
+
+// newSemaphore creates a semaphore with n slots, or nil when n is not positive
+func newSemaphore(n int) semaphore {
+	if n <= 0 {
+		return nil
+	}
+	return make(semaphore, n)
+}
+
+// acquire takes a slot, waiting until one is free or ctx ends
+func (s semaphore) acquire(ctx context.Context, serverName string) error {
+	if s == nil {
+		return nil
+	}
+
+	select {
+	case s <- struct{}{}:
+		return nil
+	default:
+	}
+
+	requestid.Printf(ctx, "Server %s is at its limit of %d concurrent calls, queueing", serverName, cap(s))
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// tryAcquire takes a slot without queueing behind other calls for longer than wait, reporting
+// whether it got one
+func (s semaphore) tryAcquire(ctx context.Context, wait time.Duration) bool {
+	if s == nil {
+		return true
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case s <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release frees a slot taken by acquire
+func (s semaphore) release() {
+	if s != nil {
+		<-s
+	}
+}
@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestMaxConcurrency(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxConcurrency int
+		wantMaxRunning int
+	}{
+		{name: "serialized server", maxConcurrency: 1, wantMaxRunning: 1},
+		{name: "limited server", maxConcurrency: 2, wantMaxRunning: 2},
+		{name: "unlimited server", wantMaxRunning: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			running, maxRunning := 0, 0
+			client := newFakeClient("query")
+			client.handlers["query"] = func(ctx context.Context, arguments map[string]interface{}) (map[string]interface{}, error) {
+				mu.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				mu.Unlock()
+				time.Sleep(30 * time.Millisecond)
+				mu.Lock()
+				running--
+				mu.Unlock()
+				return textResult("rows"), nil
+			}
+			config := types.MCPConfig{MCPServers: map[string]types.MCPServer{"db": {MaxConcurrency: tt.maxConcurrency}}}
+			p := newTestProxy(t, config, map[string]types.MCPClient{"db": client})
+
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, err := p.UseTool(context.Background(), "query", types.ToolRequest{}); err != nil {
+						t.Errorf("UseTool() error = %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if maxRunning != tt.wantMaxRunning {
+				t.Errorf("%d calls ran at once, want %d", maxRunning, tt.wantMaxRunning)
+			}
+		})
+	}
+}
+
+func TestQueuedCallCancelled(t *testing.T) {
+	release := make(chan struct{})
+	client := newFakeClient("query")
+	client.handlers["query"] = func(ctx context.Context, arguments map[string]interface{}) (map[string]interface{}, error) {
+		<-release
+		return textResult("rows"), nil
+	}
+	config := types.MCPConfig{MCPServers: map[string]types.MCPServer{"db": {MaxConcurrency: 1}}}
+	p := newTestProxy(t, config, map[string]types.MCPClient{"db": client})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.UseTool(context.Background(), "query", types.ToolRequest{})
+	}()
+	defer func() {
+		close(release)
+		<-done
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// The second call waits for the slot and gives up with its context
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if _, err := p.UseTool(ctx, "query", types.ToolRequest{}); !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, types.ErrToolTimeout) {
+		t.Errorf("UseTool() queued past its deadline error = %v, want a timeout", err)
+	}
+	client.mu.Lock()
+	calls := client.calls
+	client.mu.Unlock()
+	if calls != 1 {
+		t.Errorf("server received %d calls, want only the one holding the slot", calls)
+	}
+}
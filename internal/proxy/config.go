@@ -0,0 +1,74 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultConfigTimeout bounds fetching a remote config when Options.ConfigTimeout is unset
+const defaultConfigTimeout = 10 * time.Second
+
+// maxRemoteConfigSize caps the body of a fetched config, so a misbehaving endpoint cannot exhaust memory
+const maxRemoteConfigSize = 10 << 20
+
+// Options configures how a SmartProxy loads its config, and defaults for settings the config leaves unset
+type Options struct {
+	ConfigTimeout time.Duration     // limit for fetching an http(s) config URL
+	ConfigHeaders map[string]string // extra request headers for a config URL, e.g. Authorization
+
+	LLMProvider    string        // provider type ("openai" or "gemini") used when the config declares no providers; empty picks the first API key set
+	ConnectTimeout time.Duration // default for proxy.connectTimeout
+	ReadTimeout    time.Duration // default for proxy.readTimeout
+	LogLevel       string        // default logLevel for servers that do not set one
+}
+
+// isRemoteConfig reports whether the config location is an http(s) URL rather than a file path
+func isRemoteConfig(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// readConfig reads the raw config from a file path or fetches it from an http(s) URL
+func readConfig(location string, opts Options) ([]byte, error) {
+	if !isRemoteConfig(location) {
+		return ioutil.ReadFile(location)
+	}
+
+	timeout := opts.ConfigTimeout
+	if timeout <= 0 {
+		timeout = defaultConfigTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	for name, value := range opts.ConfigHeaders {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: unexpected status %s", location, resp.Status)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxRemoteConfigSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxRemoteConfigSize {
+		return nil, fmt.Errorf("fetching %s: config exceeds %d bytes", location, maxRemoteConfigSize)
+	}
+	return data, nil
+}
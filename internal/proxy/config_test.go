@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadRemoteConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		headers map[string]string
+		wantErr string // substring of the error; empty expects the body back
+	}{
+		{name: "fetches the config", status: http.StatusOK, body: `{"mcpServers": {}}`},
+		{name: "sends the configured headers", status: http.StatusOK, body: `{}`, headers: map[string]string{"Authorization": "Bearer token", "X-Tenant": "acme"}},
+		{name: "error status", status: http.StatusForbidden, body: "denied", wantErr: "403 Forbidden"},
+		{name: "oversized body", status: http.StatusOK, body: strings.Repeat(" ", maxRemoteConfigSize+1), wantErr: "exceeds"},
+		{name: "body at the size limit", status: http.StatusOK, body: strings.Repeat(" ", maxRemoteConfigSize)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r.Header.Clone()
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			data, err := readConfig(server.URL+"/mcp.json", Options{ConfigHeaders: tt.headers})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readConfig() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || string(data) != tt.body {
+				t.Fatalf("readConfig() = %d bytes, %v, want the %d byte body", len(data), err, len(tt.body))
+			}
+			for name, value := range tt.headers {
+				if got := received.Get(name); got != value {
+					t.Errorf("request header %s = %q, want %q", name, got, value)
+				}
+			}
+			if got := received.Get("Accept"); got != "application/json" {
+				t.Errorf("request header Accept = %q, want application/json", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestConfirmation(t *testing.T) {
+	tests := []struct {
+		name      string
+		settings  types.ProxySettings
+		tool      string // name called
+		confirm   bool
+		wantGated bool
+	}{
+		{name: "destructive annotation without confirm", settings: types.ProxySettings{RequireConfirmation: true}, tool: "drop", wantGated: true},
+		{name: "destructive annotation with confirm", settings: types.ProxySettings{RequireConfirmation: true}, tool: "drop", confirm: true},
+		{name: "pattern without confirm", settings: types.ProxySettings{RequireConfirmation: true, DestructivePatterns: []string{"delete_*"}}, tool: "delete_row", wantGated: true},
+		{name: "pattern with confirm", settings: types.ProxySettings{RequireConfirmation: true, DestructivePatterns: []string{"delete_*"}}, tool: "delete_row", confirm: true},
+		{name: "safe tool", settings: types.ProxySettings{RequireConfirmation: true, DestructivePatterns: []string{"delete_*"}}, tool: "read"},
+		{name: "gating disabled", settings: types.ProxySettings{DestructivePatterns: []string{"delete_*"}}, tool: "delete_row"},
+		{
+			name:      "alias of a tool matching a pattern",
+			settings:  types.ProxySettings{RequireConfirmation: true, DestructivePatterns: []string{"delete_*"}, Aliases: map[string]string{"remove": "delete_row"}},
+			tool:      "remove",
+			wantGated: true,
+		},
+		{
+			name:      "alias of an annotated tool",
+			settings:  types.ProxySettings{RequireConfirmation: true, Aliases: map[string]string{"wipe": "drop"}},
+			tool:      "wipe",
+			wantGated: true,
+		},
+		{
+			name:      "namespaced tool matching a pattern",
+			settings:  types.ProxySettings{RequireConfirmation: true, DestructivePatterns: []string{"delete_*"}, NamespaceSeparator: "."},
+			tool:      "db.delete_row",
+			wantGated: true,
+		},
+		{
+			name:     "namespaced tool with confirm",
+			settings: types.ProxySettings{RequireConfirmation: true, DestructivePatterns: []string{"delete_*"}, NamespaceSeparator: "."},
+			tool:     "db.delete_row",
+			confirm:  true,
+		},
+		{
+			name:      "namespaced annotated tool",
+			settings:  types.ProxySettings{RequireConfirmation: true, NamespaceSeparator: "."},
+			tool:      "db.drop",
+			wantGated: true,
+		},
+		{
+			name:      "alias of a namespaced tool",
+			settings:  types.ProxySettings{RequireConfirmation: true, DestructivePatterns: []string{"delete_*"}, NamespaceSeparator: ".", Aliases: map[string]string{"remove": "db.delete_row"}},
+			tool:      "remove",
+			wantGated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destructive := true
+			client := newFakeClient("read", "delete_row", "drop")
+			client.tools[2].Annotations = &types.ToolAnnotations{DestructiveHint: &destructive}
+			p := newTestProxy(t, types.MCPConfig{Proxy: tt.settings}, map[string]types.MCPClient{"db": client})
+
+			_, err := p.UseTool(context.Background(), tt.tool, types.ToolRequest{Confirm: tt.confirm})
+			var confirmErr *types.ConfirmationRequiredError
+			if gated := errors.As(err, &confirmErr); gated != tt.wantGated {
+				t.Fatalf("UseTool(%s) error = %v, want gated = %v", tt.tool, err, tt.wantGated)
+			}
+			if tt.wantGated {
+				if client.calls != 0 {
+					t.Errorf("unconfirmed call reached the server")
+				}
+				return
+			}
+			if err != nil || client.calls != 1 {
+				t.Errorf("UseTool(%s) error = %v after %d server calls, want one successful call", tt.tool, err, client.calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"mcp-smart-proxy/internal/mcp"
+	"mcp-smart-proxy/pkg/types"
+)
+
+// DefaultHealthCheckTimeout bounds a health check call when its timeout is unset
+const DefaultHealthCheckTimeout = 5 * time.Second
+
+// Deep health statuses
+const (
+	deepHealthOK       = "ok"
+	deepHealthDegraded = "degraded"
+	deepHealthDown     = "down"
+	deepHealthBusy     = "busy"
+)
+
+// maxHealthResultText is how much of an unexpected health check result is quoted in its error
+const maxHealthResultText = 200
+
+// healthCheckSlotWait is how long a health check waits for a free slot on a server at its
+// maxConcurrency limit before reporting it busy rather than adding a call beyond the limit
+const healthCheckSlotWait = time.Second
+
+// DeepHealth calls the health check tool of every server that configures one, concurrently, and
+// reports whether each answered as expected. Unlike Health it proves that servers work, at the
+// cost of a tool call each; the calls bypass retries, circuit breakers and usage statistics but
+// respect maxConcurrency, so a server whose slots stay taken is reported busy instead
+func (p *SmartProxy) DeepHealth(ctx context.Context) types.DeepHealthReport {
+	type target struct {
+		client types.MCPClient // nil when the server is not connected
+		check  types.HealthCheck
+		slots  semaphore
+	}
+
+	p.mu.RLock()
+	targets := make(map[string]target)
+	for name, server := range p.config.MCPServers {
+		if server.HealthCheck == nil || server.HealthCheck.Tool == "" {
+			continue
+		}
+		t := target{check: *server.HealthCheck, slots: p.slots[name]}
+		if p.connected(name) {
+			t.client = p.clients[name]
+		}
+		targets[name] = t
+	}
+	p.mu.RUnlock()
+
+	report := types.DeepHealthReport{Status: deepHealthOK, Servers: make(map[string]types.DeepHealthResult, len(targets))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, t := range targets {
+		wg.Add(1)
+		go func(name string, t target) {
+			defer wg.Done()
+			var result types.DeepHealthResult
+			if t.client != nil && !t.slots.tryAcquire(ctx, healthCheckSlotWait) {
+				result = types.DeepHealthResult{Status: deepHealthBusy, Error: fmt.Sprintf("all %d concurrent call slots in use", cap(t.slots))}
+			} else {
+				result = runHealthCheck(ctx, t.client, t.check)
+				if t.client != nil {
+					t.slots.release()
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			report.Servers[name] = result
+			// A busy server is working; it was only not checked
+			if result.Status != deepHealthOK && result.Status != deepHealthBusy {
+				report.Status = deepHealthDegraded
+			}
+		}(name, t)
+	}
+	wg.Wait()
+	report.Checked = time.Now()
+	return report
+}
+
+// runHealthCheck calls a server's health check tool and judges the answer: an error result or
+// one without the expected text is degraded, no answer at all is down
+func runHealthCheck(ctx context.Context, client types.MCPClient, check types.HealthCheck) types.DeepHealthResult {
+	if client == nil {
+		return types.DeepHealthResult{Status: deepHealthDown, Error: "not connected"}
+	}
+
+	timeout := time.Duration(check.Timeout)
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	started := time.Now()
+	result, err := client.CallTool(ctx, check.Tool, check.Arguments)
+	health := types.DeepHealthResult{Status: deepHealthOK, Latency: types.Duration(time.Since(started))}
+	switch {
+	case errors.Is(err, mcp.ErrToolError):
+		health.Status, health.Error = deepHealthDegraded, err.Error()
+	case err != nil:
+		health.Status, health.Error = deepHealthDown, err.Error()
+	default:
+		text := resultText(result)
+		if isError, _ := result["isError"].(bool); isError {
+			health.Status, health.Error = deepHealthDegraded, fmt.Sprintf("health check tool %s reported an error: %q", check.Tool, truncate(text, maxHealthResultText))
+		} else if !strings.Contains(text, check.Expect) {
+			health.Status, health.Error = deepHealthDegraded, fmt.Sprintf("unexpected result from health check tool %s: %q does not contain %q", check.Tool, truncate(text, maxHealthResultText), check.Expect)
+		}
+	}
+	return health
+}
+
+// resultText joins the text content items of a tool result
+func resultText(result map[string]interface{}) string {
+	content, _ := result["content"].([]interface{})
+	var texts []string
+	for _, raw := range content {
+		item, _ := raw.(map[string]interface{})
+		if text, ok := item["text"].(string); ok {
+			texts = append(texts, text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
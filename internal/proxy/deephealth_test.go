@@ -0,0 +1,91 @@
+package proxy
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestDeepHealth(t *testing.T) {
+	tests := []struct {
+		name           string
+		answer         map[string]interface{}
+		err            error
+		maxConcurrency int
+		busy           bool // a UseTool call holds the server's slots during the check
+		wantStatus     string
+		wantReport     string
+		wantCheckCalls int
+	}{
+		{name: "expected answer", answer: textResult("pong"), wantStatus: deepHealthOK, wantReport: deepHealthOK, wantCheckCalls: 1},
+		{name: "unexpected answer", answer: textResult("ERR"), wantStatus: deepHealthDegraded, wantReport: deepHealthDegraded, wantCheckCalls: 1},
+		{name: "error result", answer: map[string]interface{}{"isError": true, "content": []interface{}{}}, wantStatus: deepHealthDegraded, wantReport: deepHealthDegraded, wantCheckCalls: 1},
+		{name: "no answer", err: errFake, wantStatus: deepHealthDown, wantReport: deepHealthDegraded, wantCheckCalls: 1},
+		{name: "free slot is used", answer: textResult("pong"), maxConcurrency: 2, busy: true, wantStatus: deepHealthOK, wantReport: deepHealthOK, wantCheckCalls: 1},
+		{name: "taken slots report busy", answer: textResult("pong"), maxConcurrency: 1, busy: true, wantStatus: deepHealthBusy, wantReport: deepHealthOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeClient("ping", "work")
+			var checkCalls int
+			release := make(chan struct{})
+			started := make(chan struct{})
+			client.handlers["ping"] = func(ctx context.Context, arguments map[string]interface{}) (map[string]interface{}, error) {
+				checkCalls++
+				return tt.answer, tt.err
+			}
+			client.handlers["work"] = func(ctx context.Context, arguments map[string]interface{}) (map[string]interface{}, error) {
+				close(started)
+				<-release
+				return textResult("done"), nil
+			}
+			config := types.MCPConfig{MCPServers: map[string]types.MCPServer{"db": {
+				MaxConcurrency: tt.maxConcurrency,
+				HealthCheck:    &types.HealthCheck{Tool: "ping", Expect: "pong"},
+			}}}
+			p := newTestProxy(t, config, map[string]types.MCPClient{"db": client})
+
+			done := make(chan struct{})
+			if tt.busy {
+				go func() {
+					defer close(done)
+					p.UseTool(context.Background(), "work", types.ToolRequest{})
+				}()
+				<-started
+			} else {
+				close(done)
+			}
+			report := p.DeepHealth(context.Background())
+			close(release)
+			<-done
+
+			result := report.Servers["db"]
+			if result.Status != tt.wantStatus || report.Status != tt.wantReport {
+				t.Errorf("DeepHealth() = %s (server %s, %q), want %s (server %s)", report.Status, result.Status, result.Error, tt.wantReport, tt.wantStatus)
+			}
+			if checkCalls != tt.wantCheckCalls {
+				t.Errorf("health check tool called %d times, want %d", checkCalls, tt.wantCheckCalls)
+			}
+		})
+	}
+}
+
+func TestDeepHealthNotConnected(t *testing.T) {
+	config := types.MCPConfig{MCPServers: map[string]types.MCPServer{
+		"db": {MaxConcurrency: 1, HealthCheck: &types.HealthCheck{Tool: "ping"}},
+	}}
+	p := newTestProxy(t, config, map[string]types.MCPClient{"other": newFakeClient("read")})
+
+	started := time.Now()
+	result := p.DeepHealth(context.Background()).Servers["db"]
+	if result.Status != deepHealthDown || !strings.Contains(result.Error, "not connected") {
+		t.Errorf("DeepHealth() for an unconnected server = %+v, want down", result)
+	}
+	if elapsed := time.Since(started); elapsed >= healthCheckSlotWait {
+		t.Errorf("DeepHealth() took %v for an unconnected server, want no wait for a slot", elapsed)
+	}
+}
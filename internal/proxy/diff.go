@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// diffTools compares two tool catalogs by name, reporting tools whose description,
+// schema, annotations or server changed
+func diffTools(before, after map[string]types.Tool) *types.ToolDiff {
+	diff := &types.ToolDiff{
+		Added:      []string{},
+		Removed:    []string{},
+		Changed:    []string{},
+		ComputedAt: time.Now(),
+	}
+
+	for name, newTool := range after {
+		oldTool, existed := before[name]
+		switch {
+		case !existed:
+			diff.Added = append(diff.Added, name)
+		case !toolsEqual(oldTool, newTool):
+			diff.Changed = append(diff.Changed, name)
+		}
+	}
+
+	for name := range before {
+		if _, exists := after[name]; !exists {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
+
+// toolsEqual reports whether two versions of a tool are identical
+func toolsEqual(a, b types.Tool) bool {
+	return a.Description == b.Description &&
+		a.ServerName == b.ServerName &&
+		reflect.DeepEqual(a.InputSchema, b.InputSchema) &&
+		reflect.DeepEqual(a.OutputSchema, b.OutputSchema) &&
+		reflect.DeepEqual(a.Annotations, b.Annotations)
+}
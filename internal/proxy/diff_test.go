@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestDiffTools(t *testing.T) {
+	readOnly := true
+	base := fakeTool("read", "string")
+	base.ServerName = "files"
+	changed := func(change func(tool *types.Tool)) types.Tool {
+		tool := base
+		change(&tool)
+		return tool
+	}
+
+	tests := []struct {
+		name        string
+		after       types.Tool
+		wantChanged bool
+	}{
+		{name: "unchanged", after: base},
+		{name: "description", after: changed(func(tool *types.Tool) { tool.Description = "Read a file" }), wantChanged: true},
+		{name: "input schema", after: fakeTool("read", "integer"), wantChanged: true},
+		{name: "output schema", after: changed(func(tool *types.Tool) { tool.OutputSchema = map[string]interface{}{"type": "object"} }), wantChanged: true},
+		{name: "annotations", after: changed(func(tool *types.Tool) { tool.Annotations = &types.ToolAnnotations{ReadOnlyHint: &readOnly} }), wantChanged: true},
+		{name: "server", after: changed(func(tool *types.Tool) { tool.ServerName = "backup" }), wantChanged: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.after.ServerName == "" {
+				tt.after.ServerName = base.ServerName
+			}
+			diff := diffTools(map[string]types.Tool{"read": base}, map[string]types.Tool{"read": tt.after})
+			if (len(diff.Changed) == 1) != tt.wantChanged || len(diff.Added) != 0 || len(diff.Removed) != 0 {
+				t.Errorf("diffTools() = %+v, want read changed = %v and nothing else", diff, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestRefreshDiff(t *testing.T) {
+	client := newFakeClient("keep", "edit", "drop")
+	p := newTestProxy(t, types.MCPConfig{}, map[string]types.MCPClient{"files": client})
+	if diff := p.LastDiff(); diff != nil {
+		t.Fatalf("LastDiff() before any refresh = %+v, want nil", diff)
+	}
+
+	client.update(func(c *fakeClient) {
+		c.tools = []types.Tool{fakeTool("keep", "string"), fakeTool("edit", "integer"), fakeTool("new_b", "string"), fakeTool("new_a", "string")}
+	})
+	if err := p.RefreshTools(context.Background()); err != nil {
+		t.Fatalf("RefreshTools() error = %v", err)
+	}
+
+	diff := p.LastDiff()
+	if diff == nil {
+		t.Fatal("LastDiff() = nil, want the refresh's changes")
+	}
+	got := strings.Join([]string{strings.Join(diff.Added, ","), strings.Join(diff.Removed, ","), strings.Join(diff.Changed, ",")}, " ")
+	if want := "new_a,new_b drop edit"; got != want {
+		t.Errorf("LastDiff() added, removed and changed = %q, want %q", got, want)
+	}
+
+	// A refresh without changes replaces the previous diff with an empty one
+	if err := p.RefreshTools(context.Background()); err != nil {
+		t.Fatalf("RefreshTools() error = %v", err)
+	}
+	if diff := p.LastDiff(); diff == nil || !diff.Empty() {
+		t.Errorf("LastDiff() after an unchanged refresh = %+v, want an empty diff", diff)
+	}
+}
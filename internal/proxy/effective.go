@@ -0,0 +1,195 @@
+package proxy
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"mcp-smart-proxy/internal/llm"
+	"mcp-smart-proxy/internal/secrets"
+	"mcp-smart-proxy/internal/selector"
+	"mcp-smart-proxy/pkg/types"
+)
+
+// redacted replaces secret values in the effective config
+const redacted = "[redacted]"
+
+// EffectiveConfig returns the config the proxy is running with: servers added or removed at
+// runtime are reflected, defaults are filled in, and values that may hold secrets (env values,
+// flag values and URL passwords in args, health check arguments, capabilities and tenant API
+// keys) are redacted unless they are secret references. Only admins may read it
+func (p *SmartProxy) EffectiveConfig(ctx context.Context) (types.MCPConfig, error) {
+	if err := p.authorizeAdmin(ctx); err != nil {
+		return types.MCPConfig{}, err
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	config := p.config
+	config.MCPServers = make(map[string]types.MCPServer, len(p.config.MCPServers))
+	for name, server := range p.config.MCPServers {
+		if server.Transport == "" {
+			server.Transport = TransportStdio
+		}
+		server.LogLevel = p.serverLogLevel(server)
+		server.Env = redactEnv(server.Env)
+		server.Args = redactArgs(server.Args)
+		if server.HealthCheck != nil {
+			healthCheck := *server.HealthCheck
+			healthCheck.Arguments, _ = redactStrings(healthCheck.Arguments).(map[string]interface{})
+			server.HealthCheck = &healthCheck
+		}
+		server.Capabilities, _ = redactStrings(server.Capabilities).(map[string]interface{})
+		config.MCPServers[name] = server
+	}
+
+	if p.config.Proxy.Tenants != nil {
+		config.Proxy.Tenants = make(map[string]types.Tenant, len(p.config.Proxy.Tenants))
+		for name, tenant := range p.config.Proxy.Tenants {
+			tenant.APIKey = redactValue(tenant.APIKey)
+			config.Proxy.Tenants[name] = tenant
+		}
+	}
+
+	maxTools := p.maxTools()
+	config.Proxy.MaxTools = &maxTools
+	maxServerTools := p.maxServerTools()
+	config.Proxy.MaxServerTools = &maxServerTools
+	config.Proxy.DefaultProvider = p.defaultLLM
+	if config.Proxy.DefaultTier == "" {
+		config.Proxy.DefaultTier = string(llm.DefaultTier)
+	}
+	if config.Proxy.Selector == "" {
+		config.Proxy.Selector = selector.StrategyLLM
+	}
+	config.Proxy.ConnectTimeout = orDefault(config.Proxy.ConnectTimeout, types.Duration(p.opts.ConnectTimeout))
+	config.Proxy.ReadTimeout = orDefault(config.Proxy.ReadTimeout, types.Duration(p.opts.ReadTimeout))
+	config.Proxy.LLMTimeout = orDefault(config.Proxy.LLMTimeout, types.Duration(DefaultLLMTimeout))
+	if config.Proxy.PromptTokenBudget > 0 && config.Proxy.ChunkConcurrency <= 0 {
+		config.Proxy.ChunkConcurrency = selector.DefaultChunkConcurrency
+	}
+	if config.Proxy.BreakerThreshold > 0 {
+		config.Proxy.BreakerCooldown = orDefault(config.Proxy.BreakerCooldown, types.Duration(defaultBreakerCooldown))
+	}
+	return config, nil
+}
+
+// redactEnv copies env with every value that is not a secret reference redacted
+func redactEnv(env map[string]string) map[string]string {
+	if env == nil {
+		return nil
+	}
+	redactedEnv := make(map[string]string, len(env))
+	for name, value := range env {
+		redactedEnv[name] = redactValue(value)
+	}
+	return redactedEnv
+}
+
+// redactArgs copies a server's command-line arguments with flag values and URL passwords
+// redacted. The value of "--name=value" and the argument after a bare "--name" are hidden; after
+// a short flag such as "-y", usually a switch, only when its name looks like it takes a secret or
+// it is one of the single letters commonly taking credentials (-k, -p, -t, -u). Flags themselves
+// and other positional arguments, such as paths, are kept
+func redactArgs(args []string) []string {
+	if args == nil {
+		return nil
+	}
+	redactedArgs := make([]string, len(args))
+	takesValue := false
+	for i, arg := range args {
+		isFlag := strings.HasPrefix(arg, "-") && arg != "-" && arg != "--"
+		name, value, hasValue := strings.Cut(arg, "=")
+		switch {
+		case isFlag && hasValue:
+			redactedArgs[i] = name + "=" + redactValue(value)
+		case isFlag:
+			redactedArgs[i] = arg
+		case takesValue:
+			redactedArgs[i] = redactValue(arg)
+		default:
+			redactedArgs[i] = redactURLPassword(arg)
+		}
+		takesValue = isFlag && !hasValue && (strings.HasPrefix(arg, "--") || secretName(arg) || secretShortFlag(arg))
+	}
+	return redactedArgs
+}
+
+// secretName reports whether a flag or key name suggests it holds a credential
+func secretName(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range []string{"key", "token", "secret", "pass", "auth", "credential"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// secretShortFlag reports whether arg is a single-letter flag commonly taking a key, password,
+// token or user:password pair, as in "curl -u user:pass" or "mysql -p secret"
+func secretShortFlag(arg string) bool {
+	switch arg {
+	case "-k", "-p", "-t", "-u":
+		return true
+	}
+	return false
+}
+
+// redactURLPassword hides the password of a URL such as a database connection string
+func redactURLPassword(value string) string {
+	if !strings.Contains(value, "://") {
+		return value
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return value
+	}
+	if _, ok := u.User.Password(); !ok {
+		return value
+	}
+	scheme := strings.Index(value, "://") + len("://")
+	authority := value[scheme:]
+	if end := strings.IndexAny(authority, "/?#"); end >= 0 {
+		authority = authority[:end]
+	}
+	// The password may itself contain "@"; the host follows the last one
+	userinfo := authority[:strings.LastIndex(authority, "@")]
+	username, _, _ := strings.Cut(userinfo, ":")
+	return value[:scheme] + username + ":" + redacted + value[scheme+len(userinfo):]
+}
+
+// redactStrings copies a decoded JSON value with every string in it redacted, keeping its
+// structure, keys, numbers and booleans
+func redactStrings(value interface{}) interface{} {
+	switch value := value.(type) {
+	case string:
+		return redactValue(value)
+	case map[string]interface{}:
+		if value == nil {
+			return value
+		}
+		copied := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			copied[key] = redactStrings(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+		for i, item := range value {
+			copied[i] = redactStrings(item)
+		}
+		return copied
+	default:
+		return value
+	}
+}
+
+// redactValue hides a secret; secret references only name the secret and are kept
+func redactValue(value string) string {
+	if value == "" || strings.HasPrefix(value, secrets.RefPrefix) {
+		return value
+	}
+	return redacted
+}
@@ -0,0 +1,176 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// DefaultEnrichMinLength is the description length below which tools are enriched when
+// proxy.enrichMinLength is unset
+const DefaultEnrichMinLength = 40
+
+// enrichPrompt asks the LLM for a fuller description of one tool
+const enrichPrompt = `Tool name: %s
+Description: %s
+Input schema: %s
+
+Write one or two sentences describing what this tool does and when it should be used, for an index that matches user requests to tools. Reply with the description only.`
+
+// descriptionCache holds LLM-written descriptions of sparse tools, keyed by a hash of the tool's
+// name, description and input schema so a changed tool is enriched again
+type descriptionCache struct {
+	mu           sync.Mutex
+	descriptions map[string]string
+	pending      map[string]bool // keys being enriched, so overlapping syncs do not repeat work
+}
+
+func newDescriptionCache() *descriptionCache {
+	return &descriptionCache{descriptions: make(map[string]string), pending: make(map[string]bool)}
+}
+
+// get returns the enriched description for a tool, if one has been written
+func (c *descriptionCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	description, ok := c.descriptions[key]
+	return description, ok
+}
+
+// claim marks a key as being enriched, returning false if it is done or already in progress
+func (c *descriptionCache) claim(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.descriptions[key]; ok || c.pending[key] {
+		return false
+	}
+	c.pending[key] = true
+	return true
+}
+
+// finish records the outcome of a claimed key; an empty description lets a later sync retry
+func (c *descriptionCache) finish(key, description string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, key)
+	if description != "" {
+		c.descriptions[key] = description
+	}
+}
+
+// enrichKey identifies the text a tool's enriched description was written from
+func enrichKey(tool types.Tool) string {
+	schema, _ := json.Marshal(tool.InputSchema)
+	sum := sha256.Sum256([]byte(tool.Name + "\x00" + tool.Description + "\x00" + string(schema)))
+	return hex.EncodeToString(sum[:])
+}
+
+// sparse reports whether a tool's description is too short to select it reliably
+func (p *SmartProxy) sparse(tool types.Tool) bool {
+	minLength := p.config.Proxy.EnrichMinLength
+	if minLength <= 0 {
+		minLength = DefaultEnrichMinLength
+	}
+	return len(strings.TrimSpace(tool.Description)) < minLength
+}
+
+// enrichTools writes descriptions for the sparse tools in the cache that have none yet, one LLM
+// call per tool; it runs in the background after each sync
+func (p *SmartProxy) enrichTools(ctx context.Context, completer types.CompletionProvider) {
+	p.mu.RLock()
+	var tools []types.Tool
+	for _, tool := range p.toolCache.Tools {
+		if p.sparse(tool) {
+			tools = append(tools, tool)
+		}
+	}
+	p.mu.RUnlock()
+
+	for _, tool := range tools {
+		key := enrichKey(tool)
+		if !p.enriched.claim(key) {
+			continue
+		}
+
+		description, err := p.enrichTool(ctx, completer, tool)
+		if err != nil {
+			log.Printf("Failed to enrich the description of tool %s: %v", tool.Name, err)
+		}
+		p.enriched.finish(key, description)
+	}
+}
+
+// enrichTool asks the LLM for a fuller description of one tool
+func (p *SmartProxy) enrichTool(ctx context.Context, completer types.CompletionProvider, tool types.Tool) (string, error) {
+	schema, err := json.Marshal(tool.InputSchema)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := p.selectionContext(ctx)
+	defer cancel()
+	result, err := completer.CreateMessage(ctx, types.SamplingRequest{
+		Messages:  []types.SamplingMessage{{Role: "user", Text: fmt.Sprintf(enrichPrompt, tool.Name, tool.Description, schema)}},
+		MaxTokens: 200,
+	})
+	if err != nil {
+		return "", selectionError(ctx, err)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
+
+// startEnrichment enriches sparse tools in the background when proxy.enrichDescriptions is on;
+// the caller must hold p.mu
+func (p *SmartProxy) startEnrichment() {
+	if !p.config.Proxy.EnrichDescriptions {
+		return
+	}
+	completer, ok := p.providers[p.defaultLLM].(types.CompletionProvider)
+	if !ok {
+		return
+	}
+	go p.enrichTools(context.Background(), completer)
+}
+
+// withEnrichedDescription returns the tool with its enriched description, if it has one, for
+// use in selection; the caller must hold p.mu
+func (p *SmartProxy) withEnrichedDescription(tool types.Tool) types.Tool {
+	if !p.config.Proxy.EnrichDescriptions || !p.sparse(tool) {
+		return tool
+	}
+	if description, ok := p.enriched.get(enrichKey(tool)); ok {
+		tool.Description = description
+	}
+	return tool
+}
+
+// originalTools puts back the backend's own descriptions in selected tools, so enrichment only
+// affects how tools are selected
+func (p *SmartProxy) originalTools(tools []types.Tool) []types.Tool {
+	if !p.config.Proxy.EnrichDescriptions {
+		return tools
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for i, tool := range tools {
+		tools[i] = p.originalTool(tool)
+	}
+	return tools
+}
+
+// originalTool puts back the backend's own description in one selected tool; the caller must
+// hold p.mu
+func (p *SmartProxy) originalTool(tool types.Tool) types.Tool {
+	if cached, ok := p.toolCache.Tools[tool.Name]; ok {
+		tool.Description = cached.Description
+	}
+	return tool
+}
@@ -0,0 +1,125 @@
+package proxy
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// enrichingProvider writes "Enriched <name>" descriptions and records the descriptions it is
+// given to select from
+type enrichingProvider struct {
+	mu       sync.Mutex
+	enriched []string          // tools it was asked to describe
+	seen     map[string]string // tool name -> description in the last selection
+}
+
+func (p *enrichingProvider) CreateMessage(ctx context.Context, req types.SamplingRequest) (*types.SamplingResult, error) {
+	name := strings.TrimPrefix(strings.SplitN(req.Messages[0].Text, "\n", 2)[0], "Tool name: ")
+	p.mu.Lock()
+	p.enriched = append(p.enriched, name)
+	p.mu.Unlock()
+	return &types.SamplingResult{Text: " Enriched " + name + "\n"}, nil
+}
+
+func (p *enrichingProvider) SelectBestTools(ctx context.Context, query string, tools []types.Tool) ([]types.Tool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.seen = make(map[string]string)
+	for _, tool := range tools {
+		p.seen[tool.Name] = tool.Description
+	}
+	return tools, nil
+}
+
+// enrichedCount returns how many descriptions the provider has written
+func (p *enrichingProvider) enrichedCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.enriched)
+}
+
+func TestEnrichDescriptions(t *testing.T) {
+	long := "Search the internal wiki for pages matching a free-text query"
+	client := newFakeClient("search", "wiki")
+	client.tools[0].Description = "search"
+	client.tools[1].Description = long
+
+	provider := &enrichingProvider{}
+	config := types.MCPConfig{Proxy: types.ProxySettings{EnrichDescriptions: true}}
+	p, err := NewInMemory(config, provider, map[string]types.MCPClient{"files": client})
+	if err != nil {
+		t.Fatalf("NewInMemory() error = %v", err)
+	}
+	if err := p.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+
+	// Enrichment runs in the background after the sync
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(5 * time.Millisecond) {
+		if _, ok := p.enriched.get(enrichKey(client.tools[0])); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("search was not enriched within 1s of Initialize")
+		}
+	}
+
+	tools, err := p.DiscoverTools(context.Background(), types.ProxyRequest{Query: "find a page"})
+	if err != nil {
+		t.Fatalf("DiscoverTools() error = %v", err)
+	}
+	provider.mu.Lock()
+	seen := provider.seen
+	provider.mu.Unlock()
+	if seen["search"] != "Enriched search" || seen["wiki"] != long {
+		t.Errorf("selection saw descriptions %q, want search enriched and wiki unchanged", seen)
+	}
+	for _, tool := range tools {
+		if tool.Name == "search" && tool.Description != "search" {
+			t.Errorf("DiscoverTools() returned search described as %q, want the backend's own description", tool.Description)
+		}
+	}
+
+	// Enriched descriptions are cached across syncs
+	if err := p.SoftRefreshTools(context.Background()); err != nil {
+		t.Fatalf("SoftRefreshTools() error = %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := provider.enrichedCount(); got != 1 {
+		t.Errorf("enriched %d descriptions after a refresh, want only search's once", got)
+	}
+}
+
+func TestEnrichDescriptionsIsOptIn(t *testing.T) {
+	client := newFakeClient("search")
+	client.tools[0].Description = "search"
+	provider := &enrichingProvider{}
+	p, err := NewInMemory(types.MCPConfig{}, provider, map[string]types.MCPClient{"files": client})
+	if err != nil {
+		t.Fatalf("NewInMemory() error = %v", err)
+	}
+	if err := p.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+
+	if _, err := p.DiscoverTools(context.Background(), types.ProxyRequest{Query: "find a page"}); err != nil {
+		t.Fatalf("DiscoverTools() error = %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := provider.enrichedCount(); got != 0 {
+		t.Errorf("enriched %d descriptions with enrichDescriptions off, want none", got)
+	}
+
+	// A provider that cannot write text is rejected up front
+	config := types.MCPConfig{Proxy: types.ProxySettings{EnrichDescriptions: true}}
+	if _, err := NewInMemory(config, fakeProvider{}, map[string]types.MCPClient{"files": newFakeClient("search")}); err == nil {
+		t.Error("NewInMemory() with enrichDescriptions and a selection-only provider succeeded, want an error")
+	}
+}
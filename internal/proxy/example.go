@@ -0,0 +1,112 @@
+package proxy
+
+import (
+	"context"
+)
+
+// placeholderFormats are the example strings for common JSON Schema string formats
+var placeholderFormats = map[string]string{
+	"date-time": "2024-01-01T00:00:00Z",
+	"date":      "2024-01-01",
+	"time":      "00:00:00Z",
+	"email":     "user@example.com",
+	"uri":       "https://example.com",
+	"url":       "https://example.com",
+	"uuid":      "00000000-0000-0000-0000-000000000000",
+	"ipv4":      "192.0.2.1",
+	"ipv6":      "2001:db8::1",
+}
+
+// ExampleArguments returns a sample arguments object for a tool built from its input schema
+func (p *SmartProxy) ExampleArguments(ctx context.Context, toolName string) (map[string]interface{}, error) {
+	tool, err := p.GetTool(ctx, toolName)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, _ := tool.InputSchema.(map[string]interface{})
+	arguments, ok := exampleValue(schema).(map[string]interface{})
+	if !ok {
+		// Tool arguments are always an object, whatever the schema claims
+		arguments = map[string]interface{}{}
+	}
+	return arguments, nil
+}
+
+// exampleValue builds a value satisfying a schema, preferring the schema's own default, examples,
+// const or enum and otherwise using a placeholder for its type. Objects get every declared
+// property and arrays a single item
+func exampleValue(schema map[string]interface{}) interface{} {
+	if value, ok := schema["default"]; ok {
+		return value
+	}
+	if examples, ok := schema["examples"].([]interface{}); ok && len(examples) > 0 {
+		return examples[0]
+	}
+	if value, ok := schema["example"]; ok {
+		return value
+	}
+	if value, ok := schema["const"]; ok {
+		return value
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	for _, keyword := range []string{"anyOf", "oneOf", "allOf"} {
+		if alternatives, ok := schema[keyword].([]interface{}); ok {
+			for _, raw := range alternatives {
+				alternative, _ := raw.(map[string]interface{})
+				if declared, _ := schemaTypes(alternative["type"]); len(declared) == 1 && declared[0] == "null" {
+					continue
+				}
+				return exampleValue(alternative)
+			}
+		}
+	}
+
+	switch exampleType(schema) {
+	case "object":
+		properties, _ := schema["properties"].(map[string]interface{})
+		object := make(map[string]interface{}, len(properties))
+		for name, raw := range properties {
+			property, _ := raw.(map[string]interface{})
+			object[name] = exampleValue(property)
+		}
+		return object
+	case "array":
+		items, _ := schema["items"].(map[string]interface{})
+		return []interface{}{exampleValue(items)}
+	case "string":
+		if placeholder, ok := placeholderFormats[getString(schema, "format")]; ok {
+			return placeholder
+		}
+		return "string"
+	case "integer", "number":
+		if minimum, ok := schema["minimum"]; ok {
+			return minimum
+		}
+		return 0
+	case "boolean":
+		return false
+	default:
+		return nil
+	}
+}
+
+// exampleType picks the type to give an example for: the first non-null declared type, or one
+// implied by the object or array keywords
+func exampleType(schema map[string]interface{}) string {
+	declared, _ := schemaTypes(schema["type"])
+	for _, t := range declared {
+		if t != "null" {
+			return t
+		}
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	if _, ok := schema["items"]; ok {
+		return "array"
+	}
+	return ""
+}
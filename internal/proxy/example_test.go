@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestExampleValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   string // the example as JSON
+	}{
+		{name: "string", schema: `{"type": "string"}`, want: `"string"`},
+		{name: "string format", schema: `{"type": "string", "format": "email"}`, want: `"user@example.com"`},
+		{name: "integer", schema: `{"type": "integer"}`, want: `0`},
+		{name: "number minimum", schema: `{"type": "number", "minimum": 1.5}`, want: `1.5`},
+		{name: "boolean", schema: `{"type": "boolean"}`, want: `false`},
+		{name: "default wins", schema: `{"type": "integer", "default": 10, "examples": [5]}`, want: `10`},
+		{name: "first example", schema: `{"type": "string", "examples": ["/tmp/a.txt", "/tmp/b.txt"]}`, want: `"/tmp/a.txt"`},
+		{name: "enum", schema: `{"type": "string", "enum": ["asc", "desc"]}`, want: `"asc"`},
+		{name: "const", schema: `{"const": "v1"}`, want: `"v1"`},
+		{name: "nullable type", schema: `{"type": ["null", "string"]}`, want: `"string"`},
+		{name: "anyOf skips null", schema: `{"anyOf": [{"type": "null"}, {"type": "integer"}]}`, want: `0`},
+		{name: "array of objects", schema: `{"type": "array", "items": {"type": "object", "properties": {"id": {"type": "integer"}}}}`, want: `[{"id":0}]`},
+		{name: "object implied by properties", schema: `{"properties": {"name": {"type": "string"}}}`, want: `{"name":"string"}`},
+		{name: "unknown type", schema: `{}`, want: `null`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var schema map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.schema), &schema); err != nil {
+				t.Fatal(err)
+			}
+			got, _ := json.Marshal(exampleValue(schema))
+			if string(got) != tt.want {
+				t.Errorf("exampleValue(%s) = %s, want %s", tt.schema, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExampleArguments(t *testing.T) {
+	client := newFakeClient("search", "noargs")
+	json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {
+			"query": {"type": "string", "examples": ["open issues"]},
+			"limit": {"type": "integer", "minimum": 1, "default": 20},
+			"filter": {
+				"type": "object",
+				"properties": {
+					"since": {"type": "string", "format": "date"},
+					"labels": {"type": "array", "items": {"type": "string", "enum": ["bug", "feature"]}},
+					"open": {"type": "boolean"}
+				}
+			}
+		},
+		"required": ["query"]
+	}`), &client.tools[0].InputSchema)
+	client.tools[1].InputSchema = nil
+	p := newTestProxy(t, types.MCPConfig{}, map[string]types.MCPClient{"github": client})
+
+	tests := []struct {
+		tool    string
+		want    string // the arguments as JSON
+		wantErr error
+	}{
+		{tool: "search", want: `{"filter":{"labels":["bug"],"open":false,"since":"2024-01-01"},"limit":20,"query":"open issues"}`},
+		{tool: "noargs", want: `{}`},
+		{tool: "missing", wantErr: types.ErrToolNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			arguments, err := p.ExampleArguments(context.Background(), tt.tool)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("ExampleArguments(%s) error = %v, want %v", tt.tool, err, tt.wantErr)
+				}
+				return
+			}
+			got, _ := json.Marshal(arguments)
+			if err != nil || string(got) != tt.want {
+				t.Errorf("ExampleArguments(%s) = %s, %v, want %s", tt.tool, got, err, tt.want)
+			}
+		})
+	}
+}
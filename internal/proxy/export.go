@@ -0,0 +1,151 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// maxExportedNameLength is the longest function name LLM APIs accept
+const maxExportedNameLength = 64
+
+// ExportOpenAITools returns the tools visible to the caller as OpenAI function definitions, in
+// name order. Names OpenAI would reject are rewritten and reported in Names so that calls can be
+// mapped back to proxy tools
+func (p *SmartProxy) ExportOpenAITools(ctx context.Context) (*types.OpenAIToolExport, error) {
+	tools, names, renamed, err := p.exportedTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &types.OpenAIToolExport{Tools: make([]types.OpenAITool, len(tools)), Names: renamed}
+	for i, tool := range tools {
+		export.Tools[i] = types.OpenAITool{
+			Type: "function",
+			Function: types.OpenAIFunction{
+				Name:        names[i],
+				Description: tool.Description,
+				Parameters:  exportSchema(tool.InputSchema),
+			},
+		}
+	}
+	return export, nil
+}
+
+// ExportAnthropicTools returns the tools visible to the caller in Anthropic's tool-use format, in
+// name order, with the same name rewriting and schema adjustments as ExportOpenAITools
+func (p *SmartProxy) ExportAnthropicTools(ctx context.Context) (*types.AnthropicToolExport, error) {
+	tools, names, renamed, err := p.exportedTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &types.AnthropicToolExport{Tools: make([]types.AnthropicTool, len(tools)), Names: renamed}
+	for i, tool := range tools {
+		export.Tools[i] = types.AnthropicTool{
+			Name:        names[i],
+			Description: tool.Description,
+			InputSchema: exportSchema(tool.InputSchema),
+		}
+	}
+	return export, nil
+}
+
+// exportedTools lists the tools visible to the caller sorted by name, with their exported names
+// and the map from changed names back to tools
+func (p *SmartProxy) exportedTools(ctx context.Context) ([]types.Tool, []string, map[string]string, error) {
+	tools, err := p.ListTools(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+
+	names, renamed := exportNames(tools)
+	return tools, names, renamed, nil
+}
+
+// exportNames maps tool names onto the characters function-calling APIs allow (letters, digits,
+// underscores and hyphens, at most 64 of them), e.g. "github/create.issue" becomes
+// "github_create_issue". Names made equal by the rewrite get a numeric suffix. It returns the
+// exported name of each tool and a map from every changed name back to its tool
+func exportNames(tools []types.Tool) ([]string, map[string]string) {
+	names := make([]string, len(tools))
+	taken := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		taken[tool.Name] = true
+	}
+
+	var renamed map[string]string
+	for i, tool := range tools {
+		name := exportName(tool.Name)
+		if name == tool.Name {
+			names[i] = name
+			continue
+		}
+		base := name
+		for n := 2; taken[name]; n++ {
+			suffix := fmt.Sprintf("_%d", n)
+			name = truncate(base, maxExportedNameLength-len(suffix)) + suffix
+		}
+		taken[name] = true
+		names[i] = name
+		if renamed == nil {
+			renamed = make(map[string]string)
+		}
+		renamed[name] = tool.Name
+	}
+	return names, renamed
+}
+
+// exportName replaces the characters of a tool name that function-calling APIs reject
+func exportName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	if name == "" {
+		return "tool"
+	}
+	return truncate(name, maxExportedNameLength)
+}
+
+// truncate shortens an ASCII string to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// exportSchema adapts an input schema for function-calling APIs, which require an object schema
+// with properties and reject some meta keywords. The cached schema is copied, not modified
+func exportSchema(schema interface{}) map[string]interface{} {
+	source, _ := schema.(map[string]interface{})
+	exported := make(map[string]interface{}, len(source)+2)
+	for key, value := range source {
+		switch key {
+		case "$schema", "$id":
+			// Meta keywords some APIs refuse and none need
+			continue
+		}
+		exported[key] = value
+	}
+
+	// Arguments are always an object, whatever the schema claims; a nullable object becomes a
+	// plain one, as the APIs do not accept a list of types at the top level
+	exported["type"] = "object"
+	if _, ok := exported["properties"].(map[string]interface{}); !ok {
+		exported["properties"] = map[string]interface{}{}
+	}
+	if required, ok := exported["required"].([]interface{}); !ok || len(required) == 0 {
+		delete(exported, "required")
+	}
+	return exported
+}
@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// exportClient serves tools whose names and schemas need adjusting before export
+func exportClient() *fakeClient {
+	client := newFakeClient()
+	client.tools = []types.Tool{
+		{Name: "read", Description: "Read a file", InputSchema: map[string]interface{}{
+			"$schema":    "http://json-schema.org/draft-07/schema#",
+			"type":       "object",
+			"properties": map[string]interface{}{"path": map[string]interface{}{"type": "string"}},
+			"required":   []interface{}{"path"},
+		}},
+		{Name: "create.issue", Description: "Open an issue", InputSchema: map[string]interface{}{
+			"type":     []interface{}{"object", "null"},
+			"required": []interface{}{},
+		}},
+		{Name: "create_issue", Description: "Open an issue the other way"},
+	}
+	return client
+}
+
+func TestExportOpenAITools(t *testing.T) {
+	p := newTestProxy(t, types.MCPConfig{}, map[string]types.MCPClient{"files": exportClient()})
+
+	export, err := p.ExportOpenAITools(context.Background())
+	if err != nil {
+		t.Fatalf("ExportOpenAITools() error = %v", err)
+	}
+	got, err := json.Marshal(export)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{
+		"tools": [
+			{"type": "function", "function": {"name": "create_issue_2", "description": "Open an issue", "parameters": {"type": "object", "properties": {}}}},
+			{"type": "function", "function": {"name": "create_issue", "description": "Open an issue the other way", "parameters": {"type": "object", "properties": {}}}},
+			{"type": "function", "function": {"name": "read", "description": "Read a file", "parameters": {"type": "object", "properties": {"path": {"type": "string"}}, "required": ["path"]}}}
+		],
+		"names": {"create_issue_2": "create.issue"}
+	}`
+	var gotJSON, wantJSON interface{}
+	json.Unmarshal(got, &gotJSON)
+	json.Unmarshal([]byte(want), &wantJSON)
+	if !reflect.DeepEqual(gotJSON, wantJSON) {
+		t.Errorf("ExportOpenAITools() = %s, want %s", got, want)
+	}
+
+	tool, err := p.GetTool(context.Background(), "read")
+	if err != nil {
+		t.Fatalf("GetTool() error = %v", err)
+	}
+	if _, ok := tool.InputSchema.(map[string]interface{})["$schema"]; !ok {
+		t.Errorf("ExportOpenAITools() modified the cached schema of read: %v", tool.InputSchema)
+	}
+}
+
+func TestExportAnthropicTools(t *testing.T) {
+	p := newTestProxy(t, types.MCPConfig{}, map[string]types.MCPClient{"files": exportClient()})
+
+	export, err := p.ExportAnthropicTools(context.Background())
+	if err != nil {
+		t.Fatalf("ExportAnthropicTools() error = %v", err)
+	}
+	got, err := json.Marshal(export)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{
+		"tools": [
+			{"name": "create_issue_2", "description": "Open an issue", "input_schema": {"type": "object", "properties": {}}},
+			{"name": "create_issue", "description": "Open an issue the other way", "input_schema": {"type": "object", "properties": {}}},
+			{"name": "read", "description": "Read a file", "input_schema": {"type": "object", "properties": {"path": {"type": "string"}}, "required": ["path"]}}
+		],
+		"names": {"create_issue_2": "create.issue"}
+	}`
+	var gotJSON, wantJSON interface{}
+	json.Unmarshal(got, &gotJSON)
+	json.Unmarshal([]byte(want), &wantJSON)
+	if !reflect.DeepEqual(gotJSON, wantJSON) {
+		t.Errorf("ExportAnthropicTools() = %s, want %s", got, want)
+	}
+}
+
+func TestExportName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "read_file", want: "read_file"},
+		{name: "get-weather", want: "get-weather"},
+		{name: "github/create.issue", want: "github_create_issue"},
+		{name: "café", want: "caf_"},
+		{name: "", want: "tool"},
+		{name: strings.Repeat("a", 70), want: strings.Repeat("a", maxExportedNameLength)},
+	}
+
+	for _, tt := range tests {
+		if got := exportName(tt.name); got != tt.want {
+			t.Errorf("exportName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExportNameSuffixFitsTheLimit(t *testing.T) {
+	long := strings.Repeat("a", maxExportedNameLength)
+	tools := []types.Tool{{Name: long}, {Name: long + ".b"}}
+
+	names, renamed := exportNames(tools)
+	if names[0] != long {
+		t.Errorf("exportNames() renamed the valid name %q to %q", long, names[0])
+	}
+	if want := long[:maxExportedNameLength-2] + "_2"; names[1] != want || renamed[want] != tools[1].Name {
+		t.Errorf("exportNames() = %q, %v, want %q mapped back to the tool", names[1], renamed, want)
+	}
+}
@@ -0,0 +1,107 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"time"
+
+	"mcp-smart-proxy/internal/mcp"
+	"mcp-smart-proxy/internal/requestid"
+	"mcp-smart-proxy/pkg/types"
+)
+
+// route is one server able to serve a tool call
+type route struct {
+	serverName string
+	client     types.MCPClient
+	breaker    *breaker
+	slots      semaphore
+	retry      retryPolicy
+}
+
+// retryPolicy is the retry behaviour for calls to one server
+type retryPolicy struct {
+	retries int
+	backoff time.Duration
+}
+
+// routes lists the connected servers offering a tool, primary first then replicas in failover
+// order; the caller must hold p.mu
+func (p *SmartProxy) routes(toolName string) []route {
+	serverNames := append([]string{p.toolCache.ServerMap[toolName]}, p.replicas[toolName]...)
+
+	var routes []route
+	for _, serverName := range serverNames {
+		if client, ok := p.clients[serverName]; ok {
+			routes = append(routes, route{
+				serverName: serverName,
+				client:     client,
+				breaker:    p.breakers[serverName],
+				slots:      p.slots[serverName],
+				retry:      p.serverRetryPolicy(p.config.MCPServers[serverName]),
+			})
+		}
+	}
+	return routes
+}
+
+// callRoutes calls a tool on each route in turn, moving to the next only when a server is
+// unavailable or failing; errors reported by the tool itself are returned immediately
+func (p *SmartProxy) callRoutes(ctx context.Context, toolName string, routes []route, arguments map[string]interface{}) (map[string]interface{}, error) {
+	var lastErr error
+	for i, r := range routes {
+		if i > 0 {
+			requestid.Printf(ctx, "Failing over tool %s to server %s", toolName, r.serverName)
+		}
+
+		if err := r.breaker.allow(r.serverName); err != nil {
+			requestid.Printf(ctx, "Skipping server %s for tool %s: %v", r.serverName, toolName, err)
+			lastErr = err
+			continue
+		}
+
+		if err := r.slots.acquire(ctx, r.serverName); err != nil {
+			return nil, err
+		}
+		requestid.Printf(ctx, "Calling tool %s on server %s", toolName, r.serverName)
+		result, err := p.callWithRetry(ctx, r.client, p.remoteName(r.serverName, toolName), arguments, r.retry)
+		r.slots.release()
+		r.breaker.record(err)
+		p.stats.recordServer(r.serverName, err)
+		if err == nil {
+			return result, nil
+		}
+
+		requestid.Printf(ctx, "Tool %s on server %s failed: %v", toolName, r.serverName, err)
+		lastErr = err
+		if errors.Is(err, mcp.ErrUnresponsive) {
+			go p.reconnect(r.serverName, r.client)
+		}
+		if !isServerFailure(err) || ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// sameTool reports whether two servers' tools are interchangeable for failover
+func sameTool(a, b types.Tool) bool {
+	return a.Name == b.Name &&
+		reflect.DeepEqual(a.InputSchema, b.InputSchema) &&
+		reflect.DeepEqual(a.OutputSchema, b.OutputSchema)
+}
+
+// addReplica records another server offering an identical tool, keeping the servers in name
+// order with the first as primary; the caller must hold p.mu
+func (p *SmartProxy) addReplica(existing types.Tool, serverName string) {
+	servers := append([]string{existing.ServerName}, p.replicas[existing.Name]...)
+	servers = append(servers, serverName)
+	sort.Strings(servers)
+
+	existing.ServerName = servers[0]
+	p.toolCache.Tools[existing.Name] = existing
+	p.toolCache.ServerMap[existing.Name] = servers[0]
+	p.replicas[existing.Name] = servers[1:]
+}
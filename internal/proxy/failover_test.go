@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"mcp-smart-proxy/internal/mcp"
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestFailover(t *testing.T) {
+	tests := []struct {
+		name             string
+		breakerThreshold int
+		errs             map[string]error // failure of each server's search tool
+		calls            int              // calls made; the last one is checked
+		wantServer       string           // server answering the last call
+		wantErr          error
+		wantCalls        map[string]int // calls each server received in total
+	}{
+		{name: "primary serves", calls: 1, wantServer: "alpha", wantCalls: map[string]int{"alpha": 1}},
+		{name: "failing primary fails over", errs: map[string]error{"alpha": mcp.ErrClosed}, calls: 1, wantServer: "beta", wantCalls: map[string]int{"alpha": 1, "beta": 1}},
+		{name: "failover follows name order", errs: map[string]error{"alpha": mcp.ErrClosed, "beta": mcp.ErrProcessExited}, calls: 1, wantServer: "gamma", wantCalls: map[string]int{"alpha": 1, "beta": 1, "gamma": 1}},
+		{name: "tool errors are not failed over", errs: map[string]error{"alpha": mcp.ErrToolError}, calls: 1, wantErr: types.ErrToolFailed, wantCalls: map[string]int{"alpha": 1}},
+		{name: "every server failing", errs: map[string]error{"alpha": mcp.ErrClosed, "beta": mcp.ErrClosed, "gamma": mcp.ErrClosed}, calls: 1, wantErr: types.ErrServerUnavailable, wantCalls: map[string]int{"alpha": 1, "beta": 1, "gamma": 1}},
+		{name: "open breaker is skipped", breakerThreshold: 1, errs: map[string]error{"alpha": mcp.ErrClosed}, calls: 2, wantServer: "beta", wantCalls: map[string]int{"alpha": 1, "beta": 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients := make(map[string]*fakeClient)
+			mcpClients := make(map[string]types.MCPClient)
+			for _, serverName := range []string{"alpha", "beta", "gamma"} {
+				serverName := serverName
+				client := newFakeClient("search")
+				client.handlers["search"] = func(ctx context.Context, arguments map[string]interface{}) (map[string]interface{}, error) {
+					if err := tt.errs[serverName]; err != nil {
+						return nil, err
+					}
+					return textResult(serverName), nil
+				}
+				clients[serverName] = client
+				mcpClients[serverName] = client
+			}
+			config := types.MCPConfig{Proxy: types.ProxySettings{DeduplicateTools: true, BreakerThreshold: tt.breakerThreshold}}
+			p := newTestProxy(t, config, mcpClients)
+
+			var result map[string]interface{}
+			var err error
+			for i := 0; i < tt.calls; i++ {
+				result, err = p.UseTool(context.Background(), "search", types.ToolRequest{})
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("UseTool() error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil || resultText(result) != tt.wantServer {
+				t.Errorf("UseTool() = %v, %v, want an answer from %s", result, err, tt.wantServer)
+			}
+
+			for serverName, client := range clients {
+				if client.calls != tt.wantCalls[serverName] {
+					t.Errorf("server %s received %d calls, want %d", serverName, client.calls, tt.wantCalls[serverName])
+				}
+			}
+		})
+	}
+}
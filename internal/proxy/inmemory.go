@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+
+	"mcp-smart-proxy/internal/mcp"
+	"mcp-smart-proxy/pkg/types"
+)
+
+// TransportInMemory is the transport of servers handed to NewInMemory as ready-made clients
+const TransportInMemory = "memory"
+
+// NewInMemory creates a SmartProxy around ready-made MCP clients and a single LLM provider,
+// without reading a config file or spawning servers, so tests and benchmarks can exercise the
+// proxy logic in isolation. config supplies the proxy settings; its providers are ignored. Each
+// client becomes a server of the "memory" transport under its map key, keeping any per-server
+// settings config holds for that name. Call Initialize to list the clients' tools.
+//
+// The proxy owns the clients from then on: Close and a full RefreshTools close them like any
+// other connection, so use SoftRefreshTools to re-list tools.
+func NewInMemory(config types.MCPConfig, provider types.LLMProvider, clients map[string]types.MCPClient) (*SmartProxy, error) {
+	if provider == nil {
+		return nil, fmt.Errorf("an LLM provider is required")
+	}
+
+	servers := make(map[string]types.MCPServer, len(config.MCPServers)+len(clients))
+	for serverName, server := range config.MCPServers {
+		servers[serverName] = server
+	}
+	for serverName := range clients {
+		server := servers[serverName]
+		server.Transport = TransportInMemory
+		servers[serverName] = server
+	}
+	config.MCPServers = servers
+
+	proxy := newSmartProxy(config, "", Options{})
+	proxy.providers = map[string]types.LLMProvider{"default": provider}
+	proxy.defaultLLM = "default"
+	proxy.factories[TransportInMemory] = func(ctx context.Context, serverName string, server types.MCPServer, env map[string]string, opts mcp.Options) (types.MCPClient, error) {
+		client, ok := clients[serverName]
+		if !ok {
+			return nil, fmt.Errorf("no in-memory client for server %s", serverName)
+		}
+		return client, nil
+	}
+
+	if err := proxy.setup(); err != nil {
+		return nil, err
+	}
+	return proxy, nil
+}
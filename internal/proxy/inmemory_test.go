@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestNewInMemory(t *testing.T) {
+	if _, err := NewInMemory(types.MCPConfig{}, nil, nil); err == nil {
+		t.Error("NewInMemory() without a provider succeeded, want an error")
+	}
+
+	config := types.MCPConfig{MCPServers: map[string]types.MCPServer{"files": {Tags: []string{"fs"}}}}
+	p := newTestProxy(t, config, map[string]types.MCPClient{"files": newFakeClient("read")})
+
+	tools, err := p.DiscoverTools(context.Background(), types.ProxyRequest{Query: "read a file", Tag: "fs"})
+	if err != nil || len(tools) != 1 || tools[0].Name != "read" {
+		t.Fatalf("DiscoverTools() = %v, %v, want the read tool of the tagged server", tools, err)
+	}
+	if server := p.toolServer("files"); server.Transport != TransportInMemory || !server.Connected {
+		t.Errorf("server files = %+v, want a connected %s server", server, TransportInMemory)
+	}
+}
+
+func BenchmarkDiscoverTools(b *testing.B) {
+	for _, size := range []struct{ servers, tools int }{{1, 10}, {10, 100}, {20, 500}} {
+		b.Run(fmt.Sprintf("%dx%d", size.servers, size.tools), func(b *testing.B) {
+			clients := make(map[string]types.MCPClient, size.servers)
+			for s := 0; s < size.servers; s++ {
+				names := make([]string, size.tools)
+				for i := range names {
+					names[i] = fmt.Sprintf("server%d_tool%d", s, i)
+				}
+				clients[fmt.Sprintf("server%d", s)] = newFakeClient(names...)
+			}
+			p := newTestProxy(b, types.MCPConfig{}, clients)
+			req := types.ProxyRequest{Query: "create an issue"}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := p.DiscoverTools(context.Background(), req); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package proxy
+
+import "strings"
+
+// qualifiedName is the name a server's tool is exposed under: the bare tool name, or the server
+// name and tool name joined by proxy.namespaceSeparator when namespacing is enabled
+func (p *SmartProxy) qualifiedName(serverName, toolName string) string {
+	if p.config.Proxy.NamespaceSeparator == "" {
+		return toolName
+	}
+	return serverName + p.config.Proxy.NamespaceSeparator + toolName
+}
+
+// remoteName maps an exposed tool name back to the name the given server knows it by
+func (p *SmartProxy) remoteName(serverName, toolName string) string {
+	if p.config.Proxy.NamespaceSeparator == "" {
+		return toolName
+	}
+	return strings.TrimPrefix(toolName, serverName+p.config.Proxy.NamespaceSeparator)
+}
@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestNamespaceFormats(t *testing.T) {
+	tests := []struct {
+		separator string
+		want      map[string]string // exposed name -> server
+	}{
+		{separator: "", want: map[string]string{"log": "git", "log/tail": "git", "list": "cloud"}},
+		{separator: ".", want: map[string]string{"git.log": "git", "git.log/tail": "git", "cloud.list": "cloud"}},
+		{separator: "__", want: map[string]string{"git__log": "git", "git__log/tail": "git", "cloud__list": "cloud"}},
+		{separator: "/", want: map[string]string{"git/log": "git", "git/log/tail": "git", "cloud/list": "cloud"}},
+	}
+
+	for _, tt := range tests {
+		t.Run("separator "+tt.separator, func(t *testing.T) {
+			config := types.MCPConfig{Proxy: types.ProxySettings{NamespaceSeparator: tt.separator}}
+			p := newTestProxy(t, config, map[string]types.MCPClient{
+				"git":   newFakeClient("log", "log/tail"),
+				"cloud": newFakeClient("list"),
+			})
+
+			tools, err := p.DiscoverTools(context.Background(), types.ProxyRequest{Query: "show the log"})
+			if err != nil {
+				t.Fatalf("DiscoverTools() error = %v", err)
+			}
+			var got, want []string
+			for _, tool := range tools {
+				got = append(got, tool.Name+"@"+tool.ServerName)
+			}
+			for name, serverName := range tt.want {
+				want = append(want, name+"@"+serverName)
+			}
+			sort.Strings(got)
+			sort.Strings(want)
+			if strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Errorf("DiscoverTools() = %v, want %v", got, want)
+			}
+
+			for name, serverName := range tt.want {
+				if server, _ := servingServer(p, name); server != serverName {
+					t.Errorf("%s is routed to %q, want %s", name, server, serverName)
+				}
+				// The fake answers with the name the server received
+				remote := strings.TrimPrefix(name, serverName+tt.separator)
+				result, err := p.UseTool(context.Background(), name, types.ToolRequest{})
+				if err != nil || resultText(result) != remote {
+					t.Errorf("UseTool(%s) = %v, %v, want a call to %s", name, result, err, remote)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package proxy
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// outputWarningsKey is the _meta entry listing how a result deviates from the tool's outputSchema
+const outputWarningsKey = "outputSchemaWarnings"
+
+// checkOutput validates a result's structuredContent against the tool's outputSchema and returns
+// the result with any problems listed under _meta; the original result is not modified
+func checkOutput(tool types.Tool, result map[string]interface{}) (map[string]interface{}, []string) {
+	if tool.OutputSchema == nil {
+		return result, nil
+	}
+	if isError, _ := result["isError"].(bool); isError {
+		return result, nil
+	}
+
+	var warnings []string
+	if structured, ok := result["structuredContent"]; ok {
+		warnings = validateSchema(tool.OutputSchema, structured, "structuredContent")
+	} else {
+		warnings = []string{"tool declares an outputSchema but returned no structuredContent"}
+	}
+	if len(warnings) == 0 {
+		return result, nil
+	}
+
+	annotated := make(map[string]interface{}, len(result)+1)
+	for key, value := range result {
+		annotated[key] = value
+	}
+	meta := make(map[string]interface{})
+	if existing, ok := result["_meta"].(map[string]interface{}); ok {
+		for key, value := range existing {
+			meta[key] = value
+		}
+	}
+	meta[outputWarningsKey] = warnings
+	annotated["_meta"] = meta
+	return annotated, warnings
+}
+
+// validateSchema checks a decoded JSON value against the commonly used subset of JSON Schema
+// (type, enum, properties, required, additionalProperties and items), returning one message per violation
+func validateSchema(schema interface{}, value interface{}, path string) []string {
+	s, ok := schema.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if allowed, ok := schemaTypes(s["type"]); ok && !matchesAnyType(value, allowed) {
+		return []string{fmt.Sprintf("%s: expected %s, got %s", path, joinTypes(allowed), jsonType(value))}
+	}
+
+	var problems []string
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if reflect.DeepEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s: value is not one of %v", path, enum))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := s["properties"].(map[string]interface{})
+		if required, ok := s["required"].([]interface{}); ok {
+			for _, raw := range required {
+				if name, ok := raw.(string); ok {
+					if _, present := v[name]; !present {
+						problems = append(problems, fmt.Sprintf("%s: missing required property %q", path, name))
+					}
+				}
+			}
+		}
+
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if propertySchema, ok := properties[name]; ok {
+				problems = append(problems, validateSchema(propertySchema, v[name], path+"."+name)...)
+				continue
+			}
+			switch additional := s["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					problems = append(problems, fmt.Sprintf("%s: unexpected property %q", path, name))
+				}
+			case map[string]interface{}:
+				problems = append(problems, validateSchema(additional, v[name], path+"."+name)...)
+			}
+		}
+
+	case []interface{}:
+		if items, ok := s["items"]; ok {
+			for i, item := range v {
+				problems = append(problems, validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+
+	return problems
+}
+
+// schemaTypes reads a schema's type keyword, which may be a single name or a list
+func schemaTypes(raw interface{}) ([]string, bool) {
+	switch t := raw.(type) {
+	case string:
+		return []string{t}, true
+	case []interface{}:
+		var names []string
+		for _, item := range t {
+			if name, ok := item.(string); ok {
+				names = append(names, name)
+			}
+		}
+		return names, len(names) > 0
+	}
+	return nil, false
+}
+
+// matchesAnyType reports whether value has one of the JSON Schema types
+func matchesAnyType(value interface{}, allowed []string) bool {
+	actual := jsonType(value)
+	for _, t := range allowed {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType names the JSON Schema type of a decoded JSON value
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// joinTypes formats a type list for messages
+func joinTypes(names []string) string {
+	if len(names) == 1 {
+		return names[0]
+	}
+	return fmt.Sprintf("one of %v", names)
+}
@@ -0,0 +1,91 @@
+package proxy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// reportTool declares an output schema for a report with a required title and a list of counts
+func reportTool() types.Tool {
+	tool := fakeTool("report", "string")
+	tool.OutputSchema = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"title":  map[string]interface{}{"type": "string"},
+			"counts": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}},
+			"status": map[string]interface{}{"enum": []interface{}{"ok", "partial"}},
+		},
+		"required":             []interface{}{"title"},
+		"additionalProperties": false,
+	}
+	return tool
+}
+
+func TestCheckOutput(t *testing.T) {
+	structured := func(content map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"content": []interface{}{}, "structuredContent": content}
+	}
+
+	tests := []struct {
+		name   string
+		tool   types.Tool
+		result map[string]interface{}
+		want   []string
+	}{
+		{name: "conforming result", tool: reportTool(), result: structured(map[string]interface{}{"title": "Q3", "counts": []interface{}{1.0, 2.0}, "status": "ok"})},
+		{name: "wrong type", tool: reportTool(), result: structured(map[string]interface{}{"title": 3.0}), want: []string{"structuredContent.title: expected string, got integer"}},
+		{name: "missing required property", tool: reportTool(), result: structured(map[string]interface{}{"counts": []interface{}{}}), want: []string{`structuredContent: missing required property "title"`}},
+		{name: "unexpected property", tool: reportTool(), result: structured(map[string]interface{}{"title": "Q3", "extra": true}), want: []string{`structuredContent: unexpected property "extra"`}},
+		{name: "bad array item", tool: reportTool(), result: structured(map[string]interface{}{"title": "Q3", "counts": []interface{}{1.0, 1.5}}), want: []string{"structuredContent.counts[1]: expected integer, got number"}},
+		{name: "value outside the enum", tool: reportTool(), result: structured(map[string]interface{}{"title": "Q3", "status": "failed"}), want: []string{"structuredContent.status: value is not one of [ok partial]"}},
+		{name: "no structured content", tool: reportTool(), result: textResult("Q3"), want: []string{"tool declares an outputSchema but returned no structuredContent"}},
+		{name: "error results are not checked", tool: reportTool(), result: map[string]interface{}{"isError": true, "content": []interface{}{}}},
+		{name: "tool without an output schema", tool: fakeTool("read", "string"), result: structured(map[string]interface{}{"anything": 1.0})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings := checkOutput(tt.tool, tt.result)
+			if !reflect.DeepEqual(warnings, tt.want) {
+				t.Errorf("checkOutput() warnings = %q, want %q", warnings, tt.want)
+			}
+			meta, _ := got["_meta"].(map[string]interface{})
+			if listed, _ := meta[outputWarningsKey].([]string); !reflect.DeepEqual(listed, tt.want) {
+				t.Errorf("_meta.%s = %q, want %q", outputWarningsKey, listed, tt.want)
+			}
+			if _, annotated := tt.result["_meta"]; annotated {
+				t.Errorf("checkOutput() modified the original result: %v", tt.result)
+			}
+		})
+	}
+}
+
+func TestUseToolChecksOutput(t *testing.T) {
+	client := newFakeClient()
+	client.tools = []types.Tool{reportTool()}
+	client.handlers["report"] = func(ctx context.Context, arguments map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{
+			"content":           []interface{}{},
+			"structuredContent": map[string]interface{}{"counts": []interface{}{}},
+			"_meta":             map[string]interface{}{"trace": "abc"},
+		}, nil
+	}
+	p := newTestProxy(t, types.MCPConfig{}, map[string]types.MCPClient{"reports": client})
+
+	tool, err := p.GetTool(context.Background(), "report")
+	if err != nil || !reflect.DeepEqual(tool.OutputSchema, reportTool().OutputSchema) {
+		t.Fatalf("GetTool(report) = %+v, %v, want the declared output schema", tool, err)
+	}
+
+	result, err := p.UseTool(context.Background(), "report", types.ToolRequest{})
+	if err != nil {
+		t.Fatalf("UseTool() error = %v, want the result with warnings", err)
+	}
+	meta, _ := result["_meta"].(map[string]interface{})
+	if meta["trace"] != "abc" || !reflect.DeepEqual(meta[outputWarningsKey], []string{`structuredContent: missing required property "title"`}) {
+		t.Errorf("_meta = %v, want the server's entries and the missing title", meta)
+	}
+}
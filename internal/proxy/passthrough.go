@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"mcp-smart-proxy/internal/requestid"
+	"mcp-smart-proxy/pkg/types"
+)
+
+// Passthrough forwards a raw JSON-RPC request to a server and returns its response unchanged. It
+// must be enabled with proxy.passthrough; tenants restricted to tools rather than whole servers
+// cannot use it, since a raw request bypasses the tool rules. Methods the proxy manages itself
+// are refused with ErrMethodNotPassable; see passable
+func (p *SmartProxy) Passthrough(ctx context.Context, serverName string, req types.RPCRequest) (map[string]interface{}, error) {
+	if !p.config.Proxy.Passthrough {
+		return nil, types.ErrPassthroughDisabled
+	}
+	if !passable(req.Method) {
+		return nil, fmt.Errorf("%w: %s", types.ErrMethodNotPassable, req.Method)
+	}
+
+	p.mu.RLock()
+	_, configured := p.config.MCPServers[serverName]
+	client, connected := p.clients[serverName]
+	tenant := p.tenant(ctx)
+	p.mu.RUnlock()
+	if !configured || (tenant != nil && (tenant.Tools != nil || tenant.Servers != nil) && !contains(tenant.Servers, serverName)) {
+		return nil, fmt.Errorf("%w: %s", types.ErrServerNotFound, serverName)
+	}
+	if !connected {
+		return nil, fmt.Errorf("%w: no client for server %s", types.ErrServerUnavailable, serverName)
+	}
+	raw, ok := client.(types.RawMCPClient)
+	if !ok {
+		return nil, fmt.Errorf("%w: the client of server %s cannot send raw requests", errors.ErrUnsupported, serverName)
+	}
+
+	requestid.Printf(ctx, "Passing %s through to server %s", req.Method, serverName)
+	response, err := raw.Request(ctx, req.Method, req.Params)
+	if err != nil {
+		return nil, classifyCallError(err)
+	}
+	return response, nil
+}
+
+// passable reports whether a method may be sent through Passthrough. The handshake and
+// notifications belong to the connection, and tool calls must go through UseTool so that
+// confirmation, argument rules, output checks, concurrency limits, circuit breakers and usage
+// statistics all apply
+func passable(method string) bool {
+	return method != "initialize" && method != "tools/call" && !strings.HasPrefix(method, "notifications/")
+}
@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"log"
+
+	"mcp-smart-proxy/internal/llm"
+	"mcp-smart-proxy/pkg/types"
+)
+
+// withPinnedTools puts the configured always-included tools first, drops them from later in the
+// selection and re-applies the selection cap; pinned tools missing from the catalog are skipped
+func (p *SmartProxy) withPinnedTools(selected []types.Tool, allTools []types.Tool) []types.Tool {
+	if len(p.config.Proxy.PinnedTools) == 0 {
+		return selected
+	}
+
+	merged := p.pinnedTools(allTools)
+	seen := make(map[string]bool, len(merged))
+	for _, tool := range merged {
+		seen[tool.Name] = true
+	}
+	for _, tool := range selected {
+		if !seen[tool.Name] {
+			merged = append(merged, tool)
+			seen[tool.Name] = true
+		}
+	}
+
+	if maxTools := p.maxTools(); maxTools > 0 && len(merged) > maxTools {
+		merged = merged[:maxTools]
+	}
+	return merged
+}
+
+// pinnedTools returns the configured always-included tools present in allTools, in config order
+func (p *SmartProxy) pinnedTools(allTools []types.Tool) []types.Tool {
+	byName := make(map[string]types.Tool, len(allTools))
+	for _, tool := range allTools {
+		byName[tool.Name] = tool
+	}
+
+	seen := make(map[string]bool)
+	var pinned []types.Tool
+	for _, name := range p.config.Proxy.PinnedTools {
+		if tool, ok := byName[name]; ok && !seen[name] {
+			pinned = append(pinned, tool)
+			seen[name] = true
+		}
+	}
+	return pinned
+}
+
+// DefaultMaxServerTools is the number of tools accepted from one server when proxy.maxServerTools
+// is unset
+const DefaultMaxServerTools = 1000
+
+// capServerTools truncates a server's tool list to proxy.maxServerTools, logging a warning when
+// tools are dropped
+func (p *SmartProxy) capServerTools(serverName string, tools []types.Tool) []types.Tool {
+	limit := p.maxServerTools()
+	if limit <= 0 || len(tools) <= limit {
+		return tools
+	}
+	log.Printf("Server %s advertised %d tools; keeping the first %d (proxy.maxServerTools)", serverName, len(tools), limit)
+	return tools[:limit]
+}
+
+// maxServerTools returns the configured per-server tool cap; 0 means no limit
+func (p *SmartProxy) maxServerTools() int {
+	if p.config.Proxy.MaxServerTools != nil {
+		return *p.config.Proxy.MaxServerTools
+	}
+	return DefaultMaxServerTools
+}
+
+// maxTools returns the configured selection cap; 0 means no limit
+func (p *SmartProxy) maxTools() int {
+	if p.config.Proxy.MaxTools != nil {
+		return *p.config.Proxy.MaxTools
+	}
+	return llm.DefaultMaxTools
+}
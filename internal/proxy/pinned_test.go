@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// rankingProvider selects the named candidates in the given order
+type rankingProvider []string
+
+func (names rankingProvider) SelectBestTools(ctx context.Context, query string, tools []types.Tool) ([]types.Tool, error) {
+	byName := make(map[string]types.Tool, len(tools))
+	for _, tool := range tools {
+		byName[tool.Name] = tool
+	}
+	var selected []types.Tool
+	for _, name := range names {
+		if tool, ok := byName[name]; ok {
+			selected = append(selected, tool)
+		}
+	}
+	return selected, nil
+}
+
+func TestPinnedTools(t *testing.T) {
+	tests := []struct {
+		name     string
+		pinned   []string
+		maxTools int
+		selected rankingProvider
+		want     string // comma-separated tool names in order
+	}{
+		{name: "no pinned tools", selected: rankingProvider{"write", "read"}, want: "write,read"},
+		{name: "pinned tool the LLM did not select", pinned: []string{"help"}, selected: rankingProvider{"write", "read"}, want: "help,write,read"},
+		{name: "pinned tool the LLM also selected", pinned: []string{"help"}, selected: rankingProvider{"write", "help"}, want: "help,write"},
+		{name: "pinned tools keep config order", pinned: []string{"read", "help"}, selected: rankingProvider{"write"}, want: "read,help,write"},
+		{name: "pinned tool missing from the catalog", pinned: []string{"status", "help"}, selected: rankingProvider{"read"}, want: "help,read"},
+		{name: "duplicate pins", pinned: []string{"help", "help"}, selected: rankingProvider{"read"}, want: "help,read"},
+		{name: "cap applies after pinning", pinned: []string{"help"}, maxTools: 2, selected: rankingProvider{"write", "read"}, want: "help,write"},
+		{name: "nothing selected", pinned: []string{"help"}, selected: rankingProvider{}, want: "help"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.MCPConfig{Proxy: types.ProxySettings{PinnedTools: tt.pinned}}
+			if tt.maxTools > 0 {
+				config.Proxy.MaxTools = &tt.maxTools
+			}
+			p, err := NewInMemory(config, tt.selected, map[string]types.MCPClient{"files": newFakeClient("read", "write", "help")})
+			if err != nil {
+				t.Fatalf("NewInMemory() error = %v", err)
+			}
+			if err := p.Initialize(context.Background()); err != nil {
+				t.Fatalf("Initialize() error = %v", err)
+			}
+			t.Cleanup(func() { p.Close() })
+
+			tools, err := p.DiscoverTools(context.Background(), types.ProxyRequest{Query: "edit a file"})
+			if err != nil {
+				t.Fatalf("DiscoverTools() error = %v", err)
+			}
+			if got := joinToolNames(tools); got != tt.want {
+				t.Errorf("DiscoverTools() = %s, want %s", got, tt.want)
+			}
+
+			var streamed []types.Tool
+			err = p.DiscoverToolsStream(context.Background(), types.ProxyRequest{Query: "edit a file"}, func(tool types.Tool) error {
+				streamed = append(streamed, tool)
+				return nil
+			})
+			if err != nil || joinToolNames(streamed) != tt.want {
+				t.Errorf("DiscoverToolsStream() = %s, %v, want %s", joinToolNames(streamed), err, tt.want)
+			}
+		})
+	}
+}
+
+// joinToolNames returns the comma-separated names of tools in order
+func joinToolNames(tools []types.Tool) string {
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Name
+	}
+	return strings.Join(names, ",")
+}
+
+func TestMaxServerTools(t *testing.T) {
+	limit := func(n int) *int { return &n }
+
+	tests := []struct {
+		name      string
+		limit     *int
+		tools     int
+		wantTools int
+		wantWarn  bool
+	}{
+		{name: "under the default cap", tools: 20, wantTools: 20},
+		{name: "over the default cap", tools: DefaultMaxServerTools + 5, wantTools: DefaultMaxServerTools, wantWarn: true},
+		{name: "over a configured cap", limit: limit(3), tools: 5, wantTools: 3, wantWarn: true},
+		{name: "at a configured cap", limit: limit(5), tools: 5, wantTools: 5},
+		{name: "no limit", limit: limit(0), tools: DefaultMaxServerTools + 5, wantTools: DefaultMaxServerTools + 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := make([]string, tt.tools)
+			for i := range names {
+				names[i] = fmt.Sprintf("tool_%04d", i)
+			}
+			config := types.MCPConfig{Proxy: types.ProxySettings{MaxServerTools: tt.limit}}
+
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			p := newTestProxy(t, config, map[string]types.MCPClient{"flood": newFakeClient(names...)})
+			log.SetOutput(os.Stderr)
+
+			if got := p.Health().Servers["flood"]; got != tt.wantTools {
+				t.Errorf("tools kept from flood = %d, want %d", got, tt.wantTools)
+			}
+			if tt.wantTools < tt.tools {
+				if _, err := p.GetTool(context.Background(), names[tt.wantTools]); err == nil {
+					t.Errorf("GetTool(%s) found a tool past the cap", names[tt.wantTools])
+				}
+			}
+			warned := strings.Contains(logs.String(), fmt.Sprintf("Server flood advertised %d tools; keeping the first %d", tt.tools, tt.wantTools))
+			if warned != tt.wantWarn {
+				t.Errorf("log = %q, want a truncation warning = %v", logs.String(), tt.wantWarn)
+			}
+		})
+	}
+}
@@ -4,30 +4,75 @@ package proxy
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
+	"path"
+	"sort"
 	"sync"
+	"syscall"
 	"time"
 
 	"mcp-smart-proxy/internal/llm"
 	"mcp-smart-proxy/internal/mcp"
+	"mcp-smart-proxy/internal/requestid"
+	"mcp-smart-proxy/internal/secrets"
+	"mcp-smart-proxy/internal/selector"
 	"mcp-smart-proxy/pkg/types"
 )
 
+// defaultRetryBackoff is the delay before the first retry when proxy.retryBackoff is unset
+const defaultRetryBackoff = 200 * time.Millisecond
+
 // SmartProxy is the main proxy server that manages MCP servers and tool selection
 type SmartProxy struct {
 	config      types.MCPConfig
+	configPath  string
+	opts        Options
 	toolCache   *types.ToolCache
-	llmProvider types.LLMProvider
+	providers   map[string]types.LLMProvider
+	defaultLLM  string
 	clients     map[string]types.MCPClient
+	secrets     types.SecretProvider
+	results     *resultCache
+	breakers    map[string]*breaker      // per-server circuit breakers, replaced on refresh
+	slots       map[string]semaphore     // per-server limits on concurrent calls, replaced on refresh
+	replicas    map[string][]string      // tool name -> further servers offering it identically, in failover order
+	serverTools map[string][]types.Tool  // server name -> tools as the server reports them, after capping
+	factories   map[string]ClientFactory // transport name -> client constructor
+	stats       *usageStats
+	calls       *callRegistry // in-flight tool calls by call ID
+	embeddings  *selector.EmbeddingCache
+	enriched    *descriptionCache          // LLM-written descriptions of sparse tools
+	transforms  map[string]ResultTransform // name -> result transform registered with SetTransform
+	versions    map[string]toolVersion     // tool name -> when it appeared and last changed, as of the last sync
+	removed     map[string]toolVersion     // tool name -> last version of a tool that left the cache
+	failures    map[string]string          // server name -> error of its last failed connection attempt
+	restarts    map[string]*restartState   // server name -> recent restarts of an unresponsive server
+	startup     *types.StartupSummary      // outcome of Initialize
+	lastDiff    *types.ToolDiff
+	synced      chan struct{} // closed and replaced whenever the tool cache changes
 	mu          sync.RWMutex
 }
 
-// New creates a new SmartProxy instance
+// BatchConcurrency bounds how many selections of a batch discovery run at once
+const BatchConcurrency = 4
+
+// DefaultLLMTimeout bounds the LLM call of a tool selection when proxy.llmTimeout is unset, leaving
+// part of the HTTP handlers' 30s budget for the rest of the request
+const DefaultLLMTimeout = 20 * time.Second
+
+// New creates a new SmartProxy instance from a config file path or http(s) URL
 func New(configPath string) (*SmartProxy, error) {
+	return NewWithOptions(configPath, Options{})
+}
+
+// NewWithOptions creates a new SmartProxy instance with the given config loading options
+func NewWithOptions(configPath string, opts Options) (*SmartProxy, error) {
 	// Load configuration
-	configData, err := ioutil.ReadFile(configPath)
+	configData, err := readConfig(configPath, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
@@ -37,157 +82,1285 @@ func New(configPath string) (*SmartProxy, error) {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
-	// Initialize LLM provider
-	llmProvider, err := llm.NewProvider()
-	if err != nil {
+	proxy := newSmartProxy(config, configPath, opts)
+
+	// Initialize LLM providers
+	if err := proxy.initProviders(); err != nil {
 		return nil, fmt.Errorf("failed to initialize LLM provider: %w", err)
 	}
+	if err := proxy.setup(); err != nil {
+		return nil, err
+	}
+
+	return proxy, nil
+}
 
-	proxy := &SmartProxy{
+// newSmartProxy returns a proxy with empty caches and no LLM providers
+func newSmartProxy(config types.MCPConfig, configPath string, opts Options) *SmartProxy {
+	return &SmartProxy{
 		config:      config,
+		configPath:  configPath,
+		opts:        opts,
 		toolCache:   &types.ToolCache{Tools: make(map[string]types.Tool), ServerMap: make(map[string]string)},
-		llmProvider: llmProvider,
 		clients:     make(map[string]types.MCPClient),
+		results:     newResultCache(time.Duration(config.Proxy.ResultCacheTTL)),
+		breakers:    make(map[string]*breaker),
+		slots:       make(map[string]semaphore),
+		failures:    make(map[string]string),
+		restarts:    make(map[string]*restartState),
+		replicas:    make(map[string][]string),
+		serverTools: make(map[string][]types.Tool),
+		factories:   defaultClientFactories(),
+		stats:       newUsageStats(),
+		calls:       newCallRegistry(),
+		embeddings:  selector.NewEmbeddingCache(),
+		enriched:    newDescriptionCache(),
+		versions:    make(map[string]toolVersion),
+		removed:     make(map[string]toolVersion),
+		synced:      make(chan struct{}),
 	}
+}
 
-	return proxy, nil
+// setup validates the selector settings and loads the secrets file once providers are in place
+func (p *SmartProxy) setup() error {
+	if _, err := p.selector(""); err != nil {
+		return fmt.Errorf("failed to initialize tool selector: %w", err)
+	}
+
+	if p.config.Proxy.EnrichDescriptions {
+		if _, ok := p.providers[p.defaultLLM].(types.CompletionProvider); !ok {
+			return fmt.Errorf("enrichDescriptions: the default LLM provider cannot generate text")
+		}
+	}
+
+	// Adding a server runs its command, so it must never be open to unauthenticated callers
+	if p.config.Proxy.ServerManagement && !p.hasAdminTenant() {
+		return fmt.Errorf("serverManagement: configure a tenant with \"admin\": true to authorize it")
+	}
+
+	if path := p.config.Proxy.EmbeddingCacheFile; path != "" {
+		p.embeddings = selector.NewFileEmbeddingCache(path)
+		if err := p.embeddings.Load(); err != nil {
+			log.Printf("Ignoring embedding cache %s, tools will be re-embedded: %v", path, err)
+		}
+	}
+
+	if p.config.Proxy.SecretsFile != "" {
+		provider, err := secrets.NewFileProvider(p.config.Proxy.SecretsFile)
+		if err != nil {
+			return fmt.Errorf("failed to load secrets: %w", err)
+		}
+		p.secrets = provider
+	}
+	return nil
 }
 
-// Initialize discovers all tools from configured MCP servers
+// initProviders creates the configured LLM providers, falling back to a single env-based provider
+func (p *SmartProxy) initProviders() error {
+	settings, err := p.llmSettings()
+	if err != nil {
+		return err
+	}
+
+	if len(p.config.Proxy.Providers) == 0 {
+		provider, err := llm.NewProviderOfType(p.opts.LLMProvider, settings)
+		if err != nil {
+			return err
+		}
+		p.providers = map[string]types.LLMProvider{"default": provider}
+		p.defaultLLM = "default"
+		return nil
+	}
+
+	providers, err := llm.NewProviders(p.config.Proxy.Providers, settings)
+	if err != nil {
+		return err
+	}
+
+	defaultName := p.config.Proxy.DefaultProvider
+	if defaultName == "" && len(providers) == 1 {
+		for name := range providers {
+			defaultName = name
+		}
+	}
+	if _, ok := providers[defaultName]; !ok {
+		return fmt.Errorf("defaultProvider %q is not one of the configured providers", defaultName)
+	}
+
+	p.providers = providers
+	p.defaultLLM = defaultName
+	return nil
+}
+
+// llmSettings derives the selection settings shared by all providers from the config
+func (p *SmartProxy) llmSettings() (llm.Settings, error) {
+	settings := llm.DefaultSettings()
+	settings.MaxTools = p.maxTools()
+
+	models, err := settings.Models.WithOverrides(p.config.Proxy.Models)
+	if err != nil {
+		return settings, err
+	}
+	settings.Models = models
+
+	promptText := p.config.Proxy.PromptTemplate
+	if promptText == "" && p.config.Proxy.PromptTemplateFile != "" {
+		data, err := ioutil.ReadFile(p.config.Proxy.PromptTemplateFile)
+		if err != nil {
+			return settings, fmt.Errorf("failed to read prompt template: %w", err)
+		}
+		promptText = string(data)
+	}
+	if promptText != "" {
+		prompt, err := llm.ParsePromptTemplate(promptText)
+		if err != nil {
+			return settings, err
+		}
+		settings.Prompt = prompt
+	}
+
+	if p.config.Proxy.DefaultTier != "" {
+		tier, err := llm.ParseTier(p.config.Proxy.DefaultTier)
+		if err != nil {
+			return settings, fmt.Errorf("defaultTier: %w", err)
+		}
+		settings.DefaultTier = tier
+	}
+	return settings, nil
+}
+
+// withTier validates the request's model tier and attaches it to ctx for the provider
+func withTier(ctx context.Context, req types.ProxyRequest) (context.Context, error) {
+	tier, err := llm.ParseTier(req.Tier)
+	if err != nil {
+		return nil, err
+	}
+	if tier == "" {
+		return ctx, nil
+	}
+	return llm.WithTier(ctx, tier), nil
+}
+
+// selectionContext bounds a tool selection by proxy.llmTimeout, so a slow model cannot use up the
+// whole request budget, and carries the servers' selection hints for the prompt
+func (p *SmartProxy) selectionContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	p.mu.RLock()
+	var hints map[string]string
+	for name, server := range p.config.MCPServers {
+		if server.SelectionHint != "" {
+			if hints == nil {
+				hints = make(map[string]string)
+			}
+			hints[name] = server.SelectionHint
+		}
+	}
+	p.mu.RUnlock()
+	if hints != nil {
+		ctx = llm.WithServerHints(ctx, hints)
+	}
+
+	timeout := time.Duration(orDefault(p.config.Proxy.LLMTimeout, types.Duration(DefaultLLMTimeout)))
+	return context.WithTimeoutCause(ctx, timeout, fmt.Errorf("%w after %s", types.ErrLLMTimeout, timeout))
+}
+
+// selectionError reports ErrLLMTimeout instead of err when the selection ran out of time
+func selectionError(ctx context.Context, err error) error {
+	if cause := context.Cause(ctx); errors.Is(cause, types.ErrLLMTimeout) {
+		return cause
+	}
+	return err
+}
+
+// provider returns the named LLM provider, or the default provider when name is empty
+func (p *SmartProxy) provider(name string) (types.LLMProvider, error) {
+	if name == "" {
+		name = p.defaultLLM
+	}
+	provider, ok := p.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", types.ErrUnknownProvider, name)
+	}
+	return provider, nil
+}
+
+// selector builds the configured selection strategy on top of the named LLM provider
+func (p *SmartProxy) selector(providerName string) (types.Selector, error) {
+	provider, err := p.provider(providerName)
+	if err != nil {
+		return nil, err
+	}
+	return selector.New(p.config.Proxy.Selector, provider, selector.Options{
+		MaxTools:         p.maxTools(),
+		HybridCandidates: p.config.Proxy.HybridCandidates,
+		Embeddings:       p.embeddings,
+		TokenBudget:      p.config.Proxy.PromptTokenBudget,
+		ChunkConcurrency: p.config.Proxy.ChunkConcurrency,
+	})
+}
+
+// sampler returns the default LLM provider as a sampling backend for servers that opted in
+func (p *SmartProxy) sampler(serverName string, serverConfig types.MCPServer) types.CompletionProvider {
+	if !serverConfig.Sampling {
+		return nil
+	}
+
+	sampler, ok := p.providers[p.defaultLLM].(types.CompletionProvider)
+	if !ok {
+		log.Printf("Server %s requested sampling but the default LLM provider does not support it", serverName)
+		return nil
+	}
+	return sampler
+}
+
+// SetSecretProvider overrides the provider used to resolve secret references in server env values
+func (p *SmartProxy) SetSecretProvider(provider types.SecretProvider) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.secrets = provider
+}
+
+// Initialize discovers all tools from configured MCP servers and records a StartupSummary, also
+// when a required server fails
 func (p *SmartProxy) Initialize(ctx context.Context) error {
 	log.Println("Initializing Smart Proxy...")
 
+	if err := p.checkTransforms(); err != nil {
+		return err
+	}
+
 	// Discover all tools from configured servers
-	if err := p.discoverAllTools(ctx); err != nil {
+	started := time.Now()
+	err := p.discoverAllTools(ctx)
+	summary := p.recordStartup(started)
+	log.Printf("Connected %d of %d servers with %d tools in %s", summary.Connected, summary.Attempted, summary.Tools, time.Duration(summary.Duration).Round(time.Millisecond))
+	if err != nil {
 		return fmt.Errorf("failed to discover tools: %w", err)
 	}
-
-	log.Printf("Discovered %d tools from %d servers", len(p.toolCache.Tools), len(p.config.MCPServers))
 	return nil
 }
 
-// discoverAllTools connects to all configured MCP servers and caches their tools
-func (p *SmartProxy) discoverAllTools(ctx context.Context) error {
+// recordStartup builds and keeps the summary of the discovery that began at started
+func (p *SmartProxy) recordStartup(started time.Time) types.StartupSummary {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	summary := types.StartupSummary{
+		Attempted: len(p.config.MCPServers),
+		Tools:     len(p.toolCache.Tools),
+		Finished:  time.Now(),
+	}
+	summary.Duration = types.Duration(summary.Finished.Sub(started))
+	for name := range p.config.MCPServers {
+		if p.connected(name) {
+			summary.Connected++
+		} else if reason, ok := p.failures[name]; ok {
+			if summary.Failed == nil {
+				summary.Failed = make(map[string]string)
+			}
+			summary.Failed[name] = reason
+		}
+	}
+	p.startup = &summary
+	return summary
+}
+
+// Startup returns the summary recorded by Initialize, or nil before it has run
+func (p *SmartProxy) Startup() *types.StartupSummary {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.startup == nil {
+		return nil
+	}
+	summary := *p.startup
+	return &summary
+}
+
+// discoverAllTools connects to all configured MCP servers concurrently and caches each server's
+// tools as soon as it answers, so a slow server does not hold back the others; servers that fail
+// are skipped, but failures of required servers are returned once every server has been tried
+func (p *SmartProxy) discoverAllTools(ctx context.Context) error {
+	p.mu.RLock()
+	servers := make(map[string]types.MCPServer, len(p.config.MCPServers))
 	for serverName, serverConfig := range p.config.MCPServers {
-		log.Printf("Connecting to server: %s", serverName)
+		servers[serverName] = serverConfig
+	}
+	secretProvider := p.secrets
+	p.mu.RUnlock()
 
-		client, err := mcp.NewStdioClient(serverConfig.Command, serverConfig.Args, serverConfig.Env)
-		if err != nil {
-			log.Printf("Failed to connect to server %s: %v", serverName, err)
+	var (
+		wg           sync.WaitGroup
+		errMu        sync.Mutex
+		requiredErrs []error
+	)
+	for serverName, serverConfig := range servers {
+		wg.Add(1)
+		go func(serverName string, serverConfig types.MCPServer) {
+			defer wg.Done()
+
+			requestid.Printf(ctx, "Connecting to server: %s", serverName)
+			if err := p.publishServer(ctx, serverName, serverConfig, secretProvider); err != nil {
+				requestid.Printf(ctx, "Failed to start server %s: %v", serverName, err)
+				p.mu.Lock()
+				p.failures[serverName] = err.Error()
+				p.mu.Unlock()
+				if serverConfig.Required {
+					errMu.Lock()
+					requiredErrs = append(requiredErrs, fmt.Errorf("required server %s: %w", serverName, err))
+					errMu.Unlock()
+				}
+			}
+		}(serverName, serverConfig)
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	p.markSynced()
+	p.mu.Unlock()
+
+	// Report required failures in a stable order
+	sort.Slice(requiredErrs, func(i, j int) bool { return requiredErrs[i].Error() < requiredErrs[j].Error() })
+	return errors.Join(requiredErrs...)
+}
+
+// publishServer connects a configured server without holding p.mu and then registers it, making
+// its tools visible right away
+func (p *SmartProxy) publishServer(ctx context.Context, serverName string, serverConfig types.MCPServer, secretProvider types.SecretProvider) error {
+	p.mu.RLock()
+	factory, err := p.clientFactory(serverConfig)
+	p.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	client, tools, err := p.connectServer(ctx, factory, serverName, serverConfig, secretProvider)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, exists := p.config.MCPServers[serverName]; !exists {
+		// Removed while connecting
+		client.Close()
+		return nil
+	}
+	p.registerServer(serverName, client, tools)
+	requestid.Printf(ctx, "Server %s provided %d tools", serverName, len(tools))
+	return nil
+}
+
+// serverLogLevel returns the log level to request from a server, falling back to Options.LogLevel
+func (p *SmartProxy) serverLogLevel(serverConfig types.MCPServer) string {
+	if serverConfig.LogLevel != "" {
+		return serverConfig.LogLevel
+	}
+	return p.opts.LogLevel
+}
+
+// connectServer starts a server through its transport's factory and lists its tools; it does
+// not touch shared state
+func (p *SmartProxy) connectServer(ctx context.Context, factory ClientFactory, serverName string, serverConfig types.MCPServer, secretProvider types.SecretProvider) (types.MCPClient, []types.Tool, error) {
+	env, err := secrets.ResolveEnv(serverConfig.Env, secretProvider)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve environment: %w", err)
+	}
+
+	client, err := factory(ctx, serverName, serverConfig, env, mcp.Options{
+		ConnectTimeout: time.Duration(orDefault(serverConfig.ConnectTimeout, orDefault(p.config.Proxy.ConnectTimeout, types.Duration(p.opts.ConnectTimeout)))),
+		ReadTimeout:    time.Duration(orDefault(serverConfig.ReadTimeout, orDefault(p.config.Proxy.ReadTimeout, types.Duration(p.opts.ReadTimeout)))),
+		CancelGrace:    time.Duration(orDefault(serverConfig.CancelGrace, p.config.Proxy.CancelGrace)),
+		Roots:          serverConfig.Roots,
+		Sampler:        p.sampler(serverName, serverConfig),
+		Batch:          serverConfig.Batch,
+		MaxMessageSize: p.config.Proxy.MaxResultBytes,
+		Name:           serverName,
+		LogLevel:       p.serverLogLevel(serverConfig),
+		IDPrefix:       serverConfig.RequestIDPrefix,
+		ClientInfo:     clientInfo(serverConfig),
+		Capabilities:   serverConfig.Capabilities,
+		Limits:         resourceLimits(serverConfig),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect: %w", err)
+	}
+
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		client.Close()
+		return nil, nil, fmt.Errorf("failed to list tools: %w", err)
+	}
+
+	return client, tools, nil
+}
+
+// clientInfo returns the server's clientInfo override, or the zero value to keep the defaults
+func clientInfo(server types.MCPServer) types.ClientInfo {
+	if server.ClientInfo == nil {
+		return types.ClientInfo{}
+	}
+	return *server.ClientInfo
+}
+
+// resourceLimits returns the server's process limits, or the zero value when it has none
+func resourceLimits(server types.MCPServer) types.ResourceLimits {
+	if server.Limits == nil {
+		return types.ResourceLimits{}
+	}
+	return *server.Limits
+}
+
+// registerServer adds a connected server and its tools to the cache; the caller must hold p.mu
+func (p *SmartProxy) registerServer(serverName string, client types.MCPClient, tools []types.Tool) {
+	p.clients[serverName] = client
+	p.breakers[serverName] = newBreaker(p.config.Proxy.BreakerThreshold, time.Duration(p.config.Proxy.BreakerCooldown))
+	p.slots[serverName] = newSemaphore(p.config.MCPServers[serverName].MaxConcurrency)
+	delete(p.failures, serverName)
+	delete(p.restarts, serverName)
+	p.registerTools(serverName, tools)
+}
+
+// registerTools adds a server's tools to the cache; the caller must hold p.mu
+func (p *SmartProxy) registerTools(serverName string, tools []types.Tool) {
+	tools = p.capServerTools(serverName, tools)
+	p.recordServerTools(serverName, tools)
+
+	for _, tool := range tools {
+		name := p.qualifiedName(serverName, tool.Name)
+		if target, ok := p.config.Proxy.Aliases[name]; ok && target != name {
+			log.Printf("Tool %s of server %s shadows the alias %s for %s; calls by that name go to the real tool", name, serverName, name, target)
+		}
+		if p.searchToolEnabled(name) {
+			log.Printf("Tool %s of server %s is hidden by the proxy's own %s tool", name, serverName, name)
+		}
+	}
+	p.placeTools(serverName, tools)
+}
+
+// placeTools puts a server's tools into the cache, resolving name collisions with the tools
+// already there; the caller must hold p.mu
+func (p *SmartProxy) placeTools(serverName string, tools []types.Tool) {
+	for _, tool := range tools {
+		tool.Name = p.qualifiedName(serverName, tool.Name)
+		if p.searchToolEnabled(tool.Name) {
 			continue
 		}
+		tool.ServerName = serverName
+		tool.InputSchema = normalizeInputSchema(tool.InputSchema)
 
-		p.clients[serverName] = client
+		// Servers register in whatever order they answer; precedence follows server names so the
+		// outcome does not depend on that order
+		existing, exists := p.toolCache.Tools[tool.Name]
+		if exists && existing.ServerName != serverName {
+			if p.config.Proxy.DeduplicateTools && sameTool(existing, tool) {
+				p.addReplica(existing, serverName)
+				continue
+			}
+			if serverName > existing.ServerName {
+				// The server sorting first wins, as it is the primary among replicas
+				continue
+			}
+		}
 
-		tools, err := client.ListTools(ctx)
-		if err != nil {
-			log.Printf("Failed to list tools from server %s: %v", serverName, err)
-			client.Close()
-			delete(p.clients, serverName)
-			continue
+		delete(p.replicas, tool.Name)
+		p.toolCache.Tools[tool.Name] = tool
+		p.toolCache.ServerMap[tool.Name] = serverName
+	}
+}
+
+// ListTools returns all cached tools, each with the transport and health of its server, and the
+// search tool when it is enabled
+func (p *SmartProxy) ListTools(ctx context.Context) ([]types.Tool, error) {
+	tenant := p.tenant(ctx)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.listTools(tenant), nil
+}
+
+// listTools returns the cached tools visible to the tenant, annotated for listings; the caller
+// must hold p.mu
+func (p *SmartProxy) listTools(tenant *types.Tenant) []types.Tool {
+	servers := make(map[string]*types.ToolServer)
+	var tools []types.Tool
+	if p.config.Proxy.SearchTool {
+		tools = append(tools, searchTool())
+	}
+	for name, tool := range p.toolCache.Tools {
+		if p.visible(tenant, name) {
+			tools = append(tools, p.annotateTool(tool, servers))
 		}
+	}
+	return tools
+}
+
+// EachTool calls fn with every cached tool visible to the caller, annotated as by ListTools, one
+// tool at a time so a large catalog is never copied whole. Each tool is looked up under a short
+// read lock, so a slow fn does not hold up refreshes; tools removed meanwhile are skipped. It
+// stops at the first error fn returns
+func (p *SmartProxy) EachTool(ctx context.Context, fn func(types.Tool) error) error {
+	tenant := p.tenant(ctx)
 
-		// Cache tools
-		for _, tool := range tools {
-			tool.ServerName = serverName
-			p.toolCache.Tools[tool.Name] = tool
-			p.toolCache.ServerMap[tool.Name] = serverName
+	p.mu.RLock()
+	names := make([]string, 0, len(p.toolCache.Tools))
+	for name := range p.toolCache.Tools {
+		if p.visible(tenant, name) {
+			names = append(names, name)
 		}
+	}
+	p.mu.RUnlock()
 
-		log.Printf("Server %s provided %d tools", serverName, len(tools))
+	if p.config.Proxy.SearchTool {
+		if err := fn(searchTool()); err != nil {
+			return err
+		}
 	}
+	servers := make(map[string]*types.ToolServer)
+	for _, name := range names {
+		p.mu.RLock()
+		tool, ok := p.toolCache.Tools[name]
+		if ok {
+			tool = p.annotateTool(tool, servers)
+		}
+		p.mu.RUnlock()
 
-	p.toolCache.LastSync = time.Now()
+		if !ok {
+			continue
+		}
+		if err := fn(tool); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// ListTools returns all cached tools
-func (p *SmartProxy) ListTools(ctx context.Context) ([]types.Tool, error) {
+// annotateTool adds a cached tool's server, aliases and version times for listings, reusing the
+// server descriptions already in servers; the caller must hold p.mu
+func (p *SmartProxy) annotateTool(tool types.Tool, servers map[string]*types.ToolServer) types.Tool {
+	server, ok := servers[tool.ServerName]
+	if !ok {
+		server = p.toolServer(tool.ServerName)
+		servers[tool.ServerName] = server
+	}
+	tool.Server = server
+	tool.Aliases = p.aliasesOf(tool.Name)
+	if version, ok := p.versions[tool.Name]; ok {
+		firstSeen, modified := version.firstSeen, version.modified
+		tool.FirstSeen, tool.ModifiedAt = &firstSeen, &modified
+	}
+	return tool
+}
+
+// GetTool returns the cached tool with the given name or alias
+func (p *SmartProxy) GetTool(ctx context.Context, toolName string) (*types.Tool, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	var tools []types.Tool
-	for _, tool := range p.toolCache.Tools {
-		tools = append(tools, tool)
+	toolName = p.resolveAlias(toolName)
+	if p.searchToolEnabled(toolName) {
+		tool := searchTool()
+		return &tool, nil
+	}
+	tool, exists := p.toolCache.Tools[toolName]
+	if !exists || !p.visible(p.tenant(ctx), toolName) {
+		return nil, fmt.Errorf("%w: %s", types.ErrToolNotFound, toolName)
 	}
+	return &tool, nil
+}
 
-	return tools, nil
+// DiscoverTools uses the configured selection strategy to pick the most relevant tools for a query
+func (p *SmartProxy) DiscoverTools(ctx context.Context, req types.ProxyRequest) ([]types.Tool, error) {
+	sel, err := p.selector(req.Provider)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = withTier(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	allTools := p.snapshotTools(ctx, req.Tag, req.Exclude)
+	if len(allTools) == 0 {
+		// Nothing to choose from; skip the LLM round trip
+		requestid.Printf(ctx, "Discovery skipped: %v", types.ErrNoToolsAvailable)
+		return nil, types.ErrNoToolsAvailable
+	}
+
+	selectedTools, err := p.selectTools(ctx, sel, req.Query, allTools)
+	if err != nil {
+		return nil, err
+	}
+
+	requestid.Printf(ctx, "Selected %d of %d tools", len(selectedTools), len(allTools))
+	return selectedTools, nil
 }
 
-// DiscoverTools uses LLM to select the most relevant tools for a query
-func (p *SmartProxy) DiscoverTools(ctx context.Context, query string) ([]types.Tool, error) {
+// DiscoverToolsBatch runs one selection per query over a single snapshot of the catalog, at most
+// BatchConcurrency at a time. A query whose selection fails gets an error in its result; errors
+// affecting every query, such as an unknown provider, are returned instead
+func (p *SmartProxy) DiscoverToolsBatch(ctx context.Context, req types.BatchDiscoveryRequest) ([]types.DiscoveryResult, error) {
+	sel, err := p.selector(req.Provider)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = withTier(ctx, types.ProxyRequest{Tier: req.Tier})
+	if err != nil {
+		return nil, err
+	}
+
+	allTools := p.snapshotTools(ctx, req.Tag, nil)
+	if len(allTools) == 0 {
+		requestid.Printf(ctx, "Discovery skipped: %v", types.ErrNoToolsAvailable)
+		return nil, types.ErrNoToolsAvailable
+	}
+
+	results := make([]types.DiscoveryResult, len(req.Queries))
+	slots := make(chan struct{}, BatchConcurrency)
+	var wg sync.WaitGroup
+	for i, query := range req.Queries {
+		wg.Add(1)
+		go func(i int, query string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			results[i] = types.DiscoveryResult{Query: query, RecommendedTools: []types.Tool{}}
+			selectedTools, err := p.selectTools(ctx, sel, query, allTools)
+			if err != nil {
+				results[i].Error = err.Error()
+				return
+			}
+			results[i].RecommendedTools = selectedTools
+		}(i, query)
+	}
+	wg.Wait()
+
+	requestid.Printf(ctx, "Ran %d selections over %d tools", len(req.Queries), len(allTools))
+	return results, nil
+}
+
+// selectTools runs one selection within proxy.llmTimeout, applies the server weights and puts
+// the pinned tools first
+func (p *SmartProxy) selectTools(ctx context.Context, sel types.Selector, query string, allTools []types.Tool) ([]types.Tool, error) {
+	selectCtx, cancel := p.selectionContext(ctx)
+	defer cancel()
+
+	selectedTools, err := sel.Select(selectCtx, query, allTools)
+	if err != nil {
+		err = selectionError(selectCtx, err)
+		requestid.Printf(ctx, "Tool selection failed: %v", err)
+		return nil, fmt.Errorf("failed to select tools: %w", err)
+	}
+	return p.originalTools(p.withPinnedTools(p.withServerWeights(selectedTools), allTools)), nil
+}
+
+// DiscoverToolsDebug runs LLM discovery, whatever the configured strategy, and returns the prompt, candidates and raw LLM response alongside the selection
+func (p *SmartProxy) DiscoverToolsDebug(ctx context.Context, req types.ProxyRequest) (*types.DiscoveryDebug, error) {
+	if !p.config.Proxy.Debug {
+		return nil, types.ErrDebugDisabled
+	}
+
+	llmProvider, err := p.provider(req.Provider)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = withTier(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, ok := llmProvider.(types.DebugLLMProvider)
+	if !ok {
+		return nil, fmt.Errorf("LLM provider does not support debug discovery")
+	}
+
+	allTools := p.snapshotTools(ctx, req.Tag, req.Exclude)
+	if len(allTools) == 0 {
+		return nil, types.ErrNoToolsAvailable
+	}
+
+	selectCtx, cancel := p.selectionContext(ctx)
+	defer cancel()
+	debug, err := provider.SelectBestToolsDebug(selectCtx, req.Query, allTools)
+	if err != nil {
+		err = selectionError(selectCtx, err)
+		return nil, fmt.Errorf("failed to select tools: %w", err)
+	}
+	debug.SelectedTools = p.originalTools(p.withPinnedTools(p.withServerWeights(debug.SelectedTools), allTools))
+
+	return debug, nil
+}
+
+// DiscoverToolsStream runs discovery and passes each selected tool to emit as soon as it is known,
+// pinned tools first; strategies without streaming support deliver their selection all at once
+func (p *SmartProxy) DiscoverToolsStream(ctx context.Context, req types.ProxyRequest, emit func(types.Tool) error) error {
+	sel, err := p.selector(req.Provider)
+	if err != nil {
+		return err
+	}
+	ctx, err = withTier(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	allTools := p.snapshotTools(ctx, req.Tag, req.Exclude)
+	if len(allTools) == 0 {
+		requestid.Printf(ctx, "Discovery skipped: %v", types.ErrNoToolsAvailable)
+		return types.ErrNoToolsAvailable
+	}
+
+	sink := &selectionSink{maxTools: p.maxTools(), sent: make(map[string]bool), emit: func(tool types.Tool) error {
+		p.mu.RLock()
+		tool = p.originalTool(tool)
+		p.mu.RUnlock()
+		return emit(tool)
+	}}
+	selectCtx, cancel := p.selectionContext(ctx)
+	defer cancel()
+	if err := sink.stream(selectCtx, sel, req.Query, allTools, p.pinnedTools(allTools)); err != nil {
+		err = selectionError(selectCtx, err)
+		requestid.Printf(ctx, "Tool selection failed: %v", err)
+		return fmt.Errorf("failed to select tools: %w", err)
+	}
+
+	requestid.Printf(ctx, "Selected %d of %d tools", len(sink.sent), len(allTools))
+	return nil
+}
+
+// snapshotTools copies the cached tools visible to the caller into a slice; a non-empty tag
+// keeps only tools served by a server carrying that tag, and tools named in exclude (directly or
+// by alias) are left out
+func (p *SmartProxy) snapshotTools(ctx context.Context, tag string, exclude []string) []types.Tool {
+	tenant := p.tenant(ctx)
+
 	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	excluded := make(map[string]bool, len(exclude))
+	for _, name := range exclude {
+		excluded[p.resolveAlias(name)] = true
+	}
+
 	allTools := make([]types.Tool, 0, len(p.toolCache.Tools))
-	for _, tool := range p.toolCache.Tools {
-		allTools = append(allTools, tool)
+	for name, tool := range p.toolCache.Tools {
+		if tag != "" && !p.toolHasTag(name, tag) {
+			continue
+		}
+		if excluded[name] {
+			continue
+		}
+		if !p.visible(tenant, name) {
+			continue
+		}
+		allTools = append(allTools, p.withEnrichedDescription(tool))
+	}
+	return allTools
+}
+
+// toolHasTag reports whether the tool's server or one of its replicas carries the tag
+func (p *SmartProxy) toolHasTag(toolName, tag string) bool {
+	serverNames := append([]string{p.toolCache.ServerMap[toolName]}, p.replicas[toolName]...)
+	for _, serverName := range serverNames {
+		for _, t := range p.config.MCPServers[serverName].Tags {
+			if t == tag {
+				return true
+			}
+		}
 	}
+	return false
+}
+
+// UseTool executes a specific tool, named directly or by alias, with the given request
+// arguments; the call can be cancelled through CancelCall while it runs
+func (p *SmartProxy) UseTool(ctx context.Context, toolName string, req types.ToolRequest) (map[string]interface{}, error) {
+	p.mu.RLock()
+	toolName = p.resolveAlias(toolName)
 	p.mu.RUnlock()
 
-	// Use LLM to select best tools
-	selectedTools, err := p.llmProvider.SelectBestTools(ctx, query, allTools)
+	id := callID(req)
+	ctx, done, err := p.calls.start(ctx, id, toolName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to select tools: %w", err)
+		return nil, err
 	}
+	defer done()
 
-	return selectedTools, nil
+	result, err := p.useTool(ctx, toolName, req)
+	if err != nil && errors.Is(context.Cause(ctx), types.ErrCallCancelled) {
+		requestid.Printf(ctx, "Call %s to tool %s was cancelled", id, toolName)
+		return nil, fmt.Errorf("tool %s: %w", toolName, types.ErrCallCancelled)
+	}
+	return result, err
 }
 
-// UseTool executes a specific tool with the given arguments
-func (p *SmartProxy) UseTool(ctx context.Context, toolName string, arguments map[string]interface{}) (map[string]interface{}, error) {
+// useTool resolves, checks and dispatches a tool call
+func (p *SmartProxy) useTool(ctx context.Context, toolName string, req types.ToolRequest) (map[string]interface{}, error) {
+	if p.searchToolEnabled(toolName) {
+		return p.callSearchTool(ctx, req.Arguments)
+	}
+
 	p.mu.RLock()
 	serverName, exists := p.toolCache.ServerMap[toolName]
 	if !exists {
 		p.mu.RUnlock()
-		return nil, fmt.Errorf("tool %s not found", toolName)
+		return nil, fmt.Errorf("%w: %s", types.ErrToolNotFound, toolName)
 	}
 
-	client, exists := p.clients[serverName]
-	if !exists {
+	tenant := p.tenant(ctx)
+	if !p.visible(tenant, toolName) {
+		p.mu.RUnlock()
+		return nil, fmt.Errorf("%w: %s", types.ErrToolNotFound, toolName)
+	}
+
+	routes := tenantRoutes(tenant, toolName, p.routes(toolName))
+	if len(routes) == 0 {
 		p.mu.RUnlock()
-		return nil, fmt.Errorf("client for server %s not available", serverName)
+		return nil, fmt.Errorf("%w: no client for server %s", types.ErrServerUnavailable, serverName)
 	}
+	tool := p.toolCache.Tools[toolName]
 	p.mu.RUnlock()
 
+	if err := p.checkArguments(toolName, req.Arguments); err != nil {
+		return nil, err
+	}
+
+	arguments, err := prepareArguments(tool, req.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	if p.config.Proxy.CoerceArguments {
+		coerceArguments(tool, arguments)
+	}
+
+	if reason := p.confirmationReason(tool); reason != "" && !req.Confirm {
+		return nil, &types.ConfirmationRequiredError{Tool: toolName, Reason: reason}
+	}
+
+	cacheKey, cacheable := "", false
+	if tool.IsReadOnly() && p.results.enabled() {
+		cacheKey, cacheable = p.results.key(toolName, arguments)
+	}
+	if cacheable && !req.NoCache {
+		if result, ok := p.results.get(cacheKey); ok {
+			requestid.Printf(ctx, "Serving tool %s from result cache", toolName)
+			p.stats.recordTool(toolName, nil)
+			return p.transformResult(ctx, tool, result)
+		}
+	}
+
 	// Execute tool
-	result, err := client.CallTool(ctx, toolName, arguments)
+	result, err := p.callRoutes(ctx, toolName, routes, arguments)
+	p.stats.recordTool(toolName, err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute tool %s: %w", toolName, err)
+		return nil, fmt.Errorf("failed to execute tool %s: %w", toolName, classifyCallError(err))
+	}
+	requestid.Printf(ctx, "Tool %s returned %s", toolName, mcp.DescribeResult(result))
+
+	result, warnings := checkOutput(tool, result)
+	for _, warning := range warnings {
+		requestid.Printf(ctx, "Tool %s result does not match its outputSchema: %s", toolName, warning)
+	}
+
+	if cacheable {
+		p.results.set(cacheKey, result)
+	}
+
+	return p.transformResult(ctx, tool, result)
+}
+
+// serverRetryPolicy returns how often and after what initial backoff calls to a server are retried,
+// preferring the server's own settings over the proxy-wide ones
+func (p *SmartProxy) serverRetryPolicy(server types.MCPServer) retryPolicy {
+	policy := retryPolicy{
+		retries: p.config.Proxy.Retries,
+		backoff: time.Duration(orDefault(server.RetryBackoff, p.config.Proxy.RetryBackoff)),
+	}
+	if server.Retries != nil {
+		policy.retries = *server.Retries
+	}
+	if policy.backoff <= 0 {
+		policy.backoff = defaultRetryBackoff
+	}
+	return policy
+}
+
+// orDefault returns d unless it is unset, in which case fallback
+func orDefault(d, fallback types.Duration) types.Duration {
+	if d > 0 {
+		return d
+	}
+	return fallback
+}
+
+// callWithRetry calls a tool, retrying transient failures with exponential backoff
+func (p *SmartProxy) callWithRetry(ctx context.Context, client types.MCPClient, toolName string, arguments map[string]interface{}, policy retryPolicy) (map[string]interface{}, error) {
+	backoff := policy.backoff
+	for attempt := 0; ; attempt++ {
+		result, err := client.CallTool(ctx, toolName, arguments)
+		if err == nil || attempt >= policy.retries || !isTransient(err) {
+			return result, err
+		}
+
+		requestid.Printf(ctx, "Transient error calling tool %s (attempt %d), retrying in %s: %v", toolName, attempt+1, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, err
+		}
+		backoff *= 2
+	}
+}
+
+// isTransient reports whether a tool call error is worth retrying; tool-reported and
+// validation errors are not, timeouts and connection resets are
+func isTransient(err error) bool {
+	if errors.Is(err, mcp.ErrTimeout) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EAGAIN) {
+		return true
 	}
 
-	return result, nil
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
 }
 
-// RefreshTools rediscovers all tools from configured servers
+// classifyCallError wraps a failed call with the types sentinel for its error class; errors
+// outside the known classes are returned unchanged
+func classifyCallError(err error) error {
+	switch {
+	case errors.Is(err, mcp.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %w", types.ErrToolTimeout, err)
+	case errors.Is(err, mcp.ErrToolError):
+		return fmt.Errorf("%w: %w", types.ErrToolFailed, err)
+	case isServerFailure(err):
+		return fmt.Errorf("%w: %w", types.ErrServerUnavailable, err)
+	default:
+		return err
+	}
+}
+
+// confirmationReason explains why a tool needs confirmation, or returns "" when it does not.
+// Patterns match the server's own tool name as well as the exposed one, so namespacing the
+// catalog does not let delete_* style patterns miss
+func (p *SmartProxy) confirmationReason(tool types.Tool) string {
+	if !p.config.Proxy.RequireConfirmation {
+		return ""
+	}
+
+	if tool.IsDestructive() {
+		return "annotated as destructive"
+	}
+
+	remote := p.remoteName(tool.ServerName, tool.Name)
+	for _, pattern := range p.config.Proxy.DestructivePatterns {
+		if matched, _ := path.Match(pattern, tool.Name); matched {
+			return fmt.Sprintf("matches destructive pattern %q", pattern)
+		}
+		if matched, _ := path.Match(pattern, remote); matched {
+			return fmt.Sprintf("matches destructive pattern %q", pattern)
+		}
+	}
+
+	return ""
+}
+
+// RefreshTools rediscovers all tools from configured servers; only admins may refresh
 func (p *SmartProxy) RefreshTools(ctx context.Context) error {
-	log.Println("Refreshing tool cache...")
+	if err := p.authorizeAdmin(ctx); err != nil {
+		return err
+	}
+	requestid.Printf(ctx, "Refreshing tool cache...")
 
 	// Close existing clients
 	p.mu.Lock()
+	before := p.toolCache.Tools
 	for _, client := range p.clients {
 		client.Close()
 	}
 	p.clients = make(map[string]types.MCPClient)
+	p.breakers = make(map[string]*breaker)
+	p.slots = make(map[string]semaphore)
+	p.restarts = make(map[string]*restartState)
+	p.replicas = make(map[string][]string)
+	p.serverTools = make(map[string][]types.Tool)
 	p.toolCache.Tools = make(map[string]types.Tool)
 	p.toolCache.ServerMap = make(map[string]string)
 	p.mu.Unlock()
+	p.results.clear()
 
 	// Rediscover tools
-	return p.discoverAllTools(ctx)
+	err := p.discoverAllTools(ctx)
+	p.recordDiff(ctx, before)
+	return err
+}
+
+// SoftRefreshTools re-lists the tools of every connected server over its existing connection,
+// so in-flight calls are not interrupted; only servers that fail to answer, or were not
+// connected, are (re)started. Only admins may refresh
+func (p *SmartProxy) SoftRefreshTools(ctx context.Context) error {
+	if err := p.authorizeAdmin(ctx); err != nil {
+		return err
+	}
+	requestid.Printf(ctx, "Soft-refreshing tool cache...")
+
+	p.mu.RLock()
+	servers := make(map[string]types.MCPServer, len(p.config.MCPServers))
+	clients := make(map[string]types.MCPClient, len(p.config.MCPServers))
+	for serverName, serverConfig := range p.config.MCPServers {
+		servers[serverName] = serverConfig
+		if client, ok := p.clients[serverName]; ok {
+			clients[serverName] = client
+		}
+	}
+	secretProvider := p.secrets
+	p.mu.RUnlock()
+
+	// List and reconnect without holding p.mu, so calls and discovery carry on meanwhile
+	var (
+		wg         sync.WaitGroup
+		listingsMu sync.Mutex
+		listings   = make(map[string]relisting, len(servers))
+	)
+	for serverName, serverConfig := range servers {
+		wg.Add(1)
+		go func(serverName string, serverConfig types.MCPServer) {
+			defer wg.Done()
+			listing := p.relist(ctx, serverName, serverConfig, clients[serverName], secretProvider)
+			listingsMu.Lock()
+			listings[serverName] = listing
+			listingsMu.Unlock()
+		}(serverName, serverConfig)
+	}
+	wg.Wait()
+
+	serverNames := make([]string, 0, len(listings))
+	for serverName := range listings {
+		serverNames = append(serverNames, serverName)
+	}
+	sort.Strings(serverNames)
+
+	p.mu.Lock()
+	before := p.toolCache.Tools
+	p.replicas = make(map[string][]string)
+	p.toolCache.Tools = make(map[string]types.Tool)
+	p.toolCache.ServerMap = make(map[string]string)
+
+	var requiredErrs []error
+	for _, serverName := range serverNames {
+		listing, previous := listings[serverName], clients[serverName]
+		reconnected := listing.client != nil && listing.client != previous
+		serverConfig, configured := p.config.MCPServers[serverName]
+		switch {
+		case !configured:
+			// Removed while refreshing
+			if reconnected {
+				listing.client.Close()
+			}
+		case listing.err != nil:
+			requestid.Printf(ctx, "Failed to start server %s: %v", serverName, listing.err)
+			if previous != nil && p.clients[serverName] == previous {
+				delete(p.clients, serverName)
+			}
+			delete(p.serverTools, serverName)
+			p.failures[serverName] = listing.err.Error()
+			if serverConfig.Required {
+				requiredErrs = append(requiredErrs, fmt.Errorf("required server %s: %w", serverName, listing.err))
+			}
+		case reconnected:
+			p.registerServer(serverName, listing.client, listing.tools)
+			requestid.Printf(ctx, "Server %s provided %d tools", serverName, len(listing.tools))
+		default:
+			p.registerTools(serverName, listing.tools)
+		}
+	}
+	// Servers added while refreshing keep their tools
+	for serverName, tools := range p.serverTools {
+		if _, listed := listings[serverName]; !listed {
+			p.placeTools(serverName, tools)
+		}
+	}
+	p.markSynced()
+	p.mu.Unlock()
+	p.results.clear()
+
+	p.recordDiff(ctx, before)
+	return errors.Join(requiredErrs...)
+}
+
+// relisting is the outcome of re-listing one server during a soft refresh
+type relisting struct {
+	client types.MCPClient // connection the tools came from, a new one if the server was reconnected
+	tools  []types.Tool
+	err    error
+}
+
+// relist lists a server's tools over its existing connection, (re)connecting it when there is
+// none or the listing fails; it does not touch shared state
+func (p *SmartProxy) relist(ctx context.Context, serverName string, serverConfig types.MCPServer, client types.MCPClient, secretProvider types.SecretProvider) relisting {
+	if client != nil {
+		tools, err := client.ListTools(ctx)
+		if err == nil {
+			return relisting{client: client, tools: tools}
+		}
+		requestid.Printf(ctx, "Server %s did not list its tools, reconnecting: %v", serverName, err)
+		client.Close()
+	}
+
+	p.mu.RLock()
+	factory, err := p.clientFactory(serverConfig)
+	p.mu.RUnlock()
+	if err != nil {
+		return relisting{err: err}
+	}
+	client, tools, err := p.connectServer(ctx, factory, serverName, serverConfig, secretProvider)
+	return relisting{client: client, tools: tools, err: err}
 }
 
-// Close shuts down the proxy and all MCP clients
+// recordDiff compares the refreshed catalog with the one before the refresh and keeps the result
+func (p *SmartProxy) recordDiff(ctx context.Context, before map[string]types.Tool) {
+	p.mu.Lock()
+	diff := diffTools(before, p.toolCache.Tools)
+	p.lastDiff = diff
+	p.mu.Unlock()
+
+	if diff.Empty() {
+		requestid.Printf(ctx, "Refresh complete: no tool changes")
+	} else {
+		requestid.Printf(ctx, "Refresh complete: added %v, removed %v, changed %v", diff.Added, diff.Removed, diff.Changed)
+	}
+}
+
+// LastDiff returns the tool changes detected by the most recent refresh, or nil before the first refresh
+func (p *SmartProxy) LastDiff() *types.ToolDiff {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.lastDiff
+}
+
+// Ready reports whether initial discovery has completed and at least one tool is available
+func (p *SmartProxy) Ready() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return !p.toolCache.LastSync.IsZero() && len(p.toolCache.Tools) > 0
+}
+
+// Servers reports connection, tool count and circuit breaker state for every configured server
+func (p *SmartProxy) Servers() []types.ServerStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	toolCounts := p.toolCounts()
+	names := make([]string, 0, len(p.config.MCPServers))
+	for name := range p.config.MCPServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	statuses := make([]types.ServerStatus, 0, len(names))
+	for _, name := range names {
+		statuses = append(statuses, p.serverStatus(name, toolCounts[name]))
+	}
+	return statuses
+}
+
+// Health summarizes the catalog and server connections for monitoring
+func (p *SmartProxy) Health() types.HealthReport {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	toolCounts := p.toolCounts()
+	report := types.HealthReport{
+		Status:   "ok",
+		Tools:    len(p.toolCache.Tools),
+		Servers:  make(map[string]int, len(p.config.MCPServers)),
+		LastSync: p.toolCache.LastSync,
+		Startup:  p.startup,
+	}
+	for name := range p.config.MCPServers {
+		report.Servers[name] = toolCounts[name]
+		if p.connected(name) {
+			report.ConnectedServers++
+		} else {
+			report.Status = "degraded"
+		}
+	}
+	return report
+}
+
+// toolCounts counts the tools each server serves, replicas included; the caller must hold p.mu
+func (p *SmartProxy) toolCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, serverName := range p.toolCache.ServerMap {
+		counts[serverName]++
+	}
+	for _, serverNames := range p.replicas {
+		for _, serverName := range serverNames {
+			counts[serverName]++
+		}
+	}
+	return counts
+}
+
+// connected reports whether a server has a client whose process has not been found dead or
+// unresponsive; the caller must hold p.mu
+func (p *SmartProxy) connected(name string) bool {
+	client, ok := p.clients[name]
+	if !ok {
+		return false
+	}
+	if process, ok := client.(interface{ Exited() bool }); ok && process.Exited() {
+		return false
+	}
+	if process, ok := client.(interface{ Unresponsive() bool }); ok && process.Unresponsive() {
+		return false
+	}
+	return true
+}
+
+// serverStatus describes one server given its tool count; the caller must hold p.mu
+func (p *SmartProxy) serverStatus(name string, tools int) types.ServerStatus {
+	status := types.ServerStatus{Name: name, Tools: tools, Breaker: breakerClosed}
+	status.Connected = p.connected(name)
+	if b, ok := p.breakers[name]; ok {
+		status.Breaker, status.ConsecutiveFailures = b.status()
+	}
+	status.Error = p.failures[name]
+	if state, ok := p.restarts[name]; ok {
+		status.Restarts, status.Failed = state.count, state.gaveUp
+	}
+	status.Collisions = p.collisions(name)
+	return status
+}
+
+// toolServer describes a server for tool listings; the caller must hold p.mu
+func (p *SmartProxy) toolServer(name string) *types.ToolServer {
+	transport := p.config.MCPServers[name].Transport
+	if transport == "" {
+		transport = TransportStdio
+	}
+	status := p.serverStatus(name, 0)
+	return &types.ToolServer{
+		Transport: transport,
+		Connected: status.Connected,
+		Healthy:   status.Connected && status.Breaker != breakerOpen,
+	}
+}
+
+// Close shuts down the proxy: unsaved embedding cache entries are written first, then all MCP
+// clients are closed. It returns the error of saving the cache, if any
 func (p *SmartProxy) Close() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	// Save before stopping the servers, which can take a while
+	var err error
+	if p.embeddings != nil {
+		if err = p.embeddings.Flush(); err != nil {
+			log.Printf("Error flushing embedding cache: %v", err)
+		}
+	}
+
 	for _, client := range p.clients {
 		if err := client.Close(); err != nil {
 			log.Printf("Error closing client: %v", err)
 		}
 	}
 
-	return nil
-}
\ No newline at end of file
+	return err
+}
@@ -0,0 +1,853 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mcp-smart-proxy/internal/llm"
+	"mcp-smart-proxy/pkg/types"
+)
+
+// fakeClient is an in-memory MCP server. Each call runs the tool's handler, if any, and otherwise
+// answers with the tool's name; listing fails while listErr is set and waits for listGate to be
+// closed while that is set
+type fakeClient struct {
+	mu       sync.Mutex
+	tools    []types.Tool
+	handlers map[string]func(ctx context.Context, arguments map[string]interface{}) (map[string]interface{}, error)
+	listErr  error
+	listGate chan struct{}
+	calls    int
+	closed   bool
+}
+
+func newFakeClient(toolNames ...string) *fakeClient {
+	client := &fakeClient{handlers: make(map[string]func(context.Context, map[string]interface{}) (map[string]interface{}, error))}
+	for _, name := range toolNames {
+		client.tools = append(client.tools, fakeTool(name, "string"))
+	}
+	return client
+}
+
+// fakeTool returns a tool taking one argument of the given JSON type
+func fakeTool(name, argumentType string) types.Tool {
+	return types.Tool{
+		Name:        name,
+		Description: "The " + name + " tool",
+		InputSchema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"value": map[string]interface{}{"type": argumentType}},
+		},
+	}
+}
+
+func (c *fakeClient) ListTools(ctx context.Context) ([]types.Tool, error) {
+	c.mu.Lock()
+	gate := c.listGate
+	c.mu.Unlock()
+	if gate != nil {
+		<-gate
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.listErr != nil {
+		return nil, c.listErr
+	}
+	return append([]types.Tool(nil), c.tools...), nil
+}
+
+func (c *fakeClient) CallTool(ctx context.Context, toolName string, arguments map[string]interface{}) (map[string]interface{}, error) {
+	c.mu.Lock()
+	c.calls++
+	handler := c.handlers[toolName]
+	c.mu.Unlock()
+	if handler != nil {
+		return handler(ctx, arguments)
+	}
+	return textResult(toolName), nil
+}
+
+func (c *fakeClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+// update changes the client's state under its lock
+func (c *fakeClient) update(change func(c *fakeClient)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	change(c)
+}
+
+func textResult(text string) map[string]interface{} {
+	return map[string]interface{}{"content": []interface{}{map[string]interface{}{"type": "text", "text": text}}}
+}
+
+// fakeProvider selects every candidate tool in catalog order
+type fakeProvider struct{}
+
+func (fakeProvider) SelectBestTools(ctx context.Context, query string, tools []types.Tool) ([]types.Tool, error) {
+	return tools, nil
+}
+
+func (fakeProvider) GetName() string { return "fake" }
+
+// newTestProxy returns an initialized in-memory proxy over the given clients
+func newTestProxy(t testing.TB, config types.MCPConfig, clients map[string]types.MCPClient) *SmartProxy {
+	t.Helper()
+	p, err := NewInMemory(config, fakeProvider{}, clients)
+	if err != nil {
+		t.Fatalf("NewInMemory() error = %v", err)
+	}
+	if err := p.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+// servingServer returns the server a tool is routed to and its failover replicas
+func servingServer(p *SmartProxy, toolName string) (string, []string) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.toolCache.ServerMap[toolName], p.replicas[toolName]
+}
+
+var errFake = errors.New("fake failure")
+
+// embeddingProvider is a fakeProvider that embeds each text as a vector of its length
+type embeddingProvider struct{ fakeProvider }
+
+func (embeddingProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text)), 1}
+	}
+	return vectors, nil
+}
+
+func TestCloseFlushesEmbeddingCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	path := filepath.Join(dir, "embeddings.json")
+	config := types.MCPConfig{Proxy: types.ProxySettings{Selector: "embeddings", EmbeddingCacheFile: path}}
+	client := newFakeClient("read", "write")
+	p, err := NewInMemory(config, embeddingProvider{}, map[string]types.MCPClient{"files": client})
+	if err != nil {
+		t.Fatalf("NewInMemory() error = %v", err)
+	}
+	if err := p.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	// The cache directory is missing, so saving the embeddings after discovery fails
+	if _, err := p.DiscoverTools(context.Background(), types.ProxyRequest{Query: "read a file"}); err != nil {
+		t.Fatalf("DiscoverTools() error = %v", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Fatal("embedding cache saved into a missing directory")
+	}
+	os.Mkdir(dir, 0o755)
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("embedding cache not saved on Close: %v", err)
+	}
+	if !client.closed {
+		t.Error("Close() left a server client open")
+	}
+}
+
+// namedProvider selects the first candidate tool and records the queries it was asked
+type namedProvider struct {
+	name    string
+	queries *[]string
+}
+
+func (p namedProvider) SelectBestTools(ctx context.Context, query string, tools []types.Tool) ([]types.Tool, error) {
+	*p.queries = append(*p.queries, p.name+":"+query)
+	return tools[:1], nil
+}
+
+func TestDiscoverToolsRoutesToProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string // requested provider; empty uses the default
+		want     string // the provider and query recorded
+		wantErr  error
+	}{
+		{name: "default provider", want: "cheap:find files"},
+		{name: "requested provider", provider: "strong", want: "strong:find files"},
+		{name: "unknown provider", provider: "other", wantErr: types.ErrUnknownProvider},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queries []string
+			p := newTestProxy(t, types.MCPConfig{}, map[string]types.MCPClient{"files": newFakeClient("read")})
+			p.providers = map[string]types.LLMProvider{
+				"cheap":  namedProvider{name: "cheap", queries: &queries},
+				"strong": namedProvider{name: "strong", queries: &queries},
+			}
+			p.defaultLLM = "cheap"
+
+			_, err := p.DiscoverTools(context.Background(), types.ProxyRequest{Query: "find files", Provider: tt.provider})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DiscoverTools() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := strings.Join(queries, ","); got != tt.want {
+				t.Errorf("providers asked %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInitProviders(t *testing.T) {
+	t.Setenv("CHEAP_KEY", "cheap-key")
+	t.Setenv("STRONG_KEY", "strong-key")
+	two := map[string]types.LLMProviderConfig{
+		"cheap":  {Type: "openai", APIKeyEnv: "CHEAP_KEY", Model: "gpt-4o-mini"},
+		"strong": {Type: "openai", APIKeyEnv: "STRONG_KEY", Model: "gpt-4o"},
+	}
+
+	tests := []struct {
+		name        string
+		providers   map[string]types.LLMProviderConfig
+		defaultName string
+		wantDefault string
+		wantErr     string
+	}{
+		{name: "named default", providers: two, defaultName: "strong", wantDefault: "strong"},
+		{name: "single provider is the default", providers: map[string]types.LLMProviderConfig{"only": two["cheap"]}, wantDefault: "only"},
+		{name: "default required with several", providers: two, wantErr: `defaultProvider "" is not one of the configured providers`},
+		{name: "unknown default", providers: two, defaultName: "other", wantErr: `defaultProvider "other"`},
+		{name: "missing key", providers: map[string]types.LLMProviderConfig{"x": {Type: "openai", APIKeyEnv: "UNSET_KEY"}}, wantErr: "UNSET_KEY is not set"},
+		{name: "unknown type", providers: map[string]types.LLMProviderConfig{"x": {Type: "claude"}}, wantErr: `unsupported provider type "claude"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.MCPConfig{Proxy: types.ProxySettings{Providers: tt.providers, DefaultProvider: tt.defaultName}}
+			p := newSmartProxy(config, "", Options{})
+			err := p.initProviders()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("initProviders() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("initProviders() error = %v", err)
+			}
+			if p.defaultLLM != tt.wantDefault || len(p.providers) != len(tt.providers) {
+				t.Errorf("providers = %v with default %q, want %d with default %q", p.providers, p.defaultLLM, len(tt.providers), tt.wantDefault)
+			}
+		})
+	}
+}
+
+func TestUseToolPassesBinaryContentThrough(t *testing.T) {
+	data := base64.StdEncoding.EncodeToString([]byte{0x89, 'P', 'N', 'G', 0x00, 0xff, 0xfe})
+	image := map[string]interface{}{"content": []interface{}{map[string]interface{}{"type": "image", "mimeType": "image/png", "data": data}}}
+	client := newFakeClient("screenshot")
+	client.handlers["screenshot"] = func(ctx context.Context, arguments map[string]interface{}) (map[string]interface{}, error) {
+		return image, nil
+	}
+	p := newTestProxy(t, types.MCPConfig{}, map[string]types.MCPClient{"browser": client})
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	result, err := p.UseTool(context.Background(), "screenshot", types.ToolRequest{})
+	log.SetOutput(os.Stderr)
+
+	if err != nil || !reflect.DeepEqual(result, image) {
+		t.Errorf("UseTool() = %v, %v, want the image block unchanged", result, err)
+	}
+	if !strings.Contains(logs.String(), "image(image/png, 12 bytes base64)") || strings.Contains(logs.String(), data) {
+		t.Errorf("log = %q, want a summary of the image without its data", logs.String())
+	}
+}
+
+func TestMaxToolsSetting(t *testing.T) {
+	zero, ten := 0, 10
+	tests := []struct {
+		name     string
+		maxTools *int
+		want     int
+	}{
+		{name: "default", want: 5},
+		{name: "configured", maxTools: &ten, want: 10},
+		{name: "zero means no limit", maxTools: &zero, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newSmartProxy(types.MCPConfig{Proxy: types.ProxySettings{MaxTools: tt.maxTools}}, "", Options{})
+			settings, err := p.llmSettings()
+			if err != nil {
+				t.Fatalf("llmSettings() error = %v", err)
+			}
+			if settings.MaxTools != tt.want {
+				t.Errorf("selection limit = %d, want %d", settings.MaxTools, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmptyCatalogSkipsTheLLM(t *testing.T) {
+	failing := newFakeClient("read")
+	failing.listErr = errFake
+
+	tests := []struct {
+		name    string
+		clients map[string]types.MCPClient
+		req     types.ProxyRequest
+	}{
+		{name: "no servers", req: types.ProxyRequest{Query: "find files"}},
+		{name: "every server failed", clients: map[string]types.MCPClient{"files": failing}, req: types.ProxyRequest{Query: "find files"}},
+		{name: "no tools with the tag", clients: map[string]types.MCPClient{"files": newFakeClient("read")}, req: types.ProxyRequest{Query: "find files", Tag: "db"}},
+		{name: "every tool excluded", clients: map[string]types.MCPClient{"files": newFakeClient("read")}, req: types.ProxyRequest{Query: "find files", Exclude: []string{"read"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queries []string
+			p := newTestProxy(t, types.MCPConfig{}, tt.clients)
+			p.providers = map[string]types.LLMProvider{"default": namedProvider{name: "default", queries: &queries}}
+
+			if _, err := p.DiscoverTools(context.Background(), tt.req); !errors.Is(err, types.ErrNoToolsAvailable) {
+				t.Errorf("DiscoverTools() error = %v, want ErrNoToolsAvailable", err)
+			}
+			emit := func(types.Tool) error { return nil }
+			if err := p.DiscoverToolsStream(context.Background(), tt.req, emit); !errors.Is(err, types.ErrNoToolsAvailable) {
+				t.Errorf("DiscoverToolsStream() error = %v, want ErrNoToolsAvailable", err)
+			}
+			if tt.req.Exclude == nil {
+				// Batches take no exclusions
+				batch := types.BatchDiscoveryRequest{Queries: []string{tt.req.Query}, Tag: tt.req.Tag}
+				if _, err := p.DiscoverToolsBatch(context.Background(), batch); !errors.Is(err, types.ErrNoToolsAvailable) {
+					t.Errorf("DiscoverToolsBatch() error = %v, want ErrNoToolsAvailable", err)
+				}
+			}
+			if len(queries) != 0 {
+				t.Errorf("provider asked %q, want no LLM call", queries)
+			}
+		})
+	}
+}
+
+func TestPromptTemplateSettings(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "prompt.tmpl")
+	os.WriteFile(file, []byte("From file: {{.Query}}"), 0o644)
+
+	tests := []struct {
+		name       string
+		settings   types.ProxySettings
+		wantPrompt string // rendered for the query "q"; empty expects the default template
+		wantErr    string
+	}{
+		{name: "default template", settings: types.ProxySettings{}},
+		{name: "inline template", settings: types.ProxySettings{PromptTemplate: "Inline: {{.Query}}"}, wantPrompt: "Inline: q"},
+		{name: "template file", settings: types.ProxySettings{PromptTemplateFile: file}, wantPrompt: "From file: q"},
+		{name: "inline template wins", settings: types.ProxySettings{PromptTemplate: "Inline: {{.Query}}", PromptTemplateFile: file}, wantPrompt: "Inline: q"},
+		{name: "missing file", settings: types.ProxySettings{PromptTemplateFile: filepath.Join(dir, "missing.tmpl")}, wantErr: "failed to read prompt template"},
+		{name: "malformed template", settings: types.ProxySettings{PromptTemplate: "{{.Query"}, wantErr: "invalid prompt template"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newSmartProxy(types.MCPConfig{Proxy: tt.settings}, "", Options{})
+			settings, err := p.llmSettings()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("llmSettings() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("llmSettings() error = %v", err)
+			}
+			if tt.wantPrompt == "" {
+				if settings.Prompt != nil {
+					t.Errorf("llmSettings() prompt = %v, want the default", settings.Prompt)
+				}
+				return
+			}
+			var prompt strings.Builder
+			if settings.Prompt == nil || settings.Prompt.Execute(&prompt, llm.PromptData{Query: "q"}) != nil || prompt.String() != tt.wantPrompt {
+				t.Errorf("llmSettings() prompt renders %q, want %q", prompt.String(), tt.wantPrompt)
+			}
+		})
+	}
+}
+
+func TestTagScopedDiscovery(t *testing.T) {
+	config := types.MCPConfig{
+		MCPServers: map[string]types.MCPServer{
+			"git":  {Tags: []string{"scm"}},
+			"aws":  {Tags: []string{"cloud", "infra"}},
+			"k8s":  {Tags: []string{"infra"}},
+			"misc": {},
+		},
+		Proxy: types.ProxySettings{DeduplicateTools: true},
+	}
+	p := newTestProxy(t, config, map[string]types.MCPClient{
+		"git":  newFakeClient("git_log", "git_commit", "search"),
+		"aws":  newFakeClient("s3_list", "search"),
+		"k8s":  newFakeClient("pods_list"),
+		"misc": newFakeClient("weather"),
+	})
+
+	tests := []struct {
+		tag  string
+		want string // sorted and comma-separated
+	}{
+		{tag: "", want: "git_commit,git_log,pods_list,s3_list,search,weather"},
+		{tag: "scm", want: "git_commit,git_log,search"},
+		{tag: "cloud", want: "s3_list,search"},
+		{tag: "infra", want: "pods_list,s3_list,search"},
+	}
+
+	for _, tt := range tests {
+		tools, err := p.DiscoverTools(context.Background(), types.ProxyRequest{Query: "list things", Tag: tt.tag})
+		if err != nil {
+			t.Fatalf("DiscoverTools(tag %q) error = %v", tt.tag, err)
+		}
+		names := make([]string, len(tools))
+		for i, tool := range tools {
+			names[i] = tool.Name
+		}
+		sort.Strings(names)
+		if got := strings.Join(names, ","); got != tt.want {
+			t.Errorf("DiscoverTools(tag %q) = %s, want %s", tt.tag, got, tt.want)
+		}
+	}
+
+	if _, err := p.DiscoverTools(context.Background(), types.ProxyRequest{Query: "list things", Tag: "unknown"}); !errors.Is(err, types.ErrNoToolsAvailable) {
+		t.Errorf("DiscoverTools(tag unknown) error = %v, want ErrNoToolsAvailable", err)
+	}
+}
+
+func TestRequiredServers(t *testing.T) {
+	tests := []struct {
+		name     string
+		required []string
+		failing  []string
+		wantErr  string // empty expects Initialize to succeed
+	}{
+		{name: "failing optional server is skipped", required: []string{"files"}, failing: []string{"notes"}},
+		{name: "failing required server", required: []string{"notes"}, failing: []string{"notes"}, wantErr: "required server notes: failed to list tools: fake failure"},
+		{name: "every required failure is reported", required: []string{"notes", "db"}, failing: []string{"notes", "db"}, wantErr: "required server db: failed to list tools: fake failure\nrequired server notes: failed to list tools: fake failure"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.MCPConfig{MCPServers: make(map[string]types.MCPServer)}
+			clients := make(map[string]types.MCPClient)
+			for _, serverName := range []string{"files", "notes", "db"} {
+				client := newFakeClient(serverName + "_tool")
+				clients[serverName] = client
+				config.MCPServers[serverName] = types.MCPServer{}
+			}
+			for _, serverName := range tt.required {
+				config.MCPServers[serverName] = types.MCPServer{Required: true}
+			}
+			for _, serverName := range tt.failing {
+				clients[serverName].(*fakeClient).listErr = errFake
+			}
+			p, err := NewInMemory(config, fakeProvider{}, clients)
+			if err != nil {
+				t.Fatalf("NewInMemory() error = %v", err)
+			}
+			t.Cleanup(func() { p.Close() })
+
+			err = p.Initialize(context.Background())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Initialize() error = %v", err)
+				}
+			} else if err == nil || !strings.HasSuffix(err.Error(), tt.wantErr) {
+				t.Fatalf("Initialize() error = %v, want %q", err, tt.wantErr)
+			}
+			// The servers that did connect are still usable
+			if _, err := p.UseTool(context.Background(), "files_tool", types.ToolRequest{}); err != nil {
+				t.Errorf("UseTool(files_tool) error = %v", err)
+			}
+		})
+	}
+}
+
+func TestSoftRefreshReportsRequiredServers(t *testing.T) {
+	notes := newFakeClient("note")
+	config := types.MCPConfig{MCPServers: map[string]types.MCPServer{"notes": {Required: true}}}
+	p := newTestProxy(t, config, map[string]types.MCPClient{"notes": notes, "files": newFakeClient("read")})
+
+	notes.update(func(c *fakeClient) { c.listErr = errFake })
+	if err := p.SoftRefreshTools(context.Background()); err == nil || !strings.Contains(err.Error(), "required server notes") {
+		t.Errorf("SoftRefreshTools() error = %v, want the required server's failure", err)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	files, notes, broken := newFakeClient("read", "search"), newFakeClient("search", "note"), newFakeClient("x")
+	broken.listErr = errFake
+	config := types.MCPConfig{Proxy: types.ProxySettings{DeduplicateTools: true}}
+	p := newTestProxy(t, config, map[string]types.MCPClient{"files": files, "notes": notes, "broken": broken})
+
+	report := p.Health()
+	if report.Status != "degraded" || report.Tools != 3 || report.ConnectedServers != 2 {
+		t.Errorf("Health() = %+v, want degraded with 3 tools on 2 of 3 servers", report)
+	}
+	// The shared search tool counts for both its server and its replica
+	if want := map[string]int{"files": 2, "notes": 2, "broken": 0}; !reflect.DeepEqual(report.Servers, want) {
+		t.Errorf("Health() servers = %v, want %v", report.Servers, want)
+	}
+	firstSync := report.LastSync
+	if firstSync.IsZero() {
+		t.Fatal("Health() has no last sync after Initialize")
+	}
+
+	// A server that drops all its tools shows up with a count of zero
+	notes.update(func(c *fakeClient) { c.tools = nil })
+	if err := p.SoftRefreshTools(context.Background()); err != nil {
+		t.Fatalf("SoftRefreshTools() error = %v", err)
+	}
+	report = p.Health()
+	if report.Tools != 2 || report.Servers["notes"] != 0 || report.Servers["files"] != 2 {
+		t.Errorf("Health() after notes dropped its tools = %+v, want 2 tools and none on notes", report)
+	}
+	if !report.LastSync.After(firstSync) {
+		t.Errorf("Health() last sync = %s after a refresh, want later than %s", report.LastSync, firstSync)
+	}
+}
+
+// exitedClient is a fakeClient whose server process was found dead
+type exitedClient struct{ *fakeClient }
+
+func (exitedClient) Exited() bool { return true }
+
+func TestHealthOfAnExitedServer(t *testing.T) {
+	p := newTestProxy(t, types.MCPConfig{}, map[string]types.MCPClient{"files": newFakeClient("read"), "db": exitedClient{newFakeClient("query")}})
+
+	report := p.Health()
+	if report.Status != "degraded" || report.ConnectedServers != 1 {
+		t.Errorf("Health() = %+v, want degraded with 1 of 2 servers connected", report)
+	}
+	for _, status := range p.Servers() {
+		if status.Connected != (status.Name == "files") {
+			t.Errorf("server %s connected = %v, want only files connected", status.Name, status.Connected)
+		}
+	}
+}
+
+// countingProvider is a rankingProvider that counts the selections it is asked for
+type countingProvider struct {
+	rankingProvider
+	asked *int
+}
+
+func (p countingProvider) SelectBestTools(ctx context.Context, query string, tools []types.Tool) ([]types.Tool, error) {
+	*p.asked++
+	return p.rankingProvider.SelectBestTools(ctx, query, tools)
+}
+
+func TestSelectorSetting(t *testing.T) {
+	tests := []struct {
+		selector string
+		wantLLM  bool // the provider is asked to select
+	}{
+		{selector: "", wantLLM: true},
+		{selector: "llm", wantLLM: true},
+		{selector: "keyword"},
+		{selector: "hybrid", wantLLM: true},
+	}
+
+	for _, tt := range tests {
+		t.Run("selector "+tt.selector, func(t *testing.T) {
+			asked := 0
+			config := types.MCPConfig{Proxy: types.ProxySettings{Selector: tt.selector}}
+			p := newTestProxy(t, config, map[string]types.MCPClient{"files": newFakeClient("read_file", "send_email")})
+			p.providers = map[string]types.LLMProvider{"default": countingProvider{rankingProvider{"read_file"}, &asked}}
+
+			tools, err := p.DiscoverTools(context.Background(), types.ProxyRequest{Query: "read a file"})
+			if err != nil || len(tools) == 0 || tools[0].Name != "read_file" {
+				t.Fatalf("DiscoverTools() = %v, %v, want read_file first", tools, err)
+			}
+			if (asked > 0) != tt.wantLLM {
+				t.Errorf("provider asked %d times, want LLM selection = %v", asked, tt.wantLLM)
+			}
+		})
+	}
+}
+
+// slowProvider selects nothing until its context ends
+type slowProvider struct{}
+
+func (slowProvider) SelectBestTools(ctx context.Context, query string, tools []types.Tool) ([]types.Tool, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestLLMTimeout(t *testing.T) {
+	tests := []struct {
+		name          string
+		llmTimeout    time.Duration
+		callerTimeout time.Duration
+		wantErr       error
+	}{
+		{name: "LLM timeout", llmTimeout: 50 * time.Millisecond, callerTimeout: time.Minute, wantErr: types.ErrLLMTimeout},
+		{name: "caller gives up first", llmTimeout: time.Minute, callerTimeout: 50 * time.Millisecond, wantErr: context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.MCPConfig{Proxy: types.ProxySettings{LLMTimeout: types.Duration(tt.llmTimeout)}}
+			p, err := NewInMemory(config, slowProvider{}, map[string]types.MCPClient{"files": newFakeClient("read")})
+			if err != nil {
+				t.Fatalf("NewInMemory() error = %v", err)
+			}
+			if err := p.Initialize(context.Background()); err != nil {
+				t.Fatalf("Initialize() error = %v", err)
+			}
+			t.Cleanup(func() { p.Close() })
+
+			discover := map[string]func(ctx context.Context) error{
+				"DiscoverTools": func(ctx context.Context) error {
+					_, err := p.DiscoverTools(ctx, types.ProxyRequest{Query: "read"})
+					return err
+				},
+				"DiscoverToolsStream": func(ctx context.Context) error {
+					return p.DiscoverToolsStream(ctx, types.ProxyRequest{Query: "read"}, func(types.Tool) error { return nil })
+				},
+			}
+			for name, run := range discover {
+				ctx, cancel := context.WithTimeout(context.Background(), tt.callerTimeout)
+				start := time.Now()
+				err := run(ctx)
+				cancel()
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("%s() error = %v, want %v", name, err, tt.wantErr)
+				}
+				if tt.wantErr != types.ErrLLMTimeout && errors.Is(err, types.ErrLLMTimeout) {
+					t.Errorf("%s() error = %v, want it not blamed on the LLM timeout", name, err)
+				}
+				if elapsed := time.Since(start); elapsed > time.Second {
+					t.Errorf("%s() returned after %s, want it to stop at the timeout", name, elapsed)
+				}
+			}
+		})
+	}
+}
+
+// queryProvider selects the tools named by words of the query, in query order, and fails queries
+// containing "fail"; it records the most selections it saw running at once
+type queryProvider struct {
+	mu                  sync.Mutex
+	running, maxRunning int
+}
+
+func (p *queryProvider) SelectBestTools(ctx context.Context, query string, tools []types.Tool) ([]types.Tool, error) {
+	p.mu.Lock()
+	p.running++
+	if p.running > p.maxRunning {
+		p.maxRunning = p.running
+	}
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.running--
+		p.mu.Unlock()
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	words := strings.Fields(query)
+	var selected []types.Tool
+	for _, word := range words {
+		if word == "fail" {
+			return nil, errFake
+		}
+		for _, tool := range tools {
+			if tool.Name == word {
+				selected = append(selected, tool)
+			}
+		}
+	}
+	return selected, nil
+}
+
+func TestDiscoverToolsBatch(t *testing.T) {
+	provider := &queryProvider{}
+	p, err := NewInMemory(types.MCPConfig{}, provider, map[string]types.MCPClient{"files": newFakeClient("read", "write", "delete")})
+	if err != nil {
+		t.Fatalf("NewInMemory() error = %v", err)
+	}
+	if err := p.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+
+	queries := []string{"read", "write then delete", "fail"}
+	for i := 0; i < 2*BatchConcurrency; i++ {
+		queries = append(queries, "read")
+	}
+	results, err := p.DiscoverToolsBatch(context.Background(), types.BatchDiscoveryRequest{Queries: queries})
+	if err != nil {
+		t.Fatalf("DiscoverToolsBatch() error = %v", err)
+	}
+	if len(results) != len(queries) {
+		t.Fatalf("DiscoverToolsBatch() returned %d results, want %d", len(results), len(queries))
+	}
+
+	want := []struct {
+		tools   string
+		wantErr bool
+	}{{tools: "read"}, {tools: "write,delete"}, {wantErr: true}}
+	for i, w := range want {
+		result := results[i]
+		if result.Query != queries[i] {
+			t.Errorf("result %d query = %q, want %q", i, result.Query, queries[i])
+		}
+		if got := joinToolNames(result.RecommendedTools); got != w.tools {
+			t.Errorf("result %d tools = %s, want %s", i, got, w.tools)
+		}
+		if (result.Error != "") != w.wantErr {
+			t.Errorf("result %d error = %q, want error = %v", i, result.Error, w.wantErr)
+		}
+	}
+	if provider.maxRunning > BatchConcurrency {
+		t.Errorf("ran %d selections at once, want at most %d", provider.maxRunning, BatchConcurrency)
+	}
+
+	if _, err := p.DiscoverToolsBatch(context.Background(), types.BatchDiscoveryRequest{Queries: queries, Provider: "nope"}); !errors.Is(err, types.ErrUnknownProvider) {
+		t.Errorf("DiscoverToolsBatch() with an unknown provider error = %v, want %v", err, types.ErrUnknownProvider)
+	}
+}
+
+func TestSelectionHints(t *testing.T) {
+	var mu sync.Mutex
+	var prompts []string
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		for _, message := range req.Messages {
+			prompts = append(prompts, message.Content)
+		}
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"choices": [{"message": {"role": "assistant", "content": "[\"s3_list\"]"}, "finish_reason": "stop"}]}`)
+	}))
+	t.Cleanup(endpoint.Close)
+	t.Setenv("OPENAI_BASE_URL", endpoint.URL)
+
+	config := types.MCPConfig{MCPServers: map[string]types.MCPServer{
+		"aws":   {SelectionHint: "use only for AWS resources"},
+		"files": {},
+	}}
+	clients := map[string]types.MCPClient{"aws": newFakeClient("s3_list"), "files": newFakeClient("read")}
+	p, err := NewInMemory(config, llm.NewOpenAIProvider("test-key"), clients)
+	if err != nil {
+		t.Fatalf("NewInMemory() error = %v", err)
+	}
+	if err := p.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+
+	for _, req := range []types.ProxyRequest{{Query: "list my buckets"}, {Query: "read a file", Exclude: []string{"s3_list"}}} {
+		mu.Lock()
+		prompts = nil
+		mu.Unlock()
+		if _, err := p.DiscoverTools(context.Background(), req); err != nil {
+			t.Fatalf("DiscoverTools(%q) error = %v", req.Query, err)
+		}
+
+		mu.Lock()
+		prompt := strings.Join(prompts, "\n")
+		mu.Unlock()
+		wantHint := req.Exclude == nil
+		if got := strings.Contains(prompt, "- aws: use only for AWS resources"); got != wantHint {
+			t.Errorf("prompt for %q = %q, want the aws hint = %v", req.Query, prompt, wantHint)
+		}
+		if strings.Contains(prompt, "- files:") {
+			t.Errorf("prompt for %q = %q, want no notes for files, which has no hint", req.Query, prompt)
+		}
+	}
+}
+
+func TestExcludedTools(t *testing.T) {
+	tests := []struct {
+		name    string
+		pinned  []string
+		exclude []string
+		want    string
+	}{
+		{name: "nothing excluded", want: "write,read,delete"},
+		{name: "failed tool excluded", exclude: []string{"write"}, want: "read,delete"},
+		{name: "several excluded", exclude: []string{"write", "delete"}, want: "read"},
+		{name: "pinned tool excluded", pinned: []string{"delete"}, exclude: []string{"delete"}, want: "write,read"},
+		{name: "unknown names are ignored", exclude: []string{"format_disk"}, want: "write,read,delete"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.MCPConfig{Proxy: types.ProxySettings{PinnedTools: tt.pinned}}
+			p, err := NewInMemory(config, rankingProvider{"write", "read", "delete"}, map[string]types.MCPClient{"files": newFakeClient("read", "write", "delete")})
+			if err != nil {
+				t.Fatalf("NewInMemory() error = %v", err)
+			}
+			if err := p.Initialize(context.Background()); err != nil {
+				t.Fatalf("Initialize() error = %v", err)
+			}
+			t.Cleanup(func() { p.Close() })
+			req := types.ProxyRequest{Query: "edit a file", Exclude: tt.exclude}
+
+			tools, err := p.DiscoverTools(context.Background(), req)
+			if err != nil {
+				t.Fatalf("DiscoverTools() error = %v", err)
+			}
+			var streamed []types.Tool
+			err = p.DiscoverToolsStream(context.Background(), req, func(tool types.Tool) error {
+				streamed = append(streamed, tool)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("DiscoverToolsStream() error = %v", err)
+			}
+
+			for _, got := range []string{joinToolNames(tools), joinToolNames(streamed)} {
+				names := strings.Split(got, ",")
+				sort.Strings(names)
+				want := strings.Split(tt.want, ",")
+				sort.Strings(want)
+				if !reflect.DeepEqual(names, want) {
+					t.Errorf("recommended %s, want %s", got, tt.want)
+				}
+			}
+		})
+	}
+}
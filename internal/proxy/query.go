@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// QueryArguments builds the arguments of a GET call to a read-only tool from query parameters,
+// converting each value to the type its schema property declares. Repeated parameters form an
+// array for array properties, and object or array properties also accept a JSON value; otherwise
+// the first value is used. Tools without readOnlyHint return ErrNotReadOnly
+func (p *SmartProxy) QueryArguments(ctx context.Context, toolName string, query map[string][]string) (map[string]interface{}, error) {
+	tool, err := p.GetTool(ctx, toolName)
+	if err != nil {
+		return nil, err
+	}
+	if !tool.IsReadOnly() {
+		return nil, fmt.Errorf("%w: %s", types.ErrNotReadOnly, tool.Name)
+	}
+
+	schema, _ := tool.InputSchema.(map[string]interface{})
+	properties, _ := schema["properties"].(map[string]interface{})
+	var arguments map[string]interface{}
+	for name, values := range query {
+		if len(values) == 0 {
+			continue
+		}
+		if arguments == nil {
+			arguments = make(map[string]interface{}, len(query))
+		}
+		property, _ := properties[name].(map[string]interface{})
+		arguments[name] = queryValue(property, values)
+	}
+	return arguments, nil
+}
+
+// queryValue converts the values of one query parameter according to its property schema
+func queryValue(property map[string]interface{}, values []string) interface{} {
+	allowed, _ := schemaTypes(property["type"])
+	for _, t := range allowed {
+		if t != "object" && t != "array" {
+			continue
+		}
+		var decoded interface{}
+		if len(values) == 1 && json.Unmarshal([]byte(values[0]), &decoded) == nil && jsonType(decoded) == t {
+			return decoded
+		}
+		if t == "array" {
+			items, _ := property["items"].(map[string]interface{})
+			list := make([]interface{}, len(values))
+			for i, value := range values {
+				list[i] = queryScalar(items, value)
+			}
+			return list
+		}
+	}
+	return queryScalar(property, values[0])
+}
+
+// queryScalar converts a single query value to the first scalar type of the schema it fits,
+// leaving it a string otherwise
+func queryScalar(schema map[string]interface{}, value string) interface{} {
+	allowed, _ := schemaTypes(schema["type"])
+	if matchesAnyType(value, allowed) {
+		return value
+	}
+	for _, t := range allowed {
+		if coerced, ok := coerceValue(value, t); ok {
+			return coerced
+		}
+	}
+	return value
+}
@@ -0,0 +1,73 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestQueryArguments(t *testing.T) {
+	// lookup is read-only with arguments of every type a query parameter can convert to
+	readOnly := true
+	lookupTool := types.Tool{
+		Name:        "lookup",
+		Annotations: &types.ToolAnnotations{ReadOnlyHint: &readOnly},
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"name":    map[string]interface{}{"type": "string"},
+				"limit":   map[string]interface{}{"type": "integer"},
+				"ratio":   map[string]interface{}{"type": []interface{}{"null", "number"}},
+				"exact":   map[string]interface{}{"type": "boolean"},
+				"ids":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}},
+				"filter":  map[string]interface{}{"type": "object"},
+				"comment": map[string]interface{}{"type": "string"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		tool    string
+		query   map[string][]string
+		want    map[string]interface{}
+		wantErr error
+	}{
+		{name: "no parameters", tool: "lookup"},
+		{
+			name:  "scalars follow the schema",
+			tool:  "lookup",
+			query: map[string][]string{"name": {"42"}, "limit": {"10"}, "ratio": {"0.5"}, "exact": {"true"}},
+			want:  map[string]interface{}{"name": "42", "limit": float64(10), "ratio": 0.5, "exact": true},
+		},
+		{name: "repeated parameter forms an array", tool: "lookup", query: map[string][]string{"ids": {"1", "2"}}, want: map[string]interface{}{"ids": []interface{}{float64(1), float64(2)}}},
+		{name: "single array item", tool: "lookup", query: map[string][]string{"ids": {"7"}}, want: map[string]interface{}{"ids": []interface{}{float64(7)}}},
+		{name: "array as JSON", tool: "lookup", query: map[string][]string{"ids": {"[1,2]"}}, want: map[string]interface{}{"ids": []interface{}{float64(1), float64(2)}}},
+		{name: "object as JSON", tool: "lookup", query: map[string][]string{"filter": {`{"owner":"me"}`}}, want: map[string]interface{}{"filter": map[string]interface{}{"owner": "me"}}},
+		{name: "first value of a scalar", tool: "lookup", query: map[string][]string{"comment": {"a", "b"}}, want: map[string]interface{}{"comment": "a"}},
+		{name: "value that does not fit stays a string", tool: "lookup", query: map[string][]string{"limit": {"many"}}, want: map[string]interface{}{"limit": "many"}},
+		{name: "undeclared parameter stays a string", tool: "lookup", query: map[string][]string{"extra": {"1"}}, want: map[string]interface{}{"extra": "1"}},
+		{name: "empty parameter is skipped", tool: "lookup", query: map[string][]string{"name": {}}},
+		{name: "tool that is not read-only", tool: "write", query: map[string][]string{"value": {"x"}}, wantErr: types.ErrNotReadOnly},
+		{name: "unknown tool", tool: "missing", wantErr: types.ErrToolNotFound},
+	}
+
+	client := newFakeClient("write")
+	client.tools = append(client.tools, lookupTool)
+	p := newTestProxy(t, types.MCPConfig{}, map[string]types.MCPClient{"db": client})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.QueryArguments(context.Background(), tt.tool, tt.query)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Fatalf("QueryArguments() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("QueryArguments() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+const (
+	// reconnectTimeout bounds the handshake and tool listing when replacing an unresponsive client
+	reconnectTimeout = 30 * time.Second
+	// restartBackoff is the delay before the second restart attempt, doubled for each further one
+	restartBackoff = time.Second
+	// maxRestartBackoff caps the delay between restart attempts
+	maxRestartBackoff = time.Minute
+	// restartResetAfter is how long a restarted server must go without another restart before its
+	// restart count starts over
+	restartResetAfter = 5 * time.Minute
+)
+
+// DefaultMaxRestarts is how many times in a row an unresponsive server is restarted when its
+// maxRestarts is unset
+const DefaultMaxRestarts = 5
+
+// restartState counts a server's recent restarts so that a crash loop backs off and gives up
+type restartState struct {
+	count  int       // restart attempts since the server last stayed up for restartResetAfter
+	last   time.Time // when the last attempt started
+	gaveUp bool      // maxRestarts was reached; only a refresh or re-adding the server retries
+}
+
+// reconnect replaces the client of a server that stopped responding after a cancelled call. The
+// stuck client is closed and removed first, so calls fail over to replicas meanwhile; the tool
+// cache is left alone and is brought up to date by the next refresh. Failed attempts are retried
+// with exponential backoff until the server's maxRestarts is used up, after which the server is
+// reported as failed. Nothing happens when the client was already replaced or the server removed.
+func (p *SmartProxy) reconnect(serverName string, stale types.MCPClient) {
+	p.mu.Lock()
+	if _, configured := p.config.MCPServers[serverName]; !configured || p.clients[serverName] != stale {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.clients, serverName)
+	state, ok := p.restarts[serverName]
+	if !ok || time.Since(state.last) > restartResetAfter {
+		state = &restartState{}
+		p.restarts[serverName] = state
+	}
+	p.mu.Unlock()
+	stale.Close()
+
+	for {
+		p.mu.Lock()
+		if p.restarts[serverName] != state {
+			// Removed or refreshed meanwhile
+			p.mu.Unlock()
+			return
+		}
+		attempt := state.count
+		if attempt >= maxRestarts(p.config.MCPServers[serverName]) {
+			state.gaveUp = true
+			reason := fmt.Sprintf("gave up after %d restarts", attempt)
+			if last := p.failures[serverName]; last != "" {
+				reason += ": " + last
+			}
+			p.failures[serverName] = reason
+			p.mu.Unlock()
+			log.Printf("Giving up on server %s after %d restarts", serverName, attempt)
+			return
+		}
+		state.count++
+		p.mu.Unlock()
+
+		if delay := restartDelay(attempt); delay > 0 {
+			log.Printf("Restarting server %s in %s (attempt %d)", serverName, delay, attempt+1)
+			time.Sleep(delay)
+		}
+		done, err := p.restart(serverName, state)
+		if err != nil {
+			log.Printf("Failed to reconnect unresponsive server %s: %v", serverName, err)
+		}
+		if done {
+			return
+		}
+	}
+}
+
+// restart makes one attempt to reconnect a server. It reports done when the server is connected
+// again or no longer needs reconnecting: it was removed, refreshed or restarted elsewhere
+func (p *SmartProxy) restart(serverName string, state *restartState) (bool, error) {
+	p.mu.Lock()
+	serverConfig, configured := p.config.MCPServers[serverName]
+	if !configured || p.restarts[serverName] != state || p.clients[serverName] != nil {
+		p.mu.Unlock()
+		return true, nil
+	}
+	state.last = time.Now()
+	secretProvider := p.secrets
+	factory, err := p.clientFactory(serverConfig)
+	p.mu.Unlock()
+	if err != nil {
+		return true, err
+	}
+
+	log.Printf("Reconnecting unresponsive server %s", serverName)
+	ctx, cancel := context.WithTimeout(context.Background(), reconnectTimeout)
+	defer cancel()
+	client, _, err := p.connectServer(ctx, factory, serverName, serverConfig, secretProvider)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, configured = p.config.MCPServers[serverName]
+	if !configured || p.restarts[serverName] != state || p.clients[serverName] != nil {
+		// Removed or restarted while reconnecting
+		if err == nil {
+			client.Close()
+		}
+		return true, nil
+	}
+	if err != nil {
+		p.failures[serverName] = err.Error()
+		return false, err
+	}
+	p.clients[serverName] = client
+	delete(p.failures, serverName)
+	return true, nil
+}
+
+// restartDelay is the wait before a restart attempt: none for the first, then restartBackoff
+// doubling up to maxRestartBackoff
+func restartDelay(attempt int) time.Duration {
+	if attempt == 0 {
+		return 0
+	}
+	delay := restartBackoff
+	for i := 1; i < attempt && delay < maxRestartBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRestartBackoff {
+		delay = maxRestartBackoff
+	}
+	return delay
+}
+
+// maxRestarts returns how many restarts in a row a server gets before the proxy gives up on it
+func maxRestarts(server types.MCPServer) int {
+	if server.MaxRestarts != nil {
+		return *server.MaxRestarts
+	}
+	return DefaultMaxRestarts
+}
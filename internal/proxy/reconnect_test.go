@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestRestartDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 0, want: 0},
+		{attempt: 1, want: restartBackoff},
+		{attempt: 2, want: 2 * restartBackoff},
+		{attempt: 3, want: 4 * restartBackoff},
+		{attempt: 7, want: maxRestartBackoff},
+		{attempt: 100, want: maxRestartBackoff},
+	}
+	for _, tt := range tests {
+		if got := restartDelay(tt.attempt); got != tt.want {
+			t.Errorf("restartDelay(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxRestarts   int
+		listErr       error // makes the reconnect handshake fail
+		replaced      bool  // the client was already replaced before reconnect ran
+		wantConnected bool
+		wantFailure   string
+		wantRestarts  int
+	}{
+		{name: "restarts the server", maxRestarts: 1, wantConnected: true, wantRestarts: 1},
+		{name: "gives up when restarts are used up", maxRestarts: 1, listErr: errFake, wantFailure: "gave up after 1 restarts: failed", wantRestarts: 1},
+		{name: "no restarts allowed", maxRestarts: 0, wantFailure: "gave up after 0 restarts"},
+		{name: "client already replaced", maxRestarts: 1, replaced: true, wantConnected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeClient("read")
+			maxRestarts := tt.maxRestarts
+			config := types.MCPConfig{MCPServers: map[string]types.MCPServer{"db": {MaxRestarts: &maxRestarts}}}
+			p := newTestProxy(t, config, map[string]types.MCPClient{"db": client})
+			client.update(func(c *fakeClient) { c.listErr = tt.listErr })
+
+			var stale types.MCPClient = client
+			if tt.replaced {
+				stale = newFakeClient("read")
+			}
+			p.reconnect("db", stale)
+
+			p.mu.RLock()
+			_, connected := p.clients["db"]
+			failure := p.failures["db"]
+			restarts := 0
+			if state := p.restarts["db"]; state != nil {
+				restarts = state.count
+			}
+			p.mu.RUnlock()
+
+			if connected != tt.wantConnected {
+				t.Errorf("connected after reconnect = %v, want %v", connected, tt.wantConnected)
+			}
+			if !strings.HasPrefix(failure, tt.wantFailure) || (tt.wantFailure == "") != (failure == "") {
+				t.Errorf("failure after reconnect = %q, want %q", failure, tt.wantFailure)
+			}
+			if restarts != tt.wantRestarts {
+				t.Errorf("restart attempts = %d, want %d", restarts, tt.wantRestarts)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestSoftRefreshTools(t *testing.T) {
+	tests := []struct {
+		name        string
+		required    bool
+		failing     bool // the other server stops answering tools/list
+		wantErr     bool
+		wantAdded   string
+		wantRemoved string
+	}{
+		{name: "tools changed", wantAdded: "write"},
+		{name: "optional server fails", failing: true, wantAdded: "write", wantRemoved: "other"},
+		{name: "required server fails", required: true, failing: true, wantErr: true, wantAdded: "write", wantRemoved: "other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, other := newFakeClient("read"), newFakeClient("other")
+			config := types.MCPConfig{MCPServers: map[string]types.MCPServer{"other": {Required: tt.required}}}
+			p := newTestProxy(t, config, map[string]types.MCPClient{"files": files, "other": other})
+
+			files.update(func(c *fakeClient) { c.tools = append(c.tools, fakeTool("write", "string")) })
+			if tt.failing {
+				other.update(func(c *fakeClient) { c.listErr = errFake })
+			}
+
+			err := p.SoftRefreshTools(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SoftRefreshTools() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			diff := p.LastDiff()
+			if diff == nil {
+				t.Fatal("LastDiff() = nil, want the refresh's changes")
+			}
+			if strings.Join(diff.Added, ",") != tt.wantAdded || strings.Join(diff.Removed, ",") != tt.wantRemoved {
+				t.Errorf("LastDiff() added %v and removed %v, want %s and %s", diff.Added, diff.Removed, tt.wantAdded, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func TestSoftRefreshToolsDoesNotBlockCalls(t *testing.T) {
+	slow := newFakeClient("read")
+	p := newTestProxy(t, types.MCPConfig{}, map[string]types.MCPClient{"slow": slow})
+
+	gate := make(chan struct{})
+	slow.update(func(c *fakeClient) { c.listGate = gate })
+	refreshed := make(chan error, 1)
+	go func() { refreshed <- p.SoftRefreshTools(context.Background()) }()
+
+	// While the server is still listing, the catalog and calls stay available
+	called := make(chan error, 1)
+	go func() {
+		if _, err := p.GetTool(context.Background(), "read"); err != nil {
+			called <- err
+			return
+		}
+		_, err := p.UseTool(context.Background(), "read", types.ToolRequest{})
+		called <- err
+	}()
+	select {
+	case err := <-called:
+		if err != nil {
+			t.Errorf("UseTool() during refresh error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("UseTool() blocked while a server was being re-listed")
+	}
+
+	close(gate)
+	if err := <-refreshed; err != nil {
+		t.Errorf("SoftRefreshTools() error = %v", err)
+	}
+}
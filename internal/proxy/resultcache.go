@@ -0,0 +1,79 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// resultCache memoises results of read-only tool calls for a fixed TTL
+type resultCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cachedResult
+}
+
+type cachedResult struct {
+	result  map[string]interface{}
+	expires time.Time
+}
+
+// newResultCache creates a result cache; a zero TTL disables caching
+func newResultCache(ttl time.Duration) *resultCache {
+	return &resultCache{ttl: ttl, entries: make(map[string]cachedResult)}
+}
+
+// enabled reports whether results should be cached at all
+func (c *resultCache) enabled() bool {
+	return c.ttl > 0
+}
+
+// key derives the cache key from the tool name and a hash of its arguments;
+// json.Marshal sorts map keys so equal argument maps hash identically
+func (c *resultCache) key(toolName string, arguments map[string]interface{}) (string, bool) {
+	data, err := json.Marshal(arguments)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return toolName + ":" + hex.EncodeToString(sum[:]), true
+}
+
+// get returns a cached, unexpired result
+func (c *resultCache) get(key string) (map[string]interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.result, true
+}
+
+// set stores a result and drops any expired entries
+func (c *resultCache) set(key string, result map[string]interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cachedResult{result: result, expires: now.Add(c.ttl)}
+}
+
+// clear drops every cached result
+func (c *resultCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]cachedResult)
+}
@@ -0,0 +1,70 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestResultCache(t *testing.T) {
+	first := map[string]interface{}{"value": "a"}
+
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		readOnly  bool
+		second    types.ToolRequest
+		between   func(p *SmartProxy) // runs between the two calls
+		failFirst bool
+		wantCalls int
+	}{
+		{name: "repeated call is served from the cache", ttl: time.Minute, readOnly: true, second: types.ToolRequest{Arguments: first}, wantCalls: 1},
+		{name: "other arguments miss", ttl: time.Minute, readOnly: true, second: types.ToolRequest{Arguments: map[string]interface{}{"value": "b"}}, wantCalls: 2},
+		{name: "noCache bypasses the cache", ttl: time.Minute, readOnly: true, second: types.ToolRequest{Arguments: first, NoCache: true}, wantCalls: 2},
+		{name: "tools not marked read-only are not cached", ttl: time.Minute, second: types.ToolRequest{Arguments: first}, wantCalls: 2},
+		{name: "no TTL disables the cache", readOnly: true, second: types.ToolRequest{Arguments: first}, wantCalls: 2},
+		{
+			name: "entries expire", ttl: 20 * time.Millisecond, readOnly: true, second: types.ToolRequest{Arguments: first},
+			between: func(*SmartProxy) { time.Sleep(40 * time.Millisecond) }, wantCalls: 2,
+		},
+		{
+			name: "refresh clears the cache", ttl: time.Minute, readOnly: true, second: types.ToolRequest{Arguments: first},
+			between: func(p *SmartProxy) { p.SoftRefreshTools(context.Background()) }, wantCalls: 2,
+		},
+		{name: "failures are not cached", ttl: time.Minute, readOnly: true, second: types.ToolRequest{Arguments: first}, failFirst: true, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeClient("lookup")
+			client.tools[0].Annotations = &types.ToolAnnotations{ReadOnlyHint: &tt.readOnly}
+			failures := 0
+			if tt.failFirst {
+				failures = 1
+			}
+			client.handlers["lookup"] = func(ctx context.Context, arguments map[string]interface{}) (map[string]interface{}, error) {
+				if failures > 0 {
+					failures--
+					return nil, errFake
+				}
+				return textResult("found " + arguments["value"].(string)), nil
+			}
+			config := types.MCPConfig{Proxy: types.ProxySettings{ResultCacheTTL: types.Duration(tt.ttl)}}
+			p := newTestProxy(t, config, map[string]types.MCPClient{"db": client})
+
+			p.UseTool(context.Background(), "lookup", types.ToolRequest{Arguments: first})
+			if tt.between != nil {
+				tt.between(p)
+			}
+			result, err := p.UseTool(context.Background(), "lookup", tt.second)
+			if err != nil || resultText(result) != "found "+tt.second.Arguments["value"].(string) {
+				t.Errorf("second UseTool() = %v, %v, want the answer for its arguments", result, err)
+			}
+			if client.calls != tt.wantCalls {
+				t.Errorf("backend received %d calls, want %d", client.calls, tt.wantCalls)
+			}
+		})
+	}
+}
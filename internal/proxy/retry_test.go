@@ -0,0 +1,217 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"mcp-smart-proxy/internal/mcp"
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: mcp.ErrTimeout, want: true},
+		{err: fmt.Errorf("call failed: %w", mcp.ErrTimeout), want: true},
+		{err: &net.OpError{Op: "read", Err: syscall.ECONNRESET}, want: true},
+		{err: syscall.EAGAIN, want: true},
+		{err: &net.DNSError{Err: "timeout", IsTimeout: true}, want: true},
+		{err: mcp.ErrToolError, want: false},
+		{err: &types.MissingArgumentsError{Tool: "write"}, want: false},
+		{err: mcp.ErrClosed, want: false},
+		{err: context.Canceled, want: false},
+		{err: errFake, want: false},
+	}
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("isTransient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		retries   int
+		failures  []error // errors returned by the first calls, in order
+		wantErr   error
+		wantCalls int
+	}{
+		{name: "transient failure is retried", retries: 1, failures: []error{mcp.ErrTimeout}, wantCalls: 2},
+		{name: "connection reset is retried", retries: 1, failures: []error{syscall.ECONNRESET}, wantCalls: 2},
+		{name: "retries run out", retries: 2, failures: []error{mcp.ErrTimeout, mcp.ErrTimeout, mcp.ErrTimeout}, wantErr: types.ErrToolTimeout, wantCalls: 3},
+		{name: "no retries configured", failures: []error{mcp.ErrTimeout}, wantErr: types.ErrToolTimeout, wantCalls: 1},
+		{name: "tool errors are not retried", retries: 3, failures: []error{mcp.ErrToolError}, wantErr: types.ErrToolFailed, wantCalls: 1},
+		{name: "other errors are not retried", retries: 3, failures: []error{errFake}, wantErr: errFake, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeClient("search")
+			failures := tt.failures
+			client.handlers["search"] = func(ctx context.Context, arguments map[string]interface{}) (map[string]interface{}, error) {
+				if len(failures) > 0 {
+					err := failures[0]
+					failures = failures[1:]
+					return nil, err
+				}
+				return textResult("found"), nil
+			}
+			config := types.MCPConfig{Proxy: types.ProxySettings{Retries: tt.retries, RetryBackoff: types.Duration(time.Millisecond)}}
+			p := newTestProxy(t, config, map[string]types.MCPClient{"db": client})
+
+			result, err := p.UseTool(context.Background(), "search", types.ToolRequest{})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("UseTool() error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil || resultText(result) != "found" {
+				t.Errorf("UseTool() = %v, %v, want the retried result", result, err)
+			}
+			if client.calls != tt.wantCalls {
+				t.Errorf("backend received %d calls, want %d", client.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	client := newFakeClient("search")
+	client.handlers["search"] = func(ctx context.Context, arguments map[string]interface{}) (map[string]interface{}, error) {
+		return nil, mcp.ErrTimeout
+	}
+	config := types.MCPConfig{Proxy: types.ProxySettings{Retries: 2, RetryBackoff: types.Duration(50 * time.Millisecond)}}
+	p := newTestProxy(t, config, map[string]types.MCPClient{"db": client})
+
+	// The backoff doubles: 50ms before the first retry and 100ms before the second
+	start := time.Now()
+	p.UseTool(context.Background(), "search", types.ToolRequest{})
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("two retries took %s, want at least 150ms of backoff", elapsed)
+	}
+
+	// A caller that stops waiting ends the backoff
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if _, err := p.UseTool(ctx, "search", types.ToolRequest{}); err == nil {
+		t.Error("UseTool() succeeded, want the call's error")
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("cancelled call returned after %s, want it to stop backing off", elapsed)
+	}
+}
+
+func TestServerRetryPolicy(t *testing.T) {
+	two, zero := 2, 0
+	tests := []struct {
+		name   string
+		proxy  types.ProxySettings
+		server types.MCPServer
+		want   retryPolicy
+	}{
+		{name: "defaults", want: retryPolicy{backoff: defaultRetryBackoff}},
+		{name: "proxy-wide settings", proxy: types.ProxySettings{Retries: 3, RetryBackoff: types.Duration(time.Second)}, want: retryPolicy{retries: 3, backoff: time.Second}},
+		{name: "server overrides", proxy: types.ProxySettings{Retries: 3, RetryBackoff: types.Duration(time.Second)}, server: types.MCPServer{Retries: &two, RetryBackoff: types.Duration(time.Millisecond)}, want: retryPolicy{retries: 2, backoff: time.Millisecond}},
+		{name: "server turns retries off", proxy: types.ProxySettings{Retries: 3}, server: types.MCPServer{Retries: &zero}, want: retryPolicy{backoff: defaultRetryBackoff}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newSmartProxy(types.MCPConfig{Proxy: tt.proxy}, "", Options{})
+			if got := p.serverRetryPolicy(tt.server); got != tt.want {
+				t.Errorf("serverRetryPolicy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// slowClient answers its tool after latency, or fails with mcp.ErrTimeout once the read timeout
+// it was created with runs out first, as a stdio client does
+type slowClient struct {
+	*fakeClient
+	latency     time.Duration
+	readTimeout time.Duration
+}
+
+func (c *slowClient) CallTool(ctx context.Context, toolName string, arguments map[string]interface{}) (map[string]interface{}, error) {
+	if c.readTimeout > 0 && c.readTimeout < c.latency {
+		time.Sleep(c.readTimeout)
+		return nil, fmt.Errorf("%w after %s", mcp.ErrTimeout, c.readTimeout)
+	}
+	time.Sleep(c.latency)
+	return c.fakeClient.CallTool(ctx, toolName, arguments)
+}
+
+func TestServerTimeouts(t *testing.T) {
+	retries := 2
+	config := types.MCPConfig{
+		MCPServers: map[string]types.MCPServer{
+			"fast":    {ConnectTimeout: types.Duration(time.Second), ReadTimeout: types.Duration(20 * time.Millisecond)},
+			"patient": {ReadTimeout: types.Duration(5 * time.Second)},
+			"flaky":   {Retries: &retries, RetryBackoff: types.Duration(time.Millisecond)},
+		},
+		Proxy: types.ProxySettings{ConnectTimeout: types.Duration(time.Minute), ReadTimeout: types.Duration(20 * time.Millisecond)},
+	}
+	p := newSmartProxy(config, "", Options{})
+	p.providers = map[string]types.LLMProvider{"default": fakeProvider{}}
+	p.defaultLLM = "default"
+	if err := p.setup(); err != nil {
+		t.Fatalf("setup() error = %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+
+	var mu sync.Mutex
+	opts := make(map[string]mcp.Options)
+	flaky := newFakeClient("flaky_report")
+	failures := 2
+	flaky.handlers["flaky_report"] = func(ctx context.Context, arguments map[string]interface{}) (map[string]interface{}, error) {
+		if failures > 0 {
+			failures--
+			return nil, mcp.ErrTimeout
+		}
+		return textResult("flaky_report"), nil
+	}
+	p.SetClientFactory(TransportStdio, func(ctx context.Context, serverName string, server types.MCPServer, env map[string]string, o mcp.Options) (types.MCPClient, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		opts[serverName] = o
+		if serverName == "flaky" {
+			return flaky, nil
+		}
+		return &slowClient{fakeClient: newFakeClient(serverName + "_report"), latency: 100 * time.Millisecond, readTimeout: o.ReadTimeout}, nil
+	})
+	if err := p.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	if got := opts["fast"].ConnectTimeout; got != time.Second {
+		t.Errorf("fast connect timeout = %s, want its own 1s", got)
+	}
+	if got := opts["patient"].ConnectTimeout; got != time.Minute {
+		t.Errorf("patient connect timeout = %s, want the proxy-wide 1m", got)
+	}
+
+	start := time.Now()
+	if _, err := p.UseTool(context.Background(), "fast_report", types.ToolRequest{}); !errors.Is(err, types.ErrToolTimeout) {
+		t.Errorf("UseTool(fast_report) error = %v, want ErrToolTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 80*time.Millisecond {
+		t.Errorf("UseTool(fast_report) failed after %s, want it to fail at the 20ms read timeout", elapsed)
+	}
+	if result, err := p.UseTool(context.Background(), "patient_report", types.ToolRequest{}); err != nil || resultText(result) != "patient_report" {
+		t.Errorf("UseTool(patient_report) = %v, %v, want it to outlast the proxy-wide read timeout", result, err)
+	}
+	// Only flaky retries; the proxy-wide setting is no retries
+	if result, err := p.UseTool(context.Background(), "flaky_report", types.ToolRequest{}); err != nil || resultText(result) != "flaky_report" || flaky.calls != 3 {
+		t.Errorf("UseTool(flaky_report) = %v, %v after %d calls, want success on the third", result, err, flaky.calls)
+	}
+}
@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// normalizeInputSchema gives tools whose server omitted inputSchema an empty object schema, so
+// listings always carry one and calls accept any arguments
+func normalizeInputSchema(schema interface{}) interface{} {
+	if schema == nil {
+		return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	return schema
+}
+
+// prepareArguments fills in schema-declared defaults for absent top-level arguments and reports
+// required arguments that are still missing; the caller's map is never modified
+func prepareArguments(tool types.Tool, arguments map[string]interface{}) (map[string]interface{}, error) {
+	schema, _ := tool.InputSchema.(map[string]interface{})
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	prepared := make(map[string]interface{}, len(arguments))
+	for key, value := range arguments {
+		prepared[key] = value
+	}
+
+	for name, raw := range properties {
+		property, _ := raw.(map[string]interface{})
+		if _, present := prepared[name]; present {
+			continue
+		}
+		if value, ok := property["default"]; ok {
+			prepared[name] = value
+		}
+	}
+
+	required, _ := schema["required"].([]interface{})
+	var missing []types.MissingArgument
+	for _, raw := range required {
+		name, ok := raw.(string)
+		if !ok {
+			continue
+		}
+		if _, present := prepared[name]; present {
+			continue
+		}
+
+		property, _ := properties[name].(map[string]interface{})
+		argument := types.MissingArgument{Name: name, Description: getString(property, "description")}
+		switch t := property["type"].(type) {
+		case string:
+			argument.Type = t
+		case []interface{}:
+			// JSON Schema allows a list of types, e.g. ["string", "null"]
+			for _, item := range t {
+				if s, ok := item.(string); ok && s != "null" {
+					argument.Type = s
+					break
+				}
+			}
+		}
+		missing = append(missing, argument)
+	}
+
+	if len(missing) > 0 {
+		sort.Slice(missing, func(i, j int) bool { return missing[i].Name < missing[j].Name })
+		return nil, &types.MissingArgumentsError{Tool: tool.Name, Missing: missing}
+	}
+	if arguments == nil && len(prepared) == 0 {
+		// Keep sending no arguments rather than an empty object when there was nothing to add
+		return nil, nil
+	}
+	return prepared, nil
+}
+
+// coerceArguments converts top-level argument values in place to the scalar types declared in
+// the tool's input schema; values that do not convert cleanly and untyped properties are left alone
+func coerceArguments(tool types.Tool, arguments map[string]interface{}) {
+	schema, _ := tool.InputSchema.(map[string]interface{})
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	for name, value := range arguments {
+		property, _ := properties[name].(map[string]interface{})
+		allowed, ok := schemaTypes(property["type"])
+		if !ok || matchesAnyType(value, allowed) {
+			continue
+		}
+		for _, t := range allowed {
+			if coerced, ok := coerceValue(value, t); ok {
+				arguments[name] = coerced
+				break
+			}
+		}
+	}
+}
+
+// coerceValue converts a scalar to the given JSON Schema type
+func coerceValue(value interface{}, t string) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		switch t {
+		case "number":
+			f, err := strconv.ParseFloat(v, 64)
+			// NaN and infinities parse but cannot be sent as JSON
+			return f, err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+		case "integer":
+			i, err := strconv.ParseInt(v, 10, 64)
+			return float64(i), err == nil
+		case "boolean":
+			if v == "true" || v == "false" {
+				return v == "true", true
+			}
+		}
+	case float64:
+		if t == "string" {
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		}
+	case bool:
+		if t == "string" {
+			return strconv.FormatBool(v), true
+		}
+	}
+	return nil, false
+}
+
+// getString returns m[key] when it is a string
+func getString(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
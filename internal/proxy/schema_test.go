@@ -0,0 +1,236 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// queryTool requires query, since and limit, which has a default, and takes an optional format
+var queryTool = types.Tool{Name: "search", InputSchema: map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"query":  map[string]interface{}{"type": "string", "description": "What to look for"},
+		"limit":  map[string]interface{}{"type": "integer", "default": float64(10)},
+		"format": map[string]interface{}{"type": []interface{}{"null", "string"}, "default": "json"},
+		"since":  map[string]interface{}{"type": []interface{}{"null", "string"}, "description": "ISO date"},
+	},
+	"required": []interface{}{"query", "limit", "since"},
+}}
+
+func TestPrepareArguments(t *testing.T) {
+	tests := []struct {
+		name        string
+		tool        types.Tool
+		arguments   map[string]interface{}
+		want        map[string]interface{}
+		wantMissing []types.MissingArgument
+	}{
+		{
+			name:      "defaults fill absent arguments",
+			tool:      queryTool,
+			arguments: map[string]interface{}{"query": "bug", "since": "2024-01-01"},
+			want:      map[string]interface{}{"query": "bug", "since": "2024-01-01", "limit": float64(10), "format": "json"},
+		},
+		{
+			name:      "given arguments win over defaults",
+			tool:      queryTool,
+			arguments: map[string]interface{}{"query": "bug", "since": nil, "limit": float64(3), "format": "csv"},
+			want:      map[string]interface{}{"query": "bug", "since": nil, "limit": float64(3), "format": "csv"},
+		},
+		{
+			name:      "missing required arguments are listed with their types",
+			tool:      queryTool,
+			arguments: map[string]interface{}{"format": "csv"},
+			wantMissing: []types.MissingArgument{
+				{Name: "query", Type: "string", Description: "What to look for"},
+				{Name: "since", Type: "string", Description: "ISO date"},
+			},
+		},
+		{name: "no schema", tool: types.Tool{Name: "ping"}, arguments: map[string]interface{}{"x": 1.0}, want: map[string]interface{}{"x": 1.0}},
+		{name: "nothing to send stays nil", tool: types.Tool{Name: "ping"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var original map[string]interface{}
+			if tt.arguments != nil {
+				original = make(map[string]interface{})
+				for key, value := range tt.arguments {
+					original[key] = value
+				}
+			}
+
+			got, err := prepareArguments(tt.tool, tt.arguments)
+			var missingErr *types.MissingArgumentsError
+			if tt.wantMissing != nil {
+				if !errors.As(err, &missingErr) || !reflect.DeepEqual(missingErr.Missing, tt.wantMissing) {
+					t.Fatalf("prepareArguments() error = %v, want missing %+v", err, tt.wantMissing)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("prepareArguments() = %v, %v, want %v", got, err, tt.want)
+			}
+			if !reflect.DeepEqual(tt.arguments, original) {
+				t.Errorf("prepareArguments() changed the caller's arguments to %v", tt.arguments)
+			}
+		})
+	}
+}
+
+func TestUseToolAppliesDefaults(t *testing.T) {
+	client := newFakeClient()
+	client.tools = []types.Tool{queryTool}
+	var received map[string]interface{}
+	client.handlers["search"] = func(ctx context.Context, arguments map[string]interface{}) (map[string]interface{}, error) {
+		received = arguments
+		return textResult("found"), nil
+	}
+	p := newTestProxy(t, types.MCPConfig{}, map[string]types.MCPClient{"index": client})
+
+	_, err := p.UseTool(context.Background(), "search", types.ToolRequest{Arguments: map[string]interface{}{"query": "bug"}})
+	var missingErr *types.MissingArgumentsError
+	if !errors.As(err, &missingErr) || client.calls != 0 {
+		t.Fatalf("UseTool() without since = %v after %d calls, want a missing arguments error before any call", err, client.calls)
+	}
+
+	if _, err := p.UseTool(context.Background(), "search", types.ToolRequest{Arguments: map[string]interface{}{"query": "bug", "since": "2024-01-01"}}); err != nil {
+		t.Fatalf("UseTool() error = %v", err)
+	}
+	if want := map[string]interface{}{"query": "bug", "since": "2024-01-01", "limit": float64(10), "format": "json"}; !reflect.DeepEqual(received, want) {
+		t.Errorf("server received %v, want %v", received, want)
+	}
+}
+
+// typedTool declares one property of each scalar type and one that is untyped
+var typedTool = types.Tool{Name: "typed", InputSchema: map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"count":    map[string]interface{}{"type": "integer"},
+		"ratio":    map[string]interface{}{"type": "number"},
+		"enabled":  map[string]interface{}{"type": "boolean"},
+		"label":    map[string]interface{}{"type": "string"},
+		"optional": map[string]interface{}{"type": []interface{}{"null", "number"}},
+		"anything": map[string]interface{}{"description": "Untyped"},
+	},
+}}
+
+func TestCoerceArguments(t *testing.T) {
+	tests := []struct {
+		name     string
+		argument string
+		value    interface{}
+		want     interface{}
+	}{
+		{name: "stringified integer", argument: "count", value: "42", want: float64(42)},
+		{name: "stringified number", argument: "ratio", value: "0.25", want: 0.25},
+		{name: "stringified true", argument: "enabled", value: "true", want: true},
+		{name: "stringified false", argument: "enabled", value: "false", want: false},
+		{name: "number to string", argument: "label", value: 3.5, want: "3.5"},
+		{name: "boolean to string", argument: "label", value: true, want: "true"},
+		{name: "type list", argument: "optional", value: "7", want: float64(7)},
+		{name: "already the right type", argument: "count", value: float64(3), want: float64(3)},
+		{name: "integer that does not parse", argument: "count", value: "3.5", want: "3.5"},
+		{name: "number that does not parse", argument: "ratio", value: "lots", want: "lots"},
+		{name: "NaN is not a JSON number", argument: "ratio", value: "NaN", want: "NaN"},
+		{name: "boolean spelled differently", argument: "enabled", value: "yes", want: "yes"},
+		{name: "untyped property", argument: "anything", value: "42", want: "42"},
+		{name: "undeclared argument", argument: "extra", value: "42", want: "42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			arguments := map[string]interface{}{tt.argument: tt.value}
+			coerceArguments(typedTool, arguments)
+			if got := arguments[tt.argument]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("coerceArguments() %s = %#v, want %#v", tt.argument, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUseToolCoercesArguments(t *testing.T) {
+	tests := []struct {
+		name   string
+		coerce bool
+		want   map[string]interface{}
+	}{
+		{name: "coercion enabled", coerce: true, want: map[string]interface{}{"count": float64(42), "anything": "42"}},
+		{name: "coercion disabled", want: map[string]interface{}{"count": "42", "anything": "42"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeClient()
+			client.tools = []types.Tool{typedTool}
+			var received map[string]interface{}
+			client.handlers["typed"] = func(ctx context.Context, arguments map[string]interface{}) (map[string]interface{}, error) {
+				received = arguments
+				return textResult("ok"), nil
+			}
+			config := types.MCPConfig{Proxy: types.ProxySettings{CoerceArguments: tt.coerce}}
+			p := newTestProxy(t, config, map[string]types.MCPClient{"typed": client})
+
+			arguments := map[string]interface{}{"count": "42", "anything": "42"}
+			if _, err := p.UseTool(context.Background(), "typed", types.ToolRequest{Arguments: arguments}); err != nil {
+				t.Fatalf("UseTool() error = %v", err)
+			}
+			if !reflect.DeepEqual(received, tt.want) {
+				t.Errorf("server received %v, want %v", received, tt.want)
+			}
+			if arguments["count"] != "42" {
+				t.Errorf("UseTool() changed the caller's arguments to %v", arguments)
+			}
+		})
+	}
+}
+
+func TestSchemalessTool(t *testing.T) {
+	emptySchema := map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+
+	tests := []struct {
+		name      string
+		arguments map[string]interface{}
+	}{
+		{name: "no arguments"},
+		{name: "any arguments", arguments: map[string]interface{}{"path": "/tmp", "depth": float64(2), "flags": []interface{}{"a"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeClient()
+			client.tools = []types.Tool{{Name: "ping", Description: "Ping the server"}}
+			var received map[string]interface{}
+			client.handlers["ping"] = func(ctx context.Context, arguments map[string]interface{}) (map[string]interface{}, error) {
+				received = arguments
+				return textResult("pong"), nil
+			}
+			config := types.MCPConfig{Proxy: types.ProxySettings{CoerceArguments: true}}
+			p := newTestProxy(t, config, map[string]types.MCPClient{"net": client})
+
+			tool, err := p.GetTool(context.Background(), "ping")
+			if err != nil {
+				t.Fatalf("GetTool() error = %v", err)
+			}
+			if !reflect.DeepEqual(tool.InputSchema, emptySchema) {
+				t.Errorf("GetTool() schema = %v, want %v", tool.InputSchema, emptySchema)
+			}
+
+			if _, err := p.UseTool(context.Background(), "ping", types.ToolRequest{Arguments: tt.arguments}); err != nil {
+				t.Fatalf("UseTool() error = %v", err)
+			}
+			if len(received) != len(tt.arguments) || (len(tt.arguments) > 0 && !reflect.DeepEqual(received, tt.arguments)) {
+				t.Errorf("server received %v, want %v", received, tt.arguments)
+			}
+
+			example, err := p.ExampleArguments(context.Background(), "ping")
+			if err != nil || len(example) != 0 {
+				t.Errorf("ExampleArguments() = %v, %v, want no arguments", example, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"mcp-smart-proxy/internal/requestid"
+	"mcp-smart-proxy/pkg/types"
+)
+
+// SearchToolName is the name of the proxy's own discovery tool, listed and called like any other
+// tool when proxy.searchTool is set
+const SearchToolName = "search_tools"
+
+// searchToolEnabled reports whether name is the proxy's search tool and that tool is enabled
+func (p *SmartProxy) searchToolEnabled(name string) bool {
+	return p.config.Proxy.SearchTool && name == SearchToolName
+}
+
+// searchTool describes the search tool. It has no server; its arguments mirror /discover
+func searchTool() types.Tool {
+	readOnly := true
+	return types.Tool{
+		Name:        SearchToolName,
+		Description: "Find the tools best suited to a task. Describe the task in natural language as query; the answer lists the recommended tools with their input schemas, most relevant first.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query":   map[string]interface{}{"type": "string", "description": "The task to find tools for"},
+				"tag":     map[string]interface{}{"type": "string", "description": "Only consider tools from servers carrying this tag"},
+				"exclude": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Tools to leave out, e.g. ones that just failed"},
+			},
+			"required": []interface{}{"query"},
+		},
+		Annotations: &types.ToolAnnotations{Title: "Search tools", ReadOnlyHint: &readOnly},
+	}
+}
+
+// callSearchTool runs a discovery for a call to the search tool and answers with the recommended
+// tools as a tool result: JSON text content, and the same list as structuredContent
+func (p *SmartProxy) callSearchTool(ctx context.Context, arguments map[string]interface{}) (map[string]interface{}, error) {
+	arguments, err := prepareArguments(searchTool(), arguments)
+	if err != nil {
+		return nil, err
+	}
+	req := types.ProxyRequest{}
+	req.Query, _ = arguments["query"].(string)
+	req.Tag, _ = arguments["tag"].(string)
+	exclude, _ := arguments["exclude"].([]interface{})
+	for _, raw := range exclude {
+		if name, ok := raw.(string); ok {
+			req.Exclude = append(req.Exclude, name)
+		}
+	}
+	if req.Query == "" {
+		return nil, &types.MissingArgumentsError{Tool: SearchToolName, Missing: []types.MissingArgument{{Name: "query", Type: "string", Description: "The task to find tools for"}}}
+	}
+
+	tools, err := p.DiscoverTools(ctx, req)
+	p.stats.recordTool(SearchToolName, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute tool %s: %w", SearchToolName, err)
+	}
+	requestid.Printf(ctx, "Tool %s recommended %d tools", SearchToolName, len(tools))
+
+	listing := map[string]interface{}{"tools": tools}
+	text, err := json.Marshal(listing)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"content":           []interface{}{map[string]interface{}{"type": "text", "text": string(text)}},
+		"structuredContent": listing,
+	}, nil
+}
@@ -0,0 +1,124 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"mcp-smart-proxy/internal/auth"
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestSearchTool(t *testing.T) {
+	tests := []struct {
+		name       string
+		disabled   bool
+		principal  string
+		arguments  map[string]interface{}
+		wantTools  []string // recommended tools, sorted
+		wantMissed bool
+		wantServer bool // the call goes to the server's own search_tools
+	}{
+		{name: "recommends the catalog", arguments: map[string]interface{}{"query": "read a file"}, wantTools: []string{"read", "write"}},
+		{name: "excluded tools are left out", arguments: map[string]interface{}{"query": "read a file", "exclude": []interface{}{"read"}}, wantTools: []string{"write"}},
+		{name: "tenant sees its own tools", principal: "reader", arguments: map[string]interface{}{"query": "read a file"}, wantTools: []string{"read"}},
+		{name: "query is required", arguments: map[string]interface{}{}, wantMissed: true},
+		{name: "empty query is missing", arguments: map[string]interface{}{"query": ""}, wantMissed: true},
+		{name: "disabled", disabled: true, arguments: map[string]interface{}{"query": "read a file"}, wantServer: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.MCPConfig{Proxy: types.ProxySettings{
+				SearchTool: !tt.disabled,
+				Tenants:    map[string]types.Tenant{"reader": {APIKey: "reader-key", Tools: []string{"read"}}},
+			}}
+			// The server's own search_tools is hidden by the proxy's while that is enabled
+			p := newTestProxy(t, config, map[string]types.MCPClient{"files": newFakeClient("read", "write", SearchToolName)})
+			ctx := auth.NewContext(context.Background(), tt.principal)
+
+			result, err := p.UseTool(ctx, SearchToolName, types.ToolRequest{Arguments: tt.arguments})
+			var missingErr *types.MissingArgumentsError
+			if tt.wantMissed {
+				if !errors.As(err, &missingErr) || missingErr.Missing[0].Name != "query" {
+					t.Fatalf("UseTool(%s) error = %v, want query missing", SearchToolName, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UseTool(%s) error = %v", SearchToolName, err)
+			}
+			if tt.wantServer {
+				if text := resultText(result); text != SearchToolName {
+					t.Errorf("UseTool(%s) = %q, want the server's answer", SearchToolName, text)
+				}
+				return
+			}
+
+			listing, _ := result["structuredContent"].(map[string]interface{})
+			recommended, _ := listing["tools"].([]types.Tool)
+			var names []string
+			for _, tool := range recommended {
+				names = append(names, tool.Name)
+			}
+			// fakeProvider keeps the catalog's map order
+			sort.Strings(names)
+			if strings.Join(names, ",") != strings.Join(tt.wantTools, ",") {
+				t.Errorf("search_tools recommended %v, want %v", names, tt.wantTools)
+			}
+			if text := resultText(result); !strings.Contains(text, `"name":"read"`) && contains(tt.wantTools, "read") {
+				t.Errorf("search_tools text content %s does not list read", text)
+			}
+		})
+	}
+}
+
+func TestSearchToolIsListed(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		wantOwn bool
+	}{
+		{name: "enabled", enabled: true, wantOwn: true},
+		{name: "disabled", enabled: false, wantOwn: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.MCPConfig{Proxy: types.ProxySettings{SearchTool: tt.enabled}}
+			p := newTestProxy(t, config, map[string]types.MCPClient{"files": newFakeClient("read", SearchToolName)})
+
+			tools, _ := p.ListTools(context.Background())
+			var listed []types.Tool
+			for _, tool := range tools {
+				if tool.Name == SearchToolName {
+					listed = append(listed, tool)
+				}
+			}
+			var each []types.Tool
+			p.EachTool(context.Background(), func(tool types.Tool) error {
+				if tool.Name == SearchToolName {
+					each = append(each, tool)
+				}
+				return nil
+			})
+			// Either the proxy's tool or, while that is disabled, the server's
+			if len(listed) != 1 || len(each) != 1 {
+				t.Fatalf("%s listed %d times by ListTools and %d by EachTool, want once", SearchToolName, len(listed), len(each))
+			}
+			if ours := listed[0].ServerName == ""; ours != tt.wantOwn {
+				t.Errorf("listed %s from server %q, want the proxy's own = %v", SearchToolName, listed[0].ServerName, tt.wantOwn)
+			}
+
+			tool, err := p.GetTool(context.Background(), SearchToolName)
+			if err != nil || (tool.ServerName == "") != tt.wantOwn {
+				t.Errorf("GetTool(%s) = %+v, %v", SearchToolName, tool, err)
+			}
+			if tt.wantOwn && !tool.IsReadOnly() {
+				t.Errorf("%s is not read-only", SearchToolName)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+
+	"mcp-smart-proxy/internal/selector"
+	"mcp-smart-proxy/pkg/types"
+)
+
+// errSelectionFull stops a streamed selection once the tool limit has been reached
+var errSelectionFull = errors.New("selection full")
+
+// selectionSink forwards a streamed selection, dropping repeats and enforcing the tool limit
+type selectionSink struct {
+	maxTools int
+	sent     map[string]bool
+	emit     func(types.Tool) error
+}
+
+// stream sends the pinned tools and then the strategy's selection
+func (s *selectionSink) stream(ctx context.Context, sel types.Selector, query string, allTools, pinned []types.Tool) error {
+	for _, tool := range pinned {
+		if err := s.send(tool); err != nil {
+			return s.done(err)
+		}
+	}
+	if s.full() {
+		// Pinned tools fill the selection; there is nothing left to select
+		return nil
+	}
+
+	if streaming, ok := sel.(selector.StreamingSelector); ok {
+		return s.done(streaming.SelectStream(ctx, query, allTools, s.send))
+	}
+
+	selected, err := sel.Select(ctx, query, allTools)
+	if err != nil {
+		return err
+	}
+	for _, tool := range selected {
+		if err := s.send(tool); err != nil {
+			return s.done(err)
+		}
+	}
+	return nil
+}
+
+func (s *selectionSink) send(tool types.Tool) error {
+	if s.sent[tool.Name] {
+		return nil
+	}
+	if s.full() {
+		return errSelectionFull
+	}
+	s.sent[tool.Name] = true
+	return s.emit(tool)
+}
+
+func (s *selectionSink) full() bool {
+	return s.maxTools > 0 && len(s.sent) >= s.maxTools
+}
+
+// done treats reaching the tool limit as success
+func (s *selectionSink) done(err error) error {
+	if errors.Is(err, errSelectionFull) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"mcp-smart-proxy/internal/requestid"
+	"mcp-smart-proxy/pkg/types"
+)
+
+// AddServer starts a server at runtime, merges its tools into the cache and adds it to the config;
+// it needs proxy.serverManagement, and only admins may add servers
+func (p *SmartProxy) AddServer(ctx context.Context, server types.MCPServer) (types.ServerStatus, error) {
+	if !p.config.Proxy.ServerManagement {
+		return types.ServerStatus{}, types.ErrServerManagementDisabled
+	}
+	if err := p.authorizeAdmin(ctx); err != nil {
+		return types.ServerStatus{}, err
+	}
+
+	p.mu.RLock()
+	_, exists := p.config.MCPServers[server.Name]
+	secretProvider := p.secrets
+	factory, factoryErr := p.clientFactory(server)
+	p.mu.RUnlock()
+	if exists {
+		return types.ServerStatus{}, fmt.Errorf("%w: %s", types.ErrServerExists, server.Name)
+	}
+	if factoryErr != nil {
+		return types.ServerStatus{}, fmt.Errorf("failed to start server %s: %w", server.Name, factoryErr)
+	}
+
+	// Spawn without holding the lock so running calls are not blocked by a slow handshake
+	requestid.Printf(ctx, "Adding server: %s", server.Name)
+	client, tools, err := p.connectServer(ctx, factory, server.Name, server, secretProvider)
+	if err != nil {
+		return types.ServerStatus{}, fmt.Errorf("failed to start server %s: %w", server.Name, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, exists := p.config.MCPServers[server.Name]; exists {
+		client.Close()
+		return types.ServerStatus{}, fmt.Errorf("%w: %s", types.ErrServerExists, server.Name)
+	}
+
+	if p.config.MCPServers == nil {
+		p.config.MCPServers = make(map[string]types.MCPServer)
+	}
+	p.config.MCPServers[server.Name] = server
+	p.registerServer(server.Name, client, tools)
+	p.markSynced()
+	requestid.Printf(ctx, "Server %s provided %d tools", server.Name, len(tools))
+
+	if err := p.persistConfig(); err != nil {
+		requestid.Printf(ctx, "Failed to persist config after adding server %s: %v", server.Name, err)
+	}
+	return p.serverStatus(server.Name, len(tools)), nil
+}
+
+// RemoveServer stops a server, drops its tools from the cache and removes it from the config; it
+// needs proxy.serverManagement, and only admins may remove servers
+func (p *SmartProxy) RemoveServer(ctx context.Context, name string) error {
+	if !p.config.Proxy.ServerManagement {
+		return types.ErrServerManagementDisabled
+	}
+	if err := p.authorizeAdmin(ctx); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, exists := p.config.MCPServers[name]; !exists {
+		return fmt.Errorf("%w: %s", types.ErrServerNotFound, name)
+	}
+
+	if client, ok := p.clients[name]; ok {
+		if err := client.Close(); err != nil {
+			requestid.Printf(ctx, "Error closing client for server %s: %v", name, err)
+		}
+	}
+	delete(p.clients, name)
+	delete(p.breakers, name)
+	delete(p.slots, name)
+	delete(p.failures, name)
+	delete(p.restarts, name)
+	delete(p.config.MCPServers, name)
+
+	removed := p.unregisterServerTools(name)
+	p.markSynced()
+	requestid.Printf(ctx, "Removed server %s and its %d tools", name, removed)
+
+	if err := p.persistConfig(); err != nil {
+		requestid.Printf(ctx, "Failed to persist config after removing server %s: %v", name, err)
+	}
+	return nil
+}
+
+// unregisterServerTools drops a server's tools from the cache and returns how many it served.
+// Each tool it served passes to the next server offering that name, whether that server was
+// a replica or had its own tool shadowed by this one; the caller must hold p.mu
+func (p *SmartProxy) unregisterServerTools(name string) int {
+	removed := 0
+	for toolName, serverName := range p.toolCache.ServerMap {
+		if serverName == name || contains(p.replicas[toolName], name) {
+			removed++
+		}
+	}
+
+	delete(p.serverTools, name)
+	p.rebuildToolCache()
+	return removed
+}
+
+// rebuildToolCache places the recorded tools of every server in the cache again, in server name
+// order; the caller must hold p.mu
+func (p *SmartProxy) rebuildToolCache() {
+	p.toolCache.Tools = make(map[string]types.Tool)
+	p.toolCache.ServerMap = make(map[string]string)
+	p.replicas = make(map[string][]string)
+
+	serverNames := make([]string, 0, len(p.serverTools))
+	for serverName := range p.serverTools {
+		serverNames = append(serverNames, serverName)
+	}
+	sort.Strings(serverNames)
+	for _, serverName := range serverNames {
+		p.placeTools(serverName, p.serverTools[serverName])
+	}
+}
+
+// persistConfig writes the current config back to its file when proxy.persistServers is set;
+// the caller must hold p.mu
+func (p *SmartProxy) persistConfig() error {
+	if !p.config.Proxy.PersistServers || p.configPath == "" {
+		return nil
+	}
+	if isRemoteConfig(p.configPath) {
+		return fmt.Errorf("cannot persist to remote config %s", p.configPath)
+	}
+
+	data, err := json.MarshalIndent(p.config, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename so a crash never leaves a truncated config
+	tmp, err := ioutil.TempFile(filepath.Dir(p.configPath), ".mcp-config-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p.configPath)
+}
@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestRemoveServerHandsToolsOver(t *testing.T) {
+	tests := []struct {
+		name         string
+		deduplicate  bool
+		betaType     string // argument type of beta's search tool; alpha and gamma take a string
+		remove       []string
+		wantServer   string // server of search afterwards, empty when it is gone
+		wantReplicas []string
+		wantType     string
+	}{
+		{name: "shadowed tool takes over", betaType: "number", remove: []string{"alpha"}, wantServer: "beta", wantType: "number"},
+		{name: "losing server removed", betaType: "number", remove: []string{"beta"}, wantServer: "alpha", wantType: "string"},
+		{name: "last server removed", betaType: "number", remove: []string{"alpha", "beta", "gamma"}},
+		{name: "replica is promoted", deduplicate: true, betaType: "string", remove: []string{"alpha"}, wantServer: "beta", wantReplicas: []string{"gamma"}, wantType: "string"},
+		{name: "replica is dropped", deduplicate: true, betaType: "string", remove: []string{"beta"}, wantServer: "alpha", wantReplicas: []string{"gamma"}, wantType: "string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.MCPConfig{Proxy: types.ProxySettings{
+				DeduplicateTools: tt.deduplicate,
+				ServerManagement: true,
+				Tenants:          map[string]types.Tenant{"root": {APIKey: "root-key", Admin: true}},
+			}}
+			beta := &fakeClient{tools: []types.Tool{fakeTool("search", tt.betaType)}}
+			p := newTestProxy(t, config, map[string]types.MCPClient{
+				"alpha": newFakeClient("search"),
+				"beta":  beta,
+				"gamma": newFakeClient("search"),
+			})
+
+			for _, serverName := range tt.remove {
+				if err := p.RemoveServer(context.Background(), serverName); err != nil {
+					t.Fatalf("RemoveServer(%s) error = %v", serverName, err)
+				}
+			}
+
+			server, replicas := servingServer(p, "search")
+			if server != tt.wantServer || strings.Join(replicas, ",") != strings.Join(tt.wantReplicas, ",") {
+				t.Fatalf("search served by %q with replicas %v, want %q with %v", server, replicas, tt.wantServer, tt.wantReplicas)
+			}
+			if server == "" {
+				return
+			}
+			tool, err := p.GetTool(context.Background(), "search")
+			if err != nil {
+				t.Fatalf("GetTool() error = %v", err)
+			}
+			properties := tool.InputSchema.(map[string]interface{})["properties"].(map[string]interface{})
+			if got := properties["value"].(map[string]interface{})["type"]; got != tt.wantType {
+				t.Errorf("search takes a %v, want a %s", got, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestEachToolSkipsRemovedTools(t *testing.T) {
+	config := types.MCPConfig{Proxy: types.ProxySettings{
+		ServerManagement: true,
+		Tenants:          map[string]types.Tenant{"root": {APIKey: "root-key", Admin: true}},
+	}}
+	p := newTestProxy(t, config, map[string]types.MCPClient{"files": newFakeClient("read"), "web": newFakeClient("fetch")})
+
+	var seen []string
+	err := p.EachTool(context.Background(), func(tool types.Tool) error {
+		seen = append(seen, tool.Name)
+		if len(seen) == 1 {
+			// Remove the other server while the listing is under way
+			other := map[string]string{"files": "web", "web": "files"}[tool.ServerName]
+			return p.RemoveServer(context.Background(), other)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachTool() error = %v", err)
+	}
+	if len(seen) != 1 {
+		t.Errorf("EachTool() yielded %v, want only the tool of the remaining server", seen)
+	}
+}
@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-smart-proxy/internal/mcp"
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestStartupSummary(t *testing.T) {
+	tests := []struct {
+		name    string
+		require bool // the failing in-memory server is required
+		wantErr bool
+	}{
+		{name: "optional servers failing"},
+		{name: "required server failing", require: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broken := newFakeClient("x")
+			broken.listErr = errFake
+			config := types.MCPConfig{MCPServers: map[string]types.MCPServer{
+				"broken":  {Required: tt.require},
+				"missing": {Command: "/nonexistent/server"},
+			}}
+			p, err := NewInMemory(config, fakeProvider{}, map[string]types.MCPClient{
+				"files":  newFakeClient("read", "write"),
+				"notes":  newFakeClient("note"),
+				"broken": broken,
+			})
+			if err != nil {
+				t.Fatalf("NewInMemory() error = %v", err)
+			}
+			t.Cleanup(func() { p.Close() })
+			p.SetClientFactory(TransportStdio, func(ctx context.Context, serverName string, server types.MCPServer, env map[string]string, opts mcp.Options) (types.MCPClient, error) {
+				return nil, errFake
+			})
+
+			if p.Startup() != nil {
+				t.Error("Startup() before Initialize is set, want nil")
+			}
+			if err := p.Initialize(context.Background()); (err != nil) != tt.wantErr {
+				t.Fatalf("Initialize() error = %v, want error = %v", err, tt.wantErr)
+			}
+
+			summary := p.Startup()
+			if summary == nil {
+				t.Fatal("Startup() after Initialize = nil, want a summary")
+			}
+			if summary.Attempted != 4 || summary.Connected != 2 || summary.Tools != 3 {
+				t.Errorf("Startup() = %d of %d servers with %d tools, want 2 of 4 with 3", summary.Connected, summary.Attempted, summary.Tools)
+			}
+			if len(summary.Failed) != 2 || !strings.Contains(summary.Failed["broken"], errFake.Error()) || !strings.Contains(summary.Failed["missing"], errFake.Error()) {
+				t.Errorf("Startup() failed = %q, want broken and missing with their reasons", summary.Failed)
+			}
+			if summary.Duration <= 0 || summary.Finished.IsZero() {
+				t.Errorf("Startup() duration %s, finished %s, want both set", time.Duration(summary.Duration), summary.Finished)
+			}
+
+			if report := p.Health(); report.Startup == nil || report.Startup.Connected != 2 {
+				t.Errorf("Health() startup = %+v, want the summary", report.Startup)
+			}
+			for _, status := range p.Servers() {
+				if (status.Error != "") != (summary.Failed[status.Name] != "") {
+					t.Errorf("server %s error = %q, want the startup failure %q", status.Name, status.Error, summary.Failed[status.Name])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// usageStats counts tool and server invocations since startup
+type usageStats struct {
+	mu      sync.Mutex
+	tools   map[string]*types.UsageCounter
+	servers map[string]*types.UsageCounter
+}
+
+// newUsageStats creates empty usage statistics
+func newUsageStats() *usageStats {
+	return &usageStats{
+		tools:   make(map[string]*types.UsageCounter),
+		servers: make(map[string]*types.UsageCounter),
+	}
+}
+
+// recordTool counts a tool invocation, including ones served from the result cache
+func (s *usageStats) recordTool(toolName string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record(s.tools, toolName, err)
+}
+
+// recordServer counts a call dispatched to a server, including failed failover attempts
+func (s *usageStats) recordServer(serverName string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record(s.servers, serverName, err)
+}
+
+// record updates the named counter; the caller must hold s.mu
+func record(counters map[string]*types.UsageCounter, name string, err error) {
+	counter, ok := counters[name]
+	if !ok {
+		counter = &types.UsageCounter{}
+		counters[name] = counter
+	}
+
+	counter.Calls++
+	if err != nil {
+		counter.Errors++
+	}
+	counter.ErrorRate = float64(counter.Errors) / float64(counter.Calls)
+	counter.LastUsed = time.Now()
+}
+
+// snapshot copies the counters
+func (s *usageStats) snapshot() types.UsageStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := types.UsageStats{
+		Tools:   make(map[string]types.UsageCounter, len(s.tools)),
+		Servers: make(map[string]types.UsageCounter, len(s.servers)),
+	}
+	for name, counter := range s.tools {
+		stats.Tools[name] = *counter
+	}
+	for name, counter := range s.servers {
+		stats.Servers[name] = *counter
+	}
+	return stats
+}
+
+// Stats returns per-tool and per-server invocation counts, error rates and last use
+func (p *SmartProxy) Stats() types.UsageStats {
+	return p.stats.snapshot()
+}
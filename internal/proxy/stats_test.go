@@ -0,0 +1,56 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"mcp-smart-proxy/internal/mcp"
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestStats(t *testing.T) {
+	files := newFakeClient("read", "write")
+	files.handlers["write"] = func(ctx context.Context, arguments map[string]interface{}) (map[string]interface{}, error) {
+		return nil, mcp.ErrToolError
+	}
+	p := newTestProxy(t, types.MCPConfig{}, map[string]types.MCPClient{"files": files, "notes": newFakeClient("note")})
+
+	// Calls run concurrently so the race detector covers the counters
+	started := time.Now()
+	calls := map[string]int{"read": 12, "write": 4, "note": 3}
+	var wg sync.WaitGroup
+	for toolName, n := range calls {
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(toolName string) {
+				defer wg.Done()
+				p.UseTool(context.Background(), toolName, types.ToolRequest{})
+			}(toolName)
+		}
+	}
+	wg.Wait()
+
+	stats := p.Stats()
+	tests := []struct {
+		name    string
+		counter types.UsageCounter
+		want    types.UsageCounter
+	}{
+		{name: "tool read", counter: stats.Tools["read"], want: types.UsageCounter{Calls: 12}},
+		{name: "tool write", counter: stats.Tools["write"], want: types.UsageCounter{Calls: 4, Errors: 4, ErrorRate: 1}},
+		{name: "tool note", counter: stats.Tools["note"], want: types.UsageCounter{Calls: 3}},
+		{name: "server files", counter: stats.Servers["files"], want: types.UsageCounter{Calls: 16, Errors: 4, ErrorRate: 0.25}},
+		{name: "server notes", counter: stats.Servers["notes"], want: types.UsageCounter{Calls: 3}},
+	}
+	for _, tt := range tests {
+		got := tt.counter
+		if got.Calls != tt.want.Calls || got.Errors != tt.want.Errors || got.ErrorRate != tt.want.ErrorRate {
+			t.Errorf("%s = %+v, want %+v", tt.name, got, tt.want)
+		}
+		if got.LastUsed.Before(started) {
+			t.Errorf("%s last used %s, want a time after the calls started", tt.name, got.LastUsed)
+		}
+	}
+}
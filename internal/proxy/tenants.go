@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"mcp-smart-proxy/internal/auth"
+	"mcp-smart-proxy/internal/secrets"
+	"mcp-smart-proxy/pkg/types"
+)
+
+// Authenticate maps an API key to its tenant's principal name. With no tenants configured
+// authentication is off and every caller gets the empty principal
+func (p *SmartProxy) Authenticate(apiKey string) (string, error) {
+	if len(p.config.Proxy.Tenants) == 0 {
+		return "", nil
+	}
+	if apiKey == "" {
+		return "", types.ErrUnauthorized
+	}
+
+	p.mu.RLock()
+	secretProvider := p.secrets
+	p.mu.RUnlock()
+
+	for name, tenant := range p.config.Proxy.Tenants {
+		key := tenant.APIKey
+		if strings.HasPrefix(key, secrets.RefPrefix) {
+			if secretProvider == nil {
+				continue
+			}
+			resolved, err := secretProvider.GetSecret(strings.TrimPrefix(key, secrets.RefPrefix))
+			if err != nil {
+				continue
+			}
+			key = resolved
+		}
+		if key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) == 1 {
+			return name, nil
+		}
+	}
+	return "", types.ErrUnauthorized
+}
+
+// tenant returns the tenant of the principal in ctx, or nil for unauthenticated (unrestricted) callers
+func (p *SmartProxy) tenant(ctx context.Context) *types.Tenant {
+	principal := auth.FromContext(ctx)
+	if principal == "" {
+		return nil
+	}
+	tenant, ok := p.config.Proxy.Tenants[principal]
+	if !ok {
+		// An authenticated principal that is no longer configured sees nothing
+		return &types.Tenant{Tools: []string{}, Servers: []string{}}
+	}
+	return &tenant
+}
+
+// authorizeAdmin allows administrative operations to admin tenants and to callers without a
+// principal, i.e. every caller when no tenants are configured, and the proxy itself
+func (p *SmartProxy) authorizeAdmin(ctx context.Context) error {
+	if tenant := p.tenant(ctx); tenant != nil && !tenant.Admin {
+		return fmt.Errorf("%w: tenant %s is not an admin", types.ErrForbidden, auth.FromContext(ctx))
+	}
+	return nil
+}
+
+// hasAdminTenant reports whether any configured tenant is an admin
+func (p *SmartProxy) hasAdminTenant() bool {
+	for _, tenant := range p.config.Proxy.Tenants {
+		if tenant.Admin {
+			return true
+		}
+	}
+	return false
+}
+
+// tenantAllows reports whether the tenant may use the tool when served by serverName
+func tenantAllows(tenant *types.Tenant, toolName, serverName string) bool {
+	if tenant == nil || (tenant.Tools == nil && tenant.Servers == nil) {
+		return true
+	}
+	return contains(tenant.Tools, toolName) || contains(tenant.Servers, serverName)
+}
+
+// visible reports whether the tenant may use the tool through any server offering it; the caller
+// must hold p.mu
+func (p *SmartProxy) visible(tenant *types.Tenant, toolName string) bool {
+	if tenant == nil {
+		return true
+	}
+	serverNames := append([]string{p.toolCache.ServerMap[toolName]}, p.replicas[toolName]...)
+	for _, serverName := range serverNames {
+		if tenantAllows(tenant, toolName, serverName) {
+			return true
+		}
+	}
+	return false
+}
+
+// tenantRoutes keeps the routes to servers the tenant may reach for the tool
+func tenantRoutes(tenant *types.Tenant, toolName string, routes []route) []route {
+	if tenant == nil {
+		return routes
+	}
+	var allowed []route
+	for _, r := range routes {
+		if tenantAllows(tenant, toolName, r.serverName) {
+			allowed = append(allowed, r)
+		}
+	}
+	return allowed
+}
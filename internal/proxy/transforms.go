@@ -0,0 +1,71 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// ResultTransform rewrites a tool result before it is returned, e.g. to redact or truncate
+// content. It must return a new map rather than modify result, which may be cached.
+type ResultTransform func(ctx context.Context, tool types.Tool, result map[string]interface{}) (map[string]interface{}, error)
+
+// SetTransform registers a result transform under the name used in proxy.transforms and
+// proxy.toolTransforms, replacing any existing one; register transforms before Initialize
+func (p *SmartProxy) SetTransform(name string, transform ResultTransform) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.transforms == nil {
+		p.transforms = make(map[string]ResultTransform)
+	}
+	p.transforms[name] = transform
+}
+
+// transformNames lists the transforms applied to a tool's results: the global ones, then the tool's own
+func (p *SmartProxy) transformNames(toolName string) []string {
+	names := append([]string(nil), p.config.Proxy.Transforms...)
+	return append(names, p.config.Proxy.ToolTransforms[toolName]...)
+}
+
+// checkTransforms reports configured transform names that were never registered
+func (p *SmartProxy) checkTransforms() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	names := append([]string(nil), p.config.Proxy.Transforms...)
+	for _, toolNames := range p.config.Proxy.ToolTransforms {
+		names = append(names, toolNames...)
+	}
+	for _, name := range names {
+		if _, ok := p.transforms[name]; !ok {
+			return fmt.Errorf("transform %q is not registered", name)
+		}
+	}
+	return nil
+}
+
+// transformResult runs a tool's transform chain over its result
+func (p *SmartProxy) transformResult(ctx context.Context, tool types.Tool, result map[string]interface{}) (map[string]interface{}, error) {
+	p.mu.RLock()
+	names := p.transformNames(tool.Name)
+	chain := make([]ResultTransform, len(names))
+	for i, name := range names {
+		transform, ok := p.transforms[name]
+		if !ok {
+			p.mu.RUnlock()
+			return nil, fmt.Errorf("transform %q is not registered", name)
+		}
+		chain[i] = transform
+	}
+	p.mu.RUnlock()
+
+	for i, transform := range chain {
+		transformed, err := transform(ctx, tool, result)
+		if err != nil {
+			return nil, fmt.Errorf("transform %s failed on tool %s: %w", names[i], tool.Name, err)
+		}
+		result = transformed
+	}
+	return result, nil
+}
@@ -0,0 +1,92 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// appendText returns a transform adding suffix to the text of a result
+func appendText(suffix string) ResultTransform {
+	return func(ctx context.Context, tool types.Tool, result map[string]interface{}) (map[string]interface{}, error) {
+		return textResult(resultText(result) + suffix), nil
+	}
+}
+
+// redactPaths replaces the internal /srv/data prefix in a result's text
+func redactPaths(ctx context.Context, tool types.Tool, result map[string]interface{}) (map[string]interface{}, error) {
+	return textResult(strings.ReplaceAll(resultText(result), "/srv/data", "[redacted]")), nil
+}
+
+func TestTransforms(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings types.ProxySettings
+		tool     string
+		wantText string
+		wantErr  bool
+	}{
+		{name: "no transforms", tool: "read", wantText: "/srv/data/notes.txt"},
+		{name: "global transform", settings: types.ProxySettings{Transforms: []string{"redact"}}, tool: "read", wantText: "[redacted]/notes.txt"},
+		{name: "tool transform", settings: types.ProxySettings{ToolTransforms: map[string][]string{"read": {"redact"}}}, tool: "read", wantText: "[redacted]/notes.txt"},
+		{name: "tool transform of another tool", settings: types.ProxySettings{ToolTransforms: map[string][]string{"write": {"redact"}}}, tool: "read", wantText: "/srv/data/notes.txt"},
+		{
+			name:     "global transforms run first, in order",
+			settings: types.ProxySettings{Transforms: []string{"a", "b"}, ToolTransforms: map[string][]string{"read": {"redact", "c"}}},
+			tool:     "read",
+			wantText: "[redacted]/notes.txt abc",
+		},
+		{name: "failing transform", settings: types.ProxySettings{Transforms: []string{"fail"}}, tool: "read", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeClient("read", "write")
+			client.handlers["read"] = func(ctx context.Context, arguments map[string]interface{}) (map[string]interface{}, error) {
+				return textResult("/srv/data/notes.txt"), nil
+			}
+			p, err := NewInMemory(types.MCPConfig{Proxy: tt.settings}, fakeProvider{}, map[string]types.MCPClient{"files": client})
+			if err != nil {
+				t.Fatalf("NewInMemory() error = %v", err)
+			}
+			p.SetTransform("redact", redactPaths)
+			p.SetTransform("a", appendText(" a"))
+			p.SetTransform("b", appendText("b"))
+			p.SetTransform("c", appendText("c"))
+			p.SetTransform("fail", func(ctx context.Context, tool types.Tool, result map[string]interface{}) (map[string]interface{}, error) {
+				return nil, errFake
+			})
+			if err := p.Initialize(context.Background()); err != nil {
+				t.Fatalf("Initialize() error = %v", err)
+			}
+			t.Cleanup(func() { p.Close() })
+
+			result, err := p.UseTool(context.Background(), tt.tool, types.ToolRequest{})
+			if tt.wantErr {
+				if !errors.Is(err, errFake) {
+					t.Errorf("UseTool() error = %v, want the transform's error", err)
+				}
+				return
+			}
+			if err != nil || resultText(result) != tt.wantText {
+				t.Errorf("UseTool() = %q, %v, want %q", resultText(result), err, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestUnregisteredTransform(t *testing.T) {
+	config := types.MCPConfig{Proxy: types.ProxySettings{ToolTransforms: map[string][]string{"read": {"redact"}}}}
+	p, err := NewInMemory(config, fakeProvider{}, map[string]types.MCPClient{"files": newFakeClient("read")})
+	if err != nil {
+		t.Fatalf("NewInMemory() error = %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+
+	if err := p.Initialize(context.Background()); err == nil || !strings.Contains(err.Error(), `"redact"`) {
+		t.Errorf("Initialize() error = %v, want the unregistered transform named", err)
+	}
+}
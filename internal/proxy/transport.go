@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+
+	"mcp-smart-proxy/internal/mcp"
+	"mcp-smart-proxy/pkg/types"
+)
+
+// TransportStdio is the transport used when a server does not name one
+const TransportStdio = "stdio"
+
+// ClientFactory connects to an MCP server and completes its initialize handshake. env holds
+// the server's environment with secret references already resolved.
+type ClientFactory func(ctx context.Context, serverName string, server types.MCPServer, env map[string]string, opts mcp.Options) (types.MCPClient, error)
+
+// stdioClientFactory spawns the server as a subprocess speaking MCP over stdin and stdout
+func stdioClientFactory(ctx context.Context, serverName string, server types.MCPServer, env map[string]string, opts mcp.Options) (types.MCPClient, error) {
+	return mcp.NewStdioClient(server.Command, server.Args, env, opts)
+}
+
+// defaultClientFactories returns the built-in transports
+func defaultClientFactories() map[string]ClientFactory {
+	return map[string]ClientFactory{TransportStdio: stdioClientFactory}
+}
+
+// SetClientFactory registers the factory used for servers configured with the given transport,
+// replacing any existing one; overriding "stdio" redirects every server without a transport
+func (p *SmartProxy) SetClientFactory(transport string, factory ClientFactory) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.factories[transport] = factory
+}
+
+// clientFactory returns the factory for a server's transport; the caller must hold p.mu
+func (p *SmartProxy) clientFactory(server types.MCPServer) (ClientFactory, error) {
+	transport := server.Transport
+	if transport == "" {
+		transport = TransportStdio
+	}
+
+	factory, ok := p.factories[transport]
+	if !ok {
+		return nil, fmt.Errorf("unsupported transport %q", transport)
+	}
+	return factory, nil
+}
@@ -0,0 +1,146 @@
+package proxy
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"mcp-smart-proxy/internal/mcp"
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestClientFactories(t *testing.T) {
+	config := types.MCPConfig{MCPServers: map[string]types.MCPServer{
+		"files":  {Command: "/nonexistent/files-server", Env: map[string]string{"ROOT": "/srv"}},
+		"remote": {Transport: "custom", Command: "/nonexistent/remote-server"},
+		"socket": {Transport: "websocket", Command: "/nonexistent/socket-server"},
+	}}
+	p := newSmartProxy(config, "", Options{})
+	p.providers = map[string]types.LLMProvider{"default": fakeProvider{}}
+	p.defaultLLM = "default"
+	if err := p.setup(); err != nil {
+		t.Fatalf("setup() error = %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+
+	// Overriding stdio keeps the proxy from spawning the configured commands, which do not exist;
+	// servers connect concurrently, hence the lock
+	var mu sync.Mutex
+	var spawned []string
+	envs := make(map[string]map[string]string)
+	fake := func(transport string) ClientFactory {
+		return func(ctx context.Context, serverName string, server types.MCPServer, env map[string]string, opts mcp.Options) (types.MCPClient, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			spawned = append(spawned, transport+":"+serverName)
+			envs[serverName] = env
+			return newFakeClient(serverName + "_tool"), nil
+		}
+	}
+	p.SetClientFactory(TransportStdio, fake(TransportStdio))
+	p.SetClientFactory("custom", fake("custom"))
+
+	if err := p.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	sort.Strings(spawned)
+	if want := []string{"custom:remote", "stdio:files"}; !reflect.DeepEqual(spawned, want) {
+		t.Errorf("factories called for %v, want %v", spawned, want)
+	}
+	if want := map[string]string{"ROOT": "/srv"}; !reflect.DeepEqual(envs["files"], want) {
+		t.Errorf("stdio factory env = %v, want %v", envs["files"], want)
+	}
+	for _, toolName := range []string{"files_tool", "remote_tool"} {
+		if _, err := p.GetTool(context.Background(), toolName); err != nil {
+			t.Errorf("GetTool(%s) error = %v, want the tool of the fake client", toolName, err)
+		}
+	}
+	if failure := p.startup.Failed["socket"]; !strings.Contains(failure, `unsupported transport "websocket"`) {
+		t.Errorf("socket failure = %q, want an unsupported transport", failure)
+	}
+}
+
+func TestToolListingServerMetadata(t *testing.T) {
+	config := types.MCPConfig{
+		MCPServers: map[string]types.MCPServer{
+			"files": {Command: "/nonexistent/files-server"},
+			"db":    {Command: "/nonexistent/db-server"},
+		},
+		Proxy: types.ProxySettings{BreakerThreshold: 1},
+	}
+	clients := map[string]types.MCPClient{"files": newFakeClient("read"), "db": newFakeClient("query"), "notes": newFakeClient("note")}
+	p, err := NewInMemory(config, fakeProvider{}, map[string]types.MCPClient{"notes": clients["notes"]})
+	if err != nil {
+		t.Fatalf("NewInMemory() error = %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+	p.SetClientFactory(TransportStdio, func(ctx context.Context, serverName string, server types.MCPServer, env map[string]string, opts mcp.Options) (types.MCPClient, error) {
+		return clients[serverName], nil
+	})
+	if err := p.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	// A failing call opens db's breaker: still connected, no longer healthy
+	clients["db"].(*fakeClient).handlers["query"] = func(ctx context.Context, arguments map[string]interface{}) (map[string]interface{}, error) {
+		return nil, mcp.ErrTimeout
+	}
+	p.UseTool(context.Background(), "query", types.ToolRequest{})
+
+	tools, err := p.ListTools(context.Background())
+	if err != nil {
+		t.Fatalf("ListTools() error = %v", err)
+	}
+	want := map[string]types.ToolServer{
+		"read":  {Transport: TransportStdio, Connected: true, Healthy: true},
+		"query": {Transport: TransportStdio, Connected: true, Healthy: false},
+		"note":  {Transport: TransportInMemory, Connected: true, Healthy: true},
+	}
+	for _, tool := range tools {
+		if tool.Server == nil || *tool.Server != want[tool.Name] {
+			t.Errorf("%s server = %+v, want %+v", tool.Name, tool.Server, want[tool.Name])
+		}
+	}
+	if len(tools) != len(want) {
+		t.Errorf("ListTools() returned %d tools, want %d", len(tools), len(want))
+	}
+}
+
+func TestServerInitializeOptions(t *testing.T) {
+	capabilities := map[string]interface{}{"experimental": map[string]interface{}{"streaming": true}, "roots": nil}
+	config := types.MCPConfig{MCPServers: map[string]types.MCPServer{
+		"custom":  {Command: "/nonexistent/custom-server", ClientInfo: &types.ClientInfo{Name: "agent", Version: "2.0"}, Capabilities: capabilities},
+		"default": {Command: "/nonexistent/default-server"},
+	}}
+	p, err := NewInMemory(config, fakeProvider{}, nil)
+	if err != nil {
+		t.Fatalf("NewInMemory() error = %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+
+	var mu sync.Mutex
+	opts := make(map[string]mcp.Options)
+	p.SetClientFactory(TransportStdio, func(ctx context.Context, serverName string, server types.MCPServer, env map[string]string, o mcp.Options) (types.MCPClient, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		opts[serverName] = o
+		return newFakeClient(serverName + "_tool"), nil
+	})
+	if err := p.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	if got, want := opts["custom"].ClientInfo, (types.ClientInfo{Name: "agent", Version: "2.0"}); got != want {
+		t.Errorf("custom clientInfo = %+v, want %+v", got, want)
+	}
+	if got := opts["custom"].Capabilities; !reflect.DeepEqual(got, capabilities) {
+		t.Errorf("custom capabilities = %v, want %v", got, capabilities)
+	}
+	if got := opts["default"]; got.ClientInfo != (types.ClientInfo{}) || got.Capabilities != nil {
+		t.Errorf("default server clientInfo = %+v, capabilities = %v, want the client defaults", got.ClientInfo, got.Capabilities)
+	}
+}
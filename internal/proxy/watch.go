@@ -0,0 +1,38 @@
+package proxy
+
+import (
+	"context"
+	"time"
+)
+
+// markSynced records that the tool cache changed, stamps the tools that changed and wakes
+// WaitForSync callers; the caller must hold p.mu
+func (p *SmartProxy) markSynced() {
+	p.toolCache.LastSync = time.Now()
+	p.trackChanges(p.toolCache.LastSync)
+	p.startEnrichment()
+	if p.synced != nil {
+		close(p.synced)
+	}
+	p.synced = make(chan struct{})
+}
+
+// WaitForSync blocks until the tool cache has been synced after since, returning the time of the
+// latest sync, or until ctx ends
+func (p *SmartProxy) WaitForSync(ctx context.Context, since time.Time) (time.Time, error) {
+	for {
+		p.mu.RLock()
+		lastSync, synced := p.toolCache.LastSync, p.synced
+		p.mu.RUnlock()
+
+		if lastSync.After(since) {
+			return lastSync, nil
+		}
+
+		select {
+		case <-synced:
+		case <-ctx.Done():
+			return lastSync, ctx.Err()
+		}
+	}
+}
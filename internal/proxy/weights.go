@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"sort"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// withServerWeights reorders a selection by each tool's reciprocal rank multiplied by its
+// server's weight, so a tool from a server weighted 2 overtakes the tool ranked just above it.
+// Equal scores go to the heavier server, then keep the selection order; without weights
+// configured the selection is returned unchanged
+func (p *SmartProxy) withServerWeights(selected []types.Tool) []types.Tool {
+	p.mu.RLock()
+	weights := make([]float64, len(selected))
+	weighted := false
+	for i, tool := range selected {
+		weights[i] = serverWeight(p.config.MCPServers[tool.ServerName])
+		weighted = weighted || weights[i] != 1
+	}
+	p.mu.RUnlock()
+	if !weighted {
+		return selected
+	}
+
+	order := make([]int, len(selected))
+	for i := range order {
+		order[i] = i
+	}
+	score := func(i int) float64 { return weights[i] / float64(i+1) }
+	sort.SliceStable(order, func(a, b int) bool {
+		i, j := order[a], order[b]
+		if score(i) != score(j) {
+			return score(i) > score(j)
+		}
+		return weights[i] > weights[j]
+	})
+
+	reordered := make([]types.Tool, len(selected))
+	for k, i := range order {
+		reordered[k] = selected[i]
+	}
+	return reordered
+}
+
+// serverWeight returns a server's selection weight; unset or non-positive weights count as 1
+func serverWeight(server types.MCPServer) float64 {
+	if server.Weight <= 0 {
+		return 1
+	}
+	return server.Weight
+}
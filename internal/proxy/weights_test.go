@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestServerWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights map[string]float64 // server -> weight
+		ranking []string
+		want    string
+	}{
+		{name: "no weights", ranking: []string{"fast_search", "slow_search", "slow_read"}, want: "fast_search,slow_search,slow_read"},
+		{name: "tie goes to the heavier server", weights: map[string]float64{"slow": 2}, ranking: []string{"fast_search", "slow_search"}, want: "slow_search,fast_search"},
+		{name: "tie with the servers swapped", weights: map[string]float64{"fast": 2}, ranking: []string{"slow_search", "fast_search"}, want: "fast_search,slow_search"},
+		{name: "weight too small to overtake", weights: map[string]float64{"slow": 1.5}, ranking: []string{"fast_search", "slow_search"}, want: "fast_search,slow_search"},
+		{name: "heavy weight overtakes two places", weights: map[string]float64{"slow": 3}, ranking: []string{"fast_search", "fast_read", "slow_search"}, want: "slow_search,fast_search,fast_read"},
+		{name: "non-positive weight counts as 1", weights: map[string]float64{"slow": -2}, ranking: []string{"fast_search", "slow_search"}, want: "fast_search,slow_search"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.MCPConfig{MCPServers: map[string]types.MCPServer{
+				"fast": {Weight: tt.weights["fast"]},
+				"slow": {Weight: tt.weights["slow"]},
+			}}
+			clients := map[string]types.MCPClient{
+				"fast": newFakeClient("fast_search", "fast_read"),
+				"slow": newFakeClient("slow_search", "slow_read"),
+			}
+			p, err := NewInMemory(config, rankingProvider(tt.ranking), clients)
+			if err != nil {
+				t.Fatalf("NewInMemory() error = %v", err)
+			}
+			if err := p.Initialize(context.Background()); err != nil {
+				t.Fatalf("Initialize() error = %v", err)
+			}
+			t.Cleanup(func() { p.Close() })
+
+			tools, err := p.DiscoverTools(context.Background(), types.ProxyRequest{Query: "search"})
+			if err != nil {
+				t.Fatalf("DiscoverTools() error = %v", err)
+			}
+			if got := joinToolNames(tools); got != tt.want {
+				t.Errorf("DiscoverTools() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// Package secrets resolves secret references in MCP server environment variables
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// RefPrefix marks an env value as a reference to a named secret, e.g. "secret:github_token"
+const RefPrefix = "secret:"
+
+// FileProvider implements SecretProvider using a JSON file of name -> value pairs
+type FileProvider struct {
+	secrets map[string]string
+}
+
+// NewFileProvider loads secrets from a JSON file
+func NewFileProvider(path string) (*FileProvider, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+
+	var secrets map[string]string
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to parse secrets file: %w", err)
+	}
+
+	return &FileProvider{secrets: secrets}, nil
+}
+
+// GetSecret returns the value of the named secret
+func (p *FileProvider) GetSecret(name string) (string, error) {
+	value, ok := p.secrets[name]
+	if !ok {
+		return "", fmt.Errorf("unknown secret %q", name)
+	}
+	return value, nil
+}
+
+// ResolveEnv returns a copy of env with every secret reference replaced by its value
+func ResolveEnv(env map[string]string, provider types.SecretProvider) (map[string]string, error) {
+	resolved := make(map[string]string, len(env))
+	for key, value := range env {
+		if !strings.HasPrefix(value, RefPrefix) {
+			resolved[key] = value
+			continue
+		}
+
+		if provider == nil {
+			return nil, fmt.Errorf("env %s references a secret but no secret provider is configured", key)
+		}
+
+		secret, err := provider.GetSecret(strings.TrimPrefix(value, RefPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve env %s: %w", key, err)
+		}
+		resolved[key] = secret
+	}
+
+	return resolved, nil
+}
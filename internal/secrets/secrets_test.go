@@ -0,0 +1,94 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewFileProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string // file content; empty leaves the file missing
+		wantErr string
+	}{
+		{name: "valid file", content: `{"github_token": "ghp_abc"}`},
+		{name: "missing file", wantErr: "failed to read secrets file"},
+		{name: "invalid JSON", content: `{"github_token": `, wantErr: "failed to parse secrets file"},
+		{name: "non-string value", content: `{"port": 5432}`, wantErr: "failed to parse secrets file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "secrets.json")
+			if tt.content != "" {
+				os.WriteFile(path, []byte(tt.content), 0o600)
+			}
+
+			provider, err := NewFileProvider(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewFileProvider() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewFileProvider() error = %v", err)
+			}
+			if value, err := provider.GetSecret("github_token"); err != nil || value != "ghp_abc" {
+				t.Errorf("GetSecret(github_token) = %q, %v, want ghp_abc", value, err)
+			}
+			if _, err := provider.GetSecret("missing"); err == nil || !strings.Contains(err.Error(), `unknown secret "missing"`) {
+				t.Errorf("GetSecret(missing) error = %v, want unknown secret", err)
+			}
+		})
+	}
+}
+
+func TestResolveEnv(t *testing.T) {
+	provider := &FileProvider{secrets: map[string]string{"github_token": "ghp_abc", "empty": ""}}
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		provider *FileProvider
+		want     map[string]string
+		wantErr  string
+	}{
+		{
+			name:     "references are resolved",
+			env:      map[string]string{"GITHUB_TOKEN": RefPrefix + "github_token", "EMPTY": RefPrefix + "empty", "LOG": "debug"},
+			provider: provider,
+			want:     map[string]string{"GITHUB_TOKEN": "ghp_abc", "EMPTY": "", "LOG": "debug"},
+		},
+		{name: "prefix only at the start", env: map[string]string{"NOTE": "not a secret:github_token"}, provider: provider, want: map[string]string{"NOTE": "not a secret:github_token"}},
+		{name: "no references need no provider", env: map[string]string{"LOG": "debug"}, want: map[string]string{"LOG": "debug"}},
+		{name: "nil env", provider: provider, want: map[string]string{}},
+		{name: "missing key", env: map[string]string{"TOKEN": RefPrefix + "missing"}, provider: provider, wantErr: `failed to resolve env TOKEN: unknown secret "missing"`},
+		{name: "reference without a provider", env: map[string]string{"TOKEN": RefPrefix + "github_token"}, wantErr: "no secret provider is configured"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			var got map[string]string
+			// A nil *FileProvider must reach ResolveEnv as a nil interface
+			if tt.provider != nil {
+				got, err = ResolveEnv(tt.env, tt.provider)
+			} else {
+				got, err = ResolveEnv(tt.env, nil)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ResolveEnv() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveEnv() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
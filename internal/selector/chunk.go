@@ -0,0 +1,138 @@
+package selector
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// charsPerToken is the rough ratio used to estimate the tokens of serialized tools
+const charsPerToken = 4
+
+// estimateTokens approximates the prompt tokens a tool takes up, from the size of the JSON the
+// selection prompt embeds for it
+func estimateTokens(tool types.Tool) int {
+	encoded, _ := json.Marshal(tool)
+	return len(encoded)/charsPerToken + 1
+}
+
+// overBudget reports whether the tools are too large to select from in one LLM call
+func (s *LLM) overBudget(tools []types.Tool) bool {
+	if s.TokenBudget <= 0 || len(tools) < 2 {
+		return false
+	}
+	total := 0
+	for _, tool := range tools {
+		total += estimateTokens(tool)
+		if total > s.TokenBudget {
+			return true
+		}
+	}
+	return false
+}
+
+// chunks splits tools, in order, into runs whose estimated tokens stay within the budget. A tool
+// larger than the budget on its own gets a chunk to itself
+func (s *LLM) chunks(tools []types.Tool) [][]types.Tool {
+	var chunks [][]types.Tool
+	var current []types.Tool
+	size := 0
+	for _, tool := range tools {
+		tokens := estimateTokens(tool)
+		if len(current) > 0 && size+tokens > s.TokenBudget {
+			chunks = append(chunks, current)
+			current, size = nil, 0
+		}
+		current = append(current, tool)
+		size += tokens
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// mapChunks selects from the chunks concurrently, with at most ChunkConcurrency LLM calls in
+// flight, and returns the union of the selections, each chunk's picks in its own order and the
+// chunks in catalog order. The first failure stops chunks that have not started yet
+func (s *LLM) mapChunks(ctx context.Context, query string, tools []types.Tool) ([]types.Tool, error) {
+	limit := s.ChunkConcurrency
+	if limit <= 0 {
+		limit = DefaultChunkConcurrency
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	chunks := s.chunks(tools)
+	selections := make([][]types.Tool, len(chunks))
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+	slots := make(chan struct{}, limit)
+	for i, chunk := range chunks {
+		slots <- struct{}{}
+		if ctx.Err() != nil {
+			// A chunk failed, or the caller gave up, while this one waited for a slot
+			<-slots
+			fail(ctx.Err())
+			break
+		}
+		wg.Add(1)
+		go func(i int, chunk []types.Tool) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			selected, err := s.Provider.SelectBestTools(ctx, query, chunk)
+			if err != nil {
+				fail(err)
+				return
+			}
+			selections[i] = selected
+		}(i, chunk)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	var union []types.Tool
+	seen := make(map[string]bool)
+	for _, selected := range selections {
+		for _, tool := range selected {
+			if !seen[tool.Name] {
+				seen[tool.Name] = true
+				union = append(union, tool)
+			}
+		}
+	}
+	return union, nil
+}
+
+// selectChunked is the map-reduce selection for catalogs over the token budget: the provider
+// picks from each chunk, then ranks the union of those picks, chunking again while the union is
+// still over budget. When the chunks keep every tool the union cannot shrink, so it is ranked in
+// one call regardless of its size
+func (s *LLM) selectChunked(ctx context.Context, query string, tools []types.Tool) ([]types.Tool, error) {
+	candidates, err := s.mapChunks(ctx, query, tools)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return candidates, nil
+	}
+	if len(candidates) < len(tools) && s.overBudget(candidates) {
+		return s.selectChunked(ctx, query, candidates)
+	}
+	return s.Provider.SelectBestTools(ctx, query, candidates)
+}
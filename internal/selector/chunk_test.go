@@ -0,0 +1,161 @@
+package selector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// chunkProvider picks the tools whose names are in picks, taking delay per call, and records how
+// many calls overlapped at most; calls whose candidates include a tool named "fail" fail
+type chunkProvider struct {
+	picks map[string]bool
+	delay time.Duration
+
+	mu          sync.Mutex
+	calls       [][]string
+	inFlight    int
+	maxInFlight int
+}
+
+func (p *chunkProvider) SelectBestTools(ctx context.Context, query string, tools []types.Tool) ([]types.Tool, error) {
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	p.mu.Lock()
+	p.calls = append(p.calls, names)
+	p.inFlight++
+	if p.inFlight > p.maxInFlight {
+		p.maxInFlight = p.inFlight
+	}
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.inFlight--
+		p.mu.Unlock()
+	}()
+
+	select {
+	case <-time.After(p.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	var selected []types.Tool
+	for _, tool := range tools {
+		if tool.Name == "fail" {
+			return nil, errors.New("selection failed")
+		}
+		if p.picks[tool.Name] {
+			selected = append(selected, tool)
+		}
+	}
+	return selected, nil
+}
+
+func (p *chunkProvider) GetName() string { return "chunks" }
+
+// chunkTools returns tools named t0, t1, ... of roughly equal size
+func chunkTools(n int) []types.Tool {
+	tools := make([]types.Tool, n)
+	for i := range tools {
+		tools[i] = types.Tool{Name: fmt.Sprintf("t%d", i), Description: strings.Repeat("x", 40)}
+	}
+	return tools
+}
+
+func TestChunks(t *testing.T) {
+	tools := chunkTools(5)
+	size := estimateTokens(tools[0])
+
+	tests := []struct {
+		name   string
+		budget int
+		want   []int // chunk sizes
+	}{
+		{name: "one tool per chunk", budget: size, want: []int{1, 1, 1, 1, 1}},
+		{name: "two tools per chunk", budget: 2*size + 1, want: []int{2, 2, 1}},
+		{name: "everything fits", budget: 5 * size, want: []int{5}},
+		{name: "tool over budget gets its own chunk", budget: 1, want: []int{1, 1, 1, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, chunk := range (&LLM{TokenBudget: tt.budget}).chunks(tools) {
+				got = append(got, len(chunk))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("chunk sizes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectChunked(t *testing.T) {
+	tools := chunkTools(12)
+	budget := 3*estimateTokens(tools[0]) + 1 // three tools per chunk
+
+	tests := []struct {
+		name            string
+		concurrency     int
+		tools           []types.Tool
+		picks           []string
+		wantSelected    string
+		wantMaxInFlight int
+		wantErr         bool
+	}{
+		{name: "union is ranked", concurrency: 2, tools: tools, picks: []string{"t1", "t5", "t10"}, wantSelected: "t1,t5,t10", wantMaxInFlight: 2},
+		{name: "one chunk at a time", concurrency: 1, tools: tools, picks: []string{"t0"}, wantSelected: "t0", wantMaxInFlight: 1},
+		{name: "default concurrency", tools: tools, picks: []string{"t0"}, wantSelected: "t0", wantMaxInFlight: DefaultChunkConcurrency},
+		{name: "under budget is one call", tools: tools[:3], picks: []string{"t2"}, wantSelected: "t2", wantMaxInFlight: 1},
+		{name: "chunk failure", concurrency: 2, tools: append(chunkTools(6), types.Tool{Name: "fail"}), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &chunkProvider{picks: map[string]bool{}, delay: 20 * time.Millisecond}
+			for _, name := range tt.picks {
+				provider.picks[name] = true
+			}
+			s := &LLM{Provider: provider, TokenBudget: budget, ChunkConcurrency: tt.concurrency}
+
+			selected, err := s.Select(context.Background(), "query", tt.tools)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Select() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var names []string
+			for _, tool := range selected {
+				names = append(names, tool.Name)
+			}
+			if strings.Join(names, ",") != tt.wantSelected {
+				t.Errorf("Select() = %v, want %s", names, tt.wantSelected)
+			}
+			if provider.maxInFlight != tt.wantMaxInFlight {
+				t.Errorf("%d selection calls overlapped, want %d", provider.maxInFlight, tt.wantMaxInFlight)
+			}
+		})
+	}
+}
+
+func TestSelectChunkedStopsAfterFailure(t *testing.T) {
+	tools := append([]types.Tool{{Name: "fail"}}, chunkTools(9)...)
+	provider := &chunkProvider{delay: time.Millisecond}
+	s := &LLM{Provider: provider, TokenBudget: 3*estimateTokens(tools[1]) + 1, ChunkConcurrency: 1}
+
+	if _, err := s.Select(context.Background(), "query", tools); err == nil || err.Error() != "selection failed" {
+		t.Fatalf("Select() error = %v, want the chunk's failure", err)
+	}
+	if len(provider.calls) != 1 {
+		t.Errorf("%d chunks were selected from, want the failing one only", len(provider.calls))
+	}
+}
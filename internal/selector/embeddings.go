@@ -0,0 +1,247 @@
+package selector
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// Embeddings ranks tools by the cosine similarity between the query's embedding and each tool's
+type Embeddings struct {
+	Embedder types.Embedder
+	Cache    *EmbeddingCache
+	MaxTools int // 0 means no limit
+}
+
+// Select returns the most similar tools, best first
+func (s *Embeddings) Select(ctx context.Context, query string, tools []types.Tool) ([]types.Tool, error) {
+	vectors, err := s.Cache.lookup(ctx, s.Embedder, tools)
+	if err != nil {
+		return nil, err
+	}
+
+	queryVectors, err := s.Embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	if len(queryVectors) != 1 {
+		return nil, fmt.Errorf("expected 1 query embedding, got %d", len(queryVectors))
+	}
+
+	type scored struct {
+		tool  types.Tool
+		score float64
+	}
+	matches := make([]scored, len(tools))
+	for i, tool := range tools {
+		matches[i] = scored{tool: tool, score: cosine(queryVectors[0], vectors[i])}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	if s.MaxTools > 0 && len(matches) > s.MaxTools {
+		matches = matches[:s.MaxTools]
+	}
+
+	ranked := make([]types.Tool, len(matches))
+	for i, match := range matches {
+		ranked[i] = match.tool
+	}
+	return ranked, nil
+}
+
+// EmbeddingCache keeps tool embeddings so each tool text is embedded only once. A cache created
+// with NewFileEmbeddingCache also saves them to disk, so restarts only embed tools whose name or
+// description changed.
+type EmbeddingCache struct {
+	mu      sync.Mutex
+	vectors map[string][]float32 // hash of tool text -> embedding
+	hashes  map[string]string    // tool name -> hash of its current text
+	path    string               // file the cache is saved to; empty keeps it in memory
+	dirty   bool                 // the file is behind the cache because the last save failed
+}
+
+// embeddingFile is the on-disk form of an EmbeddingCache
+type embeddingFile struct {
+	Tools map[string]embeddingEntry `json:"tools"` // keyed by tool name
+}
+
+// embeddingEntry is one tool's saved embedding and the hash of the text it was computed from
+type embeddingEntry struct {
+	Hash      string    `json:"hash"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// NewEmbeddingCache creates an empty in-memory cache
+func NewEmbeddingCache() *EmbeddingCache {
+	return &EmbeddingCache{vectors: make(map[string][]float32), hashes: make(map[string]string)}
+}
+
+// NewFileEmbeddingCache creates an empty cache saved to path whenever new tools are embedded;
+// call Load to reuse the embeddings saved by a previous run
+func NewFileEmbeddingCache(path string) *EmbeddingCache {
+	c := NewEmbeddingCache()
+	c.path = path
+	return c
+}
+
+// Load reads the cache file, if there is one; entries are reused only while the hash of the
+// tool's text still matches
+func (c *EmbeddingCache) Load() error {
+	data, err := ioutil.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var file embeddingFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", c.path, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, entry := range file.Tools {
+		c.vectors[entry.Hash] = entry.Embedding
+		c.hashes[name] = entry.Hash
+	}
+	return nil
+}
+
+// Flush saves the cache file if a save failed since the last successful one, so embeddings are
+// not lost on shutdown; it does nothing for in-memory caches
+func (c *EmbeddingCache) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty || c.path == "" {
+		return nil
+	}
+	if err := c.save(); err != nil {
+		return fmt.Errorf("failed to save embedding cache %s: %w", c.path, err)
+	}
+	return nil
+}
+
+// save writes the current embedding of every known tool to the cache file, marking the cache
+// dirty until a write succeeds; the caller must hold c.mu
+func (c *EmbeddingCache) save() error {
+	c.dirty = true
+	file := embeddingFile{Tools: make(map[string]embeddingEntry, len(c.hashes))}
+	for name, hash := range c.hashes {
+		if vector, ok := c.vectors[hash]; ok {
+			file.Tools[name] = embeddingEntry{Hash: hash, Embedding: vector}
+		}
+	}
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename so a crash never leaves a truncated cache
+	tmp, err := ioutil.TempFile(filepath.Dir(c.path), ".embeddings-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// textHash identifies the text a tool embedding was computed from
+func textHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// lookup returns the embedding of every tool, embedding the ones not cached in a single request
+func (c *EmbeddingCache) lookup(ctx context.Context, embedder types.Embedder, tools []types.Tool) ([][]float32, error) {
+	texts := make([]string, len(tools))
+	hashes := make([]string, len(tools))
+	for i, tool := range tools {
+		texts[i] = tool.Name + ": " + tool.Description
+		hashes[i] = textHash(texts[i])
+	}
+
+	c.mu.Lock()
+	var missing, missingHashes []string
+	for i, hash := range hashes {
+		if _, ok := c.vectors[hash]; !ok {
+			missing = append(missing, texts[i])
+			missingHashes = append(missingHashes, hash)
+		}
+	}
+	c.mu.Unlock()
+
+	if len(missing) > 0 {
+		embedded, err := embedder.Embed(ctx, missing)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed tools: %w", err)
+		}
+		if len(embedded) != len(missing) {
+			return nil, fmt.Errorf("expected %d tool embeddings, got %d", len(missing), len(embedded))
+		}
+
+		c.mu.Lock()
+		for i, hash := range missingHashes {
+			c.vectors[hash] = embedded[i]
+		}
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	changed := false
+	vectors := make([][]float32, len(tools))
+	for i, tool := range tools {
+		vectors[i] = c.vectors[hashes[i]]
+		if c.hashes[tool.Name] != hashes[i] {
+			c.hashes[tool.Name] = hashes[i]
+			changed = true
+		}
+	}
+	if changed && c.path != "" {
+		if err := c.save(); err != nil {
+			log.Printf("Failed to save embedding cache %s: %v", c.path, err)
+		}
+	}
+	return vectors, nil
+}
+
+// cosine returns the cosine similarity of two vectors, or 0 when either is empty or their lengths differ
+func cosine(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
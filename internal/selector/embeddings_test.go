@@ -0,0 +1,93 @@
+package selector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// countingEmbedder embeds each text as a vector of its length and counts the texts embedded
+type countingEmbedder struct {
+	embedded int
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.embedded += len(texts)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text)), 1}
+	}
+	return vectors, nil
+}
+
+func TestEmbeddingCacheFlush(t *testing.T) {
+	tools := []types.Tool{{Name: "read", Description: "Read a file"}, {Name: "write", Description: "Write a file"}}
+
+	tests := []struct {
+		name           string
+		saveFails      bool // the cache directory is missing when the tools are embedded
+		removeFile     bool // the saved file is removed before Flush
+		wantFile       bool // a file exists after Flush
+		wantReembedded int  // tools a new cache loaded from the file has to embed again
+	}{
+		{name: "failed save is retried", saveFails: true, wantFile: true},
+		{name: "saved cache is not written again", removeFile: true, wantFile: false, wantReembedded: 2},
+		{name: "saved cache is reused", wantFile: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "cache")
+			if !tt.saveFails {
+				os.Mkdir(dir, 0o755)
+			}
+			path := filepath.Join(dir, "embeddings.json")
+
+			cache := NewFileEmbeddingCache(path)
+			if _, err := cache.lookup(context.Background(), &countingEmbedder{}, tools); err != nil {
+				t.Fatalf("lookup() error = %v", err)
+			}
+			if tt.saveFails {
+				os.Mkdir(dir, 0o755)
+			}
+			if tt.removeFile {
+				os.Remove(path)
+			}
+
+			if err := cache.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+			if _, err := os.Stat(path); (err == nil) != tt.wantFile {
+				t.Fatalf("cache file exists after Flush = %v, want %v", err == nil, tt.wantFile)
+			}
+
+			reloaded := NewFileEmbeddingCache(path)
+			if err := reloaded.Load(); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			embedder := &countingEmbedder{}
+			if _, err := reloaded.lookup(context.Background(), embedder, tools); err != nil {
+				t.Fatalf("lookup() after Load error = %v", err)
+			}
+			if embedder.embedded != tt.wantReembedded {
+				t.Errorf("embedded %d tools after Load, want %d", embedder.embedded, tt.wantReembedded)
+			}
+		})
+	}
+}
+
+func TestEmbeddingCacheFlushFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "embeddings.json")
+	cache := NewFileEmbeddingCache(path)
+	cache.lookup(context.Background(), &countingEmbedder{}, []types.Tool{{Name: "read"}})
+
+	if err := cache.Flush(); err == nil {
+		t.Error("Flush() into a missing directory succeeded, want an error")
+	}
+	if err := NewEmbeddingCache().Flush(); err != nil {
+		t.Errorf("Flush() of an in-memory cache error = %v", err)
+	}
+}
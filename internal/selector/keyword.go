@@ -0,0 +1,155 @@
+package selector
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// Keyword ranks tools by how well their names and descriptions match the query's words, weighting
+// rare words higher; it needs no LLM and tools matching no word are left out
+type Keyword struct {
+	MaxTools int // 0 means no limit
+}
+
+// Select returns the best matching tools, best first
+func (s *Keyword) Select(ctx context.Context, query string, tools []types.Tool) ([]types.Tool, error) {
+	return s.rank(query, tools), nil
+}
+
+// rank scores every tool against the query; ties keep name order so results are stable
+func (s *Keyword) rank(query string, tools []types.Tool) []types.Tool {
+	queryTerms := uniqueTerms(query)
+	if len(queryTerms) == 0 {
+		return nil
+	}
+
+	type document struct {
+		name        map[string]bool
+		description map[string]bool
+	}
+	docs := make([]document, len(tools))
+	frequency := make(map[string]int)
+	for i, tool := range tools {
+		docs[i] = document{name: termSet(tool.Name), description: termSet(tool.Description)}
+		seen := make(map[string]bool)
+		for term := range docs[i].name {
+			seen[term] = true
+		}
+		for term := range docs[i].description {
+			seen[term] = true
+		}
+		for term := range seen {
+			frequency[term]++
+		}
+	}
+
+	type scored struct {
+		tool  types.Tool
+		score float64
+	}
+	var matches []scored
+	for i, tool := range tools {
+		score := 0.0
+		for _, term := range queryTerms {
+			idf := math.Log(1 + float64(len(tools))/float64(1+frequency[term]))
+			if docs[i].name[term] {
+				// A word in the tool name says more about the tool than one in its description
+				score += 3 * idf
+			} else if docs[i].description[term] {
+				score += idf
+			}
+		}
+		if score > 0 {
+			matches = append(matches, scored{tool: tool, score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].tool.Name < matches[j].tool.Name
+	})
+	if s.MaxTools > 0 && len(matches) > s.MaxTools {
+		matches = matches[:s.MaxTools]
+	}
+
+	ranked := make([]types.Tool, len(matches))
+	for i, match := range matches {
+		ranked[i] = match.tool
+	}
+	return ranked
+}
+
+// stopWords are too common in queries and descriptions to say anything about a tool
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "i": true, "in": true, "is": true, "it": true, "me": true, "my": true,
+	"need": true, "of": true, "on": true, "or": true, "some": true, "the": true, "this": true,
+	"to": true, "want": true, "with": true,
+}
+
+// terms splits text into lower-case words, breaking snake_case, kebab-case and camelCase names
+func terms(text string) []string {
+	var words []string
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			word := stem(strings.ToLower(string(current)))
+			if !stopWords[word] {
+				words = append(words, word)
+			}
+			current = current[:0]
+		}
+	}
+
+	runes := []rune(text)
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]) {
+				flush()
+			}
+			current = append(current, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words
+}
+
+// stem drops common English suffixes so "files" matches "file" and "listing" matches "list"
+func stem(word string) string {
+	for _, suffix := range []string{"ing", "ed", "s"} {
+		if len(word) > len(suffix)+2 && strings.HasSuffix(word, suffix) {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+	return word
+}
+
+func termSet(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, term := range terms(text) {
+		set[term] = true
+	}
+	return set
+}
+
+func uniqueTerms(text string) []string {
+	var unique []string
+	seen := make(map[string]bool)
+	for _, term := range terms(text) {
+		if !seen[term] {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+	return unique
+}
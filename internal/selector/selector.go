@@ -0,0 +1,150 @@
+// Package selector provides the tool selection strategies behind discovery
+package selector
+
+import (
+	"context"
+	"fmt"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// Strategy names accepted in proxy.selector
+const (
+	StrategyLLM        = "llm"
+	StrategyKeyword    = "keyword"
+	StrategyEmbeddings = "embeddings"
+	StrategyHybrid     = "hybrid"
+)
+
+// DefaultHybridCandidates is how many keyword matches the hybrid strategy passes to the LLM
+const DefaultHybridCandidates = 20
+
+// DefaultChunkConcurrency is how many chunks of an over-budget catalog are selected from at once
+const DefaultChunkConcurrency = 4
+
+// Options configures the strategies that need more than an LLM provider
+type Options struct {
+	MaxTools         int             // tools returned by strategies that rank locally; 0 means no limit
+	HybridCandidates int             // keyword matches passed to the LLM by the hybrid strategy
+	Embeddings       *EmbeddingCache // tool embeddings shared across requests
+	TokenBudget      int             // estimated prompt tokens of candidate tools per LLM call; 0 means no limit
+	ChunkConcurrency int             // chunks selected from at once over the token budget (default 4)
+}
+
+// StreamingSelector is implemented by strategies that can report tools while still ranking
+type StreamingSelector interface {
+	types.Selector
+	SelectStream(ctx context.Context, query string, tools []types.Tool, emit func(types.Tool) error) error
+}
+
+// Validate reports whether name is a known strategy; empty selects the LLM strategy
+func Validate(name string) error {
+	switch name {
+	case "", StrategyLLM, StrategyKeyword, StrategyEmbeddings, StrategyHybrid:
+		return nil
+	default:
+		return fmt.Errorf("unknown selector %q (expected %s, %s, %s or %s)", name, StrategyLLM, StrategyKeyword, StrategyEmbeddings, StrategyHybrid)
+	}
+}
+
+// New builds the named strategy on top of an LLM provider
+func New(name string, provider types.LLMProvider, opts Options) (types.Selector, error) {
+	switch name {
+	case "", StrategyLLM:
+		return &LLM{Provider: provider, TokenBudget: opts.TokenBudget, ChunkConcurrency: opts.ChunkConcurrency}, nil
+
+	case StrategyKeyword:
+		return &Keyword{MaxTools: opts.MaxTools}, nil
+
+	case StrategyEmbeddings:
+		embedder, ok := provider.(types.Embedder)
+		if !ok {
+			return nil, fmt.Errorf("the %s selector needs an LLM provider that supports embeddings", StrategyEmbeddings)
+		}
+		cache := opts.Embeddings
+		if cache == nil {
+			cache = NewEmbeddingCache()
+		}
+		return &Embeddings{Embedder: embedder, Cache: cache, MaxTools: opts.MaxTools}, nil
+
+	case StrategyHybrid:
+		candidates := opts.HybridCandidates
+		if candidates <= 0 {
+			candidates = DefaultHybridCandidates
+		}
+		return &Hybrid{Keyword: Keyword{MaxTools: candidates}, LLM: LLM{Provider: provider, TokenBudget: opts.TokenBudget, ChunkConcurrency: opts.ChunkConcurrency}}, nil
+
+	default:
+		return nil, Validate(name)
+	}
+}
+
+// LLM asks the LLM provider to pick tools by name. Catalogs larger than TokenBudget are split into
+// chunks that are selected from separately before the union is ranked; see selectChunked
+type LLM struct {
+	Provider         types.LLMProvider
+	TokenBudget      int
+	ChunkConcurrency int // LLM calls in flight while selecting from chunks; DefaultChunkConcurrency when 0
+}
+
+// Select delegates to the provider
+func (s *LLM) Select(ctx context.Context, query string, tools []types.Tool) ([]types.Tool, error) {
+	if s.overBudget(tools) {
+		return s.selectChunked(ctx, query, tools)
+	}
+	return s.Provider.SelectBestTools(ctx, query, tools)
+}
+
+// SelectStream streams the provider's selection when it supports streaming, and otherwise emits
+// its complete selection. Over budget, only the final ranking of the chunks' union is streamed
+func (s *LLM) SelectStream(ctx context.Context, query string, tools []types.Tool, emit func(types.Tool) error) error {
+	if s.overBudget(tools) {
+		candidates, err := s.mapChunks(ctx, query, tools)
+		if err != nil || len(candidates) == 0 {
+			return err
+		}
+		if len(candidates) < len(tools) {
+			return s.SelectStream(ctx, query, candidates, emit)
+		}
+		tools = candidates
+	}
+
+	if streaming, ok := s.Provider.(types.StreamingLLMProvider); ok {
+		return streaming.SelectBestToolsStream(ctx, query, tools, emit)
+	}
+
+	selected, err := s.Provider.SelectBestTools(ctx, query, tools)
+	if err != nil {
+		return err
+	}
+	for _, tool := range selected {
+		if err := emit(tool); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Hybrid narrows the catalog with keyword matching and lets the LLM rank the remaining candidates;
+// when no tool matches any keyword the LLM sees the whole catalog
+type Hybrid struct {
+	Keyword Keyword
+	LLM     LLM
+}
+
+// Select ranks the keyword candidates with the LLM
+func (s *Hybrid) Select(ctx context.Context, query string, tools []types.Tool) ([]types.Tool, error) {
+	return s.LLM.Select(ctx, query, s.candidates(query, tools))
+}
+
+// SelectStream streams the LLM's ranking of the keyword candidates
+func (s *Hybrid) SelectStream(ctx context.Context, query string, tools []types.Tool, emit func(types.Tool) error) error {
+	return s.LLM.SelectStream(ctx, query, s.candidates(query, tools), emit)
+}
+
+func (s *Hybrid) candidates(query string, tools []types.Tool) []types.Tool {
+	if candidates := s.Keyword.rank(query, tools); len(candidates) > 0 {
+		return candidates
+	}
+	return tools
+}
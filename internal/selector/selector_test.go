@@ -0,0 +1,111 @@
+package selector
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// vocabularyProvider embeds texts as counts of a few words and, as an LLM, picks the candidates
+// whose names contain picks, recording the candidates of each call
+type vocabularyProvider struct {
+	picks      string
+	candidates [][]string
+}
+
+var vocabulary = []string{"file", "email", "issue"}
+
+func (p *vocabularyProvider) SelectBestTools(ctx context.Context, query string, tools []types.Tool) ([]types.Tool, error) {
+	var names []string
+	var selected []types.Tool
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+		if strings.Contains(tool.Name, p.picks) {
+			selected = append(selected, tool)
+		}
+	}
+	p.candidates = append(p.candidates, names)
+	return selected, nil
+}
+
+func (p *vocabularyProvider) GetName() string { return "vocabulary" }
+
+func (p *vocabularyProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = make([]float32, len(vocabulary))
+		for j, word := range vocabulary {
+			vectors[i][j] = float32(strings.Count(strings.ToLower(text), word))
+		}
+	}
+	return vectors, nil
+}
+
+// llmOnly hides the embedding support of a provider
+type llmOnly struct{ types.LLMProvider }
+
+var strategyCatalog = []types.Tool{
+	{Name: "read_file", Description: "Read a file from disk"},
+	{Name: "write_file", Description: "Write a file to disk"},
+	{Name: "send_email", Description: "Send an email message"},
+	{Name: "list_issues", Description: "List the issues of a repository"},
+}
+
+func TestStrategies(t *testing.T) {
+	tests := []struct {
+		strategy       string
+		want           string // comma-separated selection
+		wantCandidates string // candidates the LLM saw, "" when it was not asked
+	}{
+		{strategy: "", want: "read_file,write_file", wantCandidates: "read_file,write_file,send_email,list_issues"},
+		{strategy: StrategyLLM, want: "read_file,write_file", wantCandidates: "read_file,write_file,send_email,list_issues"},
+		{strategy: StrategyKeyword, want: "read_file,write_file"},
+		{strategy: StrategyEmbeddings, want: "read_file,write_file"},
+		{strategy: StrategyHybrid, want: "read_file,write_file", wantCandidates: "read_file,write_file"},
+	}
+
+	for _, tt := range tests {
+		name := tt.strategy
+		if name == "" {
+			name = "default"
+		}
+		t.Run(name, func(t *testing.T) {
+			provider := &vocabularyProvider{picks: "file"}
+			sel, err := New(tt.strategy, provider, Options{MaxTools: 2})
+			if err != nil {
+				t.Fatalf("New(%q) error = %v", tt.strategy, err)
+			}
+
+			tools, err := sel.Select(context.Background(), "read the config file", strategyCatalog)
+			if err != nil {
+				t.Fatalf("Select() error = %v", err)
+			}
+			var names []string
+			for _, tool := range tools {
+				names = append(names, tool.Name)
+			}
+			if got := strings.Join(names, ","); got != tt.want {
+				t.Errorf("Select() = %s, want %s", got, tt.want)
+			}
+
+			var candidates []string
+			for _, call := range provider.candidates {
+				candidates = append(candidates, strings.Join(call, ","))
+			}
+			if got := strings.Join(candidates, "|"); got != tt.wantCandidates {
+				t.Errorf("LLM saw candidates %q, want %q", got, tt.wantCandidates)
+			}
+		})
+	}
+}
+
+func TestNewRejectsUnusableStrategies(t *testing.T) {
+	if _, err := New("fuzzy", &vocabularyProvider{}, Options{}); err == nil || !strings.Contains(err.Error(), `unknown selector "fuzzy"`) {
+		t.Errorf("New(fuzzy) error = %v, want an unknown selector", err)
+	}
+	if _, err := New(StrategyEmbeddings, llmOnly{&vocabularyProvider{}}, Options{}); err == nil {
+		t.Error("New(embeddings) with a provider that cannot embed succeeded, want an error")
+	}
+}
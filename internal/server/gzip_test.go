@@ -0,0 +1,113 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGzipMiddleware(t *testing.T) {
+	large := strings.Repeat("tool ", 100)
+
+	tests := []struct {
+		name           string
+		minBytes       int
+		acceptEncoding string
+		status         int
+		encoding       string // Content-Encoding set by the handler itself
+		flushFirst     bool   // the handler flushes after its first, small write
+		body           string
+		wantGzip       bool
+		wantVary       bool
+	}{
+		{name: "large response", minBytes: 100, acceptEncoding: "gzip, deflate", body: large, wantGzip: true, wantVary: true},
+		{name: "small response", minBytes: 100, acceptEncoding: "gzip", body: "OK", wantVary: true},
+		{name: "client without gzip", minBytes: 100, acceptEncoding: "deflate", body: large, wantVary: true},
+		{name: "client refusing gzip", minBytes: 100, acceptEncoding: "gzip;q=0", body: large, wantVary: true},
+		{name: "error status is kept", minBytes: 100, acceptEncoding: "gzip", status: http.StatusNotFound, body: large, wantGzip: true, wantVary: true},
+		{name: "flushed before the threshold", minBytes: 100, acceptEncoding: "gzip", flushFirst: true, body: large, wantVary: true},
+		{name: "already encoded", minBytes: 100, acceptEncoding: "gzip", encoding: "br", body: large, wantVary: true},
+		{name: "disabled", minBytes: -1, acceptEncoding: "gzip", body: large},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{opts: Options{GzipMinBytes: tt.minBytes}}
+			handler := s.gzipMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				body := tt.body
+				if tt.flushFirst {
+					io.WriteString(w, body[:10])
+					w.(http.Flusher).Flush()
+					body = body[10:]
+				}
+				io.WriteString(w, body)
+			}))
+
+			req := httptest.NewRequest("GET", "/api/v1/tools", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			wantStatus := tt.status
+			if wantStatus == 0 {
+				wantStatus = http.StatusOK
+			}
+			if recorder.Code != wantStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, wantStatus)
+			}
+			if vary := recorder.Header().Get("Vary") == "Accept-Encoding"; vary != tt.wantVary {
+				t.Errorf("Vary = %q, want Accept-Encoding = %v", recorder.Header().Get("Vary"), tt.wantVary)
+			}
+
+			body := recorder.Body.String()
+			if gzipped := recorder.Header().Get("Content-Encoding") == "gzip"; gzipped != tt.wantGzip {
+				t.Fatalf("Content-Encoding = %q, want gzip = %v", recorder.Header().Get("Content-Encoding"), tt.wantGzip)
+			}
+			if tt.wantGzip {
+				reader, err := gzip.NewReader(recorder.Body)
+				if err != nil {
+					t.Fatalf("invalid gzip body: %v", err)
+				}
+				data, err := io.ReadAll(reader)
+				if err != nil {
+					t.Fatalf("invalid gzip body: %v", err)
+				}
+				body = string(data)
+			}
+			if body != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: "", want: false},
+		{header: "gzip", want: true},
+		{header: "deflate, gzip;q=0.5", want: true},
+		{header: "gzip;q=0", want: false},
+		{header: "gzip; q=0.0", want: false},
+		{header: "x-gzip", want: false},
+		{header: "identity", want: false},
+	}
+	for _, tt := range tests {
+		if got := acceptsGzip(tt.header); got != tt.want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
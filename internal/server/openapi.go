@@ -0,0 +1,615 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"mcp-smart-proxy/internal/auth"
+	"mcp-smart-proxy/pkg/types"
+)
+
+// swaggerUIPage renders the served OpenAPI document with Swagger UI from a CDN
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>MCP Smart Proxy API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>SwaggerUIBundle({url: "openapi.json", dom_id: "#swagger-ui"});</script>
+</body>
+</html>`
+
+// handleOpenAPI serves the OpenAPI 3 description of the HTTP API
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	s.writeJSONResponse(w, r, s.openAPISpec())
+}
+
+// handleDocs serves a Swagger UI page for the OpenAPI document
+func (s *Server) handleDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(swaggerUIPage))
+}
+
+// openAPISpec builds the OpenAPI document, deriving request and response schemas from the types package
+func (s *Server) openAPISpec() map[string]interface{} {
+	gen := &schemaGenerator{components: make(map[string]interface{})}
+
+	proxyResponse := gen.ref(reflect.TypeOf(types.ProxyResponse{}))
+	jsonResponse := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"description": description,
+			"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": proxyResponse}},
+		}
+	}
+	textResponse := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"description": description,
+			"content":     map[string]interface{}{"text/plain": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}},
+		}
+	}
+	jsonBody := func(t reflect.Type) map[string]interface{} {
+		return map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": gen.ref(t)}},
+		}
+	}
+	providerHeaderParameter := map[string]interface{}{
+		"name": ProviderHeader, "in": "header", "required": false,
+		"description": "LLM provider for this request, overriding the body's provider",
+		"schema":      map[string]interface{}{"type": "string"},
+	}
+
+	apiVersionParameter := map[string]interface{}{
+		"name": "apiVersion", "in": "query", "required": false,
+		"description": "Envelope version: 1 (legacy) or 2 (current, the default); also accepted as a version parameter in Accept",
+		"schema":      map[string]interface{}{"type": "string", "enum": []interface{}{types.APIVersionLegacy, types.APIVersionCurrent}},
+	}
+
+	paths := map[string]interface{}{
+		"/tools": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary": "List all discovered tools, or with since only the changes after it",
+				"parameters": []interface{}{
+					map[string]interface{}{
+						"name": "since", "in": "query", "required": false,
+						"description": "RFC 3339 time; when given, the answer is a ToolListing delta (use its lastSync as the next since)",
+						"schema":      map[string]interface{}{"type": "string", "format": "date-time"},
+					},
+					apiVersionParameter,
+				},
+				"responses": map[string]interface{}{
+					"200": jsonResponse("All cached tools in recommendedTools; with since, the tools added or changed after it plus removed names"),
+					"400": textResponse("Invalid since or apiVersion"),
+					"406": textResponse("Unsupported version in Accept"),
+				},
+			},
+		},
+		"/schema/{tool}": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary": "Get a single tool's input schema, description and annotations",
+				"parameters": []interface{}{
+					map[string]interface{}{
+						"name": "tool", "in": "path", "required": true,
+						"schema": map[string]interface{}{"type": "string"},
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "The cached tool",
+						"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": gen.ref(reflect.TypeOf(types.Tool{}))}},
+					},
+					"404": textResponse("Unknown tool"),
+				},
+			},
+		},
+		"/example/{tool}": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary": "Get sample arguments for a tool generated from its input schema",
+				"parameters": []interface{}{
+					map[string]interface{}{
+						"name": "tool", "in": "path", "required": true,
+						"schema": map[string]interface{}{"type": "string"},
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "A /use request body with the sample arguments",
+						"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": gen.ref(reflect.TypeOf(types.ToolRequest{}))}},
+					},
+					"404": textResponse("Unknown tool"),
+				},
+			},
+		},
+		"/export/openai": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary": "Export the tool catalog as OpenAI function definitions",
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "The tools field of a chat completion request, with renamed tools mapped back in names",
+						"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": gen.ref(reflect.TypeOf(types.OpenAIToolExport{}))}},
+					},
+				},
+			},
+		},
+		"/export/anthropic": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary": "Export the tool catalog in Anthropic's tool-use format",
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "The tools field of a Messages API request, with renamed tools mapped back in names",
+						"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": gen.ref(reflect.TypeOf(types.AnthropicToolExport{}))}},
+					},
+				},
+			},
+		},
+		"/discover": map[string]interface{}{
+			"post": map[string]interface{}{
+				"summary":     "Recommend the most relevant tools for a query",
+				"requestBody": jsonBody(reflect.TypeOf(types.ProxyRequest{})),
+				"parameters": []interface{}{
+					map[string]interface{}{
+						"name": "debug", "in": "query", "required": false,
+						"description": "Include the LLM prompt and raw response (requires proxy.debug)",
+						"schema":      map[string]interface{}{"type": "boolean"},
+					},
+					providerHeaderParameter,
+					apiVersionParameter,
+				},
+				"responses": map[string]interface{}{
+					"200": jsonResponse("Recommended tools ranked by relevance"),
+					"400": textResponse("Invalid request, unknown provider, unknown tier or unsupported apiVersion"),
+					"403": textResponse("Debug discovery is disabled"),
+					"406": textResponse("Unsupported version in Accept"),
+					"413": textResponse("Request body too large"),
+					"408": textResponse("Request body not received in time"),
+					"415": textResponse("Content-Type is not application/json"),
+					"503": textResponse("No tools available to choose from"),
+					"504": textResponse("Tool selection exceeded proxy.llmTimeout"),
+				},
+			},
+		},
+		"/discover/stream": map[string]interface{}{
+			"post": map[string]interface{}{
+				"summary":     "Stream recommended tools as server-sent events while the LLM ranks them",
+				"requestBody": jsonBody(reflect.TypeOf(types.ProxyRequest{})),
+				"parameters":  []interface{}{providerHeaderParameter},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "A \"tool\" event per tool in ranked order, then \"done\" (or \"error\" if selection fails part way)",
+						"content":     map[string]interface{}{"text/event-stream": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}},
+					},
+					"400": textResponse("Invalid request, unknown provider or unknown tier"),
+					"413": textResponse("Request body too large"),
+					"408": textResponse("Request body not received in time"),
+					"415": textResponse("Content-Type is not application/json"),
+					"503": textResponse("No tools available to choose from"),
+					"504": textResponse("Tool selection exceeded proxy.llmTimeout"),
+				},
+			},
+		},
+		"/discover/batch": map[string]interface{}{
+			"post": map[string]interface{}{
+				"summary":     "Recommend tools for several queries at once",
+				"requestBody": jsonBody(reflect.TypeOf(types.BatchDiscoveryRequest{})),
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "One result per query, in request order; failed selections carry an error",
+						"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": gen.ref(reflect.TypeOf(types.BatchDiscoveryResponse{}))}},
+					},
+					"400": textResponse("No queries, too many queries, unknown provider or unknown tier"),
+					"413": textResponse("Request body too large"),
+					"408": textResponse("Request body not received in time"),
+					"415": textResponse("Content-Type is not application/json"),
+					"503": textResponse("No tools available to choose from"),
+				},
+			},
+		},
+		"/use/{tool}": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary": "Execute a read-only tool with arguments from the query string",
+				"parameters": []interface{}{
+					map[string]interface{}{
+						"name": "tool", "in": "path", "required": true,
+						"schema": map[string]interface{}{"type": "string"},
+					},
+					apiVersionParameter,
+					map[string]interface{}{
+						"name": "arguments", "in": "query", "required": false,
+						"description": "Tool arguments as parameters, converted to the types of the input schema; repeat a parameter for an array",
+						"schema":      map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}},
+						"style":       "form", "explode": true,
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": jsonResponse("Tool result"),
+					"400": textResponse("Unsupported apiVersion"),
+					"404": textResponse("Unknown tool"),
+					"405": textResponse("The tool is not read-only; use POST"),
+					"406": textResponse("Unsupported version in Accept"),
+					"422": jsonResponse("Required arguments missing, listed in missing, or the tool answered with an error (errorClass tool_error)"),
+					"500": jsonResponse("Tool execution failed"),
+					"503": jsonResponse("No working server for the tool (errorClass unavailable)"),
+					"504": jsonResponse("Tool call timed out (errorClass timeout)"),
+				},
+			},
+			"post": map[string]interface{}{
+				"summary":     "Execute a tool",
+				"requestBody": jsonBody(reflect.TypeOf(types.ToolRequest{})),
+				"parameters": []interface{}{
+					map[string]interface{}{
+						"name": "tool", "in": "path", "required": true,
+						"schema": map[string]interface{}{"type": "string"},
+					},
+					apiVersionParameter,
+				},
+				"responses": map[string]interface{}{
+					"200": jsonResponse("Tool result"),
+					"400": textResponse("Invalid request or unsupported apiVersion"),
+					"403": jsonResponse("Argument key rejected by policy"),
+					"406": textResponse("Unsupported version in Accept"),
+					"412": jsonResponse("Destructive tool called without confirm"),
+					"413": textResponse("Request body too large"),
+					"408": textResponse("Request body not received in time"),
+					"415": textResponse("Content-Type is not application/json"),
+					"404": jsonResponse("Unknown tool (errorClass not_found)"),
+					"422": jsonResponse("Required arguments missing, listed in missing, or the tool answered with an error (errorClass tool_error)"),
+					"500": jsonResponse("Tool execution failed"),
+					"409": jsonResponse("Another call with this callId is in flight"),
+					"499": jsonResponse("Call cancelled through DELETE /calls/{id}"),
+					"503": jsonResponse("No working server for the tool (errorClass unavailable)"),
+					"504": jsonResponse("Tool call timed out (errorClass timeout)"),
+				},
+			},
+		},
+		"/use": map[string]interface{}{
+			"post": map[string]interface{}{
+				"summary":     "Execute the tool named in the body's tool field",
+				"requestBody": jsonBody(reflect.TypeOf(types.ToolRequest{})),
+				"parameters":  []interface{}{apiVersionParameter},
+				"responses": map[string]interface{}{
+					"200": jsonResponse("Tool result"),
+					"400": textResponse("Invalid request, missing tool or unsupported apiVersion"),
+					"403": jsonResponse("Argument key rejected by policy"),
+					"406": textResponse("Unsupported version in Accept"),
+					"412": jsonResponse("Destructive tool called without confirm"),
+					"413": textResponse("Request body too large"),
+					"408": textResponse("Request body not received in time"),
+					"415": textResponse("Content-Type is not application/json"),
+					"404": jsonResponse("Unknown tool (errorClass not_found)"),
+					"422": jsonResponse("Required arguments missing, listed in missing, or the tool answered with an error (errorClass tool_error)"),
+					"500": jsonResponse("Tool execution failed"),
+					"409": jsonResponse("Another call with this callId is in flight"),
+					"499": jsonResponse("Call cancelled through DELETE /calls/{id}"),
+					"503": jsonResponse("No working server for the tool (errorClass unavailable)"),
+					"504": jsonResponse("Tool call timed out (errorClass timeout)"),
+				},
+			},
+		},
+		"/refresh": map[string]interface{}{
+			"post": map[string]interface{}{
+				"summary": "Reconnect to all servers and rebuild the tool cache",
+				"parameters": []interface{}{
+					map[string]interface{}{
+						"name": "soft", "in": "query", "required": false,
+						"description": "Re-list tools over existing connections, reconnecting only servers that do not answer",
+						"schema":      map[string]interface{}{"type": "boolean"},
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": textResponse("Tools refreshed"),
+					"403": textResponse("The API key is not an admin's"),
+				},
+			},
+		},
+		"/refresh/diff": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary": "Tools added, removed or changed by the last refresh",
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Diff of the last refresh",
+						"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": gen.ref(reflect.TypeOf(types.ToolDiff{}))}},
+					},
+					"404": textResponse("No refresh has run yet"),
+				},
+			},
+		},
+		"/tools/watch": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary": "Wait for the tool cache to change, then return the listing",
+				"parameters": []interface{}{
+					map[string]interface{}{
+						"name": "since", "in": "query", "required": false,
+						"description": "RFC 3339 time of the last sync the client has seen (lastSync of the previous answer)",
+						"schema":      map[string]interface{}{"type": "string", "format": "date-time"},
+					},
+					map[string]interface{}{
+						"name": "timeout", "in": "query", "required": false,
+						"description": "How long to wait, e.g. 60s (default 30s, at most 5m)",
+						"schema":      map[string]interface{}{"type": "string"},
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Tools after a sync newer than since",
+						"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": gen.ref(reflect.TypeOf(types.ToolListing{}))}},
+					},
+					"304": textResponse("No sync within the timeout"),
+					"400": textResponse("Invalid since or timeout"),
+				},
+			},
+		},
+		"/config": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary": "Effective running config, with env values and tenant API keys redacted",
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Config as loaded, with runtime server changes and defaults applied",
+						"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": gen.ref(reflect.TypeOf(types.MCPConfig{}))}},
+					},
+					"403": textResponse("The API key is not an admin's"),
+				},
+			},
+		},
+		"/servers": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary": "Connection, tool count and circuit breaker state of each configured server",
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Status of each server",
+						"content": map[string]interface{}{"application/json": map[string]interface{}{"schema": map[string]interface{}{
+							"type": "array", "items": gen.ref(reflect.TypeOf(types.ServerStatus{})),
+						}}},
+					},
+				},
+			},
+			"post": map[string]interface{}{
+				"summary":     "Start a new MCP server and merge its tools into the cache",
+				"requestBody": jsonBody(reflect.TypeOf(types.MCPServer{})),
+				"responses": map[string]interface{}{
+					"201": map[string]interface{}{
+						"description": "Status of the added server",
+						"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": gen.ref(reflect.TypeOf(types.ServerStatus{}))}},
+					},
+					"400": textResponse("Missing name, or missing command for a stdio server"),
+					"403": textResponse("Server management is disabled, or the API key is not an admin's"),
+					"409": textResponse("A server with this name already exists"),
+					"408": textResponse("Request body not received in time"),
+					"415": textResponse("Content-Type is not application/json"),
+					"500": textResponse("The server failed to start"),
+				},
+			},
+		},
+		"/servers/{name}": map[string]interface{}{
+			"delete": map[string]interface{}{
+				"summary": "Stop an MCP server and remove its tools",
+				"parameters": []interface{}{
+					map[string]interface{}{
+						"name": "name", "in": "path", "required": true,
+						"schema": map[string]interface{}{"type": "string"},
+					},
+				},
+				"responses": map[string]interface{}{
+					"204": map[string]interface{}{"description": "Server removed"},
+					"403": textResponse("Server management is disabled, or the API key is not an admin's"),
+					"404": textResponse("Unknown server"),
+				},
+			},
+		},
+		"/servers/{name}/rpc": map[string]interface{}{
+			"post": map[string]interface{}{
+				"summary": "Forward a raw JSON-RPC request to one server (requires proxy.passthrough)",
+				"parameters": []interface{}{
+					map[string]interface{}{
+						"name": "name", "in": "path", "required": true,
+						"schema": map[string]interface{}{"type": "string"},
+					},
+				},
+				"requestBody": jsonBody(reflect.TypeOf(types.RPCRequest{})),
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "The server's JSON-RPC response, which may carry an error member",
+						"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": map[string]interface{}{"type": "object"}}},
+					},
+					"400": textResponse("Missing method, or initialize, tools/call or a notification"),
+					"403": textResponse("Passthrough is disabled"),
+					"404": textResponse("Unknown server"),
+					"415": textResponse("Content-Type is not application/json"),
+					"501": textResponse("The server's transport cannot send raw requests"),
+					"503": textResponse("The server is not connected"),
+					"504": textResponse("The server did not answer in time"),
+				},
+			},
+		},
+		"/calls": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary": "List tool calls in flight; tenants other than admins only see their own",
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Running calls, oldest first",
+						"content": map[string]interface{}{"application/json": map[string]interface{}{"schema": map[string]interface{}{
+							"type": "array", "items": gen.ref(reflect.TypeOf(types.InFlightCall{})),
+						}}},
+					},
+				},
+			},
+		},
+		"/calls/{id}": map[string]interface{}{
+			"delete": map[string]interface{}{
+				"summary": "Cancel an in-flight tool call",
+				"parameters": []interface{}{
+					map[string]interface{}{
+						"name": "id", "in": "path", "required": true,
+						"schema": map[string]interface{}{"type": "string"},
+					},
+				},
+				"responses": map[string]interface{}{
+					"204": map[string]interface{}{"description": "Call cancelled"},
+					"404": textResponse("No call with this ID is in flight, or it belongs to another tenant"),
+				},
+			},
+		},
+		"/stats": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary": "Invocation counts, error rates and last use per tool and per server",
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Usage since the proxy started",
+						"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": gen.ref(reflect.TypeOf(types.UsageStats{}))}},
+					},
+				},
+			},
+		},
+		"/health": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary": "Liveness check",
+				"parameters": []interface{}{
+					map[string]interface{}{
+						"name": "detail", "in": "query", "required": false,
+						"description": "Return tool counts, connected servers and last sync time as JSON; needs an API key when tenants are configured",
+						"schema":      map[string]interface{}{"type": "boolean"},
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "OK, or the health report when detail=true",
+						"content": map[string]interface{}{
+							"text/plain":       map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+							"application/json": map[string]interface{}{"schema": gen.ref(reflect.TypeOf(types.HealthReport{}))},
+						},
+					},
+				},
+			},
+		},
+		"/health/deep": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary": "Call each server's health check tool and report which answered as expected",
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Deep health per server with a healthCheck; status is degraded when any check failed; servers whose concurrency slots are all taken are reported busy and unchecked",
+						"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": gen.ref(reflect.TypeOf(types.DeepHealthReport{}))}},
+					},
+				},
+			},
+		},
+		"/ready": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary": "Readiness check",
+				"responses": map[string]interface{}{
+					"200": textResponse("Initial discovery complete and tools available"),
+					"503": textResponse("Discovery not yet complete or no tools available"),
+				},
+			},
+		},
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "MCP Smart Proxy API",
+			"description": "LLM-powered tool discovery and routing across MCP servers",
+			"version":     "1.0.0",
+		},
+		"servers": []interface{}{map[string]interface{}{"url": s.opts.PathPrefix}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": gen.components,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer"},
+				"apiKeyAuth": map[string]interface{}{"type": "apiKey", "in": "header", "name": auth.KeyHeader},
+			},
+		},
+		// An API key is only required when tenants are configured
+		"security": []interface{}{
+			map[string]interface{}{"bearerAuth": []interface{}{}},
+			map[string]interface{}{"apiKeyAuth": []interface{}{}},
+			map[string]interface{}{},
+		},
+	}
+}
+
+// schemaGenerator derives OpenAPI schemas from Go types using their json tags
+type schemaGenerator struct {
+	components map[string]interface{}
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(types.Duration(0))
+)
+
+// ref returns a schema for t, registering named structs as components referenced by $ref
+func (g *schemaGenerator) ref(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == durationType:
+		return map[string]interface{}{"type": "string", "example": "30s"}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := g.components[name]; !ok {
+			g.components[name] = nil // reserve the name before recursing
+			g.components[name] = g.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.ref(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.ref(t.Elem())}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// object builds an object schema from a struct's exported, json-tagged fields
+func (g *schemaGenerator) object(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = g.ref(field.Type)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+
+	"github.com/gorilla/mux"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	server := newTestServer(t, tenantConfig(), Options{}, map[string]types.MCPClient{"files": newFakeClient("read")})
+
+	// The document is public: integrators fetch it before they have a key
+	status, body := request(t, server, "GET", "/openapi.json", "", "")
+	if status != 200 {
+		t.Fatalf("GET /openapi.json = %d %q", status, body)
+	}
+	var spec struct {
+		OpenAPI    string                                       `json:"openapi"`
+		Servers    []struct{ URL string }                       `json:"servers"`
+		Paths      map[string]map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal([]byte(body), &spec); err != nil {
+		t.Fatalf("invalid spec %q: %v", body, err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") || len(spec.Servers) != 1 || spec.Servers[0].URL != "/api/v1" {
+		t.Errorf("spec version %q with servers %+v, want OpenAPI 3 served under /api/v1", spec.OpenAPI, spec.Servers)
+	}
+
+	// Every API route is documented with its method
+	router := NewWithOptions(nil, Options{}).Handler().(*mux.Router)
+	variable := regexp.MustCompile(`\{(\w+):[^}]*\}`)
+	routes := 0
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		methods, _ := route.GetMethods()
+		if err != nil || !strings.HasPrefix(path, "/api/v1/") {
+			return nil
+		}
+		path = variable.ReplaceAllString(strings.TrimPrefix(path, "/api/v1"), "{$1}")
+		for _, method := range methods {
+			routes++
+			if _, ok := spec.Paths[path][strings.ToLower(method)]; !ok {
+				t.Errorf("%s %s is not documented", method, path)
+			}
+		}
+		return nil
+	})
+	if routes == 0 {
+		t.Fatal("found no API routes to check")
+	}
+
+	// Every schema reference resolves
+	for _, ref := range regexp.MustCompile(`"\$ref":"#/components/schemas/(\w+)"`).FindAllStringSubmatch(body, -1) {
+		if _, ok := spec.Components.Schemas[ref[1]]; !ok {
+			t.Errorf("reference to missing schema %s", ref[1])
+		}
+	}
+	for _, name := range []string{"ProxyRequest", "ProxyResponse", "ToolRequest", "Tool"} {
+		if _, ok := spec.Components.Schemas[name]; !ok {
+			t.Errorf("schema %s is missing", name)
+		}
+	}
+}
+
+func TestDocsPage(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	NewWithOptions(nil, Options{}).Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/docs", nil))
+	if recorder.Code != 200 || !strings.Contains(recorder.Body.String(), `url: "openapi.json"`) {
+		t.Errorf("GET /docs = %d %q, want a Swagger UI page loading openapi.json", recorder.Code, recorder.Body.String())
+	}
+}
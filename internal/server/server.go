@@ -2,49 +2,273 @@
 package server
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"os"
+	"reflect"
+	"strings"
 	"time"
 
+	"mcp-smart-proxy/internal/auth"
+	"mcp-smart-proxy/internal/requestid"
 	"mcp-smart-proxy/pkg/types"
 
 	"github.com/gorilla/mux"
 )
 
+const (
+	// DefaultMaxBodyBytes is the request body limit used when Options.MaxBodyBytes is unset
+	DefaultMaxBodyBytes = 1 << 20
+	// DefaultPathPrefix is the base path of the API when Options.PathPrefix is unset
+	DefaultPathPrefix = "/api/v1"
+	// DefaultGzipMinBytes is the smallest response compressed when Options.GzipMinBytes is unset
+	DefaultGzipMinBytes = 1024
+	// DefaultReadHeaderTimeout bounds reading request headers when Options.ReadHeaderTimeout is unset
+	DefaultReadHeaderTimeout = 10 * time.Second
+	// DefaultBodyReadTimeout bounds reading a JSON request body when Options.BodyReadTimeout is unset
+	DefaultBodyReadTimeout = 10 * time.Second
+	// MaxBatchQueries caps the queries of one /discover/batch request
+	MaxBatchQueries = 20
+	// DefaultWatchTimeout is how long /tools/watch waits for a change when no timeout is given
+	DefaultWatchTimeout = 30 * time.Second
+	// MaxWatchTimeout caps the timeout a /tools/watch client may ask for
+	MaxWatchTimeout = 5 * time.Minute
+	// ShutdownTimeout is how long Run lets in-flight requests finish once its context is done
+	ShutdownTimeout = 10 * time.Second
+	// ProviderHeader names the LLM provider for one discovery request, overriding its body
+	ProviderHeader = "X-LLM-Provider"
+	// CallIDHeader carries the ID a /use call is tracked under, so it can be cancelled while it runs
+	CallIDHeader = "X-Call-ID"
+
+	// statusClientClosedRequest reports a tool call cancelled through DELETE /calls/{id}, following
+	// the nginx and grpc-gateway convention for cancelled requests
+	statusClientClosedRequest = 499
+
+	// toolListBufferSize is how much of a streamed tool list is gathered before each write
+	toolListBufferSize = 32 << 10
+)
+
 // Server wraps the smart proxy with HTTP endpoints
 type Server struct {
 	proxy ProxyInterface
+	opts  Options
+}
+
+// Options configures the HTTP server
+type Options struct {
+	MaxBodyBytes int64  // maximum accepted request body size in bytes
+	PathPrefix   string // base path the API routes are mounted under, e.g. "/mcp/api/v1"
+	GzipMinBytes int    // responses at least this large are gzip-compressed for clients that accept it; negative disables
+	StrictJSON   bool   // reject request bodies carrying fields the endpoint does not know
+
+	// Limits that close connections of clients trickling a request in; negative disables
+	ReadHeaderTimeout time.Duration // time allowed to read a request's headers
+	BodyReadTimeout   time.Duration // time allowed to read a JSON request body once the handler starts reading it
 }
 
 // ProxyInterface defines the interface for the smart proxy
 type ProxyInterface interface {
 	ListTools(ctx context.Context) ([]types.Tool, error)
-	DiscoverTools(ctx context.Context, query string) ([]types.Tool, error)
-	UseTool(ctx context.Context, toolName string, arguments map[string]interface{}) (map[string]interface{}, error)
+	EachTool(ctx context.Context, fn func(types.Tool) error) error
+	GetTool(ctx context.Context, toolName string) (*types.Tool, error)
+	ExampleArguments(ctx context.Context, toolName string) (map[string]interface{}, error)
+	QueryArguments(ctx context.Context, toolName string, query map[string][]string) (map[string]interface{}, error)
+	ExportOpenAITools(ctx context.Context) (*types.OpenAIToolExport, error)
+	ExportAnthropicTools(ctx context.Context) (*types.AnthropicToolExport, error)
+	DiscoverTools(ctx context.Context, req types.ProxyRequest) ([]types.Tool, error)
+	DiscoverToolsDebug(ctx context.Context, req types.ProxyRequest) (*types.DiscoveryDebug, error)
+	DiscoverToolsStream(ctx context.Context, req types.ProxyRequest, emit func(types.Tool) error) error
+	DiscoverToolsBatch(ctx context.Context, req types.BatchDiscoveryRequest) ([]types.DiscoveryResult, error)
+	EffectiveConfig(ctx context.Context) (types.MCPConfig, error)
+	WaitForSync(ctx context.Context, since time.Time) (time.Time, error)
+	ToolsSince(ctx context.Context, since time.Time) (*types.ToolListing, error)
+	UseTool(ctx context.Context, toolName string, req types.ToolRequest) (map[string]interface{}, error)
 	RefreshTools(ctx context.Context) error
+	SoftRefreshTools(ctx context.Context) error
+	LastDiff() *types.ToolDiff
+	Servers() []types.ServerStatus
+	Passthrough(ctx context.Context, serverName string, req types.RPCRequest) (map[string]interface{}, error)
+	Stats() types.UsageStats
+	Ready() bool
+	Health() types.HealthReport
+	DeepHealth(ctx context.Context) types.DeepHealthReport
+	Calls(ctx context.Context) []types.InFlightCall
+	CancelCall(ctx context.Context, id string) error
+	Authenticate(apiKey string) (string, error)
+	AddServer(ctx context.Context, server types.MCPServer) (types.ServerStatus, error)
+	RemoveServer(ctx context.Context, name string) error
 	Close() error
 }
 
-// New creates a new HTTP server
+// New creates a new HTTP server with default options
 func New(proxy ProxyInterface) *Server {
-	return &Server{proxy: proxy}
+	return NewWithOptions(proxy, Options{})
 }
 
-// handleList returns all available tools
+// NewWithOptions creates a new HTTP server with the given options
+func NewWithOptions(proxy ProxyInterface, opts Options) *Server {
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if opts.PathPrefix == "" {
+		opts.PathPrefix = DefaultPathPrefix
+	}
+	opts.PathPrefix = "/" + strings.Trim(opts.PathPrefix, "/")
+	if opts.GzipMinBytes == 0 {
+		opts.GzipMinBytes = DefaultGzipMinBytes
+	}
+	if opts.ReadHeaderTimeout == 0 {
+		opts.ReadHeaderTimeout = DefaultReadHeaderTimeout
+	}
+	if opts.BodyReadTimeout == 0 {
+		opts.BodyReadTimeout = DefaultBodyReadTimeout
+	}
+	return &Server{proxy: proxy, opts: opts}
+}
+
+// handleList returns all available tools, or with ?since (RFC 3339) only the tools added or
+// changed after it and the names of those removed
 func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
 
-	tools, err := s.proxy.ListTools(ctx)
+	if r.URL.Query().Has("since") {
+		since, ok := parseSince(w, r)
+		if !ok {
+			return
+		}
+		listing, err := s.proxy.ToolsSince(ctx, since)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, r, listing)
+		return
+	}
+
+	version, ok := responseVersion(w, r)
+	if !ok {
+		return
+	}
+	s.writeToolList(w, r, version, func(emit func(types.Tool) error) error {
+		return s.proxy.EachTool(ctx, emit)
+	})
+}
+
+// handleWatchTools long-polls for catalog changes: it returns the listing once the tool cache has
+// been synced after ?since (RFC 3339), or 304 Not Modified when ?timeout passes first
+func (s *Server) handleWatchTools(w http.ResponseWriter, r *http.Request) {
+	since, ok := parseSince(w, r)
+	if !ok {
+		return
+	}
+
+	timeout := DefaultWatchTimeout
+	if value := r.URL.Query().Get("timeout"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid timeout: expected a positive duration such as 30s", http.StatusBadRequest)
+			return
+		}
+		timeout = parsed
+	}
+	if timeout > MaxWatchTimeout {
+		timeout = MaxWatchTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	lastSync, err := s.proxy.WaitForSync(ctx, since)
+	if errors.Is(err, context.DeadlineExceeded) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if err != nil {
+		// The client went away
+		return
+	}
+
+	tools, err := s.proxy.ListTools(r.Context())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	response := types.ProxyResponse{RecommendedTools: tools}
-	s.writeJSONResponse(w, response)
+	s.writeJSONResponse(w, r, types.ToolListing{RecommendedTools: tools, LastSync: lastSync})
+}
+
+// parseSince reads the optional ?since timestamp, writing a 400 response when it is malformed
+func parseSince(w http.ResponseWriter, r *http.Request) (time.Time, bool) {
+	value := r.URL.Query().Get("since")
+	if value == "" {
+		return time.Time{}, true
+	}
+	since, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		http.Error(w, "Invalid since: expected an RFC 3339 timestamp", http.StatusBadRequest)
+		return time.Time{}, false
+	}
+	return since, true
+}
+
+// handleSchema returns a single tool's input schema, description and annotations
+func (s *Server) handleSchema(w http.ResponseWriter, r *http.Request) {
+	tool, err := s.proxy.GetTool(r.Context(), mux.Vars(r)["tool"])
+	if errors.Is(err, types.ErrToolNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSONResponse(w, r, tool)
+}
+
+// handleExample returns sample arguments for a tool generated from its input schema
+func (s *Server) handleExample(w http.ResponseWriter, r *http.Request) {
+	arguments, err := s.proxy.ExampleArguments(r.Context(), mux.Vars(r)["tool"])
+	if errors.Is(err, types.ErrToolNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSONResponse(w, r, types.ToolRequest{Arguments: arguments})
+}
+
+// handleExportOpenAI returns the tool catalog as OpenAI function definitions
+func (s *Server) handleExportOpenAI(w http.ResponseWriter, r *http.Request) {
+	export, err := s.proxy.ExportOpenAITools(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSONResponse(w, r, export)
+}
+
+// handleExportAnthropic returns the tool catalog in Anthropic's tool-use format
+func (s *Server) handleExportAnthropic(w http.ResponseWriter, r *http.Request) {
+	export, err := s.proxy.ExportAnthropicTools(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSONResponse(w, r, export)
 }
 
 // handleDiscover uses LLM to recommend tools based on a query
@@ -52,9 +276,12 @@ func (s *Server) handleDiscover(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
 
+	version, ok := responseVersion(w, r)
+	if !ok {
+		return
+	}
 	var req types.ProxyRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !s.decodeJSONBody(w, r, &req) {
 		return
 	}
 
@@ -62,55 +289,287 @@ func (s *Server) handleDiscover(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Query is required", http.StatusBadRequest)
 		return
 	}
+	withProviderHeader(r, &req)
 
-	tools, err := s.proxy.DiscoverTools(ctx, req.Query)
+	if r.URL.Query().Get("debug") == "true" {
+		debug, err := s.proxy.DiscoverToolsDebug(ctx, req)
+		if errors.Is(err, types.ErrDebugDisabled) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), discoverErrorStatus(err))
+			return
+		}
+
+		s.writeJSONResponse(w, r, envelope(version, types.ProxyResponse{RecommendedTools: debug.SelectedTools, Debug: debug}))
+		return
+	}
+
+	tools, err := s.proxy.DiscoverTools(ctx, req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), discoverErrorStatus(err))
 		return
 	}
 
 	response := types.ProxyResponse{RecommendedTools: tools}
-	s.writeJSONResponse(w, response)
+	s.writeJSONResponse(w, r, envelope(version, response))
+}
+
+// handleDiscoverBatch recommends tools for several queries in one request
+func (s *Server) handleDiscoverBatch(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	var req types.BatchDiscoveryRequest
+	if !s.decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	if len(req.Queries) == 0 {
+		http.Error(w, "At least one query is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Queries) > MaxBatchQueries {
+		http.Error(w, fmt.Sprintf("At most %d queries are allowed per batch", MaxBatchQueries), http.StatusBadRequest)
+		return
+	}
+	for _, query := range req.Queries {
+		if query == "" {
+			http.Error(w, "Queries must not be empty", http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, err := s.proxy.DiscoverToolsBatch(ctx, req)
+	if err != nil {
+		http.Error(w, err.Error(), discoverErrorStatus(err))
+		return
+	}
+
+	s.writeJSONResponse(w, r, types.BatchDiscoveryResponse{Results: results})
 }
 
-// handleUse executes a specific tool
+// handleDiscoverStream streams recommended tools as server-sent events while the LLM is still
+// ranking: one "tool" event per tool, then "done", or "error" if selection fails part way
+func (s *Server) handleDiscoverStream(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	var req types.ProxyRequest
+	if !s.decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	if req.Query == "" {
+		http.Error(w, "Query is required", http.StatusBadRequest)
+		return
+	}
+	withProviderHeader(r, &req)
+
+	// Headers are sent with the first tool so that failures before it get a normal status code
+	started := false
+	start := func() {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		started = true
+	}
+
+	count := 0
+	err := s.proxy.DiscoverToolsStream(ctx, req, func(tool types.Tool) error {
+		if !started {
+			start()
+		}
+		count++
+		return writeEvent(w, "tool", tool)
+	})
+	if err != nil && !started {
+		http.Error(w, err.Error(), discoverErrorStatus(err))
+		return
+	}
+	if !started {
+		start()
+	}
+
+	if err != nil {
+		requestid.Printf(ctx, "Streamed discovery failed: %v", err)
+		writeEvent(w, "error", map[string]string{"error": err.Error()})
+		return
+	}
+	writeEvent(w, "done", map[string]int{"count": count})
+}
+
+// withProviderHeader applies the X-LLM-Provider header to a discovery request. It wins over the
+// body's provider so that an operator can force a provider without touching the client; unknown
+// names are rejected by the proxy like any other provider
+func withProviderHeader(r *http.Request, req *types.ProxyRequest) {
+	if name := strings.TrimSpace(r.Header.Get(ProviderHeader)); name != "" {
+		req.Provider = name
+	}
+}
+
+// writeEvent writes one server-sent event with a JSON payload and flushes it to the client
+func writeEvent(w http.ResponseWriter, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// discoverErrorStatus maps a discovery error to its HTTP status code
+func discoverErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, types.ErrUnknownProvider), errors.Is(err, types.ErrUnknownTier):
+		return http.StatusBadRequest
+	case errors.Is(err, types.ErrNoToolsAvailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, types.ErrLLMTimeout):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// toolErrorStatus maps a tool call error class to its HTTP status code
+func toolErrorStatus(class string) int {
+	switch class {
+	case types.ErrorClassNotFound:
+		return http.StatusNotFound
+	case types.ErrorClassUnavailable:
+		return http.StatusServiceUnavailable
+	case types.ErrorClassToolError:
+		return http.StatusUnprocessableEntity
+	case types.ErrorClassTimeout:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// handleUse executes a specific tool, named either in the path or in the body's "tool" field
 func (s *Server) handleUse(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
 	defer cancel()
 
-	vars := mux.Vars(r)
-	toolName := vars["tool"]
+	version, ok := responseVersion(w, r)
+	if !ok {
+		return
+	}
+	var req types.ToolRequest
+	if !s.decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	toolName := mux.Vars(r)["tool"]
+	if toolName != "" && req.Tool != "" && toolName != req.Tool {
+		http.Error(w, fmt.Sprintf("Tool name in path (%s) does not match tool in body (%s)", toolName, req.Tool), http.StatusBadRequest)
+		return
+	}
+	if toolName == "" {
+		toolName = req.Tool
+	}
 
 	if toolName == "" {
 		http.Error(w, "Tool name is required", http.StatusBadRequest)
 		return
 	}
 
-	var req types.ToolRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	s.callTool(ctx, w, r, version, toolName, req)
+}
+
+// handleUseQuery calls a read-only tool through GET, taking its arguments from the query string
+// (all parameters but apiVersion) converted to the types of the tool's input schema
+func (s *Server) handleUseQuery(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	version, ok := responseVersion(w, r)
+	if !ok {
 		return
 	}
+	query := r.URL.Query()
+	query.Del("apiVersion")
 
-	result, err := s.proxy.UseTool(ctx, toolName, req.Arguments)
+	toolName := mux.Vars(r)["tool"]
+	arguments, err := s.proxy.QueryArguments(ctx, toolName, query)
+	if errors.Is(err, types.ErrNotReadOnly) {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, err.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+	if errors.Is(err, types.ErrToolNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		response := types.ProxyResponse{Error: err.Error()}
-		w.WriteHeader(http.StatusInternalServerError)
-		s.writeJSONResponse(w, response)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	response := types.ProxyResponse{Result: result}
-	s.writeJSONResponse(w, response)
+	s.callTool(ctx, w, r, version, toolName, types.ToolRequest{Arguments: arguments})
 }
 
-// handleRefresh refreshes the tool cache
+// callTool runs a tool call for /use and writes its result or error in the given envelope version
+func (s *Server) callTool(ctx context.Context, w http.ResponseWriter, r *http.Request, version, toolName string, req types.ToolRequest) {
+	// Calls without an explicit ID get a fresh one rather than the request ID, which clients may
+	// share across concurrent calls; GET /calls lists it with the request ID while it runs
+	if req.CallID == "" {
+		req.CallID = requestid.New()
+	}
+	callID := req.CallID
+	w.Header().Set(CallIDHeader, callID)
+
+	result, err := s.proxy.UseTool(ctx, toolName, req)
+	if err != nil {
+		var status int
+		var confirmErr *types.ConfirmationRequiredError
+		var argErr *types.ForbiddenArgumentError
+		var missingErr *types.MissingArgumentsError
+		response := types.ProxyResponse{Error: err.Error(), CallID: callID, ErrorClass: types.ErrorClass(err)}
+		switch {
+		case errors.As(err, &confirmErr):
+			status = http.StatusPreconditionFailed
+		case errors.As(err, &argErr):
+			status = http.StatusForbidden
+		case errors.As(err, &missingErr):
+			status = http.StatusUnprocessableEntity
+			response.Missing = missingErr.Missing
+		case errors.Is(err, types.ErrCallIDInUse):
+			status = http.StatusConflict
+		case errors.Is(err, types.ErrCallCancelled):
+			status = statusClientClosedRequest
+		default:
+			status = toolErrorStatus(response.ErrorClass)
+		}
+
+		w.WriteHeader(status)
+		s.writeJSONResponse(w, r, envelope(version, response))
+		return
+	}
+
+	response := types.ProxyResponse{Result: result, CallID: callID}
+	s.writeJSONResponse(w, r, envelope(version, response))
+}
+
+// handleRefresh refreshes the tool cache; ?soft=true keeps existing server connections
 func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
 	defer cancel()
 
-	if err := s.proxy.RefreshTools(ctx); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	refresh := s.proxy.RefreshTools
+	if r.URL.Query().Get("soft") == "true" {
+		refresh = s.proxy.SoftRefreshTools
+	}
+	if err := refresh(ctx); err != nil {
+		http.Error(w, err.Error(), adminErrorStatus(err))
 		return
 	}
 
@@ -118,27 +577,383 @@ func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("Tools refreshed successfully"))
 }
 
-// handleHealth provides a health check endpoint
+// handleDiff returns the tool changes detected by the last refresh
+func (s *Server) handleDiff(w http.ResponseWriter, r *http.Request) {
+	diff := s.proxy.LastDiff()
+	if diff == nil {
+		http.Error(w, "No refresh has run yet", http.StatusNotFound)
+		return
+	}
+
+	s.writeJSONResponse(w, r, diff)
+}
+
+// handleServers reports the status of every configured MCP server
+func (s *Server) handleServers(w http.ResponseWriter, r *http.Request) {
+	s.writeJSONResponse(w, r, s.proxy.Servers())
+}
+
+// handleAddServer starts a new MCP server from the request body and merges its tools
+func (s *Server) handleAddServer(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	var req types.MCPServer
+	if !s.decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	if req.Name == "" {
+		http.Error(w, "Server name is required", http.StatusBadRequest)
+		return
+	}
+	if (req.Transport == "" || req.Transport == "stdio") && req.Command == "" {
+		http.Error(w, "Command is required for stdio servers", http.StatusBadRequest)
+		return
+	}
+
+	status, err := s.proxy.AddServer(ctx, req)
+	if errors.Is(err, types.ErrServerExists) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), adminErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	s.writeJSONResponse(w, r, status)
+}
+
+// handleRemoveServer stops an MCP server and drops its tools
+func (s *Server) handleRemoveServer(w http.ResponseWriter, r *http.Request) {
+	err := s.proxy.RemoveServer(r.Context(), mux.Vars(r)["name"])
+	if errors.Is(err, types.ErrServerNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), adminErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePassthrough forwards a raw JSON-RPC request to one server and returns its response
+func (s *Server) handlePassthrough(w http.ResponseWriter, r *http.Request) {
+	var req types.RPCRequest
+	if !s.decodeJSONBody(w, r, &req) {
+		return
+	}
+	if req.Method == "" {
+		http.Error(w, "Method is required", http.StatusBadRequest)
+		return
+	}
+	response, err := s.proxy.Passthrough(r.Context(), mux.Vars(r)["name"], req)
+	if err != nil {
+		var status int
+		switch {
+		case errors.Is(err, types.ErrPassthroughDisabled):
+			status = http.StatusForbidden
+		case errors.Is(err, types.ErrMethodNotPassable):
+			status = http.StatusBadRequest
+		case errors.Is(err, types.ErrServerNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, errors.ErrUnsupported):
+			status = http.StatusNotImplemented
+		default:
+			status = toolErrorStatus(types.ErrorClass(err))
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	s.writeJSONResponse(w, r, response)
+}
+
+// handleCalls lists the tool calls currently in flight
+func (s *Server) handleCalls(w http.ResponseWriter, r *http.Request) {
+	s.writeJSONResponse(w, r, s.proxy.Calls(r.Context()))
+}
+
+// handleCancelCall aborts an in-flight tool call
+func (s *Server) handleCancelCall(w http.ResponseWriter, r *http.Request) {
+	err := s.proxy.CancelCall(r.Context(), mux.Vars(r)["id"])
+	if errors.Is(err, types.ErrCallNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleConfig returns the effective running config with secrets redacted
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	config, err := s.proxy.EffectiveConfig(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), adminErrorStatus(err))
+		return
+	}
+	s.writeJSONResponse(w, r, config)
+}
+
+// adminErrorStatus maps the error of an administrative operation to a status code: 403 for callers
+// that are not admins or operations disabled in the config, else 500
+func adminErrorStatus(err error) int {
+	if errors.Is(err, types.ErrForbidden) || errors.Is(err, types.ErrServerManagementDisabled) {
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// handleStats reports tool and server usage counts
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	s.writeJSONResponse(w, r, s.proxy.Stats())
+}
+
+// handleHealth provides a health check endpoint; ?detail=true reports tool counts and sync time as JSON
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("detail") == "true" {
+		s.writeJSONResponse(w, r, s.proxy.Health())
+		return
+	}
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
 }
 
+// handleDeepHealth calls each server's health check tool and reports which answered as expected
+func (s *Server) handleDeepHealth(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	s.writeJSONResponse(w, r, s.proxy.DeepHealth(ctx))
+}
+
+// handleReady reports readiness: 503 until initial discovery has produced at least one tool
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if !s.proxy.Ready() {
+		http.Error(w, "Not ready", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+// decodeJSONBody decodes a size-limited JSON request body, writing an error response and returning false on failure
+func (s *Server) decodeJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	rc := http.NewResponseController(w)
+	deadline := s.opts.BodyReadTimeout > 0 && rc.SetReadDeadline(time.Now().Add(s.opts.BodyReadTimeout)) == nil
+
+	r.Body = http.MaxBytesReader(w, r.Body, s.opts.MaxBodyBytes)
+	decoder := json.NewDecoder(r.Body)
+	if s.opts.StrictJSON {
+		decoder.DisallowUnknownFields()
+	}
+	err := decoder.Decode(dst)
+	if err == nil {
+		// Read the rest of the body under the deadline too, so it cannot be trickled in afterwards
+		_, err = io.Copy(io.Discard, r.Body)
+	}
+	if err == nil && deadline {
+		// The connection is still read while the request is handled, to notice the client going away
+		rc.SetReadDeadline(time.Time{})
+	}
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, fmt.Sprintf("Request body exceeds %d bytes", maxErr.Limit), http.StatusRequestEntityTooLarge)
+			return false
+		}
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			requestid.Printf(r.Context(), "Request body not received within %s, closing connection", s.opts.BodyReadTimeout)
+			w.Header().Set("Connection", "close")
+			http.Error(w, "Timed out reading request body", http.StatusRequestTimeout)
+			return false
+		}
+		http.Error(w, "Invalid request body: "+describeDecodeError(err, decoder.InputOffset()), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// describeDecodeError explains a JSON decoding failure, naming the offending field and the byte
+// offset where decoding stopped
+func describeDecodeError(err error, offset int64) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return fmt.Sprintf("malformed JSON at offset %d: %v", syntaxErr.Offset, syntaxErr)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return fmt.Sprintf("field %q must be %s, not %s (offset %d)", typeErr.Field, jsonKind(typeErr.Type), typeErr.Value, typeErr.Offset)
+	case errors.As(err, &typeErr):
+		return fmt.Sprintf("body must be %s, not %s", jsonKind(typeErr.Type), typeErr.Value)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for unknown fields
+		return fmt.Sprintf("unknown field %s (offset %d)", strings.TrimPrefix(err.Error(), "json: unknown field "), offset)
+	case errors.Is(err, io.EOF):
+		return "body is empty"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return "unexpected end of JSON"
+	default:
+		return err.Error()
+	}
+}
+
+// jsonKind names the JSON type a Go type is decoded from
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	default:
+		return t.String()
+	}
+}
+
+// writeToolList writes the same JSON as the envelope of the given version carrying the tools
+// that each yields, but encodes one tool at a time as it is yielded, so the catalog is never held
+// in memory as a list or as a single JSON document
+func (s *Server) writeToolList(w http.ResponseWriter, r *http.Request, version string, each func(emit func(types.Tool) error) error) {
+	w.Header().Set("Content-Type", "application/json")
+	// apiVersion is the only other envelope field a listing sets
+	fields := ""
+	if version != types.APIVersionLegacy {
+		fields = `"apiVersion":"` + version + `"`
+	}
+
+	buffered := bufio.NewWriterSize(w, toolListBufferSize)
+	count := 0
+	err := each(func(tool types.Tool) error {
+		data, err := json.Marshal(tool)
+		if err != nil {
+			return fmt.Errorf("failed to encode tool %s: %w", tool.Name, err)
+		}
+		if count == 0 {
+			prefix := fields
+			if prefix != "" {
+				prefix += ","
+			}
+			buffered.WriteString("{" + prefix + `"recommendedTools":[`)
+		} else {
+			buffered.WriteByte(',')
+		}
+		count++
+		_, err = buffered.Write(data)
+		return err
+	})
+	if err != nil {
+		// Headers may already be sent; the truncated body tells the client something went wrong
+		requestid.Printf(r.Context(), "Error writing tool list: %v", err)
+		buffered.Flush()
+		return
+	}
+
+	if count == 0 {
+		// recommendedTools is omitted when empty
+		buffered.WriteString("{" + fields + "}\n")
+	} else {
+		buffered.WriteString("]}\n")
+	}
+	if err := buffered.Flush(); err != nil {
+		requestid.Printf(r.Context(), "Error writing tool list: %v", err)
+	}
+}
+
 // writeJSONResponse writes a JSON response with proper headers
-func (s *Server) writeJSONResponse(w http.ResponseWriter, data interface{}) {
+func (s *Server) writeJSONResponse(w http.ResponseWriter, r *http.Request, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(data); err != nil {
-		log.Printf("Error encoding JSON response: %v", err)
+		requestid.Printf(r.Context(), "Error encoding JSON response: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
 
+// requestIDMiddleware accepts or generates an X-Request-ID, stores it in the request context and echoes it back
+func (s *Server) requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if id == "" {
+			id = requestid.New()
+		}
+
+		w.Header().Set(requestid.Header, id)
+		ctx := requestid.NewContext(r.Context(), id)
+		requestid.Printf(ctx, "%s %s", r.Method, r.URL.Path)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// authMiddleware resolves the caller's API key to a principal stored in the request context;
+// health and readiness probes are exempt
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Probes carry no key. The detailed health report names servers and why they failed to
+		// start, so like the rest of the API it needs one
+		switch strings.TrimPrefix(r.URL.Path, s.opts.PathPrefix) {
+		case "/health":
+			if r.URL.Query().Get("detail") != "true" {
+				next.ServeHTTP(w, r)
+				return
+			}
+		case "/ready":
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, err := s.proxy.Authenticate(auth.APIKey(r.Header.Get("Authorization"), r.Header.Get(auth.KeyHeader)))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if principal != "" {
+			r = r.WithContext(auth.NewContext(r.Context(), principal))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// contentTypeMiddleware rejects POST requests whose body is not declared as application/json;
+// bodyless POSTs such as /refresh need no Content-Type
+func (s *Server) contentTypeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.ContentLength == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != "application/json" {
+			http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // corsMiddleware adds CORS headers to all responses
 func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+auth.KeyHeader+", "+requestid.Header+", "+ProviderHeader)
+		w.Header().Set("Access-Control-Expose-Headers", requestid.Header+", "+CallIDHeader)
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -151,19 +966,87 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 
 // Start starts the HTTP server on the specified address
 func (s *Server) Start(addr string) error {
+	return s.Run(context.Background(), addr)
+}
+
+// Run serves HTTP on the specified address until ctx is done, then stops accepting connections
+// and waits up to ShutdownTimeout for in-flight requests before closing the rest. It returns nil
+// after a shutdown
+func (s *Server) Run(ctx context.Context, addr string) error {
+	log.Printf("Starting server on %s (API under %s)", addr, s.opts.PathPrefix)
+	server := s.httpServer(addr)
+
+	served := make(chan error, 1)
+	go func() { served <- server.ListenAndServe() }()
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down server on %s", addr)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Closing connections still open after %s: %v", ShutdownTimeout, err)
+		server.Close()
+	}
+	return nil
+}
+
+// httpServer configures the http.Server that serves the API on addr. Request bodies get their own
+// deadline in decodeJSONBody: ReadTimeout would stay in force while handlers run and cancel long
+// requests such as /tools/watch
+func (s *Server) httpServer(addr string) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: s.opts.ReadHeaderTimeout,
+	}
+}
+
+// Handler builds the HTTP handler with all routes and middleware
+func (s *Server) Handler() http.Handler {
 	r := mux.NewRouter()
 
 	// API routes
-	api := r.PathPrefix("/api/v1").Subrouter()
+	api := r.PathPrefix(s.opts.PathPrefix).Subrouter()
 	api.HandleFunc("/tools", s.handleList).Methods("GET")
+	api.HandleFunc("/tools/watch", s.handleWatchTools).Methods("GET")
+	api.HandleFunc("/schema/{tool:.+}", s.handleSchema).Methods("GET")
+	api.HandleFunc("/example/{tool:.+}", s.handleExample).Methods("GET")
+	api.HandleFunc("/export/openai", s.handleExportOpenAI).Methods("GET")
+	api.HandleFunc("/export/anthropic", s.handleExportAnthropic).Methods("GET")
 	api.HandleFunc("/discover", s.handleDiscover).Methods("POST")
-	api.HandleFunc("/use/{tool}", s.handleUse).Methods("POST")
+	api.HandleFunc("/discover/stream", s.handleDiscoverStream).Methods("POST")
+	api.HandleFunc("/discover/batch", s.handleDiscoverBatch).Methods("POST")
+	api.HandleFunc("/use", s.handleUse).Methods("POST")
+	api.HandleFunc("/use/{tool:.+}", s.handleUse).Methods("POST") // tool names may contain slashes
+	api.HandleFunc("/use/{tool:.+}", s.handleUseQuery).Methods("GET")
 	api.HandleFunc("/refresh", s.handleRefresh).Methods("POST")
+	api.HandleFunc("/refresh/diff", s.handleDiff).Methods("GET")
+	api.HandleFunc("/servers", s.handleServers).Methods("GET")
+	api.HandleFunc("/config", s.handleConfig).Methods("GET")
+	api.HandleFunc("/servers", s.handleAddServer).Methods("POST")
+	api.HandleFunc("/servers/{name}", s.handleRemoveServer).Methods("DELETE")
+	api.HandleFunc("/servers/{name}/rpc", s.handlePassthrough).Methods("POST")
+	api.HandleFunc("/calls", s.handleCalls).Methods("GET")
+	api.HandleFunc("/calls/{id}", s.handleCancelCall).Methods("DELETE")
+	api.HandleFunc("/stats", s.handleStats).Methods("GET")
 	api.HandleFunc("/health", s.handleHealth).Methods("GET")
+	api.HandleFunc("/health/deep", s.handleDeepHealth).Methods("GET")
+	api.HandleFunc("/ready", s.handleReady).Methods("GET")
+	api.Use(s.authMiddleware)
+	api.Use(s.contentTypeMiddleware)
 
-	// Add CORS middleware
+	// API documentation
+	r.HandleFunc("/openapi.json", s.handleOpenAPI).Methods("GET")
+	r.HandleFunc("/docs", s.handleDocs).Methods("GET")
+
+	// Add request ID and CORS middleware
+	r.Use(s.requestIDMiddleware)
 	r.Use(s.corsMiddleware)
+	r.Use(s.gzipMiddleware)
 
-	log.Printf("Starting server on %s", addr)
-	return http.ListenAndServe(addr, r)
-}
\ No newline at end of file
+	return r
+}
@@ -0,0 +1,1513 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mcp-smart-proxy/internal/mcp"
+	"mcp-smart-proxy/internal/proxy"
+	"mcp-smart-proxy/pkg/types"
+)
+
+// fakeClient is an in-memory MCP server whose tools echo their name. Calls to a tool named
+// "block" wait until the client's release channel is closed or the call is cancelled
+type fakeClient struct {
+	tools   []types.Tool
+	release chan struct{}
+	started chan string // receives the tool name of each call as it starts, when set
+}
+
+func newFakeClient(toolNames ...string) *fakeClient {
+	client := &fakeClient{release: make(chan struct{})}
+	for _, name := range toolNames {
+		client.tools = append(client.tools, types.Tool{
+			Name:        name,
+			Description: "The " + name + " tool",
+			InputSchema: map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+		})
+	}
+	return client
+}
+
+func (c *fakeClient) ListTools(ctx context.Context) ([]types.Tool, error) {
+	return c.tools, nil
+}
+
+func (c *fakeClient) CallTool(ctx context.Context, toolName string, arguments map[string]interface{}) (map[string]interface{}, error) {
+	if c.started != nil {
+		c.started <- toolName
+	}
+	if toolName == "block" {
+		select {
+		case <-c.release:
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		}
+	}
+	return map[string]interface{}{"content": []interface{}{map[string]interface{}{"type": "text", "text": toolName}}}, nil
+}
+
+func (c *fakeClient) Close() error { return nil }
+
+// Request answers any raw request with a result naming the method and echoing the params
+func (c *fakeClient) Request(ctx context.Context, method string, params interface{}) (map[string]interface{}, error) {
+	return map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": map[string]interface{}{"method": method, "params": params}}, nil
+}
+
+// fakeProvider selects every candidate tool in catalog order
+type fakeProvider struct{}
+
+func (fakeProvider) SelectBestTools(ctx context.Context, query string, tools []types.Tool) ([]types.Tool, error) {
+	return tools, nil
+}
+
+func (fakeProvider) GetName() string { return "fake" }
+
+// newTestServer serves the API of an initialized in-memory proxy over the given clients
+func newTestServer(t *testing.T, config types.MCPConfig, opts Options, clients map[string]types.MCPClient) *httptest.Server {
+	t.Helper()
+	return newTestServerWithProvider(t, config, opts, fakeProvider{}, clients)
+}
+
+// newTestServerWithProvider is newTestServer with the given LLM provider
+func newTestServerWithProvider(t *testing.T, config types.MCPConfig, opts Options, provider types.LLMProvider, clients map[string]types.MCPClient) *httptest.Server {
+	t.Helper()
+	p, err := proxy.NewInMemory(config, provider, clients)
+	if err != nil {
+		t.Fatalf("NewInMemory() error = %v", err)
+	}
+	if err := p.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	server := httptest.NewServer(NewWithOptions(p, opts).Handler())
+	t.Cleanup(func() {
+		server.Close()
+		p.Close()
+	})
+	return server
+}
+
+// request sends an API request with an optional API key and JSON body, returning the status and body
+func request(t *testing.T, server *httptest.Server, method, path, apiKey, body string) (int, string) {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, server.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+// tenantConfig has a tenant limited to the read and block tools, an unrestricted tenant and an admin
+func tenantConfig() types.MCPConfig {
+	return types.MCPConfig{Proxy: types.ProxySettings{Tenants: map[string]types.Tenant{
+		"reader": {APIKey: "reader-key", Tools: []string{"read", "block"}},
+		"writer": {APIKey: "writer-key"},
+		"root":   {APIKey: "root-key", Admin: true},
+	}}}
+}
+
+func TestTenantAuthorization(t *testing.T) {
+	server := newTestServer(t, tenantConfig(), Options{}, map[string]types.MCPClient{
+		"files": newFakeClient("read", "write"),
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		apiKey     string
+		body       string
+		wantStatus int
+		wantBody   string // substring of the response body
+		notInBody  string
+	}{
+		{name: "no key", method: "GET", path: "/api/v1/tools", wantStatus: http.StatusUnauthorized},
+		{name: "unknown key", method: "GET", path: "/api/v1/tools", apiKey: "nope", wantStatus: http.StatusUnauthorized},
+		{name: "health needs no key", method: "GET", path: "/api/v1/health", wantStatus: http.StatusOK},
+		{name: "ready needs no key", method: "GET", path: "/api/v1/ready", wantStatus: http.StatusOK},
+		{name: "health detail needs a key", method: "GET", path: "/api/v1/health?detail=true", wantStatus: http.StatusUnauthorized, notInBody: "files"},
+		{name: "health detail with a key", method: "GET", path: "/api/v1/health?detail=true", apiKey: "reader-key", wantStatus: http.StatusOK, wantBody: `"files"`},
+		{name: "deep health needs a key", method: "GET", path: "/api/v1/health/deep", wantStatus: http.StatusUnauthorized},
+		{name: "restricted tenant sees its tools", method: "GET", path: "/api/v1/tools", apiKey: "reader-key", wantStatus: http.StatusOK, wantBody: `"read"`, notInBody: `"write"`},
+		{name: "unrestricted tenant sees every tool", method: "GET", path: "/api/v1/tools", apiKey: "writer-key", wantStatus: http.StatusOK, wantBody: `"write"`},
+		{name: "restricted tenant calls its tool", method: "POST", path: "/api/v1/use/read", apiKey: "reader-key", body: `{}`, wantStatus: http.StatusOK},
+		{name: "restricted tenant cannot call other tools", method: "POST", path: "/api/v1/use/write", apiKey: "reader-key", body: `{}`, wantStatus: http.StatusNotFound},
+		{name: "restricted tenant cannot read other schemas", method: "GET", path: "/api/v1/schema/write", apiKey: "reader-key", wantStatus: http.StatusNotFound},
+		{name: "tenant cannot read config", method: "GET", path: "/api/v1/config", apiKey: "writer-key", wantStatus: http.StatusForbidden},
+		{name: "admin reads config", method: "GET", path: "/api/v1/config", apiKey: "root-key", wantStatus: http.StatusOK, wantBody: `"[redacted]"`, notInBody: "root-key"},
+		{name: "tenant cannot refresh", method: "POST", path: "/api/v1/refresh?soft=true", apiKey: "writer-key", wantStatus: http.StatusForbidden},
+		{name: "admin refreshes", method: "POST", path: "/api/v1/refresh?soft=true", apiKey: "root-key", wantStatus: http.StatusOK},
+		{name: "tenant cannot remove servers", method: "DELETE", path: "/api/v1/servers/files", apiKey: "writer-key", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := request(t, server, tt.method, tt.path, tt.apiKey, tt.body)
+			if status != tt.wantStatus {
+				t.Fatalf("%s %s = %d %q, want %d", tt.method, tt.path, status, body, tt.wantStatus)
+			}
+			if !strings.Contains(body, tt.wantBody) {
+				t.Errorf("body %q does not contain %q", body, tt.wantBody)
+			}
+			if tt.notInBody != "" && strings.Contains(body, tt.notInBody) {
+				t.Errorf("body %q contains %q", body, tt.notInBody)
+			}
+		})
+	}
+}
+
+func TestAPIKeyHeader(t *testing.T) {
+	server := newTestServer(t, tenantConfig(), Options{}, map[string]types.MCPClient{"files": newFakeClient("read")})
+
+	req, _ := http.NewRequest("GET", server.URL+"/api/v1/tools", nil)
+	req.Header.Set("X-API-Key", "reader-key")
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /tools with X-API-Key = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestCallsAreScopedToTenants(t *testing.T) {
+	client := newFakeClient("block")
+	client.started = make(chan string, 2)
+	server := newTestServer(t, tenantConfig(), Options{}, map[string]types.MCPClient{"files": client})
+
+	var wg sync.WaitGroup
+	for _, call := range []struct{ key, id string }{{"writer-key", "w1"}, {"reader-key", "r1"}} {
+		wg.Add(1)
+		go func(key, id string) {
+			defer wg.Done()
+			request(t, server, "POST", "/api/v1/use/block", key, `{"callId": "`+id+`"}`)
+		}(call.key, call.id)
+	}
+	defer func() {
+		close(client.release)
+		wg.Wait()
+	}()
+	<-client.started
+	<-client.started
+
+	callIDs := func(apiKey string) []string {
+		status, body := request(t, server, "GET", "/api/v1/calls", apiKey, "")
+		if status != http.StatusOK {
+			t.Fatalf("GET /calls = %d %q", status, body)
+		}
+		var calls []types.InFlightCall
+		json.Unmarshal([]byte(body), &calls)
+		var ids []string
+		for _, call := range calls {
+			ids = append(ids, call.ID+":"+call.Tenant)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name   string
+		apiKey string
+		want   string
+	}{
+		{name: "tenant sees its own call", apiKey: "writer-key", want: "w1:writer"},
+		{name: "other tenant sees its own call", apiKey: "reader-key", want: "r1:reader"},
+		{name: "admin sees every call", apiKey: "root-key", want: "r1:reader w1:writer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := callIDs(tt.apiKey)
+			if len(got) == 2 && got[0] > got[1] {
+				got[0], got[1] = got[1], got[0]
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("GET /calls = %v, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if status, _ := request(t, server, "DELETE", "/api/v1/calls/w1", "reader-key", ""); status != http.StatusNotFound {
+		t.Errorf("cancelling another tenant's call = %d, want 404", status)
+	}
+	if status, _ := request(t, server, "DELETE", "/api/v1/calls/r1", "reader-key", ""); status != http.StatusNoContent {
+		t.Errorf("cancelling an own call = %d, want 204", status)
+	}
+	if status, _ := request(t, server, "DELETE", "/api/v1/calls/w1", "root-key", ""); status != http.StatusNoContent {
+		t.Errorf("admin cancelling a call = %d, want 204", status)
+	}
+}
+
+// managementStep is a request to the server management API and its expected outcome
+type managementStep struct {
+	method, path, apiKey, body string
+	wantStatus                 int
+	wantTool                   string // tool expected in /tools afterwards, prefixed with ! when absent
+}
+
+func TestServerManagement(t *testing.T) {
+	managed := tenantConfig()
+	managed.Proxy.ServerManagement = true
+
+	tests := []struct {
+		name   string
+		config types.MCPConfig
+		steps  []managementStep
+	}{
+		{
+			name:   "disabled",
+			config: tenantConfig(),
+			steps: []managementStep{
+				{"POST", "/api/v1/servers", "root-key", `{"name": "extra", "transport": "memory"}`, http.StatusForbidden, "extra_tool"},
+				{"DELETE", "/api/v1/servers/extra", "root-key", "", http.StatusForbidden, "extra_tool"},
+			},
+		},
+		{
+			name:   "enabled",
+			config: managed,
+			steps: []managementStep{
+				{"DELETE", "/api/v1/servers/extra", "writer-key", "", http.StatusForbidden, "extra_tool"},
+				{"DELETE", "/api/v1/servers/extra", "root-key", "", http.StatusNoContent, "!extra_tool"},
+				{"DELETE", "/api/v1/servers/extra", "root-key", "", http.StatusNotFound, "!extra_tool"},
+				{"POST", "/api/v1/servers", "writer-key", `{"name": "extra", "transport": "memory"}`, http.StatusForbidden, "!extra_tool"},
+				{"POST", "/api/v1/servers", "root-key", `{"transport": "memory"}`, http.StatusBadRequest, "!extra_tool"},
+				{"POST", "/api/v1/servers", "root-key", `{"name": "extra", "transport": "memory"}`, http.StatusCreated, "extra_tool"},
+				{"POST", "/api/v1/servers", "root-key", `{"name": "extra", "transport": "memory"}`, http.StatusConflict, "extra_tool"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, tt.config, Options{}, map[string]types.MCPClient{
+				"files": newFakeClient("read"),
+				"extra": newFakeClient("extra_tool"),
+			})
+			for _, step := range tt.steps {
+				status, body := request(t, server, step.method, step.path, step.apiKey, step.body)
+				if status != step.wantStatus {
+					t.Fatalf("%s %s as %s = %d %q, want %d", step.method, step.path, step.apiKey, status, body, step.wantStatus)
+				}
+				_, tools := request(t, server, "GET", "/api/v1/tools", "root-key", "")
+				tool, wantListed := strings.TrimPrefix(step.wantTool, "!"), !strings.HasPrefix(step.wantTool, "!")
+				if listed := strings.Contains(tools, `"`+tool+`"`); listed != wantListed {
+					t.Fatalf("after %s %s: %s listed = %v, want %v", step.method, step.path, tool, listed, wantListed)
+				}
+			}
+		})
+	}
+}
+
+func TestServerManagementNeedsAdminTenant(t *testing.T) {
+	tests := []struct {
+		name    string
+		tenants map[string]types.Tenant
+		wantErr bool
+	}{
+		{name: "no tenants", wantErr: true},
+		{name: "no admin tenant", tenants: map[string]types.Tenant{"writer": {APIKey: "writer-key"}}, wantErr: true},
+		{name: "admin tenant", tenants: map[string]types.Tenant{"root": {APIKey: "root-key", Admin: true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.MCPConfig{Proxy: types.ProxySettings{ServerManagement: true, Tenants: tt.tenants}}
+			_, err := proxy.NewInMemory(config, fakeProvider{}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewInMemory() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestListToolsStreamsTheCatalog(t *testing.T) {
+	server := newTestServer(t, tenantConfig(), Options{}, map[string]types.MCPClient{
+		"files": newFakeClient("read", "write"),
+		"other": newFakeClient("block"),
+	})
+
+	tests := []struct {
+		apiKey string
+		want   []string
+	}{
+		{apiKey: "root-key", want: []string{"block", "read", "write"}},
+		{apiKey: "reader-key", want: []string{"block", "read"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.apiKey, func(t *testing.T) {
+			status, body := request(t, server, "GET", "/api/v1/tools", tt.apiKey, "")
+			if status != 200 {
+				t.Fatalf("GET /tools = %d %q", status, body)
+			}
+			var response types.ProxyResponse
+			if err := json.Unmarshal([]byte(body), &response); err != nil {
+				t.Fatalf("invalid listing %q: %v", body, err)
+			}
+			var names []string
+			for _, tool := range response.RecommendedTools {
+				names = append(names, tool.Name)
+				if tool.Server == nil || tool.Server.Transport != "memory" || !tool.Server.Connected {
+					t.Errorf("tool %s lists server %+v, want a connected memory server", tool.Name, tool.Server)
+				}
+			}
+			sort.Strings(names)
+			if response.APIVersion != types.APIVersionCurrent || strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("GET /tools = version %q with %v, want %s with %v", response.APIVersion, names, types.APIVersionCurrent, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchTool(t *testing.T) {
+	config := tenantConfig()
+	config.Proxy.SearchTool = true
+	server := newTestServer(t, config, Options{}, map[string]types.MCPClient{
+		"files": newFakeClient("read", "write"),
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		apiKey     string
+		body       string
+		wantStatus int
+		want       []string
+	}{
+		{name: "POST", method: "POST", path: "/api/v1/use/search_tools", apiKey: "writer-key", body: `{"arguments": {"query": "read a file"}}`, wantStatus: http.StatusOK, want: []string{"read", "write"}},
+		{name: "GET", method: "GET", path: "/api/v1/use/search_tools?query=read+a+file", apiKey: "writer-key", wantStatus: http.StatusOK, want: []string{"read", "write"}},
+		{name: "restricted tenant", method: "POST", path: "/api/v1/use/search_tools", apiKey: "reader-key", body: `{"arguments": {"query": "read a file"}}`, wantStatus: http.StatusOK, want: []string{"read"}},
+		{name: "without a query", method: "POST", path: "/api/v1/use/search_tools", apiKey: "writer-key", body: `{"arguments": {}}`, wantStatus: http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := request(t, server, tt.method, tt.path, tt.apiKey, tt.body)
+			if status != tt.wantStatus {
+				t.Fatalf("%s %s = %d %q, want %d", tt.method, tt.path, status, body, tt.wantStatus)
+			}
+			if tt.want == nil {
+				return
+			}
+
+			var response struct {
+				Result struct {
+					StructuredContent struct {
+						Tools []types.Tool `json:"tools"`
+					} `json:"structuredContent"`
+				} `json:"result"`
+			}
+			if err := json.Unmarshal([]byte(body), &response); err != nil {
+				t.Fatalf("invalid response %q: %v", body, err)
+			}
+			var names []string
+			for _, tool := range response.Result.StructuredContent.Tools {
+				names = append(names, tool.Name)
+			}
+			sort.Strings(names)
+			if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("search_tools recommended %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestPassthrough(t *testing.T) {
+	enabled := tenantConfig()
+	enabled.Proxy.Passthrough = true
+	clients := map[string]types.MCPClient{"files": newFakeClient("read", "write")}
+	servers := map[bool]*httptest.Server{
+		false: newTestServer(t, tenantConfig(), Options{}, clients),
+		true:  newTestServer(t, enabled, Options{}, clients),
+	}
+
+	tests := []struct {
+		name       string
+		enabled    bool
+		path       string
+		apiKey     string
+		body       string
+		wantStatus int
+		wantMethod string // method echoed in the server's result
+	}{
+		{name: "custom method", enabled: true, path: "/api/v1/servers/files/rpc", apiKey: "writer-key", body: `{"method": "resources/list", "params": {"cursor": "a"}}`, wantStatus: http.StatusOK, wantMethod: "resources/list"},
+		{name: "disabled", path: "/api/v1/servers/files/rpc", apiKey: "writer-key", body: `{"method": "resources/list"}`, wantStatus: http.StatusForbidden},
+		{name: "tool-restricted tenant", enabled: true, path: "/api/v1/servers/files/rpc", apiKey: "reader-key", body: `{"method": "resources/list"}`, wantStatus: http.StatusNotFound},
+		{name: "unknown server", enabled: true, path: "/api/v1/servers/db/rpc", apiKey: "writer-key", body: `{"method": "resources/list"}`, wantStatus: http.StatusNotFound},
+		{name: "tool call", enabled: true, path: "/api/v1/servers/files/rpc", apiKey: "writer-key", body: `{"method": "tools/call", "params": {"name": "write"}}`, wantStatus: http.StatusBadRequest},
+		{name: "initialize", enabled: true, path: "/api/v1/servers/files/rpc", apiKey: "writer-key", body: `{"method": "initialize"}`, wantStatus: http.StatusBadRequest},
+		{name: "notification", enabled: true, path: "/api/v1/servers/files/rpc", apiKey: "writer-key", body: `{"method": "notifications/cancelled"}`, wantStatus: http.StatusBadRequest},
+		{name: "missing method", enabled: true, path: "/api/v1/servers/files/rpc", apiKey: "writer-key", body: `{}`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := request(t, servers[tt.enabled], "POST", tt.path, tt.apiKey, tt.body)
+			if status != tt.wantStatus {
+				t.Fatalf("POST %s = %d %q, want %d", tt.path, status, body, tt.wantStatus)
+			}
+			if tt.wantMethod == "" {
+				return
+			}
+			var response struct {
+				Result struct {
+					Method string                 `json:"method"`
+					Params map[string]interface{} `json:"params"`
+				} `json:"result"`
+			}
+			if err := json.Unmarshal([]byte(body), &response); err != nil || response.Result.Method != tt.wantMethod || response.Result.Params["cursor"] != "a" {
+				t.Errorf("POST %s = %s, want the server's response to %s", tt.path, body, tt.wantMethod)
+			}
+		})
+	}
+}
+
+func TestCallsSharingARequestID(t *testing.T) {
+	client := newFakeClient("block")
+	client.started = make(chan string, 2)
+	server := newTestServer(t, types.MCPConfig{}, Options{}, map[string]types.MCPClient{"files": client})
+
+	type answer struct {
+		status         int
+		callID, header string
+	}
+	answers := make(chan answer, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			req, _ := http.NewRequest("POST", server.URL+"/api/v1/use/block", strings.NewReader(`{}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Request-ID", "shared")
+			resp, err := server.Client().Do(req)
+			if err != nil {
+				answers <- answer{}
+				return
+			}
+			defer resp.Body.Close()
+			var response types.ProxyResponse
+			json.NewDecoder(resp.Body).Decode(&response)
+			answers <- answer{status: resp.StatusCode, callID: response.CallID, header: resp.Header.Get(CallIDHeader)}
+		}()
+	}
+	<-client.started
+	<-client.started
+
+	status, body := request(t, server, "GET", "/api/v1/calls", "", "")
+	var calls []types.InFlightCall
+	if err := json.Unmarshal([]byte(body), &calls); status != http.StatusOK || err != nil || len(calls) != 2 {
+		t.Fatalf("GET /calls = %d %q, want both calls", status, body)
+	}
+	if calls[0].ID == calls[1].ID || calls[0].RequestID != "shared" || calls[1].RequestID != "shared" {
+		t.Fatalf("GET /calls = %+v, want distinct IDs made in request shared", calls)
+	}
+
+	// Cancel the first call by the ID it was listed under and let the other one finish
+	if status, _ := request(t, server, "DELETE", "/api/v1/calls/"+calls[0].ID, "", ""); status != http.StatusNoContent {
+		t.Fatalf("DELETE /calls/%s = %d, want 204", calls[0].ID, status)
+	}
+	first := <-answers
+	close(client.release)
+	second := <-answers
+
+	if first.status != statusClientClosedRequest || first.callID != calls[0].ID || first.header != calls[0].ID {
+		t.Errorf("cancelled call answered %+v, want %d with call ID %s", first, statusClientClosedRequest, calls[0].ID)
+	}
+	if second.status != http.StatusOK || second.callID != calls[1].ID || second.header != calls[1].ID {
+		t.Errorf("other call answered %+v, want 200 with call ID %s", second, calls[1].ID)
+	}
+}
+
+func TestBodySizeLimit(t *testing.T) {
+	server := newTestServer(t, types.MCPConfig{}, Options{MaxBodyBytes: 64}, map[string]types.MCPClient{"files": newFakeClient("read")})
+	large := `{"arguments": {"value": "` + strings.Repeat("x", 64) + `"}}`
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "use under the limit", path: "/api/v1/use/read", body: `{"arguments": {}}`, wantStatus: http.StatusOK},
+		{name: "use over the limit", path: "/api/v1/use/read", body: large, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "discover under the limit", path: "/api/v1/discover", body: `{"query": "read"}`, wantStatus: http.StatusOK},
+		{name: "discover over the limit", path: "/api/v1/discover", body: `{"query": "` + strings.Repeat("x", 64) + `"}`, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "trailing bytes over the limit", path: "/api/v1/use/read", body: `{"arguments": {}}` + strings.Repeat(" ", 64), wantStatus: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, body := request(t, server, "POST", tt.path, "", tt.body); status != tt.wantStatus {
+				t.Errorf("POST %s with %d bytes = %d %q, want %d", tt.path, len(tt.body), status, body, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestHTTPServerTimeouts(t *testing.T) {
+	tests := []struct {
+		name              string
+		opts              Options
+		wantHeaderTimeout time.Duration
+		wantBodyTimeout   time.Duration
+	}{
+		{name: "defaults", wantHeaderTimeout: DefaultReadHeaderTimeout, wantBodyTimeout: DefaultBodyReadTimeout},
+		{name: "configured", opts: Options{ReadHeaderTimeout: 3 * time.Second, BodyReadTimeout: 20 * time.Second}, wantHeaderTimeout: 3 * time.Second, wantBodyTimeout: 20 * time.Second},
+		{name: "disabled", opts: Options{ReadHeaderTimeout: -1, BodyReadTimeout: -1}, wantHeaderTimeout: -1, wantBodyTimeout: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewWithOptions(nil, tt.opts)
+			server := s.httpServer(":0")
+			if server.ReadHeaderTimeout != tt.wantHeaderTimeout || s.opts.BodyReadTimeout != tt.wantBodyTimeout {
+				t.Errorf("header timeout %s and body timeout %s, want %s and %s", server.ReadHeaderTimeout, s.opts.BodyReadTimeout, tt.wantHeaderTimeout, tt.wantBodyTimeout)
+			}
+			if server.ReadTimeout != 0 {
+				t.Errorf("ReadTimeout = %s, want none", server.ReadTimeout)
+			}
+		})
+	}
+}
+
+func TestSlowRequestsAreDropped(t *testing.T) {
+	tests := []struct {
+		name       string
+		request    string // sent in full, after which the client stalls
+		wantStatus string // status line answered before the connection closes; empty checks only the close
+	}{
+		{name: "slow headers", request: "POST /api/v1/use/read HTTP/1.1\r\nHost: proxy\r\n"},
+		{
+			name:       "slow body",
+			request:    "POST /api/v1/use/read HTTP/1.1\r\nHost: proxy\r\nContent-Type: application/json\r\nContent-Length: 100\r\n\r\n{\"argu",
+			wantStatus: "HTTP/1.1 408 Request Timeout",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := proxy.NewInMemory(types.MCPConfig{}, fakeProvider{}, map[string]types.MCPClient{"files": newFakeClient("read")})
+			if err != nil {
+				t.Fatalf("NewInMemory() error = %v", err)
+			}
+			p.Initialize(context.Background())
+			defer p.Close()
+			s := NewWithOptions(p, Options{ReadHeaderTimeout: 100 * time.Millisecond, BodyReadTimeout: 100 * time.Millisecond})
+			server := httptest.NewUnstartedServer(nil)
+			server.Config = s.httpServer("")
+			server.Start()
+			defer server.Close()
+
+			conn, err := net.Dial("tcp", server.Listener.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			conn.Write([]byte(tt.request))
+
+			// The server must close the connection well before the client gives up
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			start := time.Now()
+			answer, err := io.ReadAll(conn)
+			if err != nil {
+				t.Fatalf("connection not closed by the server: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("connection closed after %s, want about 100ms", elapsed)
+			}
+			if status, _, _ := strings.Cut(string(answer), "\r\n"); tt.wantStatus != "" && status != tt.wantStatus {
+				t.Errorf("server answered %q, want %q", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestHealthDetail(t *testing.T) {
+	server := newTestServer(t, types.MCPConfig{}, Options{}, map[string]types.MCPClient{
+		"files": newFakeClient("read", "write"),
+		"notes": newFakeClient("search"),
+	})
+
+	if status, body := request(t, server, "GET", "/api/v1/health", "", ""); status != http.StatusOK || body != "OK" {
+		t.Errorf("GET /health = %d %q, want 200 OK", status, body)
+	}
+
+	// Without tenants the detailed report is open too
+	status, body := request(t, server, "GET", "/api/v1/health?detail=true", "", "")
+	var report types.HealthReport
+	if err := json.Unmarshal([]byte(body), &report); status != http.StatusOK || err != nil {
+		t.Fatalf("GET /health?detail=true = %d %q", status, body)
+	}
+	if report.Status != "ok" || report.Tools != 3 || report.ConnectedServers != 2 || report.Servers["files"] != 2 || report.Servers["notes"] != 1 {
+		t.Errorf("health report = %+v, want 3 tools on 2 connected servers", report)
+	}
+	if report.LastSync.IsZero() || report.Startup == nil || report.Startup.Connected != 2 {
+		t.Errorf("health report = %+v, want the last sync and startup summary", report)
+	}
+
+	var fields map[string]json.RawMessage
+	json.Unmarshal([]byte(body), &fields)
+	for _, key := range []string{"status", "tools", "connectedServers", "servers", "lastSync", "startup"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("health report %s has no %q field", body, key)
+		}
+	}
+}
+
+func TestListToolsKeepsAnnotations(t *testing.T) {
+	destructive, readOnly := true, false
+	client := newFakeClient("read", "drop")
+	client.tools[1].Annotations = &types.ToolAnnotations{Title: "Drop table", DestructiveHint: &destructive, ReadOnlyHint: &readOnly}
+	server := newTestServer(t, types.MCPConfig{}, Options{}, map[string]types.MCPClient{"db": client})
+
+	status, body := request(t, server, "GET", "/api/v1/tools", "", "")
+	if status != 200 {
+		t.Fatalf("GET /tools = %d %q", status, body)
+	}
+	var response types.ProxyResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatalf("invalid listing %q: %v", body, err)
+	}
+	annotations := make(map[string]*types.ToolAnnotations)
+	for _, tool := range response.RecommendedTools {
+		annotations[tool.Name] = tool.Annotations
+	}
+	if got := annotations["drop"]; got == nil || got.Title != "Drop table" || got.DestructiveHint == nil || !*got.DestructiveHint || got.ReadOnlyHint == nil || *got.ReadOnlyHint {
+		t.Errorf("drop annotations = %+v, want the server's hints", got)
+	}
+	if got, ok := annotations["read"]; !ok || got != nil {
+		t.Errorf("read annotations = %+v, want none", got)
+	}
+}
+
+// debugProvider is a fakeProvider that reports a prompt naming the query and a canned raw
+// response selecting the read tool
+type debugProvider struct{ fakeProvider }
+
+func (debugProvider) SelectBestToolsDebug(ctx context.Context, query string, tools []types.Tool) (*types.DiscoveryDebug, error) {
+	var selected []types.Tool
+	for _, tool := range tools {
+		if tool.Name == "read" {
+			selected = append(selected, tool)
+		}
+	}
+	return &types.DiscoveryDebug{Prompt: "Pick tools for: " + query, CandidateTools: tools, RawResponse: `["read"]`, SelectedTools: selected}, nil
+}
+
+func TestDiscoverDebug(t *testing.T) {
+	clients := func() map[string]types.MCPClient {
+		return map[string]types.MCPClient{"files": newFakeClient("read", "write")}
+	}
+	query := `{"query": "read a file"}`
+
+	t.Run("disabled", func(t *testing.T) {
+		server := newTestServerWithProvider(t, types.MCPConfig{}, Options{}, debugProvider{}, clients())
+		if status, body := request(t, server, "POST", "/api/v1/discover?debug=true", "", query); status != http.StatusForbidden {
+			t.Errorf("POST /discover?debug=true = %d %q, want 403", status, body)
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		config := types.MCPConfig{Proxy: types.ProxySettings{Debug: true}}
+		server := newTestServerWithProvider(t, config, Options{}, debugProvider{}, clients())
+		status, body := request(t, server, "POST", "/api/v1/discover?debug=true", "", query)
+		if status != 200 {
+			t.Fatalf("POST /discover?debug=true = %d %q", status, body)
+		}
+		var response types.ProxyResponse
+		if err := json.Unmarshal([]byte(body), &response); err != nil || response.Debug == nil {
+			t.Fatalf("invalid debug response %q: %v", body, err)
+		}
+		if response.Debug.Prompt != "Pick tools for: read a file" || response.Debug.RawResponse != `["read"]` || len(response.Debug.CandidateTools) != 2 {
+			t.Errorf("debug payload = %+v, want the prompt, raw response and both candidates", response.Debug)
+		}
+		if len(response.RecommendedTools) != 1 || response.RecommendedTools[0].Name != "read" {
+			t.Errorf("recommended tools = %+v, want the debug selection", response.RecommendedTools)
+		}
+	})
+
+	t.Run("provider without debug support", func(t *testing.T) {
+		config := types.MCPConfig{Proxy: types.ProxySettings{Debug: true}}
+		server := newTestServer(t, config, Options{}, clients())
+		if status, body := request(t, server, "POST", "/api/v1/discover?debug=true", "", query); status == 200 {
+			t.Errorf("POST /discover?debug=true = %d %q, want an error", status, body)
+		}
+	})
+}
+
+// logBuffer collects log output, safe for the handler goroutines logging into it
+type logBuffer struct {
+	mu   sync.Mutex
+	data strings.Builder
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.data.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.data.String()
+}
+
+// captureLog redirects the standard logger into a buffer for the rest of the test
+func captureLog(t *testing.T) *logBuffer {
+	buffer := &logBuffer{}
+	log.SetOutput(buffer)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return buffer
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	server := newTestServer(t, types.MCPConfig{}, Options{}, map[string]types.MCPClient{"files": newFakeClient("read")})
+
+	tests := []struct {
+		name string
+		id   string // sent in X-Request-ID; empty sends none
+	}{
+		{name: "provided ID is kept", id: "trace-42"},
+		{name: "missing ID is generated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLog(t)
+			req, _ := http.NewRequest("POST", server.URL+"/api/v1/use/read", strings.NewReader(`{"arguments": {}}`))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.id != "" {
+				req.Header.Set("X-Request-ID", tt.id)
+			}
+			resp, err := server.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			id := resp.Header.Get("X-Request-ID")
+			if id == "" || (tt.id != "" && id != tt.id) {
+				t.Fatalf("X-Request-ID = %q, want %q", id, tt.id)
+			}
+			for _, want := range []string{"[" + id + "] POST /api/v1/use/read", "[" + id + "] Calling tool read on server files"} {
+				if !strings.Contains(logs.String(), want) {
+					t.Errorf("log does not contain %q:\n%s", want, logs)
+				}
+			}
+		})
+	}
+}
+
+func TestRefreshDiffEndpoint(t *testing.T) {
+	client := newFakeClient("read")
+	server := newTestServer(t, types.MCPConfig{}, Options{}, map[string]types.MCPClient{"files": client})
+
+	if status, body := request(t, server, "GET", "/api/v1/refresh/diff", "", ""); status != http.StatusNotFound {
+		t.Errorf("GET /refresh/diff before a refresh = %d %q, want 404", status, body)
+	}
+
+	client.tools = newFakeClient("write").tools
+	if status, body := request(t, server, "POST", "/api/v1/refresh", "", ""); status != 200 {
+		t.Fatalf("POST /refresh = %d %q", status, body)
+	}
+	status, body := request(t, server, "GET", "/api/v1/refresh/diff", "", "")
+	var diff types.ToolDiff
+	if status != 200 || json.Unmarshal([]byte(body), &diff) != nil {
+		t.Fatalf("GET /refresh/diff = %d %q", status, body)
+	}
+	if strings.Join(diff.Added, ",") != "write" || strings.Join(diff.Removed, ",") != "read" || len(diff.Changed) != 0 {
+		t.Errorf("GET /refresh/diff = %+v, want write added and read removed", diff)
+	}
+}
+
+func TestToolNamesWithSlashes(t *testing.T) {
+	server := newTestServer(t, types.MCPConfig{}, Options{}, map[string]types.MCPClient{
+		"github": newFakeClient("github/search", "repo/issues/list", "read me"),
+	})
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+		wantTool   string // tool whose name the result echoes
+	}{
+		{name: "slash in the path", path: "/api/v1/use/github/search", body: `{"arguments": {}}`, wantStatus: 200, wantTool: "github/search"},
+		{name: "several slashes", path: "/api/v1/use/repo/issues/list", body: `{"arguments": {}}`, wantStatus: 200, wantTool: "repo/issues/list"},
+		{name: "escaped slash", path: "/api/v1/use/github%2Fsearch", body: `{"arguments": {}}`, wantStatus: 200, wantTool: "github/search"},
+		{name: "escaped space", path: "/api/v1/use/read%20me", body: `{"arguments": {}}`, wantStatus: 200, wantTool: "read me"},
+		{name: "name in the body", path: "/api/v1/use", body: `{"tool": "github/search", "arguments": {}}`, wantStatus: 200, wantTool: "github/search"},
+		{name: "path and body agree", path: "/api/v1/use/github/search", body: `{"tool": "github/search"}`, wantStatus: 200, wantTool: "github/search"},
+		{name: "path and body disagree", path: "/api/v1/use/github/search", body: `{"tool": "read me"}`, wantStatus: http.StatusBadRequest},
+		{name: "no name", path: "/api/v1/use", body: `{"arguments": {}}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := request(t, server, "POST", tt.path, "", tt.body)
+			if status != tt.wantStatus {
+				t.Fatalf("POST %s = %d %q, want %d", tt.path, status, body, tt.wantStatus)
+			}
+			if tt.wantTool != "" && !strings.Contains(body, `"text":"`+tt.wantTool+`"`) {
+				t.Errorf("POST %s = %q, want the result of %s", tt.path, body, tt.wantTool)
+			}
+		})
+	}
+}
+
+func TestMissingArguments(t *testing.T) {
+	client := newFakeClient("search")
+	client.tools[0].InputSchema = map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"query": map[string]interface{}{"type": "string", "description": "What to look for"}},
+		"required":   []interface{}{"query"},
+	}
+	server := newTestServer(t, types.MCPConfig{}, Options{}, map[string]types.MCPClient{"index": client})
+
+	status, body := request(t, server, "POST", "/api/v1/use/search", "", `{"arguments": {}}`)
+	var response types.ProxyResponse
+	if status != http.StatusUnprocessableEntity || json.Unmarshal([]byte(body), &response) != nil {
+		t.Fatalf("POST /use/search without query = %d %q, want 422", status, body)
+	}
+	if want := []types.MissingArgument{{Name: "query", Type: "string", Description: "What to look for"}}; !reflect.DeepEqual(response.Missing, want) {
+		t.Errorf("missing = %+v, want %+v", response.Missing, want)
+	}
+}
+
+func TestSchemaEndpoint(t *testing.T) {
+	readOnly := true
+	client := newFakeClient("read", "write")
+	client.tools[0].InputSchema = map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"path": map[string]interface{}{"type": "string"}},
+		"required":   []interface{}{"path"},
+	}
+	client.tools[0].Annotations = &types.ToolAnnotations{ReadOnlyHint: &readOnly}
+	server := newTestServer(t, tenantConfig(), Options{}, map[string]types.MCPClient{"files": client})
+
+	tests := []struct {
+		name       string
+		apiKey     string
+		tool       string
+		wantStatus int
+	}{
+		{name: "cached tool", apiKey: "writer-key", tool: "read", wantStatus: 200},
+		{name: "unknown tool", apiKey: "writer-key", tool: "missing", wantStatus: http.StatusNotFound},
+		{name: "tool outside the tenant", apiKey: "reader-key", tool: "write", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := request(t, server, "GET", "/api/v1/schema/"+tt.tool, tt.apiKey, "")
+			if status != tt.wantStatus {
+				t.Fatalf("GET /schema/%s = %d %q, want %d", tt.tool, status, body, tt.wantStatus)
+			}
+			if status != 200 {
+				return
+			}
+			var got types.Tool
+			if err := json.Unmarshal([]byte(body), &got); err != nil {
+				t.Fatalf("invalid schema %q: %v", body, err)
+			}
+			want := client.tools[0]
+			if got.Name != want.Name || got.Description != want.Description || !reflect.DeepEqual(got.InputSchema, want.InputSchema) {
+				t.Errorf("GET /schema/%s = %+v, want the cached tool %+v", tt.tool, got, want)
+			}
+			if got.Annotations == nil || got.Annotations.ReadOnlyHint == nil || !*got.Annotations.ReadOnlyHint {
+				t.Errorf("annotations = %+v, want the read-only hint", got.Annotations)
+			}
+		})
+	}
+}
+
+func TestExampleEndpoint(t *testing.T) {
+	client := newFakeClient("read", "write")
+	client.tools[0].InputSchema = map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"path": map[string]interface{}{"type": "string", "examples": []interface{}{"/tmp/notes.txt"}}},
+	}
+	server := newTestServer(t, tenantConfig(), Options{}, map[string]types.MCPClient{"files": client})
+
+	tests := []struct {
+		name       string
+		apiKey     string
+		tool       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "example from the schema", apiKey: "writer-key", tool: "read", wantStatus: http.StatusOK, wantBody: `{"arguments":{"path":"/tmp/notes.txt"}}`},
+		{name: "unknown tool", apiKey: "writer-key", tool: "missing", wantStatus: http.StatusNotFound},
+		{name: "tool outside the tenant", apiKey: "reader-key", tool: "write", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := request(t, server, "GET", "/api/v1/example/"+tt.tool, tt.apiKey, "")
+			if status != tt.wantStatus {
+				t.Fatalf("GET /example/%s = %d %q, want %d", tt.tool, status, body, tt.wantStatus)
+			}
+			if tt.wantBody != "" && strings.TrimSpace(body) != tt.wantBody {
+				t.Errorf("GET /example/%s = %s, want %s", tt.tool, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+// echoClient is a fakeClient whose tools answer with their arguments as JSON text
+type echoClient struct{ *fakeClient }
+
+func (c echoClient) CallTool(ctx context.Context, toolName string, arguments map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(arguments)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"content": []interface{}{map[string]interface{}{"type": "text", "text": string(data)}}}, nil
+}
+
+func TestUseWithQuery(t *testing.T) {
+	client := newFakeClient("lookup", "write")
+	readOnly := true
+	client.tools[0].Annotations = &types.ToolAnnotations{ReadOnlyHint: &readOnly}
+	client.tools[0].InputSchema = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name":  map[string]interface{}{"type": "string"},
+			"limit": map[string]interface{}{"type": "integer"},
+			"tags":  map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+		"required": []interface{}{"name"},
+	}
+	server := newTestServer(t, types.MCPConfig{}, Options{}, map[string]types.MCPClient{"db": echoClient{client}})
+
+	tests := []struct {
+		name          string
+		path          string
+		wantStatus    int
+		wantArguments string
+	}{
+		{name: "typed arguments", path: "/api/v1/use/lookup?name=ada&limit=5", wantStatus: http.StatusOK, wantArguments: `{"limit":5,"name":"ada"}`},
+		{name: "repeated parameter", path: "/api/v1/use/lookup?name=ada&tags=a&tags=b", wantStatus: http.StatusOK, wantArguments: `{"name":"ada","tags":["a","b"]}`},
+		{name: "apiVersion is not an argument", path: "/api/v1/use/lookup?name=ada&apiVersion=1", wantStatus: http.StatusOK, wantArguments: `{"name":"ada"}`},
+		{name: "missing required argument", path: "/api/v1/use/lookup?limit=5", wantStatus: http.StatusUnprocessableEntity},
+		{name: "tool that is not read-only", path: "/api/v1/use/write", wantStatus: http.StatusMethodNotAllowed},
+		{name: "unknown tool", path: "/api/v1/use/missing", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := request(t, server, "GET", tt.path, "", "")
+			if status != tt.wantStatus {
+				t.Fatalf("GET %s = %d %q, want %d", tt.path, status, body, tt.wantStatus)
+			}
+			if tt.wantArguments != "" && !strings.Contains(body, strconv.Quote(tt.wantArguments)) {
+				t.Errorf("GET %s = %s, want the tool to receive %s", tt.path, body, tt.wantArguments)
+			}
+		})
+	}
+}
+
+func TestExportEndpoint(t *testing.T) {
+	server := newTestServer(t, tenantConfig(), Options{}, map[string]types.MCPClient{"files": newFakeClient("read", "write")})
+
+	tests := []struct {
+		name       string
+		apiKey     string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "openai format",
+			apiKey:     "reader-key",
+			path:       "/api/v1/export/openai",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"tools":[{"type":"function","function":{"name":"read","description":"The read tool","parameters":{"properties":{},"type":"object"}}}]}`,
+		},
+		{name: "openai format without a key", path: "/api/v1/export/openai", wantStatus: http.StatusUnauthorized},
+		{
+			name:       "anthropic format",
+			apiKey:     "reader-key",
+			path:       "/api/v1/export/anthropic",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"tools":[{"name":"read","description":"The read tool","input_schema":{"properties":{},"type":"object"}}]}`,
+		},
+		{name: "anthropic format without a key", path: "/api/v1/export/anthropic", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := request(t, server, "GET", tt.path, tt.apiKey, "")
+			if status != tt.wantStatus {
+				t.Fatalf("GET %s = %d %q, want %d", tt.path, status, body, tt.wantStatus)
+			}
+			if tt.wantBody != "" && strings.TrimSpace(body) != tt.wantBody {
+				t.Errorf("GET %s = %s, want %s", tt.path, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestDiscoverWithoutTools(t *testing.T) {
+	server := newTestServer(t, types.MCPConfig{}, Options{}, nil)
+
+	for _, path := range []string{"/api/v1/discover", "/api/v1/discover/stream"} {
+		status, body := request(t, server, "POST", path, "", `{"query": "read a file"}`)
+		if status != http.StatusServiceUnavailable || !strings.Contains(body, "no tools available") {
+			t.Errorf("POST %s without tools = %d %q, want 503 no tools available", path, status, body)
+		}
+	}
+}
+
+// providerRecorder is a proxy that records the provider each discovery asks for
+type providerRecorder struct {
+	*proxy.SmartProxy
+	mu        sync.Mutex
+	providers []string
+}
+
+func (p *providerRecorder) record(provider string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.providers = append(p.providers, provider)
+}
+
+func (p *providerRecorder) DiscoverTools(ctx context.Context, req types.ProxyRequest) ([]types.Tool, error) {
+	p.record(req.Provider)
+	return p.SmartProxy.DiscoverTools(ctx, req)
+}
+
+func (p *providerRecorder) DiscoverToolsStream(ctx context.Context, req types.ProxyRequest, emit func(types.Tool) error) error {
+	p.record(req.Provider)
+	return p.SmartProxy.DiscoverToolsStream(ctx, req, emit)
+}
+
+func TestProviderHeader(t *testing.T) {
+	p, err := proxy.NewInMemory(types.MCPConfig{}, fakeProvider{}, map[string]types.MCPClient{"files": newFakeClient("read")})
+	if err != nil {
+		t.Fatalf("NewInMemory() error = %v", err)
+	}
+	if err := p.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	recorder := &providerRecorder{SmartProxy: p}
+	server := httptest.NewServer(New(recorder).Handler())
+	t.Cleanup(func() {
+		server.Close()
+		p.Close()
+	})
+
+	tests := []struct {
+		name         string
+		header       string
+		body         string
+		wantStatus   int
+		wantProvider string
+	}{
+		{name: "no header", body: `{"query": "read"}`, wantStatus: http.StatusOK},
+		{name: "header selects the provider", header: "default", body: `{"query": "read"}`, wantStatus: http.StatusOK, wantProvider: "default"},
+		{name: "header wins over the body", header: "default", body: `{"query": "read", "provider": "nope"}`, wantStatus: http.StatusOK, wantProvider: "default"},
+		{name: "blank header is ignored", header: "  ", body: `{"query": "read", "provider": "default"}`, wantStatus: http.StatusOK, wantProvider: "default"},
+		{name: "unknown provider", header: "nope", body: `{"query": "read"}`, wantStatus: http.StatusBadRequest, wantProvider: "nope"},
+	}
+
+	for _, tt := range tests {
+		for _, path := range []string{"/api/v1/discover", "/api/v1/discover/stream"} {
+			t.Run(tt.name+" "+path, func(t *testing.T) {
+				recorder.mu.Lock()
+				recorder.providers = nil
+				recorder.mu.Unlock()
+
+				req, err := http.NewRequest("POST", server.URL+path, strings.NewReader(tt.body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				req.Header.Set("Content-Type", "application/json")
+				if tt.header != "" {
+					req.Header.Set(ProviderHeader, tt.header)
+				}
+				resp, err := server.Client().Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+
+				if resp.StatusCode != tt.wantStatus {
+					t.Errorf("POST %s with %s %q = %d, want %d", path, ProviderHeader, tt.header, resp.StatusCode, tt.wantStatus)
+				}
+				recorder.mu.Lock()
+				defer recorder.mu.Unlock()
+				if len(recorder.providers) != 1 || recorder.providers[0] != tt.wantProvider {
+					t.Errorf("discovery asked for providers %q, want %q", recorder.providers, tt.wantProvider)
+				}
+			})
+		}
+	}
+}
+
+func TestDiscoverBatch(t *testing.T) {
+	server := newTestServer(t, types.MCPConfig{}, Options{}, map[string]types.MCPClient{"files": newFakeClient("read", "write")})
+
+	tooMany := `"q"` + strings.Repeat(`, "q"`, MaxBatchQueries)
+	tests := []struct {
+		name        string
+		body        string
+		wantStatus  int
+		wantQueries []string
+	}{
+		{name: "one result per query", body: `{"queries": ["read a file", "write a file"]}`, wantStatus: http.StatusOK, wantQueries: []string{"read a file", "write a file"}},
+		{name: "no queries", body: `{"queries": []}`, wantStatus: http.StatusBadRequest},
+		{name: "empty query", body: `{"queries": ["read", ""]}`, wantStatus: http.StatusBadRequest},
+		{name: "too many queries", body: `{"queries": [` + tooMany + `]}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown provider", body: `{"queries": ["read"], "provider": "nope"}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := request(t, server, "POST", "/api/v1/discover/batch", "", tt.body)
+			if status != tt.wantStatus {
+				t.Fatalf("POST /discover/batch = %d %q, want %d", status, body, tt.wantStatus)
+			}
+			if status != http.StatusOK {
+				return
+			}
+			var resp types.BatchDiscoveryResponse
+			if err := json.Unmarshal([]byte(body), &resp); err != nil {
+				t.Fatalf("decoding %q: %v", body, err)
+			}
+			var queries []string
+			for _, result := range resp.Results {
+				queries = append(queries, result.Query)
+				if len(result.RecommendedTools) != 2 || result.Error != "" {
+					t.Errorf("result for %q = %+v, want both tools", result.Query, result)
+				}
+			}
+			if !reflect.DeepEqual(queries, tt.wantQueries) {
+				t.Errorf("result queries = %q, want %q", queries, tt.wantQueries)
+			}
+		})
+	}
+}
+
+func TestListToolsSince(t *testing.T) {
+	server := newTestServer(t, types.MCPConfig{}, Options{}, map[string]types.MCPClient{"files": newFakeClient("read", "write")})
+
+	tests := []struct {
+		name       string
+		since      string
+		wantStatus int
+		wantTools  int
+	}{
+		{name: "before the first sync", since: "2000-01-01T00:00:00Z", wantStatus: http.StatusOK, wantTools: 2},
+		{name: "after the last sync", since: time.Now().Add(time.Hour).UTC().Format(time.RFC3339), wantStatus: http.StatusOK},
+		{name: "invalid since", since: "yesterday", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := request(t, server, "GET", "/api/v1/tools?since="+url.QueryEscape(tt.since), "", "")
+			if status != tt.wantStatus {
+				t.Fatalf("GET /tools?since=%s = %d %q, want %d", tt.since, status, body, tt.wantStatus)
+			}
+			if status != http.StatusOK {
+				return
+			}
+			var listing types.ToolListing
+			if err := json.Unmarshal([]byte(body), &listing); err != nil {
+				t.Fatalf("decoding %q: %v", body, err)
+			}
+			if len(listing.RecommendedTools) != tt.wantTools || listing.LastSync.IsZero() {
+				t.Errorf("GET /tools?since=%s = %d tools synced at %s, want %d tools and the last sync", tt.since, len(listing.RecommendedTools), listing.LastSync, tt.wantTools)
+			}
+		})
+	}
+}
+
+func TestWatchTools(t *testing.T) {
+	server := newTestServer(t, types.MCPConfig{}, Options{}, map[string]types.MCPClient{"files": newFakeClient("read")})
+
+	// Without since the watch answers at once with the current sync time
+	lastSync := func() time.Time {
+		var listing types.ToolListing
+		_, body := request(t, server, "GET", "/api/v1/tools/watch", "", "")
+		if err := json.Unmarshal([]byte(body), &listing); err != nil {
+			t.Fatalf("decoding %q: %v", body, err)
+		}
+		return listing.LastSync
+	}
+	synced := lastSync()
+	since := url.QueryEscape(synced.Format(time.RFC3339Nano))
+
+	t.Run("unblocks after a refresh", func(t *testing.T) {
+		type answer struct {
+			status int
+			body   string
+		}
+		answers := make(chan answer, 1)
+		go func() {
+			status, body := request(t, server, "GET", "/api/v1/tools/watch?timeout=5s&since="+since, "", "")
+			answers <- answer{status, body}
+		}()
+
+		select {
+		case got := <-answers:
+			t.Fatalf("GET /tools/watch answered %d before a refresh, want it to wait", got.status)
+		case <-time.After(100 * time.Millisecond):
+		}
+		if status, body := request(t, server, "POST", "/api/v1/refresh", "", ""); status != http.StatusOK {
+			t.Fatalf("POST /refresh = %d %q", status, body)
+		}
+
+		got := <-answers
+		var changed types.ToolListing
+		if err := json.Unmarshal([]byte(got.body), &changed); got.status != http.StatusOK || err != nil {
+			t.Fatalf("GET /tools/watch after a refresh = %d %q, want the listing", got.status, got.body)
+		}
+		if !changed.LastSync.After(synced) || len(changed.RecommendedTools) != 1 {
+			t.Errorf("GET /tools/watch = %+v, want the read tool synced after %s", changed, synced)
+		}
+	})
+
+	since = url.QueryEscape(lastSync().Format(time.RFC3339Nano))
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{name: "times out without a change", query: "timeout=50ms&since=" + since, wantStatus: http.StatusNotModified},
+		{name: "older since answers at once", query: "timeout=5s&since=2000-01-01T00:00:00Z", wantStatus: http.StatusOK},
+		{name: "invalid since", query: "since=yesterday", wantStatus: http.StatusBadRequest},
+		{name: "invalid timeout", query: "timeout=-1s", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			status, body := request(t, server, "GET", "/api/v1/tools/watch?"+tt.query, "", "")
+			if status != tt.wantStatus {
+				t.Errorf("GET /tools/watch?%s = %d %q, want %d", tt.query, status, body, tt.wantStatus)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("GET /tools/watch?%s answered after %s, want it promptly", tt.query, elapsed)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	clients := map[string]types.MCPClient{"files": newFakeClient("read")}
+	lenient := newTestServer(t, types.MCPConfig{}, Options{}, clients)
+	strict := newTestServer(t, types.MCPConfig{}, Options{StrictJSON: true}, clients)
+
+	tests := []struct {
+		name   string
+		server *httptest.Server
+		path   string
+		body   string
+		want   string // in the 400 response; empty expects success
+	}{
+		{name: "type mismatch", server: lenient, path: "/api/v1/discover", body: `{"query": 42}`, want: `field "query" must be a string, not number`},
+		{name: "array for an object", server: lenient, path: "/api/v1/use/read", body: `{"arguments": ["a"]}`, want: `field "arguments" must be an object, not array`},
+		{name: "unknown field when strict", server: strict, path: "/api/v1/discover", body: `{"query": "read", "qurey": "x"}`, want: `unknown field "qurey" (offset`},
+		{name: "unknown field when lenient", server: lenient, path: "/api/v1/discover", body: `{"query": "read", "qurey": "x"}`},
+		{name: "malformed JSON", server: lenient, path: "/api/v1/discover", body: `{"query": "read",}`, want: "malformed JSON at offset 18"},
+		{name: "truncated body", server: lenient, path: "/api/v1/discover", body: `{"query": "re`, want: "unexpected end of JSON"},
+		{name: "not an object", server: lenient, path: "/api/v1/discover", body: `["read"]`, want: "body must be an object, not array"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := request(t, tt.server, "POST", tt.path, "", tt.body)
+			if tt.want == "" {
+				if status != http.StatusOK {
+					t.Errorf("POST %s %s = %d %q, want 200", tt.path, tt.body, status, body)
+				}
+				return
+			}
+			if status != http.StatusBadRequest || !strings.Contains(body, tt.want) {
+				t.Errorf("POST %s %s = %d %q, want 400 naming %q", tt.path, tt.body, status, body, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentType(t *testing.T) {
+	server := newTestServer(t, types.MCPConfig{}, Options{}, map[string]types.MCPClient{"files": newFakeClient("read")})
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		{name: "JSON", path: "/api/v1/discover", contentType: "application/json", body: `{"query": "read"}`, wantStatus: http.StatusOK},
+		{name: "JSON with a charset", path: "/api/v1/discover", contentType: "application/json; charset=utf-8", body: `{"query": "read"}`, wantStatus: http.StatusOK},
+		{name: "plain text", path: "/api/v1/discover", contentType: "text/plain", body: `{"query": "read"}`, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "form", path: "/api/v1/use/read", contentType: "application/x-www-form-urlencoded", body: "arguments=", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "no content type", path: "/api/v1/use/read", body: `{"arguments": {}}`, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "bodyless POST", path: "/api/v1/refresh", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", server.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			resp, err := server.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("POST %s as %q = %d, want %d", tt.path, tt.contentType, resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// failingClient serves the tools of errs, each call failing with the tool's error
+type failingClient struct {
+	errs map[string]error
+}
+
+func (c failingClient) ListTools(ctx context.Context) ([]types.Tool, error) {
+	var tools []types.Tool
+	for name := range c.errs {
+		tools = append(tools, types.Tool{Name: name, InputSchema: map[string]interface{}{"type": "object"}})
+	}
+	return tools, nil
+}
+
+func (c failingClient) CallTool(ctx context.Context, toolName string, arguments map[string]interface{}) (map[string]interface{}, error) {
+	return nil, c.errs[toolName]
+}
+
+func (failingClient) Close() error { return nil }
+
+func TestToolErrorClasses(t *testing.T) {
+	client := failingClient{errs: map[string]error{
+		"fail":  fmt.Errorf("%w: invalid path", mcp.ErrToolError),
+		"slow":  mcp.ErrTimeout,
+		"dead":  mcp.ErrClosed,
+		"crash": errors.New("unexpected reply"),
+	}}
+	server := newTestServer(t, types.MCPConfig{}, Options{}, map[string]types.MCPClient{"files": client})
+
+	tests := []struct {
+		tool       string
+		wantStatus int
+		wantClass  string
+	}{
+		{tool: "missing", wantStatus: http.StatusNotFound, wantClass: types.ErrorClassNotFound},
+		{tool: "dead", wantStatus: http.StatusServiceUnavailable, wantClass: types.ErrorClassUnavailable},
+		{tool: "fail", wantStatus: http.StatusUnprocessableEntity, wantClass: types.ErrorClassToolError},
+		{tool: "slow", wantStatus: http.StatusGatewayTimeout, wantClass: types.ErrorClassTimeout},
+		{tool: "crash", wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			status, body := request(t, server, "POST", "/api/v1/use/"+tt.tool, "", `{"arguments": {}}`)
+			var response types.ProxyResponse
+			if err := json.Unmarshal([]byte(body), &response); err != nil {
+				t.Fatalf("decoding %q: %v", body, err)
+			}
+			if status != tt.wantStatus || response.ErrorClass != tt.wantClass {
+				t.Errorf("POST /use/%s = %d with class %q, want %d with class %q", tt.tool, status, response.ErrorClass, tt.wantStatus, tt.wantClass)
+			}
+		})
+	}
+}
+
+// stalledProvider selects nothing until its context ends
+type stalledProvider struct{}
+
+func (stalledProvider) SelectBestTools(ctx context.Context, query string, tools []types.Tool) ([]types.Tool, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestDiscoverLLMTimeout(t *testing.T) {
+	config := types.MCPConfig{Proxy: types.ProxySettings{LLMTimeout: types.Duration(50 * time.Millisecond)}}
+	server := newTestServerWithProvider(t, config, Options{}, stalledProvider{}, map[string]types.MCPClient{"files": newFakeClient("read")})
+
+	for _, path := range []string{"/api/v1/discover", "/api/v1/discover/stream"} {
+		start := time.Now()
+		status, body := request(t, server, "POST", path, "", `{"query": "read a file"}`)
+		if status != http.StatusGatewayTimeout || !strings.Contains(body, "LLM") {
+			t.Errorf("POST %s with a stalled LLM = %d %q, want 504", path, status, body)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("POST %s answered after %s, want it to stop at the LLM timeout", path, elapsed)
+		}
+	}
+}
+
+func TestReady(t *testing.T) {
+	tests := []struct {
+		name    string
+		clients map[string]types.MCPClient
+		want    int // status after Initialize
+	}{
+		{name: "tools discovered", clients: map[string]types.MCPClient{"files": newFakeClient("read")}, want: http.StatusOK},
+		{name: "no tools discovered", clients: map[string]types.MCPClient{"files": newFakeClient()}, want: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := proxy.NewInMemory(types.MCPConfig{}, fakeProvider{}, tt.clients)
+			if err != nil {
+				t.Fatalf("NewInMemory() error = %v", err)
+			}
+			server := httptest.NewServer(New(p).Handler())
+			t.Cleanup(func() {
+				server.Close()
+				p.Close()
+			})
+
+			if status, body := request(t, server, "GET", "/api/v1/ready", "", ""); status != http.StatusServiceUnavailable {
+				t.Errorf("GET /ready before Initialize = %d %q, want 503", status, body)
+			}
+			if status, body := request(t, server, "GET", "/api/v1/health", "", ""); status != http.StatusOK {
+				t.Errorf("GET /health before Initialize = %d %q, want 200", status, body)
+			}
+
+			if err := p.Initialize(context.Background()); err != nil {
+				t.Fatalf("Initialize() error = %v", err)
+			}
+			if status, body := request(t, server, "GET", "/api/v1/ready", "", ""); status != tt.want {
+				t.Errorf("GET /ready after Initialize = %d %q, want %d", status, body, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestWriteToolList(t *testing.T) {
+	tools := []types.Tool{
+		{Name: "read", Description: "Read a file", ServerName: "files"},
+		{Name: "write", Description: "Write a file", ServerName: "files", Aliases: []string{"save"}},
+		{Name: "search", Description: "Search <code>", ServerName: "github"},
+	}
+
+	tests := []struct {
+		name    string
+		version string
+		tools   []types.Tool
+	}{
+		{name: "current", version: types.APIVersionCurrent, tools: tools},
+		{name: "legacy", version: types.APIVersionLegacy, tools: tools},
+		{name: "one tool", version: types.APIVersionCurrent, tools: tools[:1]},
+		{name: "empty", version: types.APIVersionCurrent},
+		{name: "empty legacy", version: types.APIVersionLegacy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s := NewWithOptions(nil, Options{})
+			s.writeToolList(w, httptest.NewRequest("GET", "/api/v1/tools", nil), tt.version, func(emit func(types.Tool) error) error {
+				for _, tool := range tt.tools {
+					if err := emit(tool); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+
+			// The streamed listing matches the envelope encoded in one piece
+			want, _ := json.Marshal(envelope(tt.version, types.ProxyResponse{RecommendedTools: tt.tools}))
+			if got := strings.TrimSpace(w.Body.String()); got != string(want) {
+				t.Errorf("writeToolList() = %s, want %s", got, want)
+			}
+			if w.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestWriteToolListStopsOnError(t *testing.T) {
+	w := httptest.NewRecorder()
+	s := NewWithOptions(nil, Options{})
+	s.writeToolList(w, httptest.NewRequest("GET", "/api/v1/tools", nil), types.APIVersionCurrent, func(emit func(types.Tool) error) error {
+		if err := emit(types.Tool{Name: "read"}); err != nil {
+			return err
+		}
+		return errors.New("catalog went away")
+	})
+
+	// The tool written so far is sent, but the listing is left unterminated
+	body := w.Body.String()
+	if !strings.Contains(body, `"name":"read"`) || strings.HasSuffix(body, "]}\n") {
+		t.Errorf("body = %q, want the read tool in an unterminated listing", body)
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+// responseVersion picks the envelope version for a request's ProxyResponse: ?apiVersion= wins,
+// then a version parameter on an Accept media type (e.g. "application/json; version=1"), then
+// the current version. An unsupported version writes 400 for the query parameter or 406 for
+// the Accept header and returns false
+func responseVersion(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if version := r.URL.Query().Get("apiVersion"); version != "" {
+		if !supportedVersion(version) {
+			http.Error(w, unsupportedVersion(version), http.StatusBadRequest)
+			return "", false
+		}
+		return version, true
+	}
+
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		_, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil || params["version"] == "" {
+			continue
+		}
+		if !supportedVersion(params["version"]) {
+			http.Error(w, unsupportedVersion(params["version"]), http.StatusNotAcceptable)
+			return "", false
+		}
+		return params["version"], true
+	}
+	return types.APIVersionCurrent, true
+}
+
+// supportedVersion reports whether the proxy can produce the given envelope version
+func supportedVersion(version string) bool {
+	return version == types.APIVersionLegacy || version == types.APIVersionCurrent
+}
+
+// unsupportedVersion is the error message for a version the proxy cannot produce
+func unsupportedVersion(version string) string {
+	return fmt.Sprintf("Unsupported API version %q: expected %s or %s", version, types.APIVersionLegacy, types.APIVersionCurrent)
+}
+
+// envelope shapes a response for the given version: the current envelope carries apiVersion and
+// every field, while the legacy one keeps only the fields it always had, including the debug
+// report of /discover?debug=true
+func envelope(version string, response types.ProxyResponse) types.ProxyResponse {
+	if version == types.APIVersionLegacy {
+		return types.ProxyResponse{
+			RecommendedTools: response.RecommendedTools,
+			Result:           response.Result,
+			Debug:            response.Debug,
+			Error:            response.Error,
+		}
+	}
+	response.APIVersion = types.APIVersionCurrent
+	return response
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"mcp-smart-proxy/pkg/types"
+)
+
+func TestResponseVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		accept      string
+		wantVersion string
+		wantStatus  int // status written for an unsupported version, 0 when accepted
+	}{
+		{name: "default", wantVersion: types.APIVersionCurrent},
+		{name: "query parameter", query: "?apiVersion=1", wantVersion: types.APIVersionLegacy},
+		{name: "accept parameter", accept: "application/json; version=1", wantVersion: types.APIVersionLegacy},
+		{name: "accept list", accept: "text/html, application/json;version=2", wantVersion: types.APIVersionCurrent},
+		{name: "query wins over accept", query: "?apiVersion=2", accept: "application/json; version=1", wantVersion: types.APIVersionCurrent},
+		{name: "accept without version", accept: "application/json", wantVersion: types.APIVersionCurrent},
+		{name: "unsupported query", query: "?apiVersion=3", wantStatus: http.StatusBadRequest},
+		{name: "unsupported accept", accept: "application/json; version=0", wantStatus: http.StatusNotAcceptable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/v1/tools"+tt.query, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+
+			version, ok := responseVersion(w, r)
+			if ok != (tt.wantStatus == 0) {
+				t.Fatalf("responseVersion() ok = %v, want %v", ok, tt.wantStatus == 0)
+			}
+			if !ok {
+				if w.Code != tt.wantStatus {
+					t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+				}
+				return
+			}
+			if version != tt.wantVersion {
+				t.Errorf("responseVersion() = %s, want %s", version, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	full := types.ProxyResponse{
+		RecommendedTools: []types.Tool{{Name: "read"}},
+		Result:           map[string]interface{}{"content": []interface{}{}},
+		Debug:            &types.DiscoveryDebug{},
+		Error:            "failed",
+		Missing:          []types.MissingArgument{{Name: "path"}},
+		CallID:           "call-1",
+		ErrorClass:       "tool_error",
+	}
+
+	tests := []struct {
+		name    string
+		version string
+		want    types.ProxyResponse
+	}{
+		{
+			name:    "legacy keeps its fields",
+			version: types.APIVersionLegacy,
+			want: types.ProxyResponse{
+				RecommendedTools: full.RecommendedTools,
+				Result:           full.Result,
+				Debug:            full.Debug,
+				Error:            full.Error,
+			},
+		},
+		{
+			name:    "current keeps everything",
+			version: types.APIVersionCurrent,
+			want: func() types.ProxyResponse {
+				response := full
+				response.APIVersion = types.APIVersionCurrent
+				return response
+			}(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := envelope(tt.version, full); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("envelope(%s) = %+v, want %+v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,28 +3,245 @@ package types
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
 // MCPServer represents a configured MCP server
 type MCPServer struct {
-	Name    string            `json:"name"`
-	Command string            `json:"command"`
-	Args    []string          `json:"args"`
-	Env     map[string]string `json:"env"`
+	Name      string            `json:"name"`
+	Transport string            `json:"transport,omitempty"` // client transport; defaults to "stdio"
+	Command   string            `json:"command"`
+	Args      []string          `json:"args"`
+	Env       map[string]string `json:"env"`
+	Roots     []Root            `json:"roots,omitempty"`
+	// Sampling lets the server ask the proxy's default LLM provider for completions
+	Sampling bool `json:"sampling,omitempty"`
+	// Batch sends initialize and the first tools/list as one JSON-RPC batch
+	Batch bool `json:"batch,omitempty"`
+	// LogLevel asks a server with the logging capability for messages at this level and above
+	LogLevel string `json:"logLevel,omitempty"`
+	// Tags group servers by domain so discovery can be scoped to one group
+	Tags []string `json:"tags,omitempty"`
+	// Required makes Initialize fail when this server cannot be connected, instead of skipping it
+	Required bool `json:"required,omitempty"`
+	// Per-server overrides of the proxy-wide timeout and retry settings
+	ConnectTimeout Duration `json:"connectTimeout,omitempty"`
+	ReadTimeout    Duration `json:"readTimeout,omitempty"`
+	CancelGrace    Duration `json:"cancelGrace,omitempty"`
+	Retries        *int     `json:"retries,omitempty"`
+	RetryBackoff   Duration `json:"retryBackoff,omitempty"`
+	// RequestIDPrefix makes the proxy send string request ids like "<prefix>1" instead of integers,
+	// for servers that confuse their own request ids with the client's
+	RequestIDPrefix string `json:"requestIdPrefix,omitempty"`
+	// ClientInfo overrides the name and version the proxy introduces itself with in initialize
+	ClientInfo *ClientInfo `json:"clientInfo,omitempty"`
+	// Capabilities are merged over the client capabilities the proxy advertises in initialize;
+	// a capability set to null is removed
+	Capabilities map[string]interface{} `json:"capabilities,omitempty"`
+	// Limits constrains the server's process (Linux only)
+	Limits *ResourceLimits `json:"limits,omitempty"`
+	// Weight biases the ranking of selected tools toward this server's (default 1)
+	Weight float64 `json:"weight,omitempty"`
+	// SelectionHint is extra context for the LLM about this server's tools, e.g. "use only for AWS"
+	SelectionHint string `json:"selectionHint,omitempty"`
+	// MaxConcurrency caps the proxy's calls in flight to this server; further calls queue (0 means no limit)
+	MaxConcurrency int `json:"maxConcurrency,omitempty"`
+	// MaxRestarts is how many times in a row the proxy restarts the server after it stops
+	// responding before giving up on it (default 5, 0 never restarts)
+	MaxRestarts *int `json:"maxRestarts,omitempty"`
+	// HealthCheck names a cheap tool the deep health check calls to verify the server works
+	HealthCheck *HealthCheck `json:"healthCheck,omitempty"`
+}
+
+// HealthCheck describes the tool call that proves a server answers sensibly, not just that it is up
+type HealthCheck struct {
+	Tool      string                 `json:"tool"` // name as the server knows it, without any namespace
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Expect    string                 `json:"expect,omitempty"`  // text the result must contain; any successful result passes when empty
+	Timeout   Duration               `json:"timeout,omitempty"` // default 5s
+}
+
+// ResourceLimits constrains an MCP server subprocess; zero fields leave the inherited setting
+type ResourceLimits struct {
+	MemoryMB int `json:"memoryMB,omitempty"` // address space limit (RLIMIT_AS) in megabytes
+	Nice     int `json:"nice,omitempty"`     // scheduling priority; negative values need CAP_SYS_NICE
+}
+
+// ClientInfo identifies the MCP client in the initialize request; empty fields keep the defaults
+type ClientInfo struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// Root is a filesystem root offered to a server through the MCP roots capability
+type Root struct {
+	URI  string `json:"uri"`
+	Name string `json:"name,omitempty"`
 }
 
 // MCPConfig represents the mcp.json configuration
 type MCPConfig struct {
 	MCPServers map[string]MCPServer `json:"mcpServers"`
+	Proxy      ProxySettings        `json:"proxy,omitempty"`
+}
+
+// ProxySettings holds optional proxy behaviour configured alongside the servers
+type ProxySettings struct {
+	RequireConfirmation bool     `json:"requireConfirmation,omitempty"`
+	DestructivePatterns []string `json:"destructivePatterns,omitempty"` // path.Match patterns on tool names
+	SecretsFile         string   `json:"secretsFile,omitempty"`         // JSON file resolving "secret:<name>" env values
+	Debug               bool     `json:"debug,omitempty"`               // enables /discover?debug=true
+	Passthrough         bool     `json:"passthrough,omitempty"`         // enables raw JSON-RPC requests through /servers/{name}/rpc
+	ServerManagement    bool     `json:"serverManagement,omitempty"`    // enables adding and removing servers through /servers; needs an admin tenant
+	SearchTool          bool     `json:"searchTool,omitempty"`          // adds the search_tools tool, which runs discovery through a tool call
+
+	Providers       map[string]LLMProviderConfig `json:"providers,omitempty"`       // named LLM providers; env-based provider when empty
+	DefaultProvider string                       `json:"defaultProvider,omitempty"` // provider used when a request names none
+	MaxTools        *int                         `json:"maxTools,omitempty"`        // tools returned per selection (default 5); 0 means no limit
+	PinnedTools     []string                     `json:"pinnedTools,omitempty"`     // tools always placed first in discovery results
+	Models          map[string]map[string]string `json:"models,omitempty"`          // provider type -> tier (fast, balanced, best) -> model
+	DefaultTier     string                       `json:"defaultTier,omitempty"`     // tier used when a request names none (default balanced)
+
+	PromptTemplate     string `json:"promptTemplate,omitempty"`     // text/template for the selection prompt; see llm.PromptData
+	PromptTemplateFile string `json:"promptTemplateFile,omitempty"` // file holding the template, used when promptTemplate is empty
+
+	ConnectTimeout Duration `json:"connectTimeout,omitempty"` // limit for each server's initialize handshake
+	ReadTimeout    Duration `json:"readTimeout,omitempty"`    // limit for each individual server response
+	CancelGrace    Duration `json:"cancelGrace,omitempty"`    // time a server gets to answer a ping after a cancelled call (default 2s)
+	LLMTimeout     Duration `json:"llmTimeout,omitempty"`     // limit for the LLM call of each tool selection (default 20s)
+
+	ResultCacheTTL Duration `json:"resultCacheTTL,omitempty"` // caches read-only tool results for this long; 0 disables
+	MaxResultBytes int      `json:"maxResultBytes,omitempty"` // largest message accepted from a server (default 32MB)
+	MaxServerTools *int     `json:"maxServerTools,omitempty"` // tools accepted from one server (default 1000); 0 means no limit
+
+	Retries      int      `json:"retries,omitempty"`      // extra attempts for tool calls failing with transient errors
+	RetryBackoff Duration `json:"retryBackoff,omitempty"` // delay before the first retry, doubled for each further attempt
+
+	BreakerThreshold int      `json:"breakerThreshold,omitempty"` // consecutive server failures that open its circuit breaker; 0 disables
+	BreakerCooldown  Duration `json:"breakerCooldown,omitempty"`  // how long an open breaker rejects calls before probing (default 30s)
+
+	Arguments     ArgumentRule            `json:"arguments,omitempty"`     // argument key policy applied to every tool
+	ToolArguments map[string]ArgumentRule `json:"toolArguments,omitempty"` // per-tool policies; allow overrides the global allow
+
+	CoerceArguments bool `json:"coerceArguments,omitempty"` // convert argument values to the types declared in the tool's input schema
+
+	Transforms     []string            `json:"transforms,omitempty"`     // result transforms applied to every tool, in order
+	ToolTransforms map[string][]string `json:"toolTransforms,omitempty"` // per-tool transforms, run after the global ones
+
+	Tenants map[string]Tenant `json:"tenants,omitempty"` // API principals by name; when set, every API request needs a key
+
+	PersistServers bool `json:"persistServers,omitempty"` // write servers added or removed through the API back to the config file
+
+	DeduplicateTools bool `json:"deduplicateTools,omitempty"` // same-named tools with identical schemas become one tool with failover
+
+	NamespaceSeparator string `json:"namespaceSeparator,omitempty"` // expose tools as <server><separator><tool>, e.g. "."; empty keeps bare names
+
+	Aliases map[string]string `json:"aliases,omitempty"` // alias -> tool name; lets agents call a tool by a stable or friendlier name
+
+	Selector         string `json:"selector,omitempty"`         // selection strategy: llm (default), keyword, embeddings or hybrid
+	HybridCandidates int    `json:"hybridCandidates,omitempty"` // tools the hybrid strategy's keyword pass hands to the LLM (default 20)
+
+	PromptTokenBudget int `json:"promptTokenBudget,omitempty"` // estimated tokens of tools per LLM selection call; larger catalogs are chunked. 0 means no limit
+	ChunkConcurrency  int `json:"chunkConcurrency,omitempty"`  // chunks of a chunked catalog selected from at once (default 4)
+
+	EmbeddingCacheFile string `json:"embeddingCacheFile,omitempty"` // file keeping tool embeddings across restarts for the embeddings selector
+
+	EnrichDescriptions bool `json:"enrichDescriptions,omitempty"` // have the default LLM write fuller descriptions of sparse tools for selection
+	EnrichMinLength    int  `json:"enrichMinLength,omitempty"`    // descriptions shorter than this are enriched (default 40 characters)
+}
+
+// ArgumentRule restricts which top-level argument keys a tool call may carry
+type ArgumentRule struct {
+	Allow []string `json:"allow,omitempty"` // when set, only these keys are accepted
+	Deny  []string `json:"deny,omitempty"`  // keys that are always rejected
+}
+
+// Tenant is an API principal and the tools it may see and call
+type Tenant struct {
+	APIKey  string   `json:"apiKey"`            // key presented as a bearer token or X-API-Key; may be a secret reference
+	Tools   []string `json:"tools,omitempty"`   // tools the tenant may use
+	Servers []string `json:"servers,omitempty"` // servers whose tools the tenant may use; with no tools or servers, everything is allowed
+	Admin   bool     `json:"admin,omitempty"`   // may manage the proxy: servers, config, refreshes and every tenant's calls
+}
+
+// Duration is a time.Duration that unmarshals from a Go duration string ("5s") or a number of seconds
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		*d = Duration(seconds * float64(time.Second))
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("invalid duration %s", data)
+	}
+
+	parsed, err := time.ParseDuration(str)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// LLMProviderConfig configures one named LLM provider
+type LLMProviderConfig struct {
+	Type      string `json:"type"`                // "openai" or "gemini"
+	Model     string `json:"model,omitempty"`     // pins one model for every tier, bypassing the model registry
+	APIKeyEnv string `json:"apiKeyEnv,omitempty"` // env var holding the API key; defaults to OPENAI_API_KEY / GEMINI_API_KEY
 }
 
 // Tool represents a tool from an MCP server
 type Tool struct {
-	Name        string      `json:"name"`
-	Description string      `json:"description"`
-	InputSchema interface{} `json:"inputSchema"`
-	ServerName  string      `json:"serverName"`
+	Name         string           `json:"name"`
+	Description  string           `json:"description"`
+	InputSchema  interface{}      `json:"inputSchema"`
+	OutputSchema interface{}      `json:"outputSchema,omitempty"` // shape of structuredContent, when declared
+	Annotations  *ToolAnnotations `json:"annotations,omitempty"`
+	ServerName   string           `json:"serverName"`
+	Server       *ToolServer      `json:"server,omitempty"`     // set in /tools listings only
+	Aliases      []string         `json:"aliases,omitempty"`    // alternative names from proxy.aliases, set in /tools listings only
+	FirstSeen    *time.Time       `json:"firstSeen,omitempty"`  // when the tool appeared in the cache, set in /tools listings only
+	ModifiedAt   *time.Time       `json:"modifiedAt,omitempty"` // when the tool appeared or last changed, set in /tools listings only
+}
+
+// ToolServer describes the server behind a tool in /tools listings
+type ToolServer struct {
+	Transport string `json:"transport"`
+	Connected bool   `json:"connected"`
+	Healthy   bool   `json:"healthy"` // connected and its circuit breaker is not open
+}
+
+// ToolAnnotations holds the optional behaviour hints an MCP server reports for a tool
+type ToolAnnotations struct {
+	Title           string `json:"title,omitempty"`
+	ReadOnlyHint    *bool  `json:"readOnlyHint,omitempty"`
+	DestructiveHint *bool  `json:"destructiveHint,omitempty"`
+	IdempotentHint  *bool  `json:"idempotentHint,omitempty"`
+	OpenWorldHint   *bool  `json:"openWorldHint,omitempty"`
+}
+
+// IsReadOnly reports whether the server flagged the tool as not modifying its environment
+func (t Tool) IsReadOnly() bool {
+	return t.Annotations != nil && t.Annotations.ReadOnlyHint != nil && *t.Annotations.ReadOnlyHint
+}
+
+// IsDestructive reports whether the server flagged the tool as destructive
+func (t Tool) IsDestructive() bool {
+	return t.Annotations != nil && t.Annotations.DestructiveHint != nil && *t.Annotations.DestructiveHint
 }
 
 // ToolCache manages cached tools from all servers
@@ -34,21 +251,342 @@ type ToolCache struct {
 	ServerMap map[string]string `json:"serverMap"` // tool name -> server name
 }
 
+// ToolDiff describes how the tool catalog changed during a refresh
+type ToolDiff struct {
+	Added      []string  `json:"added"`
+	Removed    []string  `json:"removed"`
+	Changed    []string  `json:"changed"` // description, schema, annotations or server changed
+	ComputedAt time.Time `json:"computedAt"`
+}
+
+// Empty reports whether the refresh changed nothing
+func (d *ToolDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// ServerStatus describes a configured MCP server as reported by /servers
+type ServerStatus struct {
+	Name                string `json:"name"`
+	Connected           bool   `json:"connected"`
+	Tools               int    `json:"tools"`
+	Breaker             string `json:"breaker"` // closed, open or half-open
+	ConsecutiveFailures int    `json:"consecutiveFailures"`
+	// Error is why the last attempt to connect the server failed, cleared once it connects
+	Error string `json:"error,omitempty"`
+	// Restarts counts recent attempts to restart the server after it stopped responding
+	Restarts int `json:"restarts,omitempty"`
+	// Failed reports that the proxy gave up restarting the server; a refresh tries it again
+	Failed bool `json:"failed,omitempty"`
+	// Collisions lists the server's tool names that other servers use too
+	Collisions []ToolCollision `json:"collisions,omitempty"`
+}
+
+// ToolCollision is a tool name offered by more than one server
+type ToolCollision struct {
+	Tool    string   `json:"tool"`
+	Servers []string `json:"servers"` // every server offering the name, sorted
+}
+
+// HealthReport is the detailed health summary served by /health?detail=true
+type HealthReport struct {
+	Status           string         `json:"status"` // ok, or degraded when a configured server is not connected
+	Tools            int            `json:"tools"`
+	ConnectedServers int            `json:"connectedServers"`
+	Servers          map[string]int `json:"servers"` // tool count per configured server
+	LastSync         time.Time      `json:"lastSync"`
+
+	Startup *StartupSummary `json:"startup,omitempty"` // outcome of Initialize
+}
+
+// DeepHealthReport is the outcome of calling every configured health check tool
+type DeepHealthReport struct {
+	Status  string                      `json:"status"`  // ok, or degraded when any check did not pass; busy servers do not count
+	Servers map[string]DeepHealthResult `json:"servers"` // by server name, for servers with a healthCheck
+	Checked time.Time                   `json:"checked"`
+}
+
+// DeepHealthResult is the outcome of one server's health check
+type DeepHealthResult struct {
+	Status  string   `json:"status"` // ok, degraded (answered wrongly), down (did not answer) or busy (not checked)
+	Error   string   `json:"error,omitempty"`
+	Latency Duration `json:"latency"`
+}
+
+// StartupSummary describes the outcome of connecting the configured servers in Initialize
+type StartupSummary struct {
+	Attempted int               `json:"attempted"`
+	Connected int               `json:"connected"`
+	Failed    map[string]string `json:"failed,omitempty"` // server name -> why it could not be connected
+	Tools     int               `json:"tools"`
+	Duration  Duration          `json:"duration"`
+	Finished  time.Time         `json:"finished"`
+}
+
+// UsageStats reports tool and server usage since the proxy started, as served by /stats
+type UsageStats struct {
+	Tools   map[string]UsageCounter `json:"tools"`
+	Servers map[string]UsageCounter `json:"servers"`
+}
+
+// UsageCounter counts invocations of one tool or server
+type UsageCounter struct {
+	Calls     int64     `json:"calls"`
+	Errors    int64     `json:"errors"`
+	ErrorRate float64   `json:"errorRate"` // errors / calls
+	LastUsed  time.Time `json:"lastUsed"`
+}
+
 // ProxyRequest represents a request to discover tools
 type ProxyRequest struct {
-	Query string `json:"query"`
+	Query    string `json:"query"`
+	Provider string `json:"provider,omitempty"` // named LLM provider; defaults to proxy.defaultProvider
+	Tier     string `json:"tier,omitempty"`     // model tier: fast, balanced or best; defaults to proxy.defaultTier
+	Tag      string `json:"tag,omitempty"`      // only consider tools from servers carrying this tag
+	// Exclude lists tools to leave out of the candidates, e.g. ones that just failed, so a
+	// repeated discovery recommends alternatives
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// ToolListing is the tool listing returned by /tools/watch and /tools?since=, with the sync it reflects
+type ToolListing struct {
+	RecommendedTools []Tool    `json:"recommendedTools"`
+	Removed          []string  `json:"removed,omitempty"` // tools removed after since, in /tools?since= deltas
+	LastSync         time.Time `json:"lastSync"`          // pass as since to wait for the next change
+}
+
+// BatchDiscoveryRequest asks for tool recommendations for several queries against one catalog snapshot
+type BatchDiscoveryRequest struct {
+	Queries  []string `json:"queries"`
+	Provider string   `json:"provider,omitempty"` // as in ProxyRequest, shared by every query
+	Tier     string   `json:"tier,omitempty"`
+	Tag      string   `json:"tag,omitempty"`
+}
+
+// DiscoveryResult is the recommendation for one query of a batch
+type DiscoveryResult struct {
+	Query            string `json:"query"`
+	RecommendedTools []Tool `json:"recommendedTools"`
+	Error            string `json:"error,omitempty"` // set when selection failed for this query
+}
+
+// BatchDiscoveryResponse holds one result per query, in request order
+type BatchDiscoveryResponse struct {
+	Results []DiscoveryResult `json:"results"`
 }
 
 // ToolRequest represents a request to use a tool
 type ToolRequest struct {
+	Tool      string                 `json:"tool,omitempty"` // alternative to the path segment for names with unsafe characters
 	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Confirm   bool                   `json:"confirm,omitempty"`
+	NoCache   bool                   `json:"noCache,omitempty"` // bypass the read-only result cache
+	CallID    string                 `json:"callId,omitempty"`  // ID for cancelling the call; a fresh one is generated when unset
 }
 
+// RPCRequest is a raw JSON-RPC request forwarded unchanged to one server
+type RPCRequest struct {
+	Method string      `json:"method"`
+	Params interface{} `json:"params,omitempty"`
+}
+
+// InFlightCall describes a running tool call as listed by /calls
+type InFlightCall struct {
+	ID        string    `json:"id"`
+	Tool      string    `json:"tool"`
+	Tenant    string    `json:"tenant,omitempty"`    // principal that made the call; empty when authentication is off
+	RequestID string    `json:"requestId,omitempty"` // request the call was made in, to find calls started without a callId
+	Started   time.Time `json:"started"`
+}
+
+// Response envelope versions, chosen per request with ?apiVersion= or an Accept version parameter
+const (
+	// APIVersionLegacy is the original envelope: recommendedTools, result, debug and error only, without apiVersion
+	APIVersionLegacy = "1"
+	// APIVersionCurrent is the full envelope, labelled with apiVersion
+	APIVersionCurrent = "2"
+)
+
 // ProxyResponse represents the response from the proxy
 type ProxyResponse struct {
+	APIVersion       string                 `json:"apiVersion,omitempty"` // envelope version; absent in the legacy envelope
 	RecommendedTools []Tool                 `json:"recommendedTools,omitempty"`
 	Result           map[string]interface{} `json:"result,omitempty"`
+	Debug            *DiscoveryDebug        `json:"debug,omitempty"`
 	Error            string                 `json:"error,omitempty"`
+	Missing          []MissingArgument      `json:"missing,omitempty"` // required arguments the call left out
+	CallID           string                 `json:"callId,omitempty"`  // ID the tool call was tracked under
+	ErrorClass       string                 `json:"errorClass,omitempty"`
+}
+
+// OpenAIToolExport is the tool catalog as OpenAI function definitions, shaped like the tools field
+// of a chat completion request
+type OpenAIToolExport struct {
+	Tools []OpenAITool      `json:"tools"`
+	Names map[string]string `json:"names,omitempty"` // exported name -> proxy tool name, for names that had to change
+}
+
+// OpenAITool is one tool in OpenAI's function-calling format
+type OpenAITool struct {
+	Type     string         `json:"type"` // always "function"
+	Function OpenAIFunction `json:"function"`
+}
+
+// OpenAIFunction describes a callable function to an OpenAI model
+type OpenAIFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+// AnthropicToolExport is the tool catalog in Anthropic's tool-use format, shaped like the tools
+// field of a Messages API request
+type AnthropicToolExport struct {
+	Tools []AnthropicTool   `json:"tools"`
+	Names map[string]string `json:"names,omitempty"` // exported name -> proxy tool name, for names that had to change
+}
+
+// AnthropicTool is one tool in Anthropic's tool-use format
+type AnthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+// MissingArgument describes a required tool argument absent from a call, so the caller can supply it
+type MissingArgument struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// DiscoveryDebug describes exactly what was exchanged with the LLM during tool selection
+type DiscoveryDebug struct {
+	Prompt         string `json:"prompt"`
+	CandidateTools []Tool `json:"candidateTools"`
+	RawResponse    string `json:"rawResponse"`
+	SelectedTools  []Tool `json:"selectedTools"`
+}
+
+// ErrUnknownProvider is returned when a request names an LLM provider that is not configured
+var ErrUnknownProvider = errors.New("unknown LLM provider")
+
+// ErrNoToolsAvailable is returned by discovery when no server contributed any tools
+var ErrNoToolsAvailable = errors.New("no tools available: no MCP server is connected or none reported tools")
+
+// ErrToolNotFound is returned when a request names a tool that is not in the cache
+var ErrToolNotFound = errors.New("tool not found")
+
+// ErrServerExists is returned when adding a server whose name is already configured
+var ErrServerExists = errors.New("server already exists")
+
+// ErrServerNotFound is returned when a request names a server that is not configured
+var ErrServerNotFound = errors.New("server not found")
+
+// ErrUnknownTier is returned when a request or the config names a model tier other than fast, balanced or best
+var ErrUnknownTier = errors.New("unknown model tier")
+
+// ErrDebugDisabled is returned when debug discovery is requested but not enabled in the config
+var ErrDebugDisabled = errors.New("debug discovery is disabled; set proxy.debug in the config to enable it")
+
+// ErrPassthroughDisabled is returned when a raw JSON-RPC request is sent but passthrough is not enabled in the config
+var ErrPassthroughDisabled = errors.New("JSON-RPC passthrough is disabled; set proxy.passthrough in the config to enable it")
+
+// ErrMethodNotPassable is returned for JSON-RPC methods the passthrough refuses to forward
+var ErrMethodNotPassable = errors.New("method cannot be passed through")
+
+// ErrServerManagementDisabled is returned when a server is added or removed but server management is not enabled in the config
+var ErrServerManagementDisabled = errors.New("server management is disabled; set proxy.serverManagement in the config to enable it")
+
+// ErrCircuitOpen is returned when calls to a server are short-circuited after repeated failures
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// ErrUnauthorized is returned when tenants are configured and a request carries no valid API key
+var ErrUnauthorized = errors.New("missing or invalid API key")
+
+// ErrForbidden is returned when a tenant without admin rights asks for an administrative operation
+var ErrForbidden = errors.New("this operation needs an admin API key")
+
+// ErrCallNotFound is returned when cancelling a call that is not in flight
+var ErrCallNotFound = errors.New("call not found")
+
+// ErrCallIDInUse is returned when a tool call reuses the ID of a call still in flight
+var ErrCallIDInUse = errors.New("call ID already in use")
+
+// ErrCallCancelled is returned by a tool call aborted through the cancellation API
+var ErrCallCancelled = errors.New("call cancelled")
+
+// ErrLLMTimeout is returned when tool selection does not finish within proxy.llmTimeout
+var ErrLLMTimeout = errors.New("LLM selection timed out")
+
+// ErrServerUnavailable is returned when a tool call cannot reach a working server for the tool
+var ErrServerUnavailable = errors.New("server unavailable")
+
+// ErrToolFailed is returned when the server executed a tool call and answered with an error
+var ErrToolFailed = errors.New("tool call failed")
+
+// ErrToolTimeout is returned when a tool call does not finish in time
+var ErrToolTimeout = errors.New("tool call timed out")
+
+// ErrNotReadOnly is returned when a tool without readOnlyHint is called through GET
+var ErrNotReadOnly = errors.New("tool is not read-only; call it with POST")
+
+// Error classes reported in ProxyResponse.ErrorClass for failed tool calls
+const (
+	ErrorClassNotFound    = "not_found"
+	ErrorClassUnavailable = "unavailable"
+	ErrorClassToolError   = "tool_error"
+	ErrorClassTimeout     = "timeout"
+)
+
+// ErrorClass classifies a tool call error, returning "" when it falls outside the known classes
+func ErrorClass(err error) string {
+	switch {
+	case errors.Is(err, ErrToolNotFound):
+		return ErrorClassNotFound
+	case errors.Is(err, ErrServerUnavailable), errors.Is(err, ErrCircuitOpen):
+		return ErrorClassUnavailable
+	case errors.Is(err, ErrToolFailed):
+		return ErrorClassToolError
+	case errors.Is(err, ErrToolTimeout):
+		return ErrorClassTimeout
+	default:
+		return ""
+	}
+}
+
+// ConfirmationRequiredError is returned when a destructive tool is called without confirm set
+type ConfirmationRequiredError struct {
+	Tool   string
+	Reason string
+}
+
+func (e *ConfirmationRequiredError) Error() string {
+	return fmt.Sprintf("tool %s requires confirmation (%s): resend the request with \"confirm\": true", e.Tool, e.Reason)
+}
+
+// ForbiddenArgumentError is returned when a tool call carries an argument key rejected by policy
+type ForbiddenArgumentError struct {
+	Tool     string
+	Argument string
+	Reason   string
+}
+
+func (e *ForbiddenArgumentError) Error() string {
+	return fmt.Sprintf("argument %q for tool %s is not permitted: %s", e.Argument, e.Tool, e.Reason)
+}
+
+// MissingArgumentsError is returned when a tool call omits arguments its input schema requires
+type MissingArgumentsError struct {
+	Tool    string
+	Missing []MissingArgument
+}
+
+func (e *MissingArgumentsError) Error() string {
+	names := make([]string, len(e.Missing))
+	for i, argument := range e.Missing {
+		names[i] = argument.Name
+	}
+	return fmt.Sprintf("tool %s is missing required arguments: %s", e.Tool, strings.Join(names, ", "))
 }
 
 // LLMProvider interface for different LLM providers
@@ -56,9 +594,70 @@ type LLMProvider interface {
 	SelectBestTools(ctx context.Context, query string, availableTools []Tool) ([]Tool, error)
 }
 
+// DebugLLMProvider is implemented by providers that can report the prompt and raw response behind a selection
+type DebugLLMProvider interface {
+	LLMProvider
+	SelectBestToolsDebug(ctx context.Context, query string, availableTools []Tool) (*DiscoveryDebug, error)
+}
+
+// Selector is a tool selection strategy that ranks the tools most relevant to a query
+type Selector interface {
+	Select(ctx context.Context, query string, tools []Tool) ([]Tool, error)
+}
+
+// Embedder is implemented by LLM providers that can compute text embeddings
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// StreamingLLMProvider is implemented by providers that can report selected tools while the
+// completion is still arriving; emit is called once per tool in ranked order, and an error it
+// returns stops the selection and is returned as is
+type StreamingLLMProvider interface {
+	LLMProvider
+	SelectBestToolsStream(ctx context.Context, query string, availableTools []Tool, emit func(Tool) error) error
+}
+
+// SamplingMessage is a single text message in an MCP sampling conversation
+type SamplingMessage struct {
+	Role string `json:"role"` // "user" or "assistant"
+	Text string `json:"text"`
+}
+
+// SamplingRequest is a completion requested by an MCP server through sampling/createMessage
+type SamplingRequest struct {
+	Messages     []SamplingMessage `json:"messages"`
+	SystemPrompt string            `json:"systemPrompt,omitempty"`
+	MaxTokens    int               `json:"maxTokens"`
+	Temperature  *float64          `json:"temperature,omitempty"`
+}
+
+// SamplingResult is the assistant message produced for a SamplingRequest
+type SamplingResult struct {
+	Text       string `json:"text"`
+	Model      string `json:"model"`
+	StopReason string `json:"stopReason,omitempty"`
+}
+
+// CompletionProvider is implemented by LLM providers that can serve MCP sampling requests
+type CompletionProvider interface {
+	CreateMessage(ctx context.Context, req SamplingRequest) (*SamplingResult, error)
+}
+
+// SecretProvider resolves named secrets referenced from server env values
+type SecretProvider interface {
+	GetSecret(name string) (string, error)
+}
+
 // MCPClient interface for interacting with MCP servers
 type MCPClient interface {
 	ListTools(ctx context.Context) ([]Tool, error)
 	CallTool(ctx context.Context, toolName string, arguments map[string]interface{}) (map[string]interface{}, error)
 	Close() error
-}
\ No newline at end of file
+}
+
+// RawMCPClient is implemented by clients that can send requests for arbitrary MCP methods,
+// returning the JSON-RPC response unchanged
+type RawMCPClient interface {
+	Request(ctx context.Context, method string, params interface{}) (map[string]interface{}, error)
+}